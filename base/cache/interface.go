@@ -7,4 +7,7 @@ type RemoteCache interface {
 	Get(key string) ([]byte, error)
 	GetOrSet(key string, gen RemoteEntryGenerator) ([]byte, error)
 	Set(key string, value interface{}, ttl time.Duration) error
+	// SetNX sets key only if it does not exist, and reports whether it is set.
+	SetNX(key string, value interface{}, ttl time.Duration) (bool, error)
+	Delete(key string) error
 }
@@ -14,6 +14,20 @@ type RemoteCache struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: key
+func (_m *RemoteCache) Delete(key string) error {
+	ret := _m.Called(key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Exists provides a mock function with given fields: key
 func (_m *RemoteCache) Exists(key string) (bool, error) {
 	ret := _m.Called(key)
@@ -94,3 +108,24 @@ func (_m *RemoteCache) Set(key string, value interface{}, ttl time.Duration) err
 
 	return r0
 }
+
+// SetNX provides a mock function with given fields: key, value, ttl
+func (_m *RemoteCache) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	ret := _m.Called(key, value, ttl)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, interface{}, time.Duration) bool); ok {
+		r0 = rf(key, value, ttl)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, interface{}, time.Duration) error); ok {
+		r1 = rf(key, value, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
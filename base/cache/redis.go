@@ -35,6 +35,14 @@ func (c *redisCacheImpl) Set(key string, val interface{}, ttl time.Duration) err
 	return c.client.Set(key, val, ttl).Err()
 }
 
+func (c *redisCacheImpl) SetNX(key string, val interface{}, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(key, val, ttl).Result()
+}
+
+func (c *redisCacheImpl) Delete(key string) error {
+	return c.client.Del(key).Err()
+}
+
 func (c *redisCacheImpl) GetOrSet(key string, gen RemoteEntryGenerator) ([]byte, error) {
 	v, err := c.client.Get(key).Bytes()
 	if err != nil {
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/suite"
+)
+
+type redisCacheTestSuite struct {
+	suite.Suite
+	mr *miniredis.Miniredis
+}
+
+func TestRedisCacheSuite(t *testing.T) {
+	suite.Run(t, new(redisCacheTestSuite))
+}
+
+func (s *redisCacheTestSuite) SetupTest() {
+	s.mr = miniredis.RunT(s.T())
+}
+
+func (s *redisCacheTestSuite) TestCache() {
+	for name, client := range map[string]redis.UniversalClient{
+		"standalone": redis.NewClient(&redis.Options{Addr: s.mr.Addr()}),
+		"cluster":    redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.mr.Addr()}}),
+	} {
+		defer client.Close()
+		c := NewRedis(client)
+		key := "key:" + name
+
+		_, err := c.Get(key)
+		s.True(IsErrKeyNotExist(err), name)
+		s.NoError(c.Set(key, "value", time.Minute), name)
+		ok, err := c.Exists(key)
+		s.NoError(err, name)
+		s.True(ok, name)
+		v, err := c.Get(key)
+		s.NoError(err, name)
+		s.Equal([]byte("value"), v, name)
+		s.Equal(time.Minute, s.mr.TTL(key), name)
+
+		s.NoError(c.Delete(key), name)
+		ok, err = c.Exists(key)
+		s.NoError(err, name)
+		s.False(ok, name)
+	}
+}
+
+func (s *redisCacheTestSuite) TestSetNX() {
+	client := redis.NewClient(&redis.Options{Addr: s.mr.Addr()})
+	defer client.Close()
+	c := NewRedis(client)
+
+	ok, err := c.SetNX("key", "value", time.Minute)
+	s.NoError(err)
+	s.True(ok)
+	s.Equal(time.Minute, s.mr.TTL("key"))
+
+	ok, err = c.SetNX("key", "other", time.Hour)
+	s.NoError(err)
+	s.False(ok)
+	s.mr.CheckGet(s.T(), "key", "value")
+	s.Equal(time.Minute, s.mr.TTL("key"))
+}
+
+func (s *redisCacheTestSuite) TestGetOrSet() {
+	client := redis.NewClient(&redis.Options{Addr: s.mr.Addr()})
+	defer client.Close()
+	c := NewRedis(client)
+
+	v, err := c.GetOrSet("key", func() ([]byte, time.Duration, error) {
+		return []byte("value"), time.Minute, nil
+	})
+	s.NoError(err)
+	s.Equal([]byte("value"), v)
+	s.Equal(time.Minute, s.mr.TTL("key"))
+
+	v, err = c.GetOrSet("key", func() ([]byte, time.Duration, error) {
+		s.Fail("generator is called for cached entries")
+		return nil, 0, nil
+	})
+	s.NoError(err)
+	s.Equal([]byte("value"), v)
+
+	genErr := errors.New("gen")
+	_, err = c.GetOrSet("missing", func() ([]byte, time.Duration, error) {
+		return nil, 0, genErr
+	})
+	s.ErrorIs(err, genErr)
+	s.False(s.mr.Exists("missing"))
+}
@@ -0,0 +1,238 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// fencePurgeInterval is how often fencing counters not incremented for fenceTTL are deleted from lock_fences.
+const fencePurgeInterval = time.Hour
+
+// sqlDialect defines statements of advisory locks of a database. Advisory locks are held by sessions, so all
+// statements of a lock run on the same connection.
+type sqlDialect struct {
+	// lockName converts a key to the name of the advisory lock.
+	lockName func(key string) interface{}
+	// tryLock returns true if the lock is obtained without waiting.
+	tryLock string
+	// held returns true if the lock is held by the session.
+	held   string
+	unlock string
+	// createFences creates lock_fences keeping fencing counters of keys and when they're incremented in unix
+	// milliseconds.
+	createFences string
+	// purgeFences deletes fencing counters incremented before a time in unix milliseconds.
+	purgeFences string
+	// nextToken increments and returns the fencing counter of a key, and sets its updated_at to now.
+	nextToken func(ctx context.Context, conn *sql.Conn, key string, now int64) (int64, error)
+}
+
+var mysqlDialect = &sqlDialect{
+	// names of MySQL locks are at most 64 characters
+	lockName: func(key string) interface{} {
+		if len(key) <= 64 {
+			return key
+		}
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	},
+	tryLock: "SELECT COALESCE(GET_LOCK(?, 0), 0) = 1",
+	held:    "SELECT COALESCE(IS_USED_LOCK(?) = CONNECTION_ID(), 0)",
+	unlock:  "SELECT RELEASE_LOCK(?)",
+	createFences: "CREATE TABLE IF NOT EXISTS lock_fences " +
+		"(name VARCHAR(255) PRIMARY KEY, token BIGINT NOT NULL, updated_at BIGINT NOT NULL)",
+	purgeFences: "DELETE FROM lock_fences WHERE updated_at < ?",
+	nextToken: func(ctx context.Context, conn *sql.Conn, key string, now int64) (int64, error) {
+		if _, err := conn.ExecContext(ctx, "INSERT INTO lock_fences (name, token, updated_at) VALUES (?, 1, ?) "+
+			"ON DUPLICATE KEY UPDATE token = token + 1, updated_at = VALUES(updated_at)", key, now); err != nil {
+			return 0, err
+		}
+		var token int64
+		err := conn.QueryRowContext(ctx, "SELECT token FROM lock_fences WHERE name = ?", key).Scan(&token)
+		return token, err
+	},
+}
+
+var postgresDialect = &sqlDialect{
+	// keys of Postgres locks are 64-bit integers
+	lockName: func(key string) interface{} {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		return int64(h.Sum64())
+	},
+	tryLock: "SELECT pg_try_advisory_lock($1)",
+	// a 64-bit key is split into classid and objid in pg_locks
+	held: "SELECT EXISTS (SELECT 1 FROM pg_locks WHERE locktype = 'advisory' AND pid = pg_backend_pid() " +
+		"AND granted AND ((classid::bigint << 32) | objid::bigint) = $1)",
+	unlock: "SELECT pg_advisory_unlock($1)",
+	createFences: "CREATE TABLE IF NOT EXISTS lock_fences " +
+		"(name VARCHAR(255) PRIMARY KEY, token BIGINT NOT NULL, updated_at BIGINT NOT NULL)",
+	purgeFences: "DELETE FROM lock_fences WHERE updated_at < $1",
+	nextToken: func(ctx context.Context, conn *sql.Conn, key string, now int64) (int64, error) {
+		var token int64
+		err := conn.QueryRowContext(ctx, "INSERT INTO lock_fences (name, token, updated_at) VALUES ($1, 1, $2) "+
+			"ON CONFLICT (name) DO UPDATE SET token = lock_fences.token + 1, updated_at = $2 RETURNING token",
+			key, now).Scan(&token)
+		return token, err
+	},
+}
+
+type dbLockerImpl struct {
+	db      *sql.DB
+	dialect *sqlDialect
+
+	mu sync.Mutex
+	// purgedAt is when lock_fences is purged last time.
+	purgedAt time.Time
+}
+
+type dbLockImpl struct {
+	locker *dbLockerImpl
+	conn   *sql.Conn
+	key    string
+	name   interface{}
+
+	mu    sync.Mutex
+	token int64
+}
+
+// NewMySQL creates an instance of DistributedLocker on MySQL GET_LOCK. Locks don't expire but are released if
+// the connection is closed, so ttl is ignored. Fencing tokens are allocated in lock_fences on the first call of
+// Lock.Token only, so locks not fenced write nothing, and counters not used for fenceTTL restart from 1. Each lock holds a connection of db until it's unlocked, db should be
+// a pool dedicated to locks with SetMaxOpenConns, so locks do not take connections from queries they protect.
+func NewMySQL(db *sql.DB) (DistributedLocker, error) {
+	return newDB(db, mysqlDialect)
+}
+
+// NewPostgres creates an instance of DistributedLocker on Postgres session-level advisory locks. Locks don't
+// expire but are released if the connection is closed, so ttl is ignored.
+func NewPostgres(db *sql.DB) (DistributedLocker, error) {
+	return newDB(db, postgresDialect)
+}
+
+func newDB(db *sql.DB, dialect *sqlDialect) (DistributedLocker, error) {
+	if _, err := db.Exec(dialect.createFences); err != nil {
+		return nil, fmt.Errorf("fail to create lock_fences: %w", err)
+	}
+	return &dbLockerImpl{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+func (l *dbLockerImpl) Lock(key string, ttl, retryDelay time.Duration, retryCount int) (Lock, error) {
+	ctx := context.Background()
+	// waiting for a connection of the capped pool counts in the retry window as waiting for the lock
+	connCtx, cancel := context.WithTimeout(ctx, retryDelay*time.Duration(retryCount+1))
+	conn, err := l.db.Conn(connCtx)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("%w: fail to get connection: %v", ErrNotObtained, err)
+	}
+	lock := &dbLockImpl{
+		locker: l,
+		conn:   conn,
+		key:    key,
+		name:   l.dialect.lockName(key),
+	}
+
+	for i := 0; ; i++ {
+		var ok bool
+		if err := conn.QueryRowContext(ctx, l.dialect.tryLock, lock.name).Scan(&ok); err != nil {
+			lock.discard()
+			return nil, fmt.Errorf("%w: %v", ErrNotObtained, err)
+		}
+		if ok {
+			break
+		}
+		if i >= retryCount {
+			conn.Close()
+			return nil, ErrNotObtained
+		}
+		time.Sleep(retryDelay)
+	}
+
+	return lock, nil
+}
+
+// purgeFences deletes expired fencing counters by conn at most once per fencePurgeInterval.
+func (l *dbLockerImpl) purgeFences(ctx context.Context, conn *sql.Conn, now time.Time) error {
+	l.mu.Lock()
+	if now.Sub(l.purgedAt) < fencePurgeInterval {
+		l.mu.Unlock()
+		return nil
+	}
+	l.purgedAt = now
+	l.mu.Unlock()
+
+	_, err := conn.ExecContext(ctx, l.dialect.purgeFences, now.Add(-fenceTTL).UnixNano()/int64(time.Millisecond))
+	return err
+}
+
+// Token allocates the fencing token on the first call, which returns 0 if it fails or the lock is not held. 0 is
+// less than any token, so it's rejected by resources guarded by the lock, and it's allocated again on the next call.
+func (l *dbLockImpl) Token() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.token != 0 {
+		return l.token
+	}
+
+	ctx := context.Background()
+	// the counter is incremented while holding the lock, so tokens increase with holders
+	if err := l.Refresh(); err != nil {
+		return 0
+	}
+	now := time.Now()
+	// purging is best effort, expired counters are deleted next time
+	l.locker.purgeFences(ctx, l.conn, now)
+	token, err := l.locker.dialect.nextToken(ctx, l.conn, l.key, now.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return 0
+	}
+	l.token = token
+	return l.token
+}
+
+func (l *dbLockImpl) Refresh() error {
+	var held bool
+	if err := l.conn.QueryRowContext(context.Background(), l.locker.dialect.held, l.name).Scan(&held); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotHeld, err)
+	}
+	if !held {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+func (l *dbLockImpl) Unlock() error {
+	// released is NULL or false if the lock isn't held
+	var released sql.NullBool
+	if err := l.conn.QueryRowContext(context.Background(), l.locker.dialect.unlock, l.name).Scan(&released); err != nil {
+		l.discard()
+		return err
+	}
+	if err := l.conn.Close(); err != nil {
+		return err
+	}
+	if !released.Bool {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// discard closes the connection instead of returning it to the pool, so the lock possibly held by its session
+// is released.
+func (l *dbLockImpl) discard() {
+	l.conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	l.conn.Close()
+}
@@ -0,0 +1,139 @@
+package lock
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type dbLockTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+}
+
+func TestDBLockSuite(t *testing.T) {
+	suite.Run(t, new(dbLockTestSuite))
+}
+
+func (s *dbLockTestSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	s.Require().NoError(err)
+}
+
+func (s *dbLockTestSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+	s.db.Close()
+}
+
+func (s *dbLockTestSuite) TestMySQL() {
+	s.mock.ExpectExec(mysqlDialect.createFences).WillReturnResult(sqlmock.NewResult(0, 0))
+	locker, err := NewMySQL(s.db)
+	s.Require().NoError(err)
+
+	s.mock.ExpectQuery(mysqlDialect.tryLock).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(1))
+	l, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+
+	// the token is allocated on the first call only, when expired counters are purged
+	s.mock.ExpectQuery(mysqlDialect.held).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(1))
+	s.mock.ExpectExec(mysqlDialect.purgeFences).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("INSERT INTO lock_fences (name, token, updated_at) VALUES (?, 1, ?) "+
+		"ON DUPLICATE KEY UPDATE token = token + 1, updated_at = VALUES(updated_at)").
+		WithArgs("lock", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectQuery("SELECT token FROM lock_fences WHERE name = ?").WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow(3))
+	s.Equal(int64(3), l.Token())
+	s.Equal(int64(3), l.Token())
+
+	s.mock.ExpectQuery(mysqlDialect.held).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(1))
+	s.NoError(l.Refresh())
+	s.mock.ExpectQuery(mysqlDialect.held).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(0))
+	s.ErrorIs(l.Refresh(), ErrNotHeld)
+
+	s.mock.ExpectQuery(mysqlDialect.unlock).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+	s.NoError(l.Unlock())
+}
+
+func (s *dbLockTestSuite) TestMySQLNotObtained() {
+	s.mock.ExpectExec(mysqlDialect.createFences).WillReturnResult(sqlmock.NewResult(0, 0))
+	locker, err := NewMySQL(s.db)
+	s.Require().NoError(err)
+
+	// names longer than 64 characters are hashed
+	key := strings.Repeat("k", 65)
+	for i := 0; i < 2; i++ {
+		s.mock.ExpectQuery(mysqlDialect.tryLock).WithArgs("f39cdc2584758c99cf81c1f41d2572f54e17066afffc9d187aeafe5f7cbe2122").
+			WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(0))
+	}
+	_, err = locker.Lock(key, time.Minute, time.Millisecond, 1)
+	s.ErrorIs(err, ErrNotObtained)
+}
+
+func (s *dbLockTestSuite) TestMySQLPoolExhausted() {
+	s.db.SetMaxOpenConns(1)
+	s.mock.ExpectExec(mysqlDialect.createFences).WillReturnResult(sqlmock.NewResult(0, 0))
+	locker, err := NewMySQL(s.db)
+	s.Require().NoError(err)
+
+	s.mock.ExpectQuery(mysqlDialect.tryLock).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(1))
+	l, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+
+	// the only connection is held by the lock, others give up after the retry window
+	start := time.Now()
+	_, err = locker.Lock("other", time.Minute, 10*time.Millisecond, 2)
+	s.ErrorIs(err, ErrNotObtained)
+	s.Less(time.Since(start), time.Second)
+
+	s.mock.ExpectQuery(mysqlDialect.unlock).WithArgs("lock").
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+	s.NoError(l.Unlock())
+}
+
+func (s *dbLockTestSuite) TestPostgres() {
+	s.mock.ExpectExec(postgresDialect.createFences).WillReturnResult(sqlmock.NewResult(0, 0))
+	locker, err := NewPostgres(s.db)
+	s.Require().NoError(err)
+
+	name := postgresDialect.lockName("lock")
+	s.mock.ExpectQuery(postgresDialect.tryLock).WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+	l, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+
+	// failing to allocate the token returns 0, and it's allocated again on the next call
+	insert := "INSERT INTO lock_fences (name, token, updated_at) VALUES ($1, 1, $2) " +
+		"ON CONFLICT (name) DO UPDATE SET token = lock_fences.token + 1, updated_at = $2 RETURNING token"
+	s.mock.ExpectQuery(postgresDialect.held).WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(true))
+	s.mock.ExpectExec(postgresDialect.purgeFences).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(insert).WithArgs("lock", sqlmock.AnyArg()).WillReturnError(errors.New("timeout"))
+	s.Equal(int64(0), l.Token())
+	// lock_fences is purged once per fencePurgeInterval
+	s.mock.ExpectQuery(postgresDialect.held).WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(true))
+	s.mock.ExpectQuery(insert).WithArgs("lock", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow(1))
+	s.Equal(int64(1), l.Token())
+
+	s.mock.ExpectQuery(postgresDialect.held).WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(true))
+	s.NoError(l.Refresh())
+
+	s.mock.ExpectQuery(postgresDialect.unlock).WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(false))
+	s.ErrorIs(l.Unlock(), ErrNotHeld)
+}
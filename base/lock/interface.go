@@ -1,13 +1,37 @@
 package lock
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotObtained is returned by Lock if the lock is held by others after retries.
+	ErrNotObtained = errors.New("lock not obtained")
+	// ErrNotHeld is returned by Refresh if the lock has expired, been released or taken by others.
+	ErrNotHeld = errors.New("lock not held")
+)
 
 // DistributedLocker defines an interface for distributed lock.
 type DistributedLocker interface {
+	// Lock obtains the lock of key expiring after ttl, it's retried retryCount times every retryDelay.
 	Lock(key string, ttl, retryDelay time.Duration, retryCount int) (Lock, error)
 }
 
 // Lock defines an interface fo lock.
 type Lock interface {
+	// Token returns the fencing token, which is greater than tokens of previous holders of the key.
+	// Resources guarded by the lock should reject writes with tokens less than the latest one seen. It could be
+	// allocated on the first call, and 0 is returned if that fails.
+	Token() int64
+	// Refresh extends the lock by its ttl.
+	Refresh() error
 	Unlock() error
 }
+
+// RefreshingLock defines an interface of lock refreshed in background.
+type RefreshingLock interface {
+	Lock
+	// Lost is closed if refreshing fails, holders should stop working on resources guarded by the lock.
+	Lost() <-chan struct{}
+}
@@ -0,0 +1,97 @@
+package lock
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+type memoryLockerImpl struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	locks  map[string]*memoryLockImpl
+	tokens map[string]int64
+}
+
+type memoryLockImpl struct {
+	locker   *memoryLockerImpl
+	key      string
+	ttl      time.Duration
+	token    int64
+	expireAt time.Time
+}
+
+// NewMemory creates an instance of DistributedLocker in memory for tests. Locks expire and retries wait by
+// clock, so fake clocks should be advanced to retry.
+func NewMemory(clock clock.Clock) DistributedLocker {
+	return &memoryLockerImpl{
+		clock:  clock,
+		locks:  make(map[string]*memoryLockImpl),
+		tokens: make(map[string]int64),
+	}
+}
+
+func (l *memoryLockerImpl) Lock(key string, ttl, retryDelay time.Duration, retryCount int) (Lock, error) {
+	for i := 0; ; i++ {
+		if lock := l.tryLock(key, ttl); lock != nil {
+			return lock, nil
+		}
+		if i >= retryCount {
+			return nil, ErrNotObtained
+		}
+		l.clock.Sleep(retryDelay)
+	}
+}
+
+func (l *memoryLockerImpl) tryLock(key string, ttl time.Duration) *memoryLockImpl {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if held, ok := l.locks[key]; ok && now.Before(held.expireAt) {
+		return nil
+	}
+	l.tokens[key]++
+	lock := &memoryLockImpl{
+		locker:   l,
+		key:      key,
+		ttl:      ttl,
+		token:    l.tokens[key],
+		expireAt: now.Add(ttl),
+	}
+	l.locks[key] = lock
+	return lock
+}
+
+// held reports if lock is the unexpired holder of its key, the caller should hold mu.
+func (l *memoryLockerImpl) held(lock *memoryLockImpl) bool {
+	return l.locks[lock.key] == lock && l.clock.Now().Before(lock.expireAt)
+}
+
+func (l *memoryLockImpl) Token() int64 {
+	return l.token
+}
+
+func (l *memoryLockImpl) Refresh() error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if !l.locker.held(l) {
+		return ErrNotHeld
+	}
+	l.expireAt = l.locker.clock.Now().Add(l.ttl)
+	return nil
+}
+
+func (l *memoryLockImpl) Unlock() error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if !l.locker.held(l) {
+		return ErrNotHeld
+	}
+	delete(l.locker.locks, l.key)
+	return nil
+}
@@ -0,0 +1,81 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/suite"
+)
+
+type memoryLockTestSuite struct {
+	suite.Suite
+	clock  *fakeclock.FakeClock
+	locker DistributedLocker
+}
+
+func TestMemoryLockSuite(t *testing.T) {
+	suite.Run(t, new(memoryLockTestSuite))
+}
+
+func (s *memoryLockTestSuite) SetupTest() {
+	s.clock = fakeclock.NewFakeClock(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	s.locker = NewMemory(s.clock)
+}
+
+func (s *memoryLockTestSuite) TestLock() {
+	l, err := s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(1), l.Token())
+	_, err = s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.ErrorIs(err, ErrNotObtained)
+	other, err := s.locker.Lock("other", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(1), other.Token())
+
+	s.NoError(l.Unlock())
+	s.ErrorIs(l.Unlock(), ErrNotHeld)
+	l, err = s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(2), l.Token())
+}
+
+func (s *memoryLockTestSuite) TestLockRetry() {
+	l, err := s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+
+	obtained := make(chan Lock)
+	go func() {
+		l, _ := s.locker.Lock("lock", time.Minute, time.Second, 1)
+		obtained <- l
+	}()
+	s.clock.WaitForWatcherAndIncrement(time.Second)
+	s.Nil(<-obtained)
+
+	go func() {
+		l, _ := s.locker.Lock("lock", time.Minute, time.Second, 1)
+		obtained <- l
+	}()
+	s.Eventually(func() bool { return s.clock.WatcherCount() > 0 }, time.Second, time.Millisecond)
+	s.NoError(l.Unlock())
+	s.clock.Increment(time.Second)
+	s.NotNil(<-obtained)
+}
+
+func (s *memoryLockTestSuite) TestExpire() {
+	l, err := s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	s.clock.Increment(50 * time.Second)
+	s.NoError(l.Refresh())
+	s.clock.Increment(50 * time.Second)
+	_, err = s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.ErrorIs(err, ErrNotObtained)
+
+	s.clock.Increment(10 * time.Second)
+	s.ErrorIs(l.Refresh(), ErrNotHeld)
+	taken, err := s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(2), taken.Token())
+	s.ErrorIs(l.Unlock(), ErrNotHeld)
+	s.NoError(taken.Refresh())
+}
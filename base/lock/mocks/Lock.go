@@ -9,6 +9,34 @@ type Lock struct {
 	mock.Mock
 }
 
+// Refresh provides a mock function with given fields:
+func (_m *Lock) Refresh() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Token provides a mock function with given fields:
+func (_m *Lock) Token() int64 {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
 // Unlock provides a mock function with given fields:
 func (_m *Lock) Unlock() error {
 	ret := _m.Called()
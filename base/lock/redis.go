@@ -1,50 +1,228 @@
 package lock
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
-	rlock "github.com/bsm/redis-lock"
 	"github.com/go-redis/redis"
-	"github.com/pkg/errors"
 )
 
-// DefaultRetryDelay defines default retry delay time for lock.
-const DefaultRetryDelay = 100 * time.Millisecond
+const (
+	// DefaultRetryDelay defines default retry delay time for lock.
+	DefaultRetryDelay = 100 * time.Millisecond
+
+	// fenceTTL keeps fencing counters of keys not locked for a long time from piling up, tokens restart from
+	// 1 after that.
+	fenceTTL = 7 * 24 * time.Hour
+	// clockDriftFactor is the max drift of clocks of redis nodes per ttl.
+	clockDriftFactor = 0.01
+)
+
+var (
+	// luaAcquire sets the lock if absent and returns the fencing counter, or -1 if the lock is held.
+	luaAcquire = redis.NewScript(`if redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) then
+	return tonumber(redis.call("get", KEYS[2]) or "0")
+end
+return -1`)
+	// luaFence raises the fencing counter to the token if the lock is still held.
+	luaFence = redis.NewScript(`if redis.call("get", KEYS[1]) ~= ARGV[1] then return 0 end
+local token = math.max(tonumber(redis.call("get", KEYS[2]) or "0"), tonumber(ARGV[2]))
+redis.call("set", KEYS[2], token, "px", ARGV[3])
+return 1`)
+	luaRefresh = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+	luaRelease = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+)
 
 type redisLockerImpl struct {
-	client redis.Cmdable
+	nodes  []redis.Cmdable
+	quorum int
 }
 
-type lockImpl struct {
-	locker *rlock.Locker
+type redisLockImpl struct {
+	locker *redisLockerImpl
+	keys   []string
+	value  string
+	ttl    time.Duration
+	token  int64
 }
 
-// NewRedis creates an instance of DistributedLocker.
+// NewRedis creates an instance of DistributedLocker on a redis deployment, which could be a redis.UniversalClient
+// of any mode. Locks could be lost on failover of Sentinel or Cluster since replication is asynchronous.
 func NewRedis(cmdable redis.Cmdable) DistributedLocker {
+	return NewRedlock(cmdable)
+}
+
+// NewRedlock creates an instance of DistributedLocker obtaining locks on a majority of independent redis nodes,
+// so a lock is kept if a minority of nodes fail.
+func NewRedlock(nodes ...redis.Cmdable) DistributedLocker {
 	return &redisLockerImpl{
-		client: cmdable,
+		nodes:  nodes,
+		quorum: len(nodes)/2 + 1,
 	}
 }
 
 func (l *redisLockerImpl) Lock(key string, ttl, retryDelay time.Duration, retryCount int) (Lock, error) {
-	opt := rlock.Options{
-		LockTimeout: ttl,
-		RetryCount:  retryCount,
-		RetryDelay:  retryDelay,
+	value, err := randomValue()
+	if err != nil {
+		return nil, err
+	}
+	// the fencing counter is in the same slot of Redis Cluster as the lock, so both are accessed in one script
+	key = hashTag(key)
+	lock := &redisLockImpl{
+		locker: l,
+		keys:   []string{key, key + ":fence"},
+		value:  value,
+		ttl:    ttl,
+	}
+
+	var lastErr error
+	for i := 0; ; i++ {
+		ok, err := lock.acquire()
+		if ok {
+			return lock, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		if i >= retryCount {
+			break
+		}
+		time.Sleep(retryDelay)
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotObtained, lastErr)
+	}
+	return nil, ErrNotObtained
+}
+
+// acquire sets the lock on a majority of nodes in its validity time, then raises fencing counters of those
+// nodes to a token greater than all of them. Any later holder of the lock reads at least one of the counters,
+// so its token is greater.
+func (l *redisLockImpl) acquire() (bool, error) {
+	start := time.Now()
+	counters, err := l.eval(luaAcquire, l.ttl.Milliseconds())
+	var token int64
+	acquired := 0
+	for _, counter := range counters {
+		if counter >= 0 {
+			acquired++
+		}
+		if counter >= token {
+			token = counter + 1
+		}
+	}
+
+	if acquired >= l.locker.quorum && l.valid(start) {
+		fenced, fenceErr := l.eval(luaFence, token, fenceTTL.Milliseconds())
+		if count(fenced) >= l.locker.quorum && l.valid(start) {
+			l.token = token
+			return true, nil
+		}
+		if fenceErr != nil {
+			err = fenceErr
+		}
+	}
+
+	// release nodes acquired before failing
+	l.eval(luaRelease)
+	return false, err
+}
+
+// valid reports if the lock obtained at start isn't expired on any node, allowing clock drift of nodes.
+func (l *redisLockImpl) valid(start time.Time) bool {
+	drift := time.Duration(float64(l.ttl)*clockDriftFactor) + 2*time.Millisecond
+	return time.Since(start)+drift < l.ttl
+}
+
+// eval runs script with the value of the lock on all nodes concurrently, results of failed nodes are -1.
+func (l *redisLockImpl) eval(script *redis.Script, args ...interface{}) ([]int64, error) {
+	args = append([]interface{}{l.value}, args...)
+	results := make([]int64, len(l.locker.nodes))
+	errs := make([]error, len(l.locker.nodes))
+	var wg sync.WaitGroup
+	for i, node := range l.locker.nodes {
+		wg.Add(1)
+		go func(i int, node redis.Cmdable) {
+			defer wg.Done()
+			results[i], errs[i] = script.Run(node, l.keys, args...).Int64()
+			if errs[i] != nil {
+				results[i] = -1
+			}
+		}(i, node)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, err
+		}
 	}
+	return results, nil
+}
+
+func (l *redisLockImpl) Token() int64 {
+	return l.token
+}
 
-	locker := rlock.New(l.client, key, &opt)
-	ok, err := locker.Lock()
+func (l *redisLockImpl) Refresh() error {
+	start := time.Now()
+	refreshed, err := l.eval(luaRefresh, l.ttl.Milliseconds())
+	if count(refreshed) >= l.locker.quorum && l.valid(start) {
+		return nil
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "fail to lock")
+		return fmt.Errorf("%w: %v", ErrNotHeld, err)
 	}
-	if !ok {
-		return nil, errors.New("lock timeout")
+	return ErrNotHeld
+}
+
+func (l *redisLockImpl) Unlock() error {
+	released, err := l.eval(luaRelease)
+	if count(released) >= l.locker.quorum {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
+	return ErrNotHeld
+}
 
-	return &lockImpl{locker: locker}, nil
+// count returns the number of nodes succeeded.
+func count(results []int64) int {
+	n := 0
+	for _, result := range results {
+		if result > 0 {
+			n++
+		}
+	}
+	return n
 }
 
-func (l *lockImpl) Unlock() error {
-	return l.locker.Unlock()
+func randomValue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("fail to generate lock value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashTag wraps key in braces unless it has a hash tag, so keys derived from the lock key
+// are in the same slot of Redis Cluster.
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key
+		}
+	}
+	return "{" + key + "}"
 }
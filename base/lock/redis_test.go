@@ -0,0 +1,144 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/suite"
+)
+
+type redisLockTestSuite struct {
+	suite.Suite
+	mr *miniredis.Miniredis
+}
+
+func TestRedisLockSuite(t *testing.T) {
+	suite.Run(t, new(redisLockTestSuite))
+}
+
+func (s *redisLockTestSuite) SetupTest() {
+	s.mr = miniredis.RunT(s.T())
+}
+
+func (s *redisLockTestSuite) clients() map[string]redis.UniversalClient {
+	clients := map[string]redis.UniversalClient{
+		"standalone": redis.NewClient(&redis.Options{Addr: s.mr.Addr()}),
+		"cluster":    redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.mr.Addr()}}),
+	}
+	for _, client := range clients {
+		s.T().Cleanup(func() { client.Close() })
+	}
+	return clients
+}
+
+func (s *redisLockTestSuite) TestLock() {
+	for name, client := range s.clients() {
+		locker := NewRedis(client)
+
+		l, err := locker.Lock("lock:"+name, time.Minute, time.Millisecond, 0)
+		s.Require().NoError(err, name)
+		s.True(s.mr.Exists("{lock:"+name+"}"), name)
+
+		_, err = locker.Lock("lock:"+name, time.Minute, time.Millisecond, 2)
+		s.Error(err, name)
+
+		s.NoError(l.Unlock(), name)
+		s.False(s.mr.Exists("{lock:"+name+"}"), name)
+		l, err = locker.Lock("lock:"+name, time.Minute, time.Millisecond, 0)
+		s.Require().NoError(err, name)
+		s.NoError(l.Unlock(), name)
+	}
+}
+
+func (s *redisLockTestSuite) TestLockExpired() {
+	locker := NewRedis(redis.NewClient(&redis.Options{Addr: s.mr.Addr()}))
+
+	_, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.mr.FastForward(time.Minute)
+
+	_, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.NoError(err)
+}
+
+func (s *redisLockTestSuite) TestToken() {
+	locker := NewRedis(redis.NewClient(&redis.Options{Addr: s.mr.Addr()}))
+
+	l, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(1), l.Token())
+	s.NoError(l.Unlock())
+	l, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(2), l.Token())
+	s.mr.CheckGet(s.T(), "{lock}:fence", "2")
+	s.Equal(fenceTTL, s.mr.TTL("{lock}:fence"))
+}
+
+func (s *redisLockTestSuite) TestRefresh() {
+	locker := NewRedis(redis.NewClient(&redis.Options{Addr: s.mr.Addr()}))
+
+	l, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.mr.FastForward(50 * time.Second)
+	s.NoError(l.Refresh())
+	s.Equal(time.Minute, s.mr.TTL("{lock}"))
+
+	s.mr.FastForward(time.Minute)
+	s.ErrorIs(l.Refresh(), ErrNotHeld)
+	s.ErrorIs(l.Unlock(), ErrNotHeld)
+}
+
+func (s *redisLockTestSuite) TestRedlock() {
+	mrs := []*miniredis.Miniredis{s.mr, miniredis.RunT(s.T()), miniredis.RunT(s.T())}
+	nodes := make([]redis.Cmdable, 0, len(mrs))
+	for _, mr := range mrs {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer client.Close()
+		nodes = append(nodes, client)
+	}
+	locker := NewRedlock(nodes...)
+
+	l, err := locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(1), l.Token())
+	_, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.ErrorIs(err, ErrNotObtained)
+	s.NoError(l.Unlock())
+
+	// a minority of nodes losing data doesn't lose the lock or decrease tokens
+	l, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	mrs[0].FlushAll()
+	_, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.ErrorIs(err, ErrNotObtained)
+	s.NoError(l.Refresh())
+	s.NoError(l.Unlock())
+	l, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(3), l.Token())
+	s.NoError(l.Unlock())
+
+	// a minority of nodes down
+	mrs[1].Close()
+	l, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.Require().NoError(err)
+	s.Equal(int64(4), l.Token())
+	s.NoError(l.Unlock())
+
+	// a majority of nodes down
+	mrs[2].Close()
+	_, err = locker.Lock("lock", time.Minute, time.Millisecond, 0)
+	s.ErrorIs(err, ErrNotObtained)
+	s.False(mrs[0].Exists("{lock}"))
+}
+
+func (s *redisLockTestSuite) TestHashTag() {
+	s.Equal("{shortlink:1:abc}", hashTag("shortlink:1:abc"))
+	s.Equal("lock:{1}:abc", hashTag("lock:{1}:abc"))
+	// empty braces are not hash tags
+	s.Equal("{lock:{}:abc}", hashTag("lock:{}:abc"))
+	s.Equal("{lock:{abc}", hashTag("lock:{abc"))
+}
@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+)
+
+type refreshingLockImpl struct {
+	Lock
+	lost chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// AutoRefresh refreshes l every interval until it's unlocked or refreshing fails. interval should be well
+// below ttl of the lock, so a slow refresh doesn't lose it.
+func AutoRefresh(l Lock, interval time.Duration, clock clock.Clock) RefreshingLock {
+	r := &refreshingLockImpl{
+		Lock: l,
+		lost: make(chan struct{}),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	ticker := clock.NewTicker(interval)
+	go func() {
+		defer close(r.done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C():
+				if err := r.Lock.Refresh(); err != nil {
+					zap.S().Warnf("fail to refresh lock, err: %v", err)
+					close(r.lost)
+					return
+				}
+			}
+		}
+	}()
+
+	return r
+}
+
+func (r *refreshingLockImpl) Lost() <-chan struct{} {
+	return r.lost
+}
+
+func (r *refreshingLockImpl) Unlock() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+	return r.Lock.Unlock()
+}
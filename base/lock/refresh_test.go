@@ -0,0 +1,66 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/suite"
+)
+
+type refreshTestSuite struct {
+	suite.Suite
+	clock  *fakeclock.FakeClock
+	locker DistributedLocker
+}
+
+func TestRefreshSuite(t *testing.T) {
+	suite.Run(t, new(refreshTestSuite))
+}
+
+func (s *refreshTestSuite) SetupTest() {
+	s.clock = fakeclock.NewFakeClock(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	s.locker = NewMemory(s.clock)
+}
+
+// expireAt returns when l expires, which is updated by refreshing in background.
+func (s *refreshTestSuite) expireAt(l Lock) time.Time {
+	memoryLock := l.(*memoryLockImpl)
+	memoryLock.locker.mu.Lock()
+	defer memoryLock.locker.mu.Unlock()
+	return memoryLock.expireAt
+}
+
+func (s *refreshTestSuite) TestAutoRefresh() {
+	l, err := s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	r := AutoRefresh(l, 20*time.Second, s.clock)
+
+	for i := 0; i < 5; i++ {
+		s.clock.WaitForWatcherAndIncrement(20 * time.Second)
+		expireAt := s.clock.Now().Add(time.Minute)
+		s.Eventually(func() bool { return s.expireAt(l).Equal(expireAt) }, time.Second, time.Millisecond)
+	}
+	_, err = s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.ErrorIs(err, ErrNotObtained)
+	select {
+	case <-r.Lost():
+		s.Fail("lock is lost")
+	default:
+	}
+
+	s.NoError(r.Unlock())
+	s.Equal(0, s.clock.WatcherCount())
+	_, err = s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.NoError(err)
+}
+
+func (s *refreshTestSuite) TestAutoRefreshLost() {
+	l, err := s.locker.Lock("lock", time.Minute, time.Second, 0)
+	s.Require().NoError(err)
+	r := AutoRefresh(l, 2*time.Minute, s.clock)
+
+	s.clock.WaitForWatcherAndIncrement(2 * time.Minute)
+	<-r.Lost()
+	s.ErrorIs(r.Unlock(), ErrNotHeld)
+}
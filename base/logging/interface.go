@@ -0,0 +1,10 @@
+package logging
+
+import "go.uber.org/zap"
+
+// AccessLogger defines an interface for access logs of requests.
+type AccessLogger interface {
+	// Log writes access log of request served by route, e.g. "/:url_id", at level of the route. Failed
+	// requests, e.g. responded with 5xx, are logged at error level at least.
+	Log(route string, failed bool, fields ...zap.Field)
+}
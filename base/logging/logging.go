@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxRequestIDLength limits length of request ID propagated from clients.
+const maxRequestIDLength = 128
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns logger carried by ctx, or the global logger if there is none.
+func FromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.L()
+}
+
+// NewRequestID generates a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// IsValidRequestID checks if request ID sent by client is safe to be propagated.
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLogConfig configures levels and sampling of access logs.
+type AccessLogConfig struct {
+	// Level is the level of access logs, info if not set.
+	Level zapcore.Level
+	// RouteLevels overrides Level of routes, e.g. debug for "/:url_id" to keep redirects out of logs.
+	RouteLevels map[string]zapcore.Level
+	// SampleInitial and SampleThereafter log the first SampleInitial requests of each route and level every
+	// second, and every SampleThereafter-th request after that. Sampling is disabled if SampleInitial is 0.
+	SampleInitial    int
+	SampleThereafter int
+}
+
+type accessLoggerImpl struct {
+	logger *zap.Logger
+	config AccessLogConfig
+}
+
+// NewAccessLogger creates an instance of AccessLogger writing to logger.
+func NewAccessLogger(logger *zap.Logger, config AccessLogConfig) AccessLogger {
+	if config.SampleInitial > 0 {
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, config.SampleInitial, config.SampleThereafter)
+		}))
+	}
+	return &accessLoggerImpl{logger: logger, config: config}
+}
+
+func (l *accessLoggerImpl) Log(route string, failed bool, fields ...zap.Field) {
+	level := l.config.Level
+	if routeLevel, ok := l.config.RouteLevels[route]; ok {
+		level = routeLevel
+	}
+	if failed && level < zapcore.ErrorLevel {
+		level = zapcore.ErrorLevel
+	}
+
+	// route is the message, then requests of each route are sampled separately
+	if ce := l.logger.Check(level, route); ce != nil {
+		ce.Write(append(fields, zap.String("route", route))...)
+	}
+}
+
+// ParseRouteLevels parses levels of routes in the form of "route=level,route=level", e.g.
+// "/:url_id=debug,/api/v1/urls/:url_id/qr=warn".
+func ParseRouteLevels(s string) (map[string]zapcore.Level, error) {
+	levels := map[string]zapcore.Level{}
+	if s == "" {
+		return levels, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("route level should be route=level, got %q", pair)
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(pair[i+1:])); err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(pair[:i])] = level
+	}
+	return levels, nil
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type loggingTestSuite struct {
+	suite.Suite
+	logs   *observer.ObservedLogs
+	logger *zap.Logger
+}
+
+func (s *loggingTestSuite) SetupTest() {
+	core, logs := observer.New(zapcore.DebugLevel)
+	s.logs = logs
+	s.logger = zap.New(core)
+}
+
+func TestLoggingTestSuite(t *testing.T) {
+	suite.Run(t, new(loggingTestSuite))
+}
+
+func (s *loggingTestSuite) TestContext() {
+	s.Equal(zap.L(), FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), s.logger)
+	s.Equal(s.logger, FromContext(ctx))
+}
+
+func (s *loggingTestSuite) TestRequestID() {
+	id := NewRequestID()
+	s.Len(id, 32)
+	s.NotEqual(id, NewRequestID())
+	s.True(IsValidRequestID(id))
+
+	s.False(IsValidRequestID(""))
+	s.False(IsValidRequestID("has space"))
+	s.False(IsValidRequestID("line\nbreak"))
+	s.False(IsValidRequestID(strings.Repeat("a", maxRequestIDLength+1)))
+}
+
+func (s *loggingTestSuite) TestAccessLoggerLevels() {
+	accessLogger := NewAccessLogger(s.logger, AccessLogConfig{
+		RouteLevels: map[string]zapcore.Level{"/:url_id": zapcore.DebugLevel},
+	})
+
+	accessLogger.Log("/api/v1/urls", false, zap.Int("status", 200))
+	accessLogger.Log("/:url_id", false, zap.Int("status", 301))
+	accessLogger.Log("/:url_id", true, zap.Int("status", 500))
+
+	entries := s.logs.AllUntimed()
+	s.Require().Len(entries, 3)
+	s.Equal(zapcore.InfoLevel, entries[0].Level)
+	s.Equal("/api/v1/urls", entries[0].Message)
+	s.Equal(map[string]interface{}{"status": int64(200), "route": "/api/v1/urls"}, entries[0].ContextMap())
+	s.Equal(zapcore.DebugLevel, entries[1].Level)
+	s.Equal(zapcore.ErrorLevel, entries[2].Level)
+}
+
+func (s *loggingTestSuite) TestAccessLoggerSampling() {
+	accessLogger := NewAccessLogger(s.logger, AccessLogConfig{SampleInitial: 2, SampleThereafter: 3})
+
+	for i := 0; i < 8; i++ {
+		accessLogger.Log("/:url_id", false)
+		accessLogger.Log("/api/v1/urls", false)
+	}
+
+	// first 2 and then every 3rd of the rest of each route
+	s.Equal(4, s.logs.FilterMessage("/:url_id").Len())
+	s.Equal(4, s.logs.FilterMessage("/api/v1/urls").Len())
+}
+
+func (s *loggingTestSuite) TestParseRouteLevels() {
+	levels, err := ParseRouteLevels("/:url_id=debug, /api/v1/urls/:url_id/qr=warn")
+	s.Require().NoError(err)
+	s.Equal(map[string]zapcore.Level{
+		"/:url_id":                zapcore.DebugLevel,
+		"/api/v1/urls/:url_id/qr": zapcore.WarnLevel,
+	}, levels)
+
+	levels, err = ParseRouteLevels("")
+	s.Require().NoError(err)
+	s.Empty(levels)
+
+	_, err = ParseRouteLevels("/:url_id")
+	s.Error(err)
+	_, err = ParseRouteLevels("/:url_id=loud")
+	s.Error(err)
+}
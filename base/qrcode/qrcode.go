@@ -0,0 +1,136 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// Level defines error correction level of QR code.
+type Level string
+
+const (
+	// LevelLow recovers 7% of data.
+	LevelLow Level = "L"
+	// LevelMedium recovers 15% of data.
+	LevelMedium Level = "M"
+	// LevelQuartile recovers 25% of data.
+	LevelQuartile Level = "Q"
+	// LevelHigh recovers 30% of data.
+	LevelHigh Level = "H"
+)
+
+var recoveryLevels = map[Level]goqrcode.RecoveryLevel{
+	LevelLow:      goqrcode.Low,
+	LevelMedium:   goqrcode.Medium,
+	LevelQuartile: goqrcode.High,
+	LevelHigh:     goqrcode.Highest,
+}
+
+// Options defines options of rendering QR code.
+type Options struct {
+	// Size is width and height of the image in pixels.
+	Size int
+	// Level is error correction level.
+	Level Level
+	// Margin is width of the quiet zone in modules.
+	Margin int
+}
+
+// ParseLevel returns Level of the string, which is one of L, M, Q and H.
+func ParseLevel(s string) (Level, error) {
+	level := Level(strings.ToUpper(s))
+	if _, ok := recoveryLevels[level]; !ok {
+		return "", fmt.Errorf("invalid level: %s", s)
+	}
+	return level, nil
+}
+
+// PNG returns QR code of content encoded in PNG.
+func PNG(content string, opts Options) ([]byte, error) {
+	modules, err := bitmap(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// scale modules to the image with nearest neighbor, so image size is exactly opts.Size
+	n := len(modules)
+	if opts.Size < n {
+		return nil, fmt.Errorf("size %d is smaller than %d modules", opts.Size, n)
+	}
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), color.Palette{color.White, color.Black})
+	for y := 0; y < opts.Size; y++ {
+		row := modules[y*n/opts.Size]
+		for x := 0; x < opts.Size; x++ {
+			if row[x*n/opts.Size] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG returns QR code of content encoded in SVG, each dark module is a 1x1 square in view box.
+func SVG(content string, opts Options) ([]byte, error) {
+	modules, err := bitmap(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(modules)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, opts.Size, opts.Size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`, n, n)
+	buf.WriteString(`<path fill="#000000" d="`)
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes(), nil
+}
+
+// bitmap returns modules of QR code surrounded by margin, true means dark module.
+func bitmap(content string, opts Options) ([][]bool, error) {
+	level, ok := recoveryLevels[opts.Level]
+	if !ok {
+		return nil, fmt.Errorf("invalid level: %s", opts.Level)
+	}
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("invalid size: %d", opts.Size)
+	}
+	if opts.Margin < 0 {
+		return nil, fmt.Errorf("invalid margin: %d", opts.Margin)
+	}
+
+	code, err := goqrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	symbol := code.Bitmap()
+
+	n := len(symbol) + 2*opts.Margin
+	modules := make([][]bool, n)
+	for y := range modules {
+		modules[y] = make([]bool, n)
+	}
+	for y, row := range symbol {
+		copy(modules[y+opts.Margin][opts.Margin:], row)
+	}
+	return modules, nil
+}
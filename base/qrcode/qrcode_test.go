@@ -0,0 +1,112 @@
+package qrcode
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	gozxingqrcode "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/suite"
+)
+
+const testContent = "https://go.example.com/ejLqV3Wkyd6"
+
+var update = flag.Bool("update", false, "update golden files")
+
+var svgModule = regexp.MustCompile(`M(\d+) (\d+)h1v1h-1z`)
+
+type qrcodeTestSuite struct {
+	suite.Suite
+}
+
+func TestQRCodeSuite(t *testing.T) {
+	suite.Run(t, new(qrcodeTestSuite))
+}
+
+func (s *qrcodeTestSuite) TestPNG() {
+	for _, level := range []Level{LevelLow, LevelMedium, LevelQuartile, LevelHigh} {
+		b, err := PNG(testContent, Options{Size: 256, Level: level, Margin: 4})
+		s.Require().NoError(err)
+		s.assertGolden("png_"+string(level)+".png", b)
+
+		img, err := png.Decode(bytes.NewReader(b))
+		s.Require().NoError(err)
+		s.Equal(256, img.Bounds().Dx())
+		s.Equal(256, img.Bounds().Dy())
+		s.Equal(testContent, s.decode(img))
+	}
+}
+
+func (s *qrcodeTestSuite) TestPNGSizeTooSmall() {
+	_, err := PNG(testContent, Options{Size: 16, Level: LevelMedium, Margin: 4})
+	s.Error(err)
+}
+
+func (s *qrcodeTestSuite) TestSVG() {
+	b, err := SVG(testContent, Options{Size: 256, Level: LevelMedium, Margin: 4})
+	s.Require().NoError(err)
+	s.assertGolden("svg_M.svg", b)
+
+	// rasterize the path, 8 pixels per module, and decode it back
+	modules, err := bitmap(testContent, Options{Size: 256, Level: LevelMedium, Margin: 4})
+	s.Require().NoError(err)
+	n := len(modules)
+	img := image.NewGray(image.Rect(0, 0, n*8, n*8))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for _, m := range svgModule.FindAllStringSubmatch(string(b), -1) {
+		x, _ := strconv.Atoi(m[1])
+		y, _ := strconv.Atoi(m[2])
+		for dy := 0; dy < 8; dy++ {
+			for dx := 0; dx < 8; dx++ {
+				img.SetGray(x*8+dx, y*8+dy, color.Gray{})
+			}
+		}
+	}
+	s.Equal(testContent, s.decode(img))
+}
+
+func (s *qrcodeTestSuite) TestParseLevel() {
+	level, err := ParseLevel("q")
+	s.NoError(err)
+	s.Equal(LevelQuartile, level)
+
+	_, err = ParseLevel("X")
+	s.Error(err)
+}
+
+func (s *qrcodeTestSuite) TestInvalidOptions() {
+	_, err := SVG(testContent, Options{Size: 256, Level: "X"})
+	s.Error(err)
+	_, err = SVG(testContent, Options{Size: 256, Level: LevelLow, Margin: -1})
+	s.Error(err)
+	_, err = PNG(testContent, Options{Size: 0, Level: LevelLow})
+	s.Error(err)
+}
+
+func (s *qrcodeTestSuite) assertGolden(name string, b []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		s.Require().NoError(ioutil.WriteFile(path, b, 0644))
+	}
+	golden, err := ioutil.ReadFile(path)
+	s.Require().NoError(err)
+	s.Equal(golden, b)
+}
+
+func (s *qrcodeTestSuite) decode(img image.Image) string {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	s.Require().NoError(err)
+	result, err := gozxingqrcode.NewQRCodeReader().Decode(bmp, nil)
+	s.Require().NoError(err)
+	return result.GetText()
+}
@@ -0,0 +1,137 @@
+package redisclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+// Mode defines how redis is deployed.
+type Mode string
+
+const (
+	// ModeStandalone connects to a single redis server.
+	ModeStandalone Mode = "standalone"
+	// ModeSentinel connects to the master monitored by sentinels and follows failovers.
+	ModeSentinel Mode = "sentinel"
+	// ModeCluster connects to a redis cluster.
+	ModeCluster Mode = "cluster"
+)
+
+// ErrInvalidConfig is returned by New if the config is invalid.
+var ErrInvalidConfig = errors.New("invalid redis config")
+
+// Config defines how to connect to redis.
+type Config struct {
+	Mode Mode
+	// Addrs are the server in standalone mode, sentinels in sentinel mode or seed nodes in cluster mode.
+	Addrs []string
+	// MasterName is the name of the master monitored by sentinels, required in sentinel mode.
+	MasterName string
+	Password   string
+	// DB is not supported in cluster mode.
+	DB         int
+	PoolSize   int
+	MaxRetries int
+	// TLS is disabled if nil.
+	TLS *TLSConfig
+}
+
+// TLSConfig defines TLS of connections to redis.
+type TLSConfig struct {
+	// CAFile is a PEM file of certificates verifying servers, system roots are used if empty.
+	CAFile             string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// ParseAddrs splits comma-separated addresses.
+func ParseAddrs(s string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// New creates a redis client of the mode.
+func New(config Config) (redis.UniversalClient, error) {
+	if len(config.Addrs) == 0 {
+		return nil, fmt.Errorf("%w: no address", ErrInvalidConfig)
+	}
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Mode {
+	case ModeStandalone, "":
+		if len(config.Addrs) > 1 {
+			return nil, fmt.Errorf("%w: only one address in standalone mode", ErrInvalidConfig)
+		}
+		return redis.NewClient(&redis.Options{
+			Addr:       config.Addrs[0],
+			Password:   config.Password,
+			DB:         config.DB,
+			PoolSize:   config.PoolSize,
+			MaxRetries: config.MaxRetries,
+			TLSConfig:  tlsConfig,
+		}), nil
+	case ModeSentinel:
+		if config.MasterName == "" {
+			return nil, fmt.Errorf("%w: no master name in sentinel mode", ErrInvalidConfig)
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    config.MasterName,
+			SentinelAddrs: config.Addrs,
+			Password:      config.Password,
+			DB:            config.DB,
+			PoolSize:      config.PoolSize,
+			MaxRetries:    config.MaxRetries,
+			TLSConfig:     tlsConfig,
+		}), nil
+	case ModeCluster:
+		if config.DB != 0 {
+			return nil, fmt.Errorf("%w: db is not supported in cluster mode", ErrInvalidConfig)
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:      config.Addrs,
+			Password:   config.Password,
+			PoolSize:   config.PoolSize,
+			MaxRetries: config.MaxRetries,
+			TLSConfig:  tlsConfig,
+		}), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidConfig, config.Mode)
+	}
+}
+
+func newTLSConfig(config *TLSConfig) (*tls.Config, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificate in CA file", ErrInvalidConfig)
+		}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,101 @@
+package redisclient
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/suite"
+)
+
+type redisClientTestSuite struct {
+	suite.Suite
+}
+
+func TestRedisClientSuite(t *testing.T) {
+	suite.Run(t, new(redisClientTestSuite))
+}
+
+func (s *redisClientTestSuite) TestStandalone() {
+	mr := miniredis.RunT(s.T())
+	mr.RequireAuth("secret")
+
+	client, err := New(Config{Addrs: []string{mr.Addr()}, Password: "secret"})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.IsType(&redis.Client{}, client)
+	s.NoError(client.Set("key", "value", 0).Err())
+	mr.CheckGet(s.T(), "key", "value")
+
+	client, err = New(Config{Mode: ModeStandalone, Addrs: []string{mr.Addr()}, Password: "wrong"})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.Error(client.Ping().Err())
+}
+
+func (s *redisClientTestSuite) TestTLS() {
+	cert, err := tls.LoadX509KeyPair("testdata/server.crt", "testdata/server.key")
+	s.Require().NoError(err)
+	mr, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{cert}})
+	s.Require().NoError(err)
+	defer mr.Close()
+
+	client, err := New(Config{Addrs: []string{mr.Addr()}, TLS: &TLSConfig{CAFile: "testdata/server.crt"}})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.NoError(client.Ping().Err())
+
+	// the server isn't trusted by system roots
+	client, err = New(Config{Addrs: []string{mr.Addr()}, TLS: &TLSConfig{}})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.Error(client.Ping().Err())
+
+	client, err = New(Config{Addrs: []string{mr.Addr()}, TLS: &TLSConfig{InsecureSkipVerify: true}})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.NoError(client.Ping().Err())
+
+	_, err = New(Config{Addrs: []string{mr.Addr()}, TLS: &TLSConfig{CAFile: "testdata/server.key"}})
+	s.ErrorIs(err, ErrInvalidConfig)
+	_, err = New(Config{Addrs: []string{mr.Addr()}, TLS: &TLSConfig{CAFile: "testdata/missing.crt"}})
+	s.Error(err)
+}
+
+func (s *redisClientTestSuite) TestCluster() {
+	// miniredis serves all slots as a single node cluster
+	mr := miniredis.RunT(s.T())
+
+	client, err := New(Config{Mode: ModeCluster, Addrs: []string{mr.Addr()}})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.IsType(&redis.ClusterClient{}, client)
+	s.NoError(client.Set("key", "value", 0).Err())
+	mr.CheckGet(s.T(), "key", "value")
+}
+
+func (s *redisClientTestSuite) TestSentinel() {
+	client, err := New(Config{Mode: ModeSentinel, Addrs: []string{"127.0.0.1:26379"}, MasterName: "mymaster"})
+	s.Require().NoError(err)
+	defer client.Close()
+	s.IsType(&redis.Client{}, client)
+}
+
+func (s *redisClientTestSuite) TestInvalidConfig() {
+	for _, config := range []Config{
+		{},
+		{Addrs: []string{"127.0.0.1:6379", "127.0.0.1:6380"}},
+		{Mode: ModeSentinel, Addrs: []string{"127.0.0.1:26379"}},
+		{Mode: ModeCluster, Addrs: []string{"127.0.0.1:7000"}, DB: 1},
+		{Mode: "replica", Addrs: []string{"127.0.0.1:6379"}},
+	} {
+		_, err := New(config)
+		s.ErrorIs(err, ErrInvalidConfig, "%+v", config)
+	}
+}
+
+func (s *redisClientTestSuite) TestParseAddrs() {
+	s.Equal([]string{"10.0.0.1:26379", "10.0.0.2:26379"}, ParseAddrs(" 10.0.0.1:26379,,10.0.0.2:26379 "))
+	s.Empty(ParseAddrs(""))
+}
@@ -0,0 +1,113 @@
+// Package safehttp provides HTTP clients fetching user-given URLs, which can not reach internal networks.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrBlocked indicates the URL resolves to an address which is not allowed to connect, e.g. loopback or
+	// private networks.
+	ErrBlocked = errors.New("address is blocked")
+	// ErrRedirect indicates the URL redirects too many times or to a scheme other than http and https.
+	ErrRedirect = errors.New("invalid redirect")
+)
+
+// blockedNetworks are networks not reachable from the public internet. Loopback, link-local and multicast
+// addresses are checked by net.IP methods.
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+	"fc00::/7",
+)
+
+// Config defines config of Client.
+type Config struct {
+	// Timeout limits a request including redirects and reading body.
+	Timeout      time.Duration
+	MaxRedirects int
+	// Blocked returns whether ip is not allowed to connect, IsBlocked is used if nil.
+	Blocked func(ip net.IP) bool
+}
+
+// NewClient creates an http.Client which checks addresses after DNS resolution on every connection, including
+// redirects, so DNS rebinding can not reach blocked networks either.
+func NewClient(config Config) *http.Client {
+	blocked := config.Blocked
+	if blocked == nil {
+		blocked = IsBlocked
+	}
+
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blocked(ip) {
+				return fmt.Errorf("%w: %s", ErrBlocked, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			// proxy from environment would connect to addresses not checked
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   config.Timeout,
+			ResponseHeaderTimeout: config.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		Timeout: config.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return fmt.Errorf("%w: too many redirects", ErrRedirect)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s", ErrRedirect, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// IsBlocked returns whether ip is in networks not reachable from the public internet.
+func IsBlocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.Equal(net.IPv4bcast) {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
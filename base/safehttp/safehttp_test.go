@@ -0,0 +1,61 @@
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type safehttpTestSuite struct {
+	suite.Suite
+}
+
+func TestSafeHTTPSuite(t *testing.T) {
+	suite.Run(t, new(safehttpTestSuite))
+}
+
+func (s *safehttpTestSuite) TestBlocked() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewClient(Config{Timeout: 100 * time.Millisecond}).Get(server.URL)
+	s.True(errors.Is(err, ErrBlocked), err)
+}
+
+func (s *safehttpTestSuite) TestRedirect() {
+	server := httptest.NewServer(http.RedirectHandler("/", http.StatusFound))
+	defer server.Close()
+	client := NewClient(Config{
+		Timeout:      100 * time.Millisecond,
+		MaxRedirects: 2,
+		Blocked:      func(net.IP) bool { return false },
+	})
+
+	_, err := client.Get(server.URL)
+	s.True(errors.Is(err, ErrRedirect), err)
+}
+
+func (s *safehttpTestSuite) TestIsBlocked() {
+	for ip, blocked := range map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.20.0.1":      true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"::ffff:10.0.0.1": true,
+		"8.8.8.8":         false,
+		"2001:4860::8888": false,
+	} {
+		s.Equal(blocked, IsBlocked(net.ParseIP(ip)), ip)
+	}
+}
@@ -0,0 +1,470 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+const (
+	headerAPIKey      = "X-API-Key"
+	headerContentType = "Content-Type"
+	mimeJSON          = "application/json"
+	importFileField   = "file"
+)
+
+var (
+	defaultBaseURL    = "http://localhost"
+	defaultTimeout    = 10 * time.Second
+	defaultRetryDelay = 100 * time.Millisecond
+)
+
+// Error defines error responded by the Rest API, which unwraps to ErrBadRequest, ErrNotFound etc. by status code.
+type Error struct {
+	StatusCode int
+	// Code is machine-readable code of the error, see api.Code*.
+	Code    string
+	Message string
+	// RequestID identifies the request in server logs.
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusGone:
+		return ErrGone
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	}
+	return nil
+}
+
+type clientImpl struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+// Option defines optional configuration of Client.
+type Option func(c *clientImpl)
+
+// WithBaseURL sets base URL of the Rest API, e.g. https://sho.rt.
+func WithBaseURL(baseURL string) Option {
+	return func(c *clientImpl) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithAPIKey authenticates requests by owner's API key.
+func WithAPIKey(apiKey string) Option {
+	return func(c *clientImpl) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithTimeout sets timeout of each attempt of a request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *clientImpl) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries retries idempotent requests up to retries times on network errors and 429, 502, 503 and 504
+// responses, delay doubles after each attempt.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *clientImpl) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+// WithHTTPClient sends requests by a copy of httpClient, with Timeout set by WithTimeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *clientImpl) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient creates an instance of Client.
+func NewClient(opts ...Option) Client {
+	c := &clientImpl{
+		baseURL:    defaultBaseURL,
+		httpClient: http.DefaultClient,
+		timeout:    defaultTimeout,
+		retryDelay: defaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	httpClient := *c.httpClient
+	httpClient.Timeout = c.timeout
+	c.httpClient = &httpClient
+
+	return c
+}
+
+func (c *clientImpl) Upload(ctx context.Context, req api.UploadURLRequest) (*api.UploadURLResponse, error) {
+	var resp api.UploadURLResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/urls", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) Get(ctx context.Context, domain, urlID string) (*api.ShortLinkResponse, error) {
+	var resp api.ShortLinkResponse
+	if err := c.doJSON(ctx, http.MethodGet, urlPath(urlID), domainQuery(domain), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) List(ctx context.Context, params ListParams) (*api.ListURLsResponse, error) {
+	query := url.Values{}
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	for _, tag := range params.Tags {
+		query.Add("tag", tag)
+	}
+	for name, t := range map[string]time.Time{
+		"createdAfter":  params.CreatedAfter,
+		"createdBefore": params.CreatedBefore,
+		"expireAfter":   params.ExpireAfter,
+		"expireBefore":  params.ExpireBefore,
+	} {
+		if !t.IsZero() {
+			query.Set(name, t.UTC().Format(time.RFC3339))
+		}
+	}
+	if params.Query != "" {
+		query.Set("q", params.Query)
+	}
+	if params.Search != "" {
+		query.Set("search", params.Search)
+	}
+	if params.Broken {
+		query.Set("broken", "true")
+	}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+
+	var resp api.ListURLsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/urls", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) Update(
+	ctx context.Context,
+	domain, urlID string,
+	req api.UpdateURLRequest,
+) (*api.ShortLinkResponse, error) {
+	var resp api.ShortLinkResponse
+	if err := c.doJSON(ctx, http.MethodPatch, urlPath(urlID), domainQuery(domain), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) SetExpiry(
+	ctx context.Context,
+	domain, urlID string,
+	req api.ExpiryRequest,
+) (*api.ShortLinkResponse, error) {
+	var resp api.ShortLinkResponse
+	if err := c.doJSON(ctx, http.MethodPost, urlPath(urlID)+"/expiry", domainQuery(domain), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) Delete(ctx context.Context, domain, urlID string) error {
+	return c.doJSON(ctx, http.MethodDelete, urlPath(urlID), domainQuery(domain), nil, nil)
+}
+
+func (c *clientImpl) Restore(ctx context.Context, domain, urlID string) (*api.ShortLinkResponse, error) {
+	var resp api.ShortLinkResponse
+	if err := c.doJSON(ctx, http.MethodPost, urlPath(urlID)+"/restore", domainQuery(domain), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) Purge(ctx context.Context, domain, urlID string) error {
+	query := domainQuery(domain)
+	query.Set("purge", "true")
+	return c.doJSON(ctx, http.MethodDelete, urlPath(urlID), query, nil, nil)
+}
+
+func (c *clientImpl) Stats(ctx context.Context, domain, urlID string) (*api.StatsResponse, error) {
+	var resp api.StatsResponse
+	if err := c.doJSON(ctx, http.MethodGet, urlPath(urlID)+"/stats", domainQuery(domain), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) Health(ctx context.Context, domain, urlID string) (*api.HealthResponse, error) {
+	var resp api.HealthResponse
+	if err := c.doJSON(ctx, http.MethodGet, urlPath(urlID)+"/health", domainQuery(domain), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) QRCode(ctx context.Context, urlID string, params QRCodeParams) ([]byte, error) {
+	query := url.Values{}
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+	if params.Format != "" {
+		query.Set("format", params.Format)
+	}
+	if params.Size != 0 {
+		query.Set("size", strconv.Itoa(params.Size))
+	}
+	if params.Level != "" {
+		query.Set("level", params.Level)
+	}
+	if params.Margin != nil {
+		query.Set("margin", strconv.Itoa(*params.Margin))
+	}
+
+	res, err := c.do(ctx, http.MethodGet, urlPath(urlID)+"/qr", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return ioutil.ReadAll(res.Body)
+}
+
+func (c *clientImpl) Import(ctx context.Context, params ImportParams) (*api.ImportResponse, error) {
+	query := url.Values{}
+	format := params.Format
+	if format != "" {
+		query.Set("format", format)
+	} else {
+		format = "csv"
+	}
+	if params.Conflict != "" {
+		query.Set("conflict", params.Conflict)
+	}
+	if params.DryRun {
+		query.Set("dryRun", "true")
+	}
+
+	// file is streamed in multipart body, it's not retried since it can't be read twice
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile(importFileField, "links."+format)
+		if err == nil {
+			_, err = io.Copy(part, params.File)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	res, err := c.send(ctx, http.MethodPost, c.baseURL+"/api/v1/urls/import?"+query.Encode(), pr, mw.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusUnprocessableEntity {
+		return nil, parseError(res)
+	}
+
+	var resp api.ImportResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clientImpl) Export(ctx context.Context, format string, w io.Writer) error {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+
+	res, err := c.do(ctx, http.MethodGet, "/api/v1/urls/export", query, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// doJSON sends body in JSON and decodes response body into out.
+func (c *clientImpl) doJSON(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	res, err := c.do(ctx, method, path, query, b)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// do sends request with retries, and returns *Error if response status is not 2xx.
+func (c *clientImpl) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	retries := 0
+	if isIdempotent(method) {
+		retries = c.retries
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		var contentType string
+		if body != nil {
+			reader, contentType = bytes.NewReader(body), mimeJSON
+		}
+		res, err := c.send(ctx, method, u, reader, contentType)
+		if attempt >= retries || !shouldRetry(res, err) || ctx.Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			if res.StatusCode >= http.StatusBadRequest {
+				defer res.Body.Close()
+				return nil, parseError(res)
+			}
+			return res, nil
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c *clientImpl) send(ctx context.Context, method, u string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set(headerContentType, contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(headerAPIKey, c.apiKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func urlPath(urlID string) string {
+	return "/api/v1/urls/" + url.PathEscape(urlID)
+}
+
+func domainQuery(domain string) url.Values {
+	query := url.Values{}
+	if domain != "" {
+		query.Set("domain", domain)
+	}
+	return query
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseError reads error response, which is problem details or a JSON string.
+func parseError(res *http.Response) error {
+	e := &Error{StatusCode: res.StatusCode}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		e.Message = http.StatusText(res.StatusCode)
+		return e
+	}
+
+	var problem api.Problem
+	if err := json.Unmarshal(b, &problem); err == nil && problem.Code != "" {
+		e.Code = problem.Code
+		e.Message = problem.Detail
+		e.RequestID = problem.RequestID
+	} else if err := json.Unmarshal(b, &e.Message); err != nil {
+		e.Message = strings.TrimSpace(string(b))
+	}
+	return e
+}
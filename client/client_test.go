@@ -0,0 +1,448 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/cache"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/stats"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	testURL    = "https://boards.greenhouse.io/dcard/jobs/871123?gh_src=ok8y1h1/"
+	testAPIKey = "test-api-key"
+)
+
+type clientTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	db       *gorm.DB
+	impl     Client
+	ownerDao dao.OwnerDao
+	auditDao dao.AuditDao
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(clientTestSuite))
+}
+
+// SetupSuite serves rest with urlshortener backed by in-memory sqlite, cache and locker.
+func (s *clientTestSuite) SetupSuite() {
+	// the cache is shared, so ids are checked against blocked ids in another connection while importing in a
+	// transaction, and the db is dropped once it's closed
+	db, err := gorm.Open(sqlite.Open("file:client_test?mode=memory&cache=shared"), &gorm.Config{})
+	s.Require().NoError(err)
+	s.db = db
+
+	shortLinkDao, err := dao.NewShortLinkDao(db)
+	s.Require().NoError(err)
+	domainDao, err := dao.NewDomainDao(db)
+	s.Require().NoError(err)
+	ownerDao, err := dao.NewOwnerDao(db)
+	s.Require().NoError(err)
+	s.ownerDao = ownerDao
+	clickDao, err := dao.NewClickDao(db)
+	s.Require().NoError(err)
+	webhookDao, err := dao.NewWebhookDao(db)
+	s.Require().NoError(err)
+	auditDao, err := dao.NewAuditDao(db)
+	s.Require().NoError(err)
+	s.auditDao = auditDao
+	blockedIDDao, err := dao.NewBlockedIDDao(db)
+	s.Require().NoError(err)
+
+	defaultDomain := dao.Domain{Host: "sho.rt", Scheme: "https"}
+	s.Require().NoError(domainDao.Create(&defaultDomain))
+	s.Require().NoError(ownerDao.Create(&dao.Owner{Name: "test", APIKey: testAPIKey}))
+
+	urlShortener := urlshortener.NewURLShortener(
+		lock.NewMemory(clock.NewClock()),
+		&memoryCache{entries: map[string][]byte{}},
+		urlshortener.Daos{
+			ShortLink: shortLinkDao,
+			Domain:    domainDao,
+			Owner:     ownerDao,
+			Webhook:   webhookDao,
+			Audit:     auditDao,
+			BlockedID: blockedIDDao,
+		},
+		&defaultDomain,
+		clock.NewClock(),
+	)
+	r, err := rest.NewRest("https://sho.rt", 0, urlShortener, stats.NewStats(clickDao, clock.NewClock()), clock.NewClock())
+	s.Require().NoError(err)
+
+	s.server = httptest.NewServer(r)
+	s.impl = NewClient(WithBaseURL(s.server.URL))
+}
+
+func (s *clientTestSuite) TearDownSuite() {
+	s.server.Close()
+	db, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(db.Close())
+}
+
+func (s *clientTestSuite) upload(c Client) *api.UploadURLResponse {
+	resp, err := c.Upload(context.Background(), api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	s.Require().NoError(err)
+	return resp
+}
+
+func (s *clientTestSuite) TestUpload() {
+	resp := s.upload(s.impl)
+	s.Len(resp.ID, 11)
+	s.Equal("https://sho.rt/"+resp.ID, resp.ShortURL)
+}
+
+func (s *clientTestSuite) TestUploadInvalid() {
+	_, err := s.impl.Upload(context.Background(), api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: time.Now().Add(-time.Hour).Format(time.RFC3339),
+	})
+	s.True(errors.Is(err, ErrBadRequest))
+
+	var e *Error
+	s.Require().True(errors.As(err, &e))
+	s.Equal(http.StatusBadRequest, e.StatusCode)
+	s.Equal("expireAt should be greater than now", e.Message)
+}
+
+func (s *clientTestSuite) TestUploadInvalidAPIKey() {
+	_, err := NewClient(WithBaseURL(s.server.URL), WithAPIKey("unknown")).Upload(context.Background(), api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	s.True(errors.Is(err, ErrUnauthorized))
+}
+
+func (s *clientTestSuite) TestStats() {
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(testAPIKey))
+	resp := s.upload(owned)
+
+	st, err := owned.Stats(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(resp.ID, st.ID)
+	s.Equal(int64(0), st.Clicks)
+
+	// owned short link is not visible to others
+	_, err = s.impl.Stats(context.Background(), "", resp.ID)
+	s.True(errors.Is(err, ErrNotFound))
+}
+
+func (s *clientTestSuite) TestHealth() {
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(testAPIKey))
+	resp := s.upload(owned)
+
+	h, err := owned.Health(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(resp.ID, h.ID)
+	s.False(h.Broken)
+	s.Nil(h.CheckedAt)
+
+	page, err := owned.List(context.Background(), ListParams{Broken: true})
+	s.Require().NoError(err)
+	s.Empty(page.Items)
+}
+
+func (s *clientTestSuite) TestStatsNotFound() {
+	_, err := s.impl.Stats(context.Background(), "", "abcdefghijk")
+	s.True(errors.Is(err, ErrNotFound))
+
+	var e *Error
+	s.Require().True(errors.As(err, &e))
+	s.Equal(api.CodeNotFound, e.Code)
+	s.Equal("short link is not found", e.Message)
+	s.NotEmpty(e.RequestID)
+}
+
+func (s *clientTestSuite) TestQRCode() {
+	resp := s.upload(s.impl)
+
+	b, err := s.impl.QRCode(context.Background(), resp.ID, QRCodeParams{Format: "svg", Size: 128})
+	s.Require().NoError(err)
+	s.Contains(string(b), "<svg")
+}
+
+func (s *clientTestSuite) TestRetries() {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"abcdefghijk","clicks":3}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(WithBaseURL(server.URL), WithRetries(2, time.Millisecond))
+	st, err := c.Stats(context.Background(), "", "abcdefghijk")
+	s.Require().NoError(err)
+	s.Equal(int64(3), st.Clicks)
+	s.Equal(int32(3), atomic.LoadInt32(&attempts))
+
+	// upload is not idempotent, never retried
+	atomic.StoreInt32(&attempts, 0)
+	_, err = c.Upload(context.Background(), api.UploadURLRequest{URL: testURL})
+	s.True(errors.Is(err, ErrServer))
+	s.Equal(int32(1), atomic.LoadInt32(&attempts))
+}
+
+func (s *clientTestSuite) TestContextCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.impl.Stats(ctx, "", "abcdefghijk")
+	s.True(errors.Is(err, context.Canceled))
+}
+
+// memoryCache implements cache.RemoteCache in memory, entries never expire.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (c *memoryCache) Exists(key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key]
+	return ok, nil
+}
+
+func (c *memoryCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.entries[key]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return v, nil
+}
+
+func (c *memoryCache) GetOrSet(key string, gen cache.RemoteEntryGenerator) ([]byte, error) {
+	if v, err := c.Get(key); err == nil {
+		return v, nil
+	}
+	v, _, err := gen()
+	if err != nil {
+		return nil, err
+	}
+	return v, c.Set(key, v, 0)
+}
+
+func (c *memoryCache) Set(key string, value interface{}, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(key, value)
+}
+
+func (c *memoryCache) SetNX(key string, value interface{}, _ time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return false, nil
+	}
+	if err := c.set(key, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *memoryCache) set(key string, value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		c.entries[key] = v
+	case string:
+		c.entries[key] = []byte(v)
+	default:
+		return errors.New("unsupported value")
+	}
+	return nil
+}
+
+func (c *memoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+func (s *clientTestSuite) TestGetUpdateDelete() {
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(testAPIKey))
+	resp := s.upload(owned)
+
+	link, err := owned.Get(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(testURL, link.URL)
+	s.Equal("sho.rt", link.Domain)
+
+	url := "https://example.com"
+	preview := true
+	link, err = owned.Update(context.Background(), "", resp.ID, api.UpdateURLRequest{URL: &url, Preview: &preview})
+	s.Require().NoError(err)
+	s.Equal(url, link.URL)
+	s.True(link.Preview)
+
+	link, err = owned.Get(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(url, link.URL)
+
+	// only the owner can update
+	_, err = s.impl.Update(context.Background(), "", resp.ID, api.UpdateURLRequest{URL: &url})
+	s.True(errors.Is(err, ErrUnauthorized))
+
+	// deleted short links are kept for restoring but can't be updated
+	s.Require().NoError(owned.Delete(context.Background(), "", resp.ID))
+	link, err = owned.Get(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(dao.StatusDeleted, link.Status)
+	s.NotNil(link.DeletedAt)
+	_, err = owned.Update(context.Background(), "", resp.ID, api.UpdateURLRequest{URL: &url})
+	s.True(errors.Is(err, ErrGone))
+
+	link, err = owned.Restore(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(dao.StatusActive, link.Status)
+	s.Nil(link.DeletedAt)
+
+	s.Require().NoError(owned.Purge(context.Background(), "", resp.ID))
+	_, err = owned.Get(context.Background(), "", resp.ID)
+	s.True(errors.Is(err, ErrNotFound))
+
+	// changes are recorded with the source of requests
+	events, err := s.auditDao.List(dao.AuditFilter{URLID: resp.ID, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(events, 5)
+	s.Equal([]string{dao.AuditPurge, dao.AuditRestore, dao.AuditDelete, dao.AuditUpdate, dao.AuditCreate},
+		[]string{events[0].Action, events[1].Action, events[2].Action, events[3].Action, events[4].Action})
+	s.Contains(events[3].Before, testURL)
+	s.Contains(events[3].After, url)
+	for _, event := range events {
+		s.NotZero(event.OwnerID)
+		s.Equal("127.0.0.1", event.SourceIP)
+		s.NotEmpty(event.RequestID)
+	}
+}
+
+func (s *clientTestSuite) TestSetExpiry() {
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(testAPIKey))
+	resp := s.upload(owned)
+	// the cache entry is refreshed
+	_, err := owned.Get(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+
+	link, err := owned.SetExpiry(context.Background(), "", resp.ID, api.ExpiryRequest{Never: true})
+	s.Require().NoError(err)
+	s.Equal(dao.NeverExpire, link.ExpireAt)
+	link, err = owned.Get(context.Background(), "", resp.ID)
+	s.Require().NoError(err)
+	s.Equal(dao.NeverExpire, link.ExpireAt)
+
+	_, err = owned.SetExpiry(context.Background(), "", resp.ID, api.ExpiryRequest{})
+	s.True(errors.Is(err, ErrBadRequest))
+}
+
+func (s *clientTestSuite) TestList() {
+	owner := dao.Owner{Name: "list", APIKey: "list-api-key"}
+	s.Require().NoError(s.ownerDao.Create(&owner))
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(owner.APIKey))
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, s.upload(owned).ID)
+	}
+
+	page, err := owned.List(context.Background(), ListParams{Limit: 2})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 2)
+	s.Equal(ids[2], page.Items[0].ID)
+	s.NotEmpty(page.NextCursor)
+
+	page, err = owned.List(context.Background(), ListParams{Cursor: page.NextCursor, Limit: 2})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Equal(ids[0], page.Items[0].ID)
+	s.Empty(page.NextCursor)
+}
+
+func (s *clientTestSuite) TestListFilter() {
+	owner := dao.Owner{Name: "filter", APIKey: "filter-api-key"}
+	s.Require().NoError(s.ownerDao.Create(&owner))
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(owner.APIKey))
+	s.upload(owned)
+	tagged, err := owned.Upload(context.Background(), api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: time.Now().Add(time.Hour).Format(time.RFC3339),
+		Title:    "Summer sale",
+		Tags:     []string{"campaign", "summer"},
+	})
+	s.Require().NoError(err)
+
+	page, err := owned.List(context.Background(), ListParams{Tags: []string{"summer", "campaign"}})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Equal(tagged.ID, page.Items[0].ID)
+	s.Equal("Summer sale", page.Items[0].Title)
+
+	page, err = owned.List(context.Background(), ListParams{Query: "sale", CreatedAfter: time.Now().Add(-time.Hour)})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Equal(tagged.ID, page.Items[0].ID)
+}
+
+func (s *clientTestSuite) TestImportExport() {
+	owner := dao.Owner{Name: "bulk", APIKey: "bulk-api-key"}
+	s.Require().NoError(s.ownerDao.Create(&owner))
+	owned := NewClient(WithBaseURL(s.server.URL), WithAPIKey(owner.APIKey))
+	expireAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	file := "id,url,expireAt,utm\n" +
+		"import-a," + testURL + "," + expireAt + ",utm_source=a\n" +
+		"import-b," + testURL + "," + expireAt + ",\n"
+
+	resp, err := owned.Import(context.Background(), ImportParams{File: strings.NewReader(file), DryRun: true})
+	s.Require().NoError(err)
+	s.Equal(api.ImportResponse{Total: 2, Created: 2, DryRun: true}, *resp)
+
+	resp, err = owned.Import(context.Background(), ImportParams{File: strings.NewReader(file)})
+	s.Require().NoError(err)
+	s.True(resp.Committed)
+
+	link, err := owned.Get(context.Background(), "", "import-a")
+	s.Require().NoError(err)
+	s.Equal(map[string]string{"utm_source": "a"}, link.UTM)
+
+	resp, err = owned.Import(context.Background(), ImportParams{File: strings.NewReader(file), Conflict: "fail"})
+	s.Require().NoError(err)
+	s.False(resp.Committed)
+	s.Equal(2, resp.Failed)
+
+	var buf bytes.Buffer
+	s.Require().NoError(owned.Export(context.Background(), "jsonl", &buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	s.Require().Len(lines, 2)
+	s.Contains(lines[0], `"id":"import-a"`)
+
+	_, err = s.impl.Import(context.Background(), ImportParams{File: strings.NewReader(file)})
+	s.True(errors.Is(err, ErrUnauthorized))
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+var (
+	// ErrBadRequest indicates the request is invalid, e.g. validation fails.
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized indicates the API key is invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden indicates the owner is not allowed to do the operation.
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound indicates the short link does not exist or is expired.
+	ErrNotFound = errors.New("not found")
+	// ErrGone indicates the short link is disabled or deleted.
+	ErrGone = errors.New("gone")
+	// ErrConflict indicates the id is used by another short link.
+	ErrConflict = errors.New("conflict")
+	// ErrServer indicates the server fails to handle the request.
+	ErrServer = errors.New("server error")
+)
+
+// QRCodeParams defines parameters of rendering QR code of a short link, zero values use server defaults.
+type QRCodeParams struct {
+	// Domain is host of the short link domain, default domain is used if empty.
+	Domain string
+	// Format is png or svg.
+	Format string
+	Size   int
+	// Level is error correction level, one of L, M, Q and H.
+	Level  string
+	Margin *int
+}
+
+// ListParams defines parameters of listing short links of the owner, zero values use server defaults.
+type ListParams struct {
+	// Cursor is NextCursor of the previous page, empty for the first page.
+	Cursor string
+	Limit  int
+	// Tags lists short links having all of them.
+	Tags []string
+	// CreatedAfter, CreatedBefore, ExpireAfter and ExpireBefore bound time ranges of short links, after is
+	// inclusive and before is exclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	ExpireAfter   time.Time
+	ExpireBefore  time.Time
+	// Query matches URL or title of short links by Search, substring or fulltext.
+	Query  string
+	Search string
+	// Broken lists short links whose destinations are broken only.
+	Broken bool
+	// Status lists short links in the status only, one of active, disabled and deleted. Deleted short links are
+	// excluded if it's empty.
+	Status string
+}
+
+// ImportParams defines parameters of importing short links, zero values use server defaults.
+type ImportParams struct {
+	// File is content of import file, which is streamed to the server.
+	File io.Reader
+	// Format is csv or jsonl.
+	Format string
+	// Conflict defines how short links with existing ids are handled, one of skip, overwrite and fail.
+	Conflict string
+	// DryRun validates the file without importing.
+	DryRun bool
+}
+
+// Client defines interface of the Rest API client.
+type Client interface {
+	Upload(ctx context.Context, req api.UploadURLRequest) (*api.UploadURLResponse, error)
+	// Get returns short link urlID in domain, default domain is used if domain is empty.
+	Get(ctx context.Context, domain, urlID string) (*api.ShortLinkResponse, error)
+	// List returns a page of short links owned by the API key owner.
+	List(ctx context.Context, params ListParams) (*api.ListURLsResponse, error)
+	Update(ctx context.Context, domain, urlID string, req api.UpdateURLRequest) (*api.ShortLinkResponse, error)
+	// SetExpiry extends, shortens or removes the expiry of short link urlID in domain, it's not retried since
+	// extending is not idempotent.
+	SetExpiry(ctx context.Context, domain, urlID string, req api.ExpiryRequest) (*api.ShortLinkResponse, error)
+	// Delete soft deletes short link urlID in domain, which could be restored until it's purged.
+	Delete(ctx context.Context, domain, urlID string) error
+	// Restore makes deleted short link urlID in domain active again.
+	Restore(ctx context.Context, domain, urlID string) (*api.ShortLinkResponse, error)
+	// Purge deletes short link urlID in domain permanently, whether it's deleted or not.
+	Purge(ctx context.Context, domain, urlID string) error
+	// Stats returns click stats of short link urlID in domain, default domain is used if domain is empty.
+	Stats(ctx context.Context, domain, urlID string) (*api.StatsResponse, error)
+	// Health returns the latest health check of the destination of short link urlID in domain.
+	Health(ctx context.Context, domain, urlID string) (*api.HealthResponse, error)
+	// QRCode returns QR code image of short link urlID.
+	QRCode(ctx context.Context, urlID string, params QRCodeParams) ([]byte, error)
+	// Import imports short links of the API key owner from a file. The response is returned without error if
+	// some records fail, check Failed and Committed of it.
+	Import(ctx context.Context, params ImportParams) (*api.ImportResponse, error)
+	// Export writes all short links of the API key owner to w in format, csv or jsonl.
+	Export(ctx context.Context, format string, w io.Writer) error
+}
@@ -0,0 +1,289 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	client "github.com/georgechang0117/url-shortener/client"
+	api "github.com/georgechang0117/url-shortener/rest/api"
+	mock "github.com/stretchr/testify/mock"
+
+	context "context"
+	io "io"
+)
+
+// Client is an autogenerated mock type for the Client type
+type Client struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, domain, urlID
+func (_m *Client) Delete(ctx context.Context, domain string, urlID string) error {
+	ret := _m.Called(ctx, domain, urlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, domain, urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Export provides a mock function with given fields: ctx, format, w
+func (_m *Client) Export(ctx context.Context, format string, w io.Writer) error {
+	ret := _m.Called(ctx, format, w)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, io.Writer) error); ok {
+		r0 = rf(ctx, format, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, domain, urlID
+func (_m *Client) Get(ctx context.Context, domain string, urlID string) (*api.ShortLinkResponse, error) {
+	ret := _m.Called(ctx, domain, urlID)
+
+	var r0 *api.ShortLinkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *api.ShortLinkResponse); ok {
+		r0 = rf(ctx, domain, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ShortLinkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Health provides a mock function with given fields: ctx, domain, urlID
+func (_m *Client) Health(ctx context.Context, domain string, urlID string) (*api.HealthResponse, error) {
+	ret := _m.Called(ctx, domain, urlID)
+
+	var r0 *api.HealthResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *api.HealthResponse); ok {
+		r0 = rf(ctx, domain, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.HealthResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Import provides a mock function with given fields: ctx, params
+func (_m *Client) Import(ctx context.Context, params client.ImportParams) (*api.ImportResponse, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *api.ImportResponse
+	if rf, ok := ret.Get(0).(func(context.Context, client.ImportParams) *api.ImportResponse); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ImportResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, client.ImportParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, params
+func (_m *Client) List(ctx context.Context, params client.ListParams) (*api.ListURLsResponse, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *api.ListURLsResponse
+	if rf, ok := ret.Get(0).(func(context.Context, client.ListParams) *api.ListURLsResponse); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ListURLsResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, client.ListParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: ctx, domain, urlID
+func (_m *Client) Purge(ctx context.Context, domain string, urlID string) error {
+	ret := _m.Called(ctx, domain, urlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, domain, urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// QRCode provides a mock function with given fields: ctx, urlID, params
+func (_m *Client) QRCode(ctx context.Context, urlID string, params client.QRCodeParams) ([]byte, error) {
+	ret := _m.Called(ctx, urlID, params)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.QRCodeParams) []byte); ok {
+		r0 = rf(ctx, urlID, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.QRCodeParams) error); ok {
+		r1 = rf(ctx, urlID, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: ctx, domain, urlID
+func (_m *Client) Restore(ctx context.Context, domain string, urlID string) (*api.ShortLinkResponse, error) {
+	ret := _m.Called(ctx, domain, urlID)
+
+	var r0 *api.ShortLinkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *api.ShortLinkResponse); ok {
+		r0 = rf(ctx, domain, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ShortLinkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetExpiry provides a mock function with given fields: ctx, domain, urlID, req
+func (_m *Client) SetExpiry(ctx context.Context, domain string, urlID string, req api.ExpiryRequest) (*api.ShortLinkResponse, error) {
+	ret := _m.Called(ctx, domain, urlID, req)
+
+	var r0 *api.ShortLinkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, api.ExpiryRequest) *api.ShortLinkResponse); ok {
+		r0 = rf(ctx, domain, urlID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ShortLinkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, api.ExpiryRequest) error); ok {
+		r1 = rf(ctx, domain, urlID, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stats provides a mock function with given fields: ctx, domain, urlID
+func (_m *Client) Stats(ctx context.Context, domain string, urlID string) (*api.StatsResponse, error) {
+	ret := _m.Called(ctx, domain, urlID)
+
+	var r0 *api.StatsResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *api.StatsResponse); ok {
+		r0 = rf(ctx, domain, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.StatsResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, domain, urlID, req
+func (_m *Client) Update(ctx context.Context, domain string, urlID string, req api.UpdateURLRequest) (*api.ShortLinkResponse, error) {
+	ret := _m.Called(ctx, domain, urlID, req)
+
+	var r0 *api.ShortLinkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, api.UpdateURLRequest) *api.ShortLinkResponse); ok {
+		r0 = rf(ctx, domain, urlID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ShortLinkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, api.UpdateURLRequest) error); ok {
+		r1 = rf(ctx, domain, urlID, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Upload provides a mock function with given fields: ctx, req
+func (_m *Client) Upload(ctx context.Context, req api.UploadURLRequest) (*api.UploadURLResponse, error) {
+	ret := _m.Called(ctx, req)
+
+	var r0 *api.UploadURLResponse
+	if rf, ok := ret.Get(0).(func(context.Context, api.UploadURLRequest) *api.UploadURLResponse); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.UploadURLResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, api.UploadURLRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/client"
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+const (
+	formatJSONL = "jsonl"
+
+	defaultExpire = "720h"
+)
+
+type cli struct {
+	client client.Client
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+	output string
+	now    func() time.Time
+}
+
+func (c *cli) run(command string, args []string) error {
+	commands := map[string]func(args []string) error{
+		"create":  c.create,
+		"get":     c.get,
+		"list":    c.list,
+		"update":  c.update,
+		"expire":  c.expire,
+		"delete":  c.delete,
+		"restore": c.restore,
+		"stats":   c.stats,
+		"health":  c.health,
+		"import":  c.importLinks,
+		"export":  c.exportLinks,
+	}
+	cmd, ok := commands[command]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", command)
+	}
+	return cmd(args)
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	return fs
+}
+
+func (c *cli) create(args []string) error {
+	fs := c.flagSet("create")
+	rawURL := fs.String("url", "", "destination URL")
+	expire := fs.String("expire", defaultExpire, "expiry, RFC3339 time or duration from now")
+	domain := fs.String("domain", "", "domain of short links, default domain if empty")
+	preview := fs.Bool("preview", false, "render preview page instead of redirecting")
+	queryMode := fs.String("query_mode", "", "how query parameters are passed through, override or preserve")
+	title := fs.String("title", "", "title of short links")
+	tags := fs.String("tags", "", "comma-separated tags of short links")
+	fallbackURL := fs.String("fallback_url", "", "URL redirected to while the destination is broken")
+	csvPath := fs.String("csv", "", "CSV file with header, columns: url, expireAt, domain, preview, queryMode")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	expireAt, err := parseExpire(*expire, c.now())
+	if err != nil {
+		return err
+	}
+	defaults := api.UploadURLRequest{
+		ExpireAt:    expireAt,
+		Domain:      *domain,
+		Preview:     *preview,
+		QueryMode:   *queryMode,
+		Title:       *title,
+		Tags:        splitTags(*tags),
+		FallbackURL: *fallbackURL,
+	}
+
+	var reqs []api.UploadURLRequest
+	switch {
+	case *rawURL != "":
+		req := defaults
+		req.URL = *rawURL
+		reqs = append(reqs, req)
+	case *csvPath != "":
+		r, err := c.open(*csvPath)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if reqs, err = readCSV(r, defaults); err != nil {
+			return err
+		}
+	default:
+		scanner := bufio.NewScanner(c.in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			req := defaults
+			req.URL = line
+			reqs = append(reqs, req)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	return c.uploadAll(reqs)
+}
+
+// uploadAll uploads reqs one by one, failed ones are reported without stopping the others.
+func (c *cli) uploadAll(reqs []api.UploadURLRequest) error {
+	var results []api.UploadURLResponse
+	var rows [][]string
+	failed := 0
+	for i, req := range reqs {
+		resp, err := c.client.Upload(context.Background(), req)
+		if err != nil {
+			failed++
+			fmt.Fprintf(c.errOut, "item %d (%s): %v\n", i+1, req.URL, err)
+			continue
+		}
+		results = append(results, *resp)
+		rows = append(rows, []string{resp.ID, resp.ShortURL, req.URL})
+	}
+
+	if err := c.print(results, []string{"ID", "SHORT URL", "URL"}, rows); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d failed", failed, len(reqs))
+	}
+	return nil
+}
+
+func (c *cli) get(args []string) error {
+	fs := c.flagSet("get")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	link, err := c.client.Get(context.Background(), *domain, id)
+	if err != nil {
+		return err
+	}
+	return c.printLink(link)
+}
+
+func (c *cli) list(args []string) error {
+	fs := c.flagSet("list")
+	limit := fs.Int("limit", 20, "max number of short links in a page")
+	cursor := fs.String("cursor", "", "cursor of the page, printed after the previous page")
+	all := fs.Bool("all", false, "list all pages")
+	tags := fs.String("tags", "", "comma-separated tags, short links having all of them are listed")
+	query := fs.String("q", "", "text matching URL or title")
+	search := fs.String("search", "", "how -q is matched, substring or fulltext")
+	broken := fs.Bool("broken", false, "list short links whose destinations are broken only")
+	status := fs.String("status", "", "list short links in the status only, active, disabled or deleted")
+	createdAfter := fs.String("created_after", "", "RFC3339 time, short links created at or after it are listed")
+	createdBefore := fs.String("created_before", "", "RFC3339 time, short links created before it are listed")
+	expireAfter := fs.String("expire_after", "", "RFC3339 time, short links expiring at or after it are listed")
+	expireBefore := fs.String("expire_before", "", "RFC3339 time, short links expiring before it are listed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	params := client.ListParams{
+		Limit:  *limit,
+		Tags:   splitTags(*tags),
+		Query:  *query,
+		Search: *search,
+		Broken: *broken,
+		Status: *status,
+	}
+	for _, bound := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{
+		{"created_after", *createdAfter, &params.CreatedAfter},
+		{"created_before", *createdBefore, &params.CreatedBefore},
+		{"expire_after", *expireAfter, &params.ExpireAfter},
+		{"expire_before", *expireBefore, &params.ExpireBefore},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", bound.name, bound.value)
+		}
+		*bound.time = t
+	}
+
+	var links []api.ShortLinkResponse
+	next := *cursor
+	for {
+		params.Cursor = next
+		page, err := c.client.List(context.Background(), params)
+		if err != nil {
+			return err
+		}
+		links = append(links, page.Items...)
+		next = page.NextCursor
+		if !*all || next == "" {
+			break
+		}
+	}
+
+	if err := c.printLinks(links); err != nil {
+		return err
+	}
+	if next != "" && c.output == outputTable {
+		fmt.Fprintf(c.errOut, "next cursor: %s\n", next)
+	}
+	return nil
+}
+
+func (c *cli) update(args []string) error {
+	fs := c.flagSet("update")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	rawURL := fs.String("url", "", "destination URL")
+	expire := fs.String("expire", "", "expiry, RFC3339 time or duration from now")
+	preview := fs.Bool("preview", false, "render preview page instead of redirecting")
+	queryMode := fs.String("query_mode", "", "how query parameters are passed through, override, preserve or empty to drop")
+	title := fs.String("title", "", "title of short link")
+	tags := fs.String("tags", "", "comma-separated tags replacing all tags, empty to remove them")
+	fallbackURL := fs.String("fallback_url", "", "URL redirected to while the destination is broken, empty to remove it")
+	status := fs.String("status", "", "status of short link, active or disabled")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// only flags given are updated
+	var req api.UpdateURLRequest
+	var visitErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			req.URL = rawURL
+		case "expire":
+			expireAt, err := parseExpire(*expire, c.now())
+			if err != nil {
+				visitErr = err
+			}
+			req.ExpireAt = &expireAt
+		case "preview":
+			req.Preview = preview
+		case "query_mode":
+			req.QueryMode = queryMode
+		case "title":
+			req.Title = title
+		case "tags":
+			tagList := splitTags(*tags)
+			if tagList == nil {
+				tagList = []string{}
+			}
+			req.Tags = &tagList
+		case "fallback_url":
+			req.FallbackURL = fallbackURL
+		case "status":
+			req.Status = status
+		}
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+
+	link, err := c.client.Update(context.Background(), *domain, id, req)
+	if err != nil {
+		return err
+	}
+	return c.printLink(link)
+}
+
+func (c *cli) expire(args []string) error {
+	fs := c.flagSet("expire")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	at := fs.String("at", "", "new expiry, RFC3339 time or duration from now")
+	extend := fs.Duration("extend", 0, "extends the expiry by the duration, from now if the short link is expired")
+	never := fs.Bool("never", false, "removes the expiry")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	req := api.ExpiryRequest{
+		ExtendSeconds: int64(extend.Seconds()),
+		Never:         *never,
+	}
+	if *at != "" {
+		if req.ExpireAt, err = parseExpire(*at, c.now()); err != nil {
+			return err
+		}
+	}
+
+	link, err := c.client.SetExpiry(context.Background(), *domain, id, req)
+	if err != nil {
+		return err
+	}
+	return c.printLink(link)
+}
+
+func (c *cli) delete(args []string) error {
+	fs := c.flagSet("delete")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	purge := fs.Bool("purge", false, "delete permanently instead of keeping it for restoring")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *purge {
+		return c.client.Purge(context.Background(), *domain, id)
+	}
+	return c.client.Delete(context.Background(), *domain, id)
+}
+
+func (c *cli) restore(args []string) error {
+	fs := c.flagSet("restore")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	link, err := c.client.Restore(context.Background(), *domain, id)
+	if err != nil {
+		return err
+	}
+	return c.printLink(link)
+}
+
+func (c *cli) stats(args []string) error {
+	fs := c.flagSet("stats")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	st, err := c.client.Stats(context.Background(), *domain, id)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{{"", strconv.FormatInt(st.Clicks, 10)}}
+	for _, name := range sortedKeys(st.Variants) {
+		rows = append(rows, []string{name, strconv.FormatInt(st.Variants[name], 10)})
+	}
+	return c.print(st, []string{"VARIANT", "CLICKS"}, rows)
+}
+
+func (c *cli) health(args []string) error {
+	fs := c.flagSet("health")
+	domain := fs.String("domain", "", "domain of short link, default domain if empty")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	h, err := c.client.Health(context.Background(), *domain, id)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{{"broken", strconv.FormatBool(h.Broken)}}
+	if h.CheckedAt == nil {
+		rows = append(rows, []string{"checkedAt", "never"})
+		return c.print(h, []string{"FIELD", "VALUE"}, rows)
+	}
+	rows = append(rows,
+		[]string{"statusCode", strconv.Itoa(h.StatusCode)},
+		[]string{"latencyMs", strconv.FormatInt(h.LatencyMS, 10)},
+		[]string{"consecutiveFailures", strconv.Itoa(h.ConsecutiveFailures)},
+	)
+	if h.Error != "" {
+		rows = append(rows, []string{"error", h.Error})
+	}
+	if h.BrokenSince != nil {
+		rows = append(rows, []string{"brokenSince", h.BrokenSince.Format(time.RFC3339)})
+	}
+	rows = append(rows, []string{"checkedAt", h.CheckedAt.Format(time.RFC3339)})
+	return c.print(h, []string{"FIELD", "VALUE"}, rows)
+}
+
+func (c *cli) importLinks(args []string) error {
+	fs := c.flagSet("import")
+	format := fs.String("format", formatJSONL, "file format, csv or jsonl")
+	conflict := fs.String("conflict", "fail", "how links with existing ids are handled, skip, overwrite or fail")
+	dryRun := fs.Bool("dry_run", false, "validate the file without importing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("file is required, - for stdin")
+	}
+
+	r, err := c.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	resp, err := c.client.Import(context.Background(), client.ImportParams{
+		File:     r,
+		Format:   *format,
+		Conflict: *conflict,
+		DryRun:   *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range resp.Errors {
+		fmt.Fprintf(c.errOut, "line %d (%s): %s\n", e.Line, e.ID, e.Message)
+	}
+	err = c.print(resp, []string{"TOTAL", "CREATED", "UPDATED", "SKIPPED", "FAILED", "COMMITTED"}, [][]string{{
+		strconv.Itoa(resp.Total),
+		strconv.Itoa(resp.Created),
+		strconv.Itoa(resp.Updated),
+		strconv.Itoa(resp.Skipped),
+		strconv.Itoa(resp.Failed),
+		strconv.FormatBool(resp.Committed),
+	}})
+	if err != nil {
+		return err
+	}
+	if resp.Failed > 0 {
+		return fmt.Errorf("%d of %d failed, nothing is imported", resp.Failed, resp.Total)
+	}
+	return nil
+}
+
+func (c *cli) exportLinks(args []string) error {
+	fs := c.flagSet("export")
+	format := fs.String("format", formatJSONL, "file format, csv or jsonl")
+	outPath := fs.String("o", "", "output file, stdout if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w := c.out
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return c.client.Export(context.Background(), *format, w)
+}
+
+// open opens file at path, - reads stdin.
+func (c *cli) open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(c.in), nil
+	}
+	return os.Open(path)
+}
+
+// parseID parses flags and returns the only positional argument as url_id.
+func parseID(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", errors.New("id of short link is required, flags should be given before id")
+	}
+	return fs.Arg(0), nil
+}
+
+// parseExpire parses expire as RFC3339 time or duration from now, and returns it in RFC3339.
+func parseExpire(expire string, now time.Time) (string, error) {
+	if d, err := time.ParseDuration(expire); err == nil {
+		return now.Add(d).UTC().Format(time.RFC3339), nil
+	}
+	t, err := time.Parse(time.RFC3339, expire)
+	if err != nil {
+		return "", fmt.Errorf("invalid expire: %s", expire)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// splitTags returns comma-separated tags in s, nil if s is empty.
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
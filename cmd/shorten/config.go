@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// config defines the profile file, which keeps endpoint and API key of each profile.
+//
+//	{"profiles": {"default": {"endpoint": "https://sho.rt", "apiKey": "my-api-key"}}}
+type config struct {
+	Profiles map[string]profile `json:"profiles"`
+}
+
+type profile struct {
+	Endpoint string `json:"endpoint"`
+	APIKey   string `json:"apiKey"`
+}
+
+// defaultConfigPath returns shorten/config.json in user config directory, e.g. ~/.config/shorten/config.json.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "shorten", "config.json")
+}
+
+// loadProfile returns profile name in the profile file at path, an empty profile is returned if the file
+// does not exist, so endpoint and API key can be given by flags only.
+func loadProfile(path, name string) (profile, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) || path == "" {
+		return profile{}, nil
+	} else if err != nil {
+		return profile{}, err
+	}
+
+	var cfg config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return profile{}, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("profile %s not found in %s", name, path)
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+// readCSV reads upload requests from CSV with header, absent columns or empty values are filled by defaults.
+func readCSV(r io.Reader, defaults api.UploadURLRequest) ([]api.UploadURLRequest, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("invalid csv header: url column is required")
+	}
+
+	var reqs []api.UploadURLRequest
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		req := defaults
+		req.URL = value("url")
+		if v := value("expireAt"); v != "" {
+			req.ExpireAt = v
+		}
+		if v := value("domain"); v != "" {
+			req.Domain = v
+		}
+		if v := value("preview"); v != "" {
+			if req.Preview, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("line %d: invalid preview: %s", line, v)
+			}
+		}
+		if v := value("queryMode"); v != "" {
+			req.QueryMode = v
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
@@ -0,0 +1,96 @@
+// Command shorten manages short links through the Rest API.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/client"
+)
+
+const usage = `Usage: shorten [flags] <command> [command flags] [args]
+
+Commands:
+  create   create short links from -url, a CSV file by -csv, or URLs in stdin (one per line)
+  get      show a short link: get [-domain host] <id>
+  list     list short links of the API key owner
+  update   update a short link: update [flags] <id>
+  expire   change expiry of a short link: expire [-domain host] [-at time|-extend duration|-never] <id>
+  delete   delete a short link: delete [-domain host] [-purge] <id>
+  restore  restore a deleted short link: restore [-domain host] <id>
+  stats    show click stats of a short link: stats [-domain host] <id>
+  health   show the latest health check of a short link: health [-domain host] <id>
+  import   create short links from a CSV or JSON Lines file: import [-format csv|jsonl] <file|->
+  export   write short links of the API key owner as CSV or JSON Lines: export [-format csv|jsonl] [-o file]
+
+Run "shorten <command> -h" for flags of a command.
+
+Flags:
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("shorten", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", defaultConfigPath(), "profile file")
+	profileName := fs.String("profile", "default", "profile in profile file")
+	endpoint := fs.String("endpoint", "", "base URL of the Rest API, overrides endpoint of profile")
+	apiKey := fs.String("api_key", "", "owner's API key, overrides API key of profile")
+	output := fs.String("output", outputTable, "output format, table or json")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of each request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *output != outputTable && *output != outputJSON {
+		return fmt.Errorf("invalid output: %s", *output)
+	}
+
+	p, err := loadProfile(*configPath, *profileName)
+	if err != nil {
+		return err
+	}
+	if *endpoint != "" {
+		p.Endpoint = *endpoint
+	}
+	if *apiKey != "" {
+		p.APIKey = *apiKey
+	}
+	if p.Endpoint == "" {
+		return errors.New("endpoint is empty, set it in profile file or by -endpoint")
+	}
+
+	c := &cli{
+		client: client.NewClient(
+			client.WithBaseURL(p.Endpoint),
+			client.WithAPIKey(p.APIKey),
+			client.WithTimeout(*timeout),
+			client.WithRetries(2, 200*time.Millisecond),
+		),
+		in:     stdin,
+		out:    stdout,
+		errOut: stderr,
+		output: *output,
+		now:    time.Now,
+	}
+	return c.run(fs.Arg(0), fs.Args()[1:])
+}
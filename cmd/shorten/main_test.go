@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/client"
+	clientmocks "github.com/georgechang0117/url-shortener/client/mocks"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testURL = "https://boards.greenhouse.io/dcard/jobs/871123?gh_src=ok8y1h1/"
+
+var testNow = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+
+type cliTestSuite struct {
+	suite.Suite
+	impl       *cli
+	mockClient *clientmocks.Client
+	in         *bytes.Buffer
+	out        *bytes.Buffer
+	errOut     *bytes.Buffer
+}
+
+func TestCLITestSuite(t *testing.T) {
+	suite.Run(t, new(cliTestSuite))
+}
+
+func (s *cliTestSuite) SetupTest() {
+	s.mockClient = &clientmocks.Client{}
+	s.in = &bytes.Buffer{}
+	s.out = &bytes.Buffer{}
+	s.errOut = &bytes.Buffer{}
+	s.impl = &cli{
+		client: s.mockClient,
+		in:     s.in,
+		out:    s.out,
+		errOut: s.errOut,
+		output: outputTable,
+		now:    func() time.Time { return testNow },
+	}
+}
+
+func (s *cliTestSuite) TestCreate() {
+	s.mockClient.On("Upload", mock.Anything, api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: "2021-07-02T00:00:00Z",
+		Preview:  true,
+	}).Return(&api.UploadURLResponse{ID: "abcdefghijk", ShortURL: "https://sho.rt/abcdefghijk"}, nil).Once()
+
+	s.Require().NoError(s.impl.run("create", []string{"-url", testURL, "-expire", "24h", "-preview"}))
+	s.Contains(s.out.String(), "https://sho.rt/abcdefghijk")
+}
+
+func (s *cliTestSuite) TestCreateFromStdin() {
+	s.in.WriteString("https://a.example.com\n\nhttps://b.example.com\n")
+	s.mockClient.On("Upload", mock.Anything, mock.MatchedBy(func(req api.UploadURLRequest) bool {
+		return req.URL == "https://a.example.com" && req.ExpireAt == "2021-07-31T00:00:00Z"
+	})).Return(&api.UploadURLResponse{ID: "abcdefghijk"}, nil).Once()
+	s.mockClient.On("Upload", mock.Anything, mock.MatchedBy(func(req api.UploadURLRequest) bool {
+		return req.URL == "https://b.example.com"
+	})).Return(nil, &client.Error{StatusCode: 400, Message: "url is invalid"}).Once()
+
+	err := s.impl.run("create", nil)
+	s.EqualError(err, "1 of 2 failed")
+	s.Contains(s.errOut.String(), "item 2 (https://b.example.com)")
+	s.Contains(s.out.String(), "abcdefghijk")
+}
+
+func (s *cliTestSuite) TestCreateFromCSV() {
+	path := filepath.Join(s.T().TempDir(), "links.csv")
+	s.Require().NoError(os.WriteFile(path, []byte("url,expireAt,preview\n"+
+		"https://a.example.com,2021-08-01T00:00:00Z,true\n"+
+		"https://b.example.com,,\n"), 0644))
+
+	s.mockClient.On("Upload", mock.Anything, api.UploadURLRequest{
+		URL:      "https://a.example.com",
+		ExpireAt: "2021-08-01T00:00:00Z",
+		Domain:   "go.example.com",
+		Preview:  true,
+	}).Return(&api.UploadURLResponse{ID: "abcdefghijk"}, nil).Once()
+	s.mockClient.On("Upload", mock.Anything, api.UploadURLRequest{
+		URL:      "https://b.example.com",
+		ExpireAt: "2021-07-31T00:00:00Z",
+		Domain:   "go.example.com",
+	}).Return(&api.UploadURLResponse{ID: "bcdefghijkl"}, nil).Once()
+
+	s.Require().NoError(s.impl.run("create", []string{"-csv", path, "-domain", "go.example.com"}))
+	s.mockClient.AssertExpectations(s.T())
+}
+
+func (s *cliTestSuite) TestListAll() {
+	s.mockClient.On("List", mock.Anything, client.ListParams{Limit: 1}).Return(&api.ListURLsResponse{
+		Items:      []api.ShortLinkResponse{{ID: "abcdefghijk", URL: testURL}},
+		NextCursor: "next",
+	}, nil).Once()
+	s.mockClient.On("List", mock.Anything, client.ListParams{Cursor: "next", Limit: 1}).Return(&api.ListURLsResponse{
+		Items: []api.ShortLinkResponse{{ID: "bcdefghijkl", URL: testURL}},
+	}, nil).Once()
+	s.impl.output = outputJSON
+
+	s.Require().NoError(s.impl.run("list", []string{"-limit", "1", "-all"}))
+	var links []api.ShortLinkResponse
+	s.Require().NoError(json.Unmarshal(s.out.Bytes(), &links))
+	s.Len(links, 2)
+}
+
+func (s *cliTestSuite) TestListFilter() {
+	s.mockClient.On("List", mock.Anything, client.ListParams{
+		Limit:        20,
+		Tags:         []string{"campaign", "summer"},
+		CreatedAfter: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		Query:        "sale",
+		Search:       "fulltext",
+	}).Return(&api.ListURLsResponse{
+		Items: []api.ShortLinkResponse{{ID: "abcdefghijk", URL: testURL}},
+	}, nil).Once()
+
+	s.Require().NoError(s.impl.run("list", []string{
+		"-tags", "campaign, summer", "-created_after", "2021-06-01T00:00:00Z", "-q", "sale", "-search", "fulltext",
+	}))
+	s.Contains(s.out.String(), "abcdefghijk")
+
+	s.Error(s.impl.run("list", []string{"-expire_before", "tomorrow"}))
+}
+
+func (s *cliTestSuite) TestUpdate() {
+	s.mockClient.On("Update", mock.Anything, "", "abcdefghijk", mock.MatchedBy(func(req api.UpdateURLRequest) bool {
+		return req.URL == nil && *req.Preview == false && *req.ExpireAt == "2021-07-08T00:00:00Z"
+	})).Return(&api.ShortLinkResponse{ID: "abcdefghijk", URL: testURL}, nil).Once()
+
+	s.Require().NoError(s.impl.run("update", []string{"-preview=false", "-expire", "168h", "abcdefghijk"}))
+	s.Contains(s.out.String(), testURL)
+
+	s.Error(s.impl.run("update", []string{"abcdefghijk", "-preview"}))
+}
+
+func (s *cliTestSuite) TestExpire() {
+	s.mockClient.On("SetExpiry", mock.Anything, "", "abcdefghijk", api.ExpiryRequest{ExtendSeconds: 86400}).
+		Return(&api.ShortLinkResponse{ID: "abcdefghijk", URL: testURL}, nil).Once()
+	s.mockClient.On("SetExpiry", mock.Anything, "", "abcdefghijk", api.ExpiryRequest{ExpireAt: "2021-07-08T00:00:00Z"}).
+		Return(&api.ShortLinkResponse{ID: "abcdefghijk", URL: testURL}, nil).Once()
+
+	s.Require().NoError(s.impl.run("expire", []string{"-extend", "24h", "abcdefghijk"}))
+	s.Require().NoError(s.impl.run("expire", []string{"-at", "168h", "abcdefghijk"}))
+	s.Contains(s.out.String(), testURL)
+
+	s.Error(s.impl.run("expire", []string{"-at", "tomorrow", "abcdefghijk"}))
+}
+
+func (s *cliTestSuite) TestDeleteRestore() {
+	s.mockClient.On("Delete", mock.Anything, "", "abcdefghijk").Return(nil).Once()
+	s.mockClient.On("Purge", mock.Anything, "", "abcdefghijk").Return(nil).Once()
+	s.mockClient.On("Restore", mock.Anything, "", "abcdefghijk").
+		Return(&api.ShortLinkResponse{ID: "abcdefghijk", URL: testURL, Status: "active"}, nil).Once()
+
+	s.Require().NoError(s.impl.run("delete", []string{"abcdefghijk"}))
+	s.Require().NoError(s.impl.run("restore", []string{"abcdefghijk"}))
+	s.Contains(s.out.String(), testURL)
+	s.Require().NoError(s.impl.run("delete", []string{"-purge", "abcdefghijk"}))
+}
+
+func (s *cliTestSuite) TestStats() {
+	s.mockClient.On("Stats", mock.Anything, "go.example.com", "abcdefghijk").Return(&api.StatsResponse{
+		ID:       "abcdefghijk",
+		Clicks:   10,
+		Variants: map[string]int64{"b": 6, "a": 4},
+	}, nil).Once()
+
+	s.Require().NoError(s.impl.run("stats", []string{"-domain", "go.example.com", "abcdefghijk"}))
+	lines := strings.Split(strings.TrimSpace(s.out.String()), "\n")
+	s.Require().Len(lines, 4)
+	s.True(strings.HasPrefix(lines[2], "a "))
+}
+
+func (s *cliTestSuite) TestHealth() {
+	brokenSince := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	s.mockClient.On("Health", mock.Anything, "", "abcdefghijk").Return(&api.HealthResponse{
+		ID:                  "abcdefghijk",
+		Broken:              true,
+		StatusCode:          502,
+		ConsecutiveFailures: 3,
+		BrokenSince:         &brokenSince,
+		CheckedAt:           &brokenSince,
+	}, nil).Once()
+
+	s.Require().NoError(s.impl.run("health", []string{"abcdefghijk"}))
+	s.Contains(s.out.String(), "brokenSince")
+	s.Contains(s.out.String(), "502")
+}
+
+func (s *cliTestSuite) TestExport() {
+	s.mockClient.On("Export", mock.Anything, "csv", s.out).Return(nil).Once()
+	s.Require().NoError(s.impl.run("export", []string{"-format", "csv"}))
+}
+
+func (s *cliTestSuite) TestImport() {
+	s.in.WriteString(`{"id":"abcdefghijk","url":"` + testURL + `","expireAt":"2021-08-01T00:00:00Z"}` + "\n")
+	s.mockClient.On("Import", mock.Anything, mock.MatchedBy(func(params client.ImportParams) bool {
+		return params.File != nil && params.Format == "jsonl" && params.Conflict == "skip" && params.DryRun
+	})).Return(&api.ImportResponse{Total: 1, Created: 1, DryRun: true}, nil).Once()
+
+	s.Require().NoError(s.impl.run("import", []string{"-conflict", "skip", "-dry_run", "-"}))
+	lines := strings.Split(strings.TrimSpace(s.out.String()), "\n")
+	s.Require().Len(lines, 2)
+	s.True(strings.HasPrefix(lines[1], "1 "))
+}
+
+func (s *cliTestSuite) TestImportFailed() {
+	s.mockClient.On("Import", mock.Anything, mock.Anything).Return(&api.ImportResponse{
+		Total:  2,
+		Failed: 1,
+		Errors: []api.ImportError{{Line: 2, ID: "abcdefghijk", Message: "id already exists"}},
+	}, nil).Once()
+
+	s.EqualError(s.impl.run("import", []string{"-"}), "1 of 2 failed, nothing is imported")
+	s.Contains(s.errOut.String(), "line 2 (abcdefghijk): id already exists")
+}
+
+func (s *cliTestSuite) TestLoadProfile() {
+	path := filepath.Join(s.T().TempDir(), "config.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{"profiles": {"prod": {"endpoint": "https://sho.rt", "apiKey": "key"}}}`), 0600))
+
+	p, err := loadProfile(path, "prod")
+	s.Require().NoError(err)
+	s.Equal(profile{Endpoint: "https://sho.rt", APIKey: "key"}, p)
+
+	_, err = loadProfile(path, "dev")
+	s.Error(err)
+
+	p, err = loadProfile(filepath.Join(s.T().TempDir(), "absent.json"), "default")
+	s.Require().NoError(err)
+	s.Empty(p.Endpoint)
+}
+
+func (s *cliTestSuite) TestRunWithoutEndpoint() {
+	err := run([]string{"-config", "", "get", "abcdefghijk"}, s.in, s.out, s.errOut)
+	s.EqualError(err, "endpoint is empty, set it in profile file or by -endpoint")
+}
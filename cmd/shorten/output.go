@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// print prints v in JSON, or header and rows as a table.
+func (c *cli) print(v interface{}, header []string, rows [][]string) error {
+	if c.output == outputJSON {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (c *cli) printLink(link *api.ShortLinkResponse) error {
+	rows := [][]string{
+		{"id", link.ID},
+		{"shortUrl", link.ShortURL},
+		{"url", link.URL},
+		{"expireAt", link.ExpireAt.Format(time.RFC3339)},
+		{"preview", strconv.FormatBool(link.Preview)},
+	}
+	if link.QueryMode != "" {
+		rows = append(rows, []string{"queryMode", link.QueryMode})
+	}
+	if link.Title != "" {
+		rows = append(rows, []string{"title", link.Title})
+	}
+	if link.Description != "" {
+		rows = append(rows, []string{"description", link.Description})
+	}
+	if len(link.Tags) > 0 {
+		rows = append(rows, []string{"tags", strings.Join(link.Tags, ",")})
+	}
+	for _, k := range sortedKeys(link.UTM) {
+		rows = append(rows, []string{k, link.UTM[k]})
+	}
+	if link.FallbackURL != "" {
+		rows = append(rows, []string{"fallbackUrl", link.FallbackURL})
+	}
+	if link.Broken {
+		rows = append(rows, []string{"broken", "true"})
+	}
+	for i, rule := range link.Rules {
+		rows = append(rows, []string{fmt.Sprintf("rules[%d]", i), rule.URL})
+	}
+	for _, variant := range link.Variants {
+		rows = append(rows, []string{"variant " + variant.Name, fmt.Sprintf("%s (weight %d)", variant.URL, variant.Weight)})
+	}
+	rows = append(rows, []string{"createdAt", link.CreatedAt.Format(time.RFC3339)})
+
+	return c.print(link, []string{"FIELD", "VALUE"}, rows)
+}
+
+func (c *cli) printLinks(links []api.ShortLinkResponse) error {
+	rows := make([][]string, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []string{link.ID, link.ShortURL, link.URL, link.ExpireAt.Format(time.RFC3339)})
+	}
+	if links == nil {
+		links = []api.ShortLinkResponse{}
+	}
+	return c.print(links, []string{"ID", "SHORT URL", "URL", "EXPIRE AT"}, rows)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]int64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,236 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns are columns of CSV, id and url are required on reading and the others are optional.
+var csvColumns = []string{"id", "domain", "url", "expireAt", "preview", "queryMode", "utm", "rules", "variants", "createdAt",
+	"title", "description", "tags", "fallbackUrl"}
+
+// maxLineSize is max size of a line in JSON Lines.
+const maxLineSize = 1024 * 1024
+
+// NewReader creates a Reader reading records in format from r.
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		return &jsonlReader{scanner: scanner}, nil
+	}
+	return nil, fmt.Errorf("invalid format: %s", format)
+}
+
+// NewWriter creates a Writer writing records in format to w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: writer}, nil
+	case FormatJSONL:
+		buf := bufio.NewWriter(w)
+		return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	}
+	return nil, fmt.Errorf("invalid format: %s", format)
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	// line is line number of the last record, assuming no field spans lines.
+	line int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv header is missing")
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"id", "url"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("invalid csv header: %s column is required", required)
+		}
+	}
+
+	return &csvReader{r: reader, columns: columns, line: 1}, nil
+}
+
+func (r *csvReader) Read() (*Record, error) {
+	fields, err := r.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		r.line = parseErr.Line
+		return nil, &ParseError{Line: parseErr.StartLine, Err: parseErr.Err}
+	} else if err != nil {
+		return nil, err
+	}
+	r.line++
+	line := r.line
+
+	value := func(column string) string {
+		if i, ok := r.columns[column]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	fail := func(format string, args ...interface{}) (*Record, error) {
+		return nil, &ParseError{Line: line, Err: fmt.Errorf(format, args...)}
+	}
+
+	record := Record{
+		ID:          value("id"),
+		Domain:      value("domain"),
+		URL:         value("url"),
+		QueryMode:   value("queryMode"),
+		Title:       value("title"),
+		Description: value("description"),
+		FallbackURL: value("fallbackUrl"),
+		Line:        line,
+	}
+	if v := value("expireAt"); v != "" {
+		if record.ExpireAt, err = time.Parse(time.RFC3339, v); err != nil {
+			return fail("invalid expireAt: %s", v)
+		}
+	}
+	if v := value("preview"); v != "" {
+		if record.Preview, err = strconv.ParseBool(v); err != nil {
+			return fail("invalid preview: %s", v)
+		}
+	}
+	if v := value("utm"); v != "" {
+		values, err := url.ParseQuery(v)
+		if err != nil {
+			return fail("invalid utm: %s", v)
+		}
+		record.UTM = map[string]string{}
+		for k := range values {
+			record.UTM[k] = values.Get(k)
+		}
+	}
+	if v := value("rules"); v != "" {
+		if err := json.Unmarshal([]byte(v), &record.Rules); err != nil {
+			return fail("invalid rules: %v", err)
+		}
+	}
+	if v := value("variants"); v != "" {
+		if err := json.Unmarshal([]byte(v), &record.Variants); err != nil {
+			return fail("invalid variants: %v", err)
+		}
+	}
+	if v := value("createdAt"); v != "" {
+		if record.CreatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+			return fail("invalid createdAt: %s", v)
+		}
+	}
+	if v := value("tags"); v != "" {
+		record.Tags = strings.Split(v, ",")
+	}
+
+	return &record, nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(record *Record) error {
+	values := url.Values{}
+	for k, v := range record.UTM {
+		values.Set(k, v)
+	}
+	var ruleJSON, variantJSON []byte
+	if len(record.Rules) > 0 {
+		ruleJSON, _ = json.Marshal(record.Rules)
+	}
+	if len(record.Variants) > 0 {
+		variantJSON, _ = json.Marshal(record.Variants)
+	}
+	var createdAt string
+	if !record.CreatedAt.IsZero() {
+		createdAt = record.CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	return w.w.Write([]string{
+		record.ID,
+		record.Domain,
+		record.URL,
+		record.ExpireAt.UTC().Format(time.RFC3339),
+		strconv.FormatBool(record.Preview),
+		record.QueryMode,
+		values.Encode(),
+		string(ruleJSON),
+		string(variantJSON),
+		createdAt,
+		record.Title,
+		record.Description,
+		strings.Join(record.Tags, ","),
+		record.FallbackURL,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlReader) Read() (*Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if strings.TrimSpace(r.scanner.Text()) == "" {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(r.scanner.Bytes(), &record); err != nil {
+			return nil, &ParseError{Line: r.line, Err: err}
+		}
+		record.Line = r.line
+		return &record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+type jsonlWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(record *Record) error {
+	return w.enc.Encode(record)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.buf.Flush()
+}
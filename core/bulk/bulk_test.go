@@ -0,0 +1,125 @@
+package bulk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var testRecords = []Record{
+	{
+		ID:        "abcdefghijk",
+		Domain:    "go.example.com",
+		URL:       "https://example.com/a",
+		ExpireAt:  time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC),
+		Preview:   true,
+		QueryMode: "override",
+		UTM:       map[string]string{"utm_source": "newsletter", "utm_medium": "email"},
+		Rules:     []rules.Rule{{Platforms: []string{"ios"}, URL: "https://apps.apple.com"}},
+		Variants: []split.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 1},
+			{Name: "b", URL: "https://example.com/b", Weight: 3},
+		},
+		CreatedAt:   time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		Title:       "Summer sale, 50% off",
+		Description: "Landing page of the summer campaign",
+		Tags:        []string{"campaign", "summer 2021"},
+		FallbackURL: "https://example.com/",
+	},
+	{
+		ID:       "legacy-1",
+		URL:      "https://example.com/b,c",
+		ExpireAt: time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+type bulkTestSuite struct {
+	suite.Suite
+}
+
+func TestBulkTestSuite(t *testing.T) {
+	suite.Run(t, new(bulkTestSuite))
+}
+
+func (s *bulkTestSuite) roundTrip(format string) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	s.Require().NoError(err)
+	for i := range testRecords {
+		s.Require().NoError(w.Write(&testRecords[i]))
+	}
+	s.Require().NoError(w.Flush())
+
+	r, err := NewReader(&buf, format)
+	s.Require().NoError(err)
+	for i := range testRecords {
+		record, err := r.Read()
+		s.Require().NoError(err)
+		expected := testRecords[i]
+		expected.Line = i + 2
+		if format == FormatJSONL {
+			expected.Line = i + 1
+		}
+		s.Equal(expected, *record, format)
+	}
+	_, err = r.Read()
+	s.Equal(io.EOF, err)
+}
+
+func (s *bulkTestSuite) TestCSV() {
+	s.roundTrip(FormatCSV)
+}
+
+func (s *bulkTestSuite) TestJSONL() {
+	s.roundTrip(FormatJSONL)
+}
+
+func (s *bulkTestSuite) TestCSVPartialColumns() {
+	r, err := NewReader(strings.NewReader("url,id\nhttps://example.com,abc\n"), FormatCSV)
+	s.Require().NoError(err)
+
+	record, err := r.Read()
+	s.Require().NoError(err)
+	s.Equal(Record{ID: "abc", URL: "https://example.com", Line: 2}, *record)
+
+	_, err = NewReader(strings.NewReader("url,domain\nhttps://example.com,\n"), FormatCSV)
+	s.Error(err)
+}
+
+func (s *bulkTestSuite) TestParseError() {
+	r, err := NewReader(strings.NewReader("id,url,expireAt\na,https://example.com,tomorrow\nb,https://example.com,\n"), FormatCSV)
+	s.Require().NoError(err)
+
+	_, err = r.Read()
+	var parseErr *ParseError
+	s.Require().True(errors.As(err, &parseErr))
+	s.Equal(2, parseErr.Line)
+
+	// reading continues after a malformed record
+	record, err := r.Read()
+	s.Require().NoError(err)
+	s.Equal("b", record.ID)
+
+	r, err = NewReader(strings.NewReader("{\"id\": \"a\"}\n\n{invalid\n"), FormatJSONL)
+	s.Require().NoError(err)
+	_, err = r.Read()
+	s.Require().NoError(err)
+	_, err = r.Read()
+	s.Require().True(errors.As(err, &parseErr))
+	s.Equal(3, parseErr.Line)
+}
+
+func (s *bulkTestSuite) TestInvalidFormat() {
+	_, err := NewReader(strings.NewReader(""), "xml")
+	s.Error(err)
+	_, err = NewWriter(&bytes.Buffer{}, "xml")
+	s.Error(err)
+}
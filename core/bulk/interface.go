@@ -0,0 +1,67 @@
+package bulk
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+)
+
+const (
+	// FormatCSV is CSV with header, utm is in query string format, rules and variants are in JSON, tags are
+	// separated by comma.
+	FormatCSV = "csv"
+	// FormatJSONL is JSON Lines, one Record per line.
+	FormatJSONL = "jsonl"
+)
+
+// Record defines a short link in import and export files, which is portable across deployments.
+type Record struct {
+	// ID is url_id of the short link.
+	ID string `json:"id"`
+	// Domain is host of the short link domain, default domain is used if empty.
+	Domain    string            `json:"domain,omitempty"`
+	URL       string            `json:"url"`
+	ExpireAt  time.Time         `json:"expireAt"`
+	Preview   bool              `json:"preview,omitempty"`
+	QueryMode string            `json:"queryMode,omitempty"`
+	UTM       map[string]string `json:"utm,omitempty"`
+	Rules     []rules.Rule      `json:"rules,omitempty"`
+	Variants  []split.Variant   `json:"variants,omitempty"`
+	// CreatedAt is kept on import if not zero.
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	FallbackURL string    `json:"fallbackUrl,omitempty"`
+	// Line is line number of the record in file, set by Reader.
+	Line int `json:"-"`
+}
+
+// ParseError indicates a record in file is malformed, Reader can keep reading the next record.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Reader defines interface of reading records from a file one by one.
+type Reader interface {
+	// Read returns the next record, io.EOF at the end of file, or *ParseError if the record is malformed.
+	Read() (*Record, error)
+}
+
+// Writer defines interface of writing records to a file one by one.
+type Writer interface {
+	Write(record *Record) error
+	// Flush writes buffered records to the underlying writer.
+	Flush() error
+}
@@ -0,0 +1,204 @@
+package dao
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// AuditCreate is the action of creating a short link, including imports.
+	AuditCreate = "create"
+	// AuditUpdate is the action of updating a short link, including imports overwriting it.
+	AuditUpdate = "update"
+	// AuditDelete is the action of soft deleting a short link.
+	AuditDelete = "delete"
+	// AuditRestore is the action of restoring a deleted short link.
+	AuditRestore = "restore"
+	// AuditPurge is the action of deleting a short link permanently.
+	AuditPurge = "purge"
+)
+
+// Actor defines who makes changes of short links, which is recorded in AuditEvent.
+type Actor struct {
+	// OwnerID is zero for anonymous actors.
+	OwnerID uint64
+	// APIKeyHash is the prefix of SHA-256 of the API key used, which identifies the key without revealing it.
+	APIKeyHash string
+	SourceIP   string
+	RequestID  string
+}
+
+// AuditEvent defines model for append-only audit log of changes of short links, it's written in the same
+// transaction as the change.
+type AuditEvent struct {
+	ID          uint64 `gorm:"primary_key,AUTO_INCREMENT"`
+	OwnerID     uint64 `gorm:"not null;default:0;index"`
+	APIKeyHash  string `gorm:"column:api_key_hash;type:varchar(16);not null;default:''"`
+	Action      string `gorm:"type:varchar(16);not null"`
+	ShortLinkID uint64 `gorm:"not null;index"`
+	Domain      string `gorm:"type:varchar(255);not null;default:''"`
+	URLID       string `gorm:"column:url_id;type:varchar(20);not null;index"`
+	// Before and After are LinkSnapshot in JSON, Before is empty for AuditCreate and After is empty for
+	// AuditPurge.
+	Before    string `gorm:"type:text"`
+	After     string `gorm:"type:text"`
+	SourceIP  string `gorm:"type:varchar(64);not null;default:''"`
+	RequestID string `gorm:"type:varchar(64);not null;default:'';index"`
+	CreatedAt time.Time
+}
+
+// LinkSnapshot defines settings of a short link recorded in AuditEvent.
+type LinkSnapshot struct {
+	ID          string    `json:"id"`
+	DomainID    uint64    `json:"domainId"`
+	OwnerID     uint64    `json:"ownerId"`
+	URL         string    `json:"url"`
+	ExpireAt    time.Time `json:"expireAt"`
+	Preview     bool      `json:"preview"`
+	Status      string    `json:"status"`
+	QueryMode   string    `json:"queryMode,omitempty"`
+	UTM         string    `json:"utm,omitempty"`
+	Rules       rules.Set `json:"rules,omitempty"`
+	Variants    split.Set `json:"variants,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        Tags      `json:"tags,omitempty"`
+	FallbackURL string    `json:"fallbackUrl,omitempty"`
+}
+
+// AuditFilter defines conditions of listing audit events, zero values are not filtered.
+type AuditFilter struct {
+	OwnerID   uint64
+	Domain    string
+	URLID     string
+	Action    string
+	RequestID string
+	// CreatedFrom is inclusive and CreatedTo is exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// BeforeID lists events with ID less than it only.
+	BeforeID uint64
+	Limit    int
+}
+
+type auditDao struct {
+	db *gorm.DB
+}
+
+// NewAuditDao creates an instance of AuditDao.
+func NewAuditDao(db *gorm.DB) (AuditDao, error) {
+	dao := &auditDao{
+		db: db,
+	}
+	return dao, dao.migrate()
+}
+
+func (d *auditDao) migrate() error {
+	return d.db.AutoMigrate(&AuditEvent{})
+}
+
+func (d *auditDao) List(filter AuditFilter) ([]*AuditEvent, error) {
+	query := d.db
+	if filter.OwnerID > 0 {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.Domain != "" {
+		query = query.Where("domain = ?", filter.Domain)
+	}
+	if filter.URLID != "" {
+		query = query.Where("url_id = ?", filter.URLID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	var events []*AuditEvent
+	if err := query.Order("id DESC").Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// createAuditEvents writes audit events of actor changing short links in tx, before is nil for AuditCreate and
+// after is nil for AuditPurge.
+func createAuditEvents(tx *gorm.DB, actor *Actor, action string, before, after []*ShortLink) error {
+	if actor == nil {
+		return nil
+	}
+	shortLinks := after
+	if action == AuditPurge {
+		shortLinks = before
+	}
+	if len(shortLinks) == 0 {
+		return nil
+	}
+
+	hosts, err := domainHosts(tx, shortLinks)
+	if err != nil {
+		return err
+	}
+	events := make([]*AuditEvent, 0, len(shortLinks))
+	for i, shortLink := range shortLinks {
+		event := &AuditEvent{
+			OwnerID:     actor.OwnerID,
+			APIKeyHash:  actor.APIKeyHash,
+			Action:      action,
+			ShortLinkID: shortLink.ID,
+			Domain:      hosts[shortLink.DomainID],
+			URLID:       shortLink.URLID,
+			SourceIP:    actor.SourceIP,
+			RequestID:   actor.RequestID,
+		}
+		if before != nil && before[i] != nil {
+			if event.Before, err = snapshot(before[i]); err != nil {
+				return err
+			}
+		}
+		if after != nil && after[i] != nil {
+			if event.After, err = snapshot(after[i]); err != nil {
+				return err
+			}
+		}
+		events = append(events, event)
+	}
+	return tx.Create(&events).Error
+}
+
+// snapshot returns LinkSnapshot of shortLink in JSON.
+func snapshot(shortLink *ShortLink) (string, error) {
+	b, err := json.Marshal(LinkSnapshot{
+		ID:          shortLink.URLID,
+		DomainID:    shortLink.DomainID,
+		OwnerID:     shortLink.OwnerID,
+		URL:         shortLink.URL,
+		ExpireAt:    shortLink.ExpireAt.UTC(),
+		Preview:     shortLink.Preview,
+		Status:      shortLink.Status,
+		QueryMode:   shortLink.QueryMode,
+		UTM:         shortLink.UTM,
+		Rules:       shortLink.Rules,
+		Variants:    shortLink.Variants,
+		Title:       shortLink.Title,
+		Description: shortLink.Description,
+		Tags:        shortLink.Tags,
+		FallbackURL: shortLink.FallbackURL,
+	})
+	return string(b), err
+}
@@ -0,0 +1,157 @@
+package dao
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type auditTestSuite struct {
+	suite.Suite
+	impl         AuditDao
+	shortLinkDao ShortLinkDao
+	db           *gorm.DB
+	domain       Domain
+	actor        Actor
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(auditTestSuite))
+}
+
+func (s *auditTestSuite) SetupTest() {
+	var err error
+	s.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	s.Require().NoError(err)
+
+	s.impl, err = NewAuditDao(s.db)
+	s.Require().NoError(err)
+	s.shortLinkDao, err = NewShortLinkDao(s.db)
+	s.Require().NoError(err)
+	domainDao, err := NewDomainDao(s.db)
+	s.Require().NoError(err)
+	s.domain = Domain{Host: "go.example.com", Scheme: "https"}
+	s.Require().NoError(domainDao.Create(&s.domain))
+	s.actor = Actor{OwnerID: 1, APIKeyHash: "0123456789abcdef", SourceIP: "203.0.113.7", RequestID: "req-1"}
+}
+
+func (s *auditTestSuite) TearDownTest() {
+	db, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(db.Close())
+}
+
+func (s *auditTestSuite) list(filter AuditFilter) []*AuditEvent {
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+	events, err := s.impl.List(filter)
+	s.Require().NoError(err)
+	return events
+}
+
+func (s *auditTestSuite) snapshot(data string) LinkSnapshot {
+	var snapshot LinkSnapshot
+	s.Require().NoError(json.Unmarshal([]byte(data), &snapshot))
+	return snapshot
+}
+
+func (s *auditTestSuite) TestAuditEvents() {
+	expireAt := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	audited := s.shortLinkDao.WithActor(&s.actor)
+	shortLink := ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "audited", URL: testURL, ExpireAt: expireAt}
+	s.Require().NoError(audited.Create(&shortLink))
+	shortLink.URL = "https://example.com/new"
+	shortLink.Tags = Tags{"new"}
+	s.Require().NoError(audited.Update(&shortLink, "URL", "Tags"))
+	s.Require().NoError(audited.Delete(shortLink.ID, expireAt))
+	s.Require().NoError(audited.Restore(shortLink.ID))
+	s.Require().NoError(audited.Purge(shortLink.ID))
+
+	events := s.list(AuditFilter{})
+	s.Require().Len(events, 5)
+	s.Equal(AuditPurge, events[0].Action)
+	s.Equal(AuditRestore, events[1].Action)
+	s.Equal(AuditDelete, events[2].Action)
+	s.Equal(AuditUpdate, events[3].Action)
+	s.Equal(AuditCreate, events[4].Action)
+	for _, event := range events {
+		s.Equal(s.actor.OwnerID, event.OwnerID)
+		s.Equal(s.actor.APIKeyHash, event.APIKeyHash)
+		s.Equal(s.actor.SourceIP, event.SourceIP)
+		s.Equal(s.actor.RequestID, event.RequestID)
+		s.Equal(shortLink.ID, event.ShortLinkID)
+		s.Equal("go.example.com", event.Domain)
+		s.Equal("audited", event.URLID)
+	}
+
+	s.Empty(events[4].Before)
+	s.Equal(testURL, s.snapshot(events[4].After).URL)
+	s.Equal(testURL, s.snapshot(events[3].Before).URL)
+	s.Equal(LinkSnapshot{
+		ID:       "audited",
+		DomainID: s.domain.ID,
+		OwnerID:  1,
+		URL:      "https://example.com/new",
+		ExpireAt: expireAt,
+		Status:   StatusActive,
+		Tags:     Tags{"new"},
+	}, s.snapshot(events[3].After))
+	s.Equal(StatusActive, s.snapshot(events[2].Before).Status)
+	s.Equal(StatusDeleted, s.snapshot(events[2].After).Status)
+	s.Equal(StatusDeleted, s.snapshot(events[1].Before).Status)
+	s.Equal(StatusActive, s.snapshot(events[1].After).Status)
+	s.Equal("https://example.com/new", s.snapshot(events[0].Before).URL)
+	s.Empty(events[0].After)
+}
+
+func (s *auditTestSuite) TestNotAudited() {
+	// writes of health checks and metadata do not go through WithActor
+	shortLink := ShortLink{DomainID: s.domain.ID, URLID: "unaudited", URL: testURL}
+	s.Require().NoError(s.shortLinkDao.Create(&shortLink))
+	s.Require().NoError(s.shortLinkDao.Purge(shortLink.ID))
+	s.Empty(s.list(AuditFilter{}))
+}
+
+func (s *auditTestSuite) TestTransaction() {
+	audited := s.shortLinkDao.WithActor(&s.actor)
+	s.Require().NoError(audited.Transaction(func(tx ShortLinkDao) error {
+		return tx.CreateBatch([]*ShortLink{
+			{DomainID: s.domain.ID, URLID: "batch1", URL: testURL},
+			{DomainID: s.domain.ID, URLID: "batch2", URL: testURL},
+		})
+	}))
+	s.Len(s.list(AuditFilter{Action: AuditCreate}), 2)
+
+	// audit events are rolled back with the change
+	s.Error(audited.Create(&ShortLink{DomainID: s.domain.ID, URLID: "batch1", URL: testURL}))
+	s.Len(s.list(AuditFilter{}), 2)
+}
+
+func (s *auditTestSuite) TestList() {
+	other := Actor{OwnerID: 2, RequestID: "req-2"}
+	first := ShortLink{DomainID: s.domain.ID, URLID: "first", URL: testURL}
+	second := ShortLink{DomainID: s.domain.ID, URLID: "second", URL: testURL}
+	s.Require().NoError(s.shortLinkDao.WithActor(&s.actor).Create(&first))
+	s.Require().NoError(s.shortLinkDao.WithActor(&other).Create(&second))
+	s.Require().NoError(s.shortLinkDao.WithActor(&other).Delete(first.ID, time.Now()))
+
+	s.Len(s.list(AuditFilter{OwnerID: 2}), 2)
+	s.Len(s.list(AuditFilter{URLID: "first"}), 2)
+	s.Len(s.list(AuditFilter{URLID: "first", Domain: "other.example.com"}), 0)
+	s.Len(s.list(AuditFilter{RequestID: "req-1"}), 1)
+	s.Len(s.list(AuditFilter{Action: AuditDelete}), 1)
+	s.Len(s.list(AuditFilter{CreatedTo: time.Now().Add(-time.Hour)}), 0)
+	s.Len(s.list(AuditFilter{CreatedFrom: time.Now().Add(-time.Hour)}), 3)
+
+	events := s.list(AuditFilter{Limit: 2})
+	s.Require().Len(events, 2)
+	events = s.list(AuditFilter{BeforeID: events[1].ID})
+	s.Require().Len(events, 1)
+	s.Equal("first", events[0].URLID)
+	s.Equal(AuditCreate, events[0].Action)
+}
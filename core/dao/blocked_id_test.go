@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type blockedIDTestSuite struct {
+	suite.Suite
+	impl BlockedIDDao
+	db   *gorm.DB
+}
+
+func TestBlockedIDSuite(t *testing.T) {
+	suite.Run(t, new(blockedIDTestSuite))
+}
+
+func (s *blockedIDTestSuite) SetupTest() {
+	var err error
+	s.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	s.Require().NoError(err)
+	s.impl, err = NewBlockedIDDao(s.db)
+	s.Require().NoError(err)
+}
+
+func (s *blockedIDTestSuite) TearDownTest() {
+	db, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(db.Close())
+}
+
+func (s *blockedIDTestSuite) TestBlock() {
+	blocked := BlockedID{URLID: "abcdefghijk", Reason: "phishing"}
+	s.Require().NoError(s.impl.Block(&blocked))
+	s.NotZero(blocked.ID)
+
+	// blocking again keeps the existing one
+	again := BlockedID{URLID: "abcdefghijk", Reason: "again"}
+	s.Require().NoError(s.impl.Block(&again))
+	s.Equal(blocked.ID, again.ID)
+	s.Equal("phishing", again.Reason)
+
+	s.Require().NoError(s.impl.Block(&BlockedID{URLID: "Abcdefghijk"}))
+	blockedIDs, err := s.impl.List()
+	s.Require().NoError(err)
+	s.Require().Len(blockedIDs, 2)
+	s.Equal("Abcdefghijk", blockedIDs[0].URLID)
+
+	exists, err := s.impl.Exists("abcdefghijk")
+	s.Require().NoError(err)
+	s.True(exists)
+
+	s.Require().NoError(s.impl.Unblock("abcdefghijk"))
+	exists, err = s.impl.Exists("abcdefghijk")
+	s.Require().NoError(err)
+	s.False(exists)
+}
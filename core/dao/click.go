@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClickCount defines model for click count of a short link variant, variant is empty if short link has no variants.
+type ClickCount struct {
+	ID          uint64 `gorm:"primary_key,AUTO_INCREMENT"`
+	ShortLinkID uint64 `gorm:"not null;index:idx_short_link_variant,unique,priority:1"`
+	Variant     string `gorm:"type:varchar(32);not null;default:'';index:idx_short_link_variant,unique,priority:2"`
+	Clicks      int64  `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type clickDao struct {
+	db *gorm.DB
+}
+
+// NewClickDao creates an instance of ClickDao.
+func NewClickDao(db *gorm.DB) (ClickDao, error) {
+	dao := &clickDao{
+		db: db,
+	}
+	return dao, dao.migrate()
+}
+
+func (d *clickDao) migrate() error {
+	return d.db.AutoMigrate(&ClickCount{})
+}
+
+func (d *clickDao) Increase(shortLinkID uint64, variant string, clicks int64) error {
+	clickCount := ClickCount{
+		ShortLinkID: shortLinkID,
+		Variant:     variant,
+		Clicks:      clicks,
+	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "short_link_id"}, {Name: "variant"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"clicks":     gorm.Expr("clicks + ?", clicks),
+				"updated_at": gorm.Expr("?", time.Now()),
+			}),
+		}).Create(&clickCount).Error; err != nil {
+			return err
+		}
+
+		var total int64
+		if err := tx.
+			Model(&ClickCount{}).
+			Where("short_link_id = ?", shortLinkID).
+			Select("COALESCE(SUM(clicks), 0)").
+			Scan(&total).Error; err != nil {
+			return err
+		}
+		milestone := ClickMilestone(total-clicks, total)
+		if milestone == 0 {
+			return nil
+		}
+
+		var shortLink ShortLink
+		if err := tx.First(&shortLink, shortLinkID).Error; IsErrRecordNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return createLinkEvents(tx, EventLinkMilestone, milestone, &shortLink)
+	})
+}
+
+func (d *clickDao) ListByShortLinkID(shortLinkID uint64) ([]ClickCount, error) {
+	var clickCounts []ClickCount
+	if err := d.db.Where("short_link_id = ?", shortLinkID).Order("variant").Find(&clickCounts).Error; err != nil {
+		return nil, err
+	}
+	return clickCounts, nil
+}
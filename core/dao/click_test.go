@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type clickTestSuite struct {
+	suite.Suite
+	impl *clickDao
+	db   *gorm.DB
+}
+
+func TestClickSuite(t *testing.T) {
+	suite.Run(t, new(clickTestSuite))
+}
+
+func (s *clickTestSuite) SetupSuite() {
+	var err error
+	s.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	s.NoError(err)
+
+	dao, err := NewClickDao(s.db)
+	s.NoError(err)
+	s.impl = dao.(*clickDao)
+}
+
+func (s *clickTestSuite) TestIncrease() {
+	s.Require().NoError(s.impl.Increase(1, "a", 2))
+	s.Require().NoError(s.impl.Increase(1, "a", 3))
+	s.Require().NoError(s.impl.Increase(1, "b", 1))
+	s.Require().NoError(s.impl.Increase(2, "", 7))
+
+	clickCounts, err := s.impl.ListByShortLinkID(1)
+	s.Require().NoError(err)
+	s.Require().Len(clickCounts, 2)
+	s.Equal("a", clickCounts[0].Variant)
+	s.Equal(int64(5), clickCounts[0].Clicks)
+	s.Equal("b", clickCounts[1].Variant)
+	s.Equal(int64(1), clickCounts[1].Clicks)
+}
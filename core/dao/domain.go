@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Domain defines model for short link domain.
+type Domain struct {
+	ID        uint64 `gorm:"primary_key,AUTO_INCREMENT"`
+	Host      string `gorm:"type:varchar(255);not null;uniqueIndex"`
+	Scheme    string `gorm:"type:varchar(10);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ShortURL returns short URL of urlID under the domain.
+func (d *Domain) ShortURL(urlID string) string {
+	return fmt.Sprintf("%s://%s/%s", d.Scheme, d.Host, urlID)
+}
+
+type domainDao struct {
+	db *gorm.DB
+}
+
+// NewDomainDao creates an instance of DomainDao.
+func NewDomainDao(db *gorm.DB) (DomainDao, error) {
+	dao := &domainDao{
+		db: db,
+	}
+	return dao, dao.migrate()
+}
+
+func (d *domainDao) migrate() error {
+	return d.db.AutoMigrate(&Domain{})
+}
+
+func (d *domainDao) Create(domain *Domain) error {
+	err := d.db.Create(domain).Error
+	return err
+}
+
+func (d *domainDao) FirstOrCreate(domain *Domain) error {
+	return d.db.Where(Domain{Host: domain.Host}).Attrs(Domain{Scheme: domain.Scheme}).FirstOrCreate(domain).Error
+}
+
+func (d *domainDao) GetByHost(host string) (*Domain, error) {
+	var domain Domain
+	if err := d.db.Where("host = ?", host).First(&domain).Error; err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
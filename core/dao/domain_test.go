@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type domainTestSuite struct {
+	suite.Suite
+	impl     *domainDao
+	ownerDao *ownerDao
+	db       *gorm.DB
+}
+
+func TestDomainSuite(t *testing.T) {
+	suite.Run(t, new(domainTestSuite))
+}
+
+func (s *domainTestSuite) SetupSuite() {
+	var err error
+	s.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	s.NoError(err)
+
+	dao, err := NewDomainDao(s.db)
+	s.NoError(err)
+	s.impl = dao.(*domainDao)
+
+	dao2, err := NewOwnerDao(s.db)
+	s.NoError(err)
+	s.ownerDao = dao2.(*ownerDao)
+}
+
+func (s *domainTestSuite) TestFirstOrCreate() {
+	domain := Domain{Host: "first.example.com", Scheme: "https"}
+	s.Require().NoError(s.impl.FirstOrCreate(&domain))
+	s.NotZero(domain.ID)
+
+	again := Domain{Host: "first.example.com", Scheme: "http"}
+	s.Require().NoError(s.impl.FirstOrCreate(&again))
+	s.Equal(domain.ID, again.ID)
+	s.Equal("https", again.Scheme)
+}
+
+func (s *domainTestSuite) TestGetByHost() {
+	domain := Domain{Host: "get.example.com", Scheme: "https"}
+	s.Require().NoError(s.impl.Create(&domain))
+
+	d, err := s.impl.GetByHost(domain.Host)
+	s.Require().NoError(err)
+	s.Equal(domain.ID, d.ID)
+	s.Equal("https://get.example.com/abc", d.ShortURL("abc"))
+
+	_, err = s.impl.GetByHost("unknown.example.com")
+	s.True(IsErrRecordNotFound(err))
+}
+
+func (s *domainTestSuite) TestOwnerGetByAPIKey() {
+	domain := Domain{Host: "owner.example.com", Scheme: "https"}
+	owner := Owner{Name: "owner", APIKey: "owner-api-key", Domains: []Domain{domain}}
+	s.Require().NoError(s.ownerDao.Create(&owner))
+
+	o, err := s.ownerDao.GetByAPIKey(owner.APIKey)
+	s.Require().NoError(err)
+	s.Require().Len(o.Domains, 1)
+	s.True(o.AllowsDomain(o.Domains[0].ID))
+	s.False(o.AllowsDomain(o.Domains[0].ID + 1))
+}
@@ -1,8 +1,117 @@
 package dao
 
-// ShortLinkDao defines interface of ShortLink operations.
+import "time"
+
+// ShortLinkDao defines interface of ShortLink operations. Create, CreateBatch, Update, Overwrite, Delete, Restore and
+// Purge write their events to the outbox of webhooks in the same transaction, see OutboxEvent, and audit events if
+// it's created by WithActor.
 type ShortLinkDao interface {
 	Create(shortLink *ShortLink) error
-	GetByURLID(urlID string) (*ShortLink, error)
-	Exists(id string) (bool, error)
+	CreateBatch(shortLinks []*ShortLink) error
+	// Update writes fields of shortLink by names, e.g. "URL" and "Title", others are left unchanged. The broken flag
+	// and the expired event are reset once URL and ExpireAt are changed. shortLink is reloaded with all fields, and
+	// ErrShortLinkDeleted is returned if it's deleted.
+	Update(shortLink *ShortLink, fields ...string) error
+	// Overwrite writes all editable fields of shortLink, a deleted short link is restored with Status of shortLink.
+	Overwrite(shortLink *ShortLink) error
+	// Delete soft deletes the short link by StatusDeleted, it's kept for restoring until purged.
+	Delete(id uint64, deletedAt time.Time) error
+	// Restore brings the deleted short link back to the status before deletion, others are left unchanged.
+	Restore(id uint64) error
+	// Purge deletes the short link permanently with its tags and health check.
+	Purge(id uint64) error
+	GetByURLID(domainID uint64, urlID string) (*ShortLink, error)
+	Exists(domainID uint64, urlID string) (bool, error)
+	// List returns at most filter.Limit short links matching filter, newest first.
+	List(filter ListFilter) ([]*ShortLink, error)
+	// GetByURLIDs returns existing short links of urlIDs in domain.
+	GetByURLIDs(domainID uint64, urlIDs []string) ([]*ShortLink, error)
+	// IterateByOwner calls fn with batches of short links of owner except deleted ones in order of ID, all batches
+	// are read from a consistent snapshot so concurrent writes are not seen.
+	IterateByOwner(ownerID uint64, batchSize int, fn func(shortLinks []*ShortLink) error) error
+	// Transaction calls fn with a ShortLinkDao in a transaction, which is committed if fn returns nil.
+	Transaction(fn func(dao ShortLinkDao) error) error
+	// WithActor returns a ShortLinkDao recording changes of actor in AuditEvent.
+	WithActor(actor *Actor) ShortLinkDao
+	AssignDomain(domainID uint64) error
+	// UpdateMetadata updates metadata of short link id if its destination is still url, title and description
+	// are updated only if they're empty. It returns whether the short link is updated.
+	UpdateMetadata(id uint64, url string, metadata Metadata) (bool, error)
+	// ListActive returns at most limit short links in StatusActive not expired at now with ID greater than afterID,
+	// in order of ID.
+	ListActive(afterID uint64, now time.Time, limit int) ([]*ShortLink, error)
+	// ListRecent returns at most limit short links in StatusActive not expired at now, newest first.
+	ListRecent(now time.Time, limit int) ([]*ShortLink, error)
+	// ListMostClicked returns at most limit short links in StatusActive not expired at now which are clicked since
+	// clickedSince, in order of total clicks descending.
+	ListMostClicked(clickedSince, now time.Time, limit int) ([]*ShortLink, error)
+	// SetBroken sets broken of short link id if its destination is still url. It returns whether the short link
+	// is changed.
+	SetBroken(id uint64, url string, broken bool) (bool, error)
+	// GetHealth returns the latest health check of short link, which is ErrRecordNotFound if it's not checked.
+	GetHealth(shortLinkID uint64) (*LinkHealth, error)
+	// GetHealths returns the latest health checks of short links which are checked.
+	GetHealths(shortLinkIDs []uint64) ([]*LinkHealth, error)
+	// SaveHealth creates or replaces the health check of health.ShortLinkID.
+	SaveHealth(health *LinkHealth) error
+	// CreateExpiredEvents raises EventLinkExpired of at most limit short links expired at now which are not
+	// notified yet or deleted, and returns number of them.
+	CreateExpiredEvents(now time.Time, limit int) (int, error)
+}
+
+// DomainDao defines interface of Domain operations.
+type DomainDao interface {
+	Create(domain *Domain) error
+	FirstOrCreate(domain *Domain) error
+	GetByHost(host string) (*Domain, error)
+}
+
+// OwnerDao defines interface of Owner operations.
+type OwnerDao interface {
+	Create(owner *Owner) error
+	// Get returns owner of id without its domains.
+	Get(id uint64) (*Owner, error)
+	GetByAPIKey(apiKey string) (*Owner, error)
+}
+
+// WebhookDao defines interface of Webhook and OutboxEvent operations.
+type WebhookDao interface {
+	Create(webhook *Webhook) error
+	Get(id uint64) (*Webhook, error)
+	// GetByIDs returns existing webhooks of ids.
+	GetByIDs(ids []uint64) ([]*Webhook, error)
+	ListByOwner(ownerID uint64) ([]*Webhook, error)
+	// Delete deletes the webhook and its events.
+	Delete(id uint64) error
+	// ListDueEvents returns at most limit pending events to deliver at now, in order of NextAttemptAt.
+	ListDueEvents(now time.Time, limit int) ([]*OutboxEvent, error)
+	GetEvent(id uint64) (*OutboxEvent, error)
+	// ListEvents returns at most filter.Limit events matching filter, newest first.
+	ListEvents(filter EventFilter) ([]*OutboxEvent, error)
+	// UpdateEvent saves delivery state of event.
+	UpdateEvent(event *OutboxEvent) error
+}
+
+// AuditDao defines interface of reading AuditEvent, which is written by ShortLinkDao only.
+type AuditDao interface {
+	// List returns at most filter.Limit audit events matching filter, newest first.
+	List(filter AuditFilter) ([]*AuditEvent, error)
+}
+
+// BlockedIDDao defines interface of BlockedID operations.
+type BlockedIDDao interface {
+	// Block blocks blockedID.URLID, which is filled by the existing one if it's blocked already.
+	Block(blockedID *BlockedID) error
+	Unblock(urlID string) error
+	// List returns all blocked IDs ordered by url_id.
+	List() ([]*BlockedID, error)
+	Exists(urlID string) (bool, error)
+}
+
+// ClickDao defines interface of ClickCount operations.
+type ClickDao interface {
+	// Increase adds clicks of the short link variant, and raises EventLinkMilestone in the same transaction if
+	// total clicks of the short link reach a milestone.
+	Increase(shortLinkID uint64, variant string, clicks int64) error
+	ListByShortLinkID(shortLinkID uint64) ([]ClickCount, error)
 }
@@ -0,0 +1,36 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	dao "github.com/georgechang0117/url-shortener/core/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AuditDao is an autogenerated mock type for the AuditDao type
+type AuditDao struct {
+	mock.Mock
+}
+
+// List provides a mock function with given fields: filter
+func (_m *AuditDao) List(filter dao.AuditFilter) ([]*dao.AuditEvent, error) {
+	ret := _m.Called(filter)
+
+	var r0 []*dao.AuditEvent
+	if rf, ok := ret.Get(0).(func(dao.AuditFilter) []*dao.AuditEvent); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.AuditEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(dao.AuditFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,85 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	dao "github.com/georgechang0117/url-shortener/core/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// BlockedIDDao is an autogenerated mock type for the BlockedIDDao type
+type BlockedIDDao struct {
+	mock.Mock
+}
+
+// Block provides a mock function with given fields: blockedID
+func (_m *BlockedIDDao) Block(blockedID *dao.BlockedID) error {
+	ret := _m.Called(blockedID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.BlockedID) error); ok {
+		r0 = rf(blockedID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Exists provides a mock function with given fields: urlID
+func (_m *BlockedIDDao) Exists(urlID string) (bool, error) {
+	ret := _m.Called(urlID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(urlID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields:
+func (_m *BlockedIDDao) List() ([]*dao.BlockedID, error) {
+	ret := _m.Called()
+
+	var r0 []*dao.BlockedID
+	if rf, ok := ret.Get(0).(func() []*dao.BlockedID); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.BlockedID)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Unblock provides a mock function with given fields: urlID
+func (_m *BlockedIDDao) Unblock(urlID string) error {
+	ret := _m.Called(urlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,50 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	dao "github.com/georgechang0117/url-shortener/core/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ClickDao is an autogenerated mock type for the ClickDao type
+type ClickDao struct {
+	mock.Mock
+}
+
+// Increase provides a mock function with given fields: shortLinkID, variant, clicks
+func (_m *ClickDao) Increase(shortLinkID uint64, variant string, clicks int64) error {
+	ret := _m.Called(shortLinkID, variant, clicks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64, string, int64) error); ok {
+		r0 = rf(shortLinkID, variant, clicks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListByShortLinkID provides a mock function with given fields: shortLinkID
+func (_m *ClickDao) ListByShortLinkID(shortLinkID uint64) ([]dao.ClickCount, error) {
+	ret := _m.Called(shortLinkID)
+
+	var r0 []dao.ClickCount
+	if rf, ok := ret.Get(0).(func(uint64) []dao.ClickCount); ok {
+		r0 = rf(shortLinkID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.ClickCount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(shortLinkID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,64 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	dao "github.com/georgechang0117/url-shortener/core/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// DomainDao is an autogenerated mock type for the DomainDao type
+type DomainDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: domain
+func (_m *DomainDao) Create(domain *dao.Domain) error {
+	ret := _m.Called(domain)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.Domain) error); ok {
+		r0 = rf(domain)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FirstOrCreate provides a mock function with given fields: domain
+func (_m *DomainDao) FirstOrCreate(domain *dao.Domain) error {
+	ret := _m.Called(domain)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.Domain) error); ok {
+		r0 = rf(domain)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByHost provides a mock function with given fields: host
+func (_m *DomainDao) GetByHost(host string) (*dao.Domain, error) {
+	ret := _m.Called(host)
+
+	var r0 *dao.Domain
+	if rf, ok := ret.Get(0).(func(string) *dao.Domain); ok {
+		r0 = rf(host)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.Domain)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(host)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
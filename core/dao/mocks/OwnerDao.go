@@ -0,0 +1,73 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	dao "github.com/georgechang0117/url-shortener/core/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OwnerDao is an autogenerated mock type for the OwnerDao type
+type OwnerDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: owner
+func (_m *OwnerDao) Create(owner *dao.Owner) error {
+	ret := _m.Called(owner)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.Owner) error); ok {
+		r0 = rf(owner)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: id
+func (_m *OwnerDao) Get(id uint64) (*dao.Owner, error) {
+	ret := _m.Called(id)
+
+	var r0 *dao.Owner
+	if rf, ok := ret.Get(0).(func(uint64) *dao.Owner); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.Owner)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByAPIKey provides a mock function with given fields: apiKey
+func (_m *OwnerDao) GetByAPIKey(apiKey string) (*dao.Owner, error) {
+	ret := _m.Called(apiKey)
+
+	var r0 *dao.Owner
+	if rf, ok := ret.Get(0).(func(string) *dao.Owner); ok {
+		r0 = rf(apiKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.Owner)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(apiKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
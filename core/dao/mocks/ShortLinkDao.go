@@ -5,6 +5,8 @@ package mocks
 import (
 	dao "github.com/georgechang0117/url-shortener/core/dao"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // ShortLinkDao is an autogenerated mock type for the ShortLinkDao type
@@ -12,6 +14,20 @@ type ShortLinkDao struct {
 	mock.Mock
 }
 
+// AssignDomain provides a mock function with given fields: domainID
+func (_m *ShortLinkDao) AssignDomain(domainID uint64) error {
+	ret := _m.Called(domainID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64) error); ok {
+		r0 = rf(domainID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Create provides a mock function with given fields: shortLink
 func (_m *ShortLinkDao) Create(shortLink *dao.ShortLink) error {
 	ret := _m.Called(shortLink)
@@ -26,20 +42,69 @@ func (_m *ShortLinkDao) Create(shortLink *dao.ShortLink) error {
 	return r0
 }
 
-// Exists provides a mock function with given fields: id
-func (_m *ShortLinkDao) Exists(id string) (bool, error) {
-	ret := _m.Called(id)
+// CreateBatch provides a mock function with given fields: shortLinks
+func (_m *ShortLinkDao) CreateBatch(shortLinks []*dao.ShortLink) error {
+	ret := _m.Called(shortLinks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]*dao.ShortLink) error); ok {
+		r0 = rf(shortLinks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateExpiredEvents provides a mock function with given fields: now, limit
+func (_m *ShortLinkDao) CreateExpiredEvents(now time.Time, limit int) (int, error) {
+	ret := _m.Called(now, limit)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(time.Time, int) int); ok {
+		r0 = rf(now, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, int) error); ok {
+		r1 = rf(now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: id, deletedAt
+func (_m *ShortLinkDao) Delete(id uint64, deletedAt time.Time) error {
+	ret := _m.Called(id, deletedAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64, time.Time) error); ok {
+		r0 = rf(id, deletedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Exists provides a mock function with given fields: domainID, urlID
+func (_m *ShortLinkDao) Exists(domainID uint64, urlID string) (bool, error) {
+	ret := _m.Called(domainID, urlID)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string) bool); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(uint64, string) bool); ok {
+		r0 = rf(domainID, urlID)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(uint64, string) error); ok {
+		r1 = rf(domainID, urlID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -47,13 +112,13 @@ func (_m *ShortLinkDao) Exists(id string) (bool, error) {
 	return r0, r1
 }
 
-// GetByURLID provides a mock function with given fields: urlID
-func (_m *ShortLinkDao) GetByURLID(urlID string) (*dao.ShortLink, error) {
-	ret := _m.Called(urlID)
+// GetByURLID provides a mock function with given fields: domainID, urlID
+func (_m *ShortLinkDao) GetByURLID(domainID uint64, urlID string) (*dao.ShortLink, error) {
+	ret := _m.Called(domainID, urlID)
 
 	var r0 *dao.ShortLink
-	if rf, ok := ret.Get(0).(func(string) *dao.ShortLink); ok {
-		r0 = rf(urlID)
+	if rf, ok := ret.Get(0).(func(uint64, string) *dao.ShortLink); ok {
+		r0 = rf(domainID, urlID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*dao.ShortLink)
@@ -61,11 +126,335 @@ func (_m *ShortLinkDao) GetByURLID(urlID string) (*dao.ShortLink, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(urlID)
+	if rf, ok := ret.Get(1).(func(uint64, string) error); ok {
+		r1 = rf(domainID, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByURLIDs provides a mock function with given fields: domainID, urlIDs
+func (_m *ShortLinkDao) GetByURLIDs(domainID uint64, urlIDs []string) ([]*dao.ShortLink, error) {
+	ret := _m.Called(domainID, urlIDs)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(uint64, []string) []*dao.ShortLink); ok {
+		r0 = rf(domainID, urlIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, []string) error); ok {
+		r1 = rf(domainID, urlIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHealth provides a mock function with given fields: shortLinkID
+func (_m *ShortLinkDao) GetHealth(shortLinkID uint64) (*dao.LinkHealth, error) {
+	ret := _m.Called(shortLinkID)
+
+	var r0 *dao.LinkHealth
+	if rf, ok := ret.Get(0).(func(uint64) *dao.LinkHealth); ok {
+		r0 = rf(shortLinkID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.LinkHealth)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(shortLinkID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHealths provides a mock function with given fields: shortLinkIDs
+func (_m *ShortLinkDao) GetHealths(shortLinkIDs []uint64) ([]*dao.LinkHealth, error) {
+	ret := _m.Called(shortLinkIDs)
+
+	var r0 []*dao.LinkHealth
+	if rf, ok := ret.Get(0).(func([]uint64) []*dao.LinkHealth); ok {
+		r0 = rf(shortLinkIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.LinkHealth)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]uint64) error); ok {
+		r1 = rf(shortLinkIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IterateByOwner provides a mock function with given fields: ownerID, batchSize, fn
+func (_m *ShortLinkDao) IterateByOwner(ownerID uint64, batchSize int, fn func([]*dao.ShortLink) error) error {
+	ret := _m.Called(ownerID, batchSize, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64, int, func([]*dao.ShortLink) error) error); ok {
+		r0 = rf(ownerID, batchSize, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: filter
+func (_m *ShortLinkDao) List(filter dao.ListFilter) ([]*dao.ShortLink, error) {
+	ret := _m.Called(filter)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(dao.ListFilter) []*dao.ShortLink); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(dao.ListFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListActive provides a mock function with given fields: afterID, now, limit
+func (_m *ShortLinkDao) ListActive(afterID uint64, now time.Time, limit int) ([]*dao.ShortLink, error) {
+	ret := _m.Called(afterID, now, limit)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(uint64, time.Time, int) []*dao.ShortLink); ok {
+		r0 = rf(afterID, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, time.Time, int) error); ok {
+		r1 = rf(afterID, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListMostClicked provides a mock function with given fields: clickedSince, now, limit
+func (_m *ShortLinkDao) ListMostClicked(clickedSince time.Time, now time.Time, limit int) ([]*dao.ShortLink, error) {
+	ret := _m.Called(clickedSince, now, limit)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time, int) []*dao.ShortLink); ok {
+		r0 = rf(clickedSince, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, time.Time, int) error); ok {
+		r1 = rf(clickedSince, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRecent provides a mock function with given fields: now, limit
+func (_m *ShortLinkDao) ListRecent(now time.Time, limit int) ([]*dao.ShortLink, error) {
+	ret := _m.Called(now, limit)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(time.Time, int) []*dao.ShortLink); ok {
+		r0 = rf(now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, int) error); ok {
+		r1 = rf(now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Overwrite provides a mock function with given fields: shortLink
+func (_m *ShortLinkDao) Overwrite(shortLink *dao.ShortLink) error {
+	ret := _m.Called(shortLink)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.ShortLink) error); ok {
+		r0 = rf(shortLink)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Purge provides a mock function with given fields: id
+func (_m *ShortLinkDao) Purge(id uint64) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Restore provides a mock function with given fields: id
+func (_m *ShortLinkDao) Restore(id uint64) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveHealth provides a mock function with given fields: health
+func (_m *ShortLinkDao) SaveHealth(health *dao.LinkHealth) error {
+	ret := _m.Called(health)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.LinkHealth) error); ok {
+		r0 = rf(health)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBroken provides a mock function with given fields: id, url, broken
+func (_m *ShortLinkDao) SetBroken(id uint64, url string, broken bool) (bool, error) {
+	ret := _m.Called(id, url, broken)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(uint64, string, bool) bool); ok {
+		r0 = rf(id, url, broken)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, string, bool) error); ok {
+		r1 = rf(id, url, broken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transaction provides a mock function with given fields: fn
+func (_m *ShortLinkDao) Transaction(fn func(dao.ShortLinkDao) error) error {
+	ret := _m.Called(fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(dao.ShortLinkDao) error) error); ok {
+		r0 = rf(fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: shortLink, fields
+func (_m *ShortLinkDao) Update(shortLink *dao.ShortLink, fields ...string) error {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, shortLink)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.ShortLink, ...string) error); ok {
+		r0 = rf(shortLink, fields...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateMetadata provides a mock function with given fields: id, url, metadata
+func (_m *ShortLinkDao) UpdateMetadata(id uint64, url string, metadata dao.Metadata) (bool, error) {
+	ret := _m.Called(id, url, metadata)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(uint64, string, dao.Metadata) bool); ok {
+		r0 = rf(id, url, metadata)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, string, dao.Metadata) error); ok {
+		r1 = rf(id, url, metadata)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
+
+// WithActor provides a mock function with given fields: actor
+func (_m *ShortLinkDao) WithActor(actor *dao.Actor) dao.ShortLinkDao {
+	ret := _m.Called(actor)
+
+	var r0 dao.ShortLinkDao
+	if rf, ok := ret.Get(0).(func(*dao.Actor) dao.ShortLinkDao); ok {
+		r0 = rf(actor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(dao.ShortLinkDao)
+		}
+	}
+
+	return r0
+}
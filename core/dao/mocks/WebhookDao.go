@@ -0,0 +1,195 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	dao "github.com/georgechang0117/url-shortener/core/dao"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// WebhookDao is an autogenerated mock type for the WebhookDao type
+type WebhookDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: webhook
+func (_m *WebhookDao) Create(webhook *dao.Webhook) error {
+	ret := _m.Called(webhook)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.Webhook) error); ok {
+		r0 = rf(webhook)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: id
+func (_m *WebhookDao) Delete(id uint64) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: id
+func (_m *WebhookDao) Get(id uint64) (*dao.Webhook, error) {
+	ret := _m.Called(id)
+
+	var r0 *dao.Webhook
+	if rf, ok := ret.Get(0).(func(uint64) *dao.Webhook); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByIDs provides a mock function with given fields: ids
+func (_m *WebhookDao) GetByIDs(ids []uint64) ([]*dao.Webhook, error) {
+	ret := _m.Called(ids)
+
+	var r0 []*dao.Webhook
+	if rf, ok := ret.Get(0).(func([]uint64) []*dao.Webhook); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]uint64) error); ok {
+		r1 = rf(ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetEvent provides a mock function with given fields: id
+func (_m *WebhookDao) GetEvent(id uint64) (*dao.OutboxEvent, error) {
+	ret := _m.Called(id)
+
+	var r0 *dao.OutboxEvent
+	if rf, ok := ret.Get(0).(func(uint64) *dao.OutboxEvent); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.OutboxEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByOwner provides a mock function with given fields: ownerID
+func (_m *WebhookDao) ListByOwner(ownerID uint64) ([]*dao.Webhook, error) {
+	ret := _m.Called(ownerID)
+
+	var r0 []*dao.Webhook
+	if rf, ok := ret.Get(0).(func(uint64) []*dao.Webhook); ok {
+		r0 = rf(ownerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDueEvents provides a mock function with given fields: now, limit
+func (_m *WebhookDao) ListDueEvents(now time.Time, limit int) ([]*dao.OutboxEvent, error) {
+	ret := _m.Called(now, limit)
+
+	var r0 []*dao.OutboxEvent
+	if rf, ok := ret.Get(0).(func(time.Time, int) []*dao.OutboxEvent); ok {
+		r0 = rf(now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.OutboxEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, int) error); ok {
+		r1 = rf(now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListEvents provides a mock function with given fields: filter
+func (_m *WebhookDao) ListEvents(filter dao.EventFilter) ([]*dao.OutboxEvent, error) {
+	ret := _m.Called(filter)
+
+	var r0 []*dao.OutboxEvent
+	if rf, ok := ret.Get(0).(func(dao.EventFilter) []*dao.OutboxEvent); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.OutboxEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(dao.EventFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateEvent provides a mock function with given fields: event
+func (_m *WebhookDao) UpdateEvent(event *dao.OutboxEvent) error {
+	ret := _m.Called(event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.OutboxEvent) error); ok {
+		r0 = rf(event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
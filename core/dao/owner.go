@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Owner defines model for owner of short links, authenticated by API key.
+type Owner struct {
+	ID      uint64   `gorm:"primary_key,AUTO_INCREMENT"`
+	Name    string   `gorm:"type:varchar(64);not null"`
+	APIKey  string   `gorm:"column:api_key;type:varchar(64);not null;uniqueIndex"`
+	Domains []Domain `gorm:"many2many:owner_domains"`
+	// FallbackURL is where browsers are redirected to for expired, disabled and deleted short links of the owner if
+	// the server is configured so, empty if the owner has none.
+	FallbackURL string `gorm:"type:varchar(256);not null;default:''"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// AllowsDomain checks if the owner is allowed to create short links under the domain.
+func (o *Owner) AllowsDomain(domainID uint64) bool {
+	for _, domain := range o.Domains {
+		if domain.ID == domainID {
+			return true
+		}
+	}
+	return false
+}
+
+type ownerDao struct {
+	db *gorm.DB
+}
+
+// NewOwnerDao creates an instance of OwnerDao.
+func NewOwnerDao(db *gorm.DB) (OwnerDao, error) {
+	dao := &ownerDao{
+		db: db,
+	}
+	return dao, dao.migrate()
+}
+
+func (d *ownerDao) migrate() error {
+	return d.db.AutoMigrate(&Domain{}, &Owner{})
+}
+
+func (d *ownerDao) Create(owner *Owner) error {
+	err := d.db.Create(owner).Error
+	return err
+}
+
+func (d *ownerDao) Get(id uint64) (*Owner, error) {
+	var owner Owner
+	if err := d.db.Where("id = ?", id).First(&owner).Error; err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
+
+func (d *ownerDao) GetByAPIKey(apiKey string) (*Owner, error) {
+	var owner Owner
+	if err := d.db.Preload("Domains").Where("api_key = ?", apiKey).First(&owner).Error; err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
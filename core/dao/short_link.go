@@ -1,23 +1,217 @@
 package dao
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// QueryModeDrop drops query parameters of request on redirect.
+	QueryModeDrop = ""
+	// QueryModeOverride merges query parameters of request into destination, request wins on conflict.
+	QueryModeOverride = "override"
+	// QueryModePreserve merges query parameters of request into destination, destination wins on conflict.
+	QueryModePreserve = "preserve"
+
+	// StatusActive is status of short links redirecting to their destinations.
+	StatusActive = "active"
+	// StatusDisabled is status of short links paused by owners, which are not redirected.
+	StatusDisabled = "disabled"
+	// StatusDeleted is status of short links deleted by owners, which are kept for restoring until purged.
+	StatusDeleted = "deleted"
+
+	// searchIndex is the full-text index of url and title, which is created for MySQL and Postgres.
+	searchIndex = "idx_short_links_search"
 )
 
+// editableFields are fields of short links written by Update. The others are written by metadata fetching, health
+// checks and expiry notifications, which are not overwritten by short links loaded before.
+var editableFields = map[string]bool{
+	"URL": true, "Preview": true, "QueryMode": true, "UTM": true, "Rules": true, "Variants": true, "Title": true,
+	"Description": true, "Tags": true, "FallbackURL": true, "Status": true, "ExpireAt": true,
+}
+
+// ErrShortLinkDeleted indicates the short link is deleted, which is not updated until it's restored.
+var ErrShortLinkDeleted = errors.New("short link is deleted")
+
+// NeverExpire is ExpireAt of short links which never expire. It's a time instead of NULL, so short links are
+// compared and indexed by expiry the same way.
+var NeverExpire = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
 // ShortLink defines model for short link.
 type ShortLink struct {
-	ID        uint64 `gorm:"primary_key,AUTO_INCREMENT"`
-	URLID     string `gorm:"column:url_id;type:varchar(20);not null;unique_index"`
-	URL       string `gorm:"type:varchar(256);not null"`
-	ExpireAt  time.Time
-	CreatedAt time.Time
+	ID        uint64    `gorm:"primary_key,AUTO_INCREMENT"`
+	DomainID  uint64    `gorm:"not null;default:0;index:idx_domain_url_id,unique,priority:1"`
+	OwnerID   uint64    `gorm:"not null;default:0;index;index:idx_owner_created_at,priority:1;index:idx_owner_expire_at,priority:1"`
+	URLID     string    `gorm:"column:url_id;type:varchar(20);not null;index:idx_domain_url_id,unique,priority:2"`
+	URL       string    `gorm:"type:varchar(256);not null"`
+	Preview   bool      `gorm:"not null;default:false"`
+	QueryMode string    `gorm:"type:varchar(10);not null;default:''"`
+	UTM       string    `gorm:"column:utm;type:varchar(512);not null;default:''"`
+	Rules     rules.Set `gorm:"type:text"`
+	Variants  split.Set `gorm:"type:text"`
+	Title     string    `gorm:"type:varchar(256);not null;default:''"`
+	// Description is not searched, it's shown with the short link only.
+	Description string `gorm:"type:varchar(1024);not null;default:''"`
+	Tags        Tags   `gorm:"type:varchar(1024)"`
+	// ImageURL and FaviconURL are fetched from the destination page after creation.
+	ImageURL   string `gorm:"type:varchar(512);not null;default:''"`
+	FaviconURL string `gorm:"type:varchar(512);not null;default:''"`
+	// MetadataFetchedAt is nil until metadata of the destination is fetched.
+	MetadataFetchedAt *time.Time
+	// FallbackURL is redirected to instead of URL while the short link is broken.
+	FallbackURL string `gorm:"type:varchar(256);not null;default:''"`
+	// Broken is set by health checks after consecutive failures of URL, see LinkHealth.
+	Broken bool `gorm:"not null;default:false"`
+	// ExpireNotified indicates EventLinkExpired is raised for ExpireAt, it's reset when ExpireAt is changed.
+	ExpireNotified bool `gorm:"not null;default:false;index:idx_expire_notified_expire_at,priority:1"`
+	// Status is one of Status*, which is empty for short links cached before it's added and means StatusActive.
+	Status string `gorm:"type:varchar(10);not null;default:'active'"`
+	// StatusBeforeDelete is Status when the short link is deleted, which is restored. It's only meaningful while
+	// Status is StatusDeleted.
+	StatusBeforeDelete string `gorm:"type:varchar(10);not null;default:''"`
+	// DeletedAt is when the short link is deleted, nil unless Status is StatusDeleted. It's not gorm.DeletedAt
+	// since deleted short links are still loaded to tell them from non-existent ones.
+	DeletedAt *time.Time
+	Domain    *Domain   `gorm:"-" json:"-"`
+	ExpireAt  time.Time `gorm:"index:idx_owner_expire_at,priority:2;index:idx_expire_notified_expire_at,priority:2"`
+	CreatedAt time.Time `gorm:"index:idx_owner_created_at,priority:2"`
 	UpdatedAt time.Time
 }
 
+// Tags defines free-form tags of a short link, stored as JSON in short_links for reading and as ShortLinkTag
+// rows for filtering by index.
+type Tags []string
+
+// Value implements driver.Valuer.
+func (t Tags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (t *Tags) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("invalid type of tags")
+	}
+	return json.Unmarshal(b, t)
+}
+
+// ShortLinkTag defines model for a tag of short link, which indexes short links by tag.
+type ShortLinkTag struct {
+	ShortLinkID uint64 `gorm:"primaryKey;autoIncrement:false;index:idx_tag_short_link_id,priority:2"`
+	Tag         string `gorm:"type:varchar(64);primaryKey;index:idx_tag_short_link_id,priority:1"`
+}
+
+// LinkHealth defines model for the latest health check of the destination of a short link.
+type LinkHealth struct {
+	ShortLinkID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	// URL is the destination checked, failures are counted from zero when the short link is updated to another.
+	URL string `gorm:"type:varchar(256);not null"`
+	// StatusCode is 0 if no response is received, Error describes why.
+	StatusCode          int    `gorm:"not null;default:0"`
+	LatencyMS           int64  `gorm:"column:latency_ms;not null;default:0"`
+	Error               string `gorm:"type:varchar(256);not null;default:''"`
+	ConsecutiveFailures int    `gorm:"not null;default:0"`
+	// BrokenSince is when the short link is flagged broken, nil if it's not broken.
+	BrokenSince *time.Time
+	CheckedAt   time.Time
+}
+
+// Metadata defines metadata fetched from the destination page of a short link.
+type Metadata struct {
+	Title       string
+	Description string
+	ImageURL    string
+	FaviconURL  string
+	FetchedAt   time.Time
+}
+
+// ListFilter defines conditions of listing short links of an owner, zero values are not filtered.
+type ListFilter struct {
+	OwnerID uint64
+	// BeforeID lists short links with ID less than it, 0 lists from the newest one.
+	BeforeID uint64
+	Limit    int
+	// Tags lists short links having all of them.
+	Tags []string
+	// CreatedFrom and CreatedTo match short links created in [CreatedFrom, CreatedTo).
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// ExpireFrom and ExpireTo match short links expiring in [ExpireFrom, ExpireTo).
+	ExpireFrom time.Time
+	ExpireTo   time.Time
+	// Query matches URL or title by substring, or by full-text search if FullText. Full-text search is
+	// supported by MySQL and Postgres, substring is matched for other databases.
+	Query    string
+	FullText bool
+	// Broken lists broken short links only.
+	Broken bool
+	// Status lists short links in the status only, deleted ones are excluded if it's empty.
+	Status string
+}
+
+// BeforeCreate implements gorm hook, short links are created in StatusActive unless Status is given.
+func (s *ShortLink) BeforeCreate(tx *gorm.DB) error {
+	if s.Status == "" {
+		s.Status = StatusActive
+	}
+	return nil
+}
+
+// RestoredStatus returns status of the deleted short link once it's restored, which is the status before deletion.
+// Short links deleted before it's kept are restored active.
+func (s *ShortLink) RestoredStatus() string {
+	if s.StatusBeforeDelete == "" {
+		return StatusActive
+	}
+	return s.StatusBeforeDelete
+}
+
+// UTMParams returns default UTM parameters of short link.
+func (s *ShortLink) UTMParams() map[string]string {
+	values, err := url.ParseQuery(s.UTM)
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(values))
+	for k := range values {
+		params[k] = values.Get(k)
+	}
+	return params
+}
+
 type shortLinkDao struct {
 	db *gorm.DB
+	// actor is recorded in audit events of writes, which are not audited if it's nil.
+	actor *Actor
 }
 
 // NewShortLinkDao creates an instance of ShortLinkDao.
@@ -29,28 +223,238 @@ func NewShortLinkDao(db *gorm.DB) (ShortLinkDao, error) {
 }
 
 func (d *shortLinkDao) migrate() error {
-	return d.db.AutoMigrate(&ShortLink{})
+	// outbox and audit log are written in transactions of short links
+	if err := d.db.AutoMigrate(
+		&ShortLink{}, &ShortLinkTag{}, &LinkHealth{}, &Webhook{}, &OutboxEvent{}, &AuditEvent{},
+	); err != nil {
+		return err
+	}
+
+	// full-text indexes are not supported by gorm tags across databases
+	switch d.db.Dialector.Name() {
+	case "mysql":
+		if d.db.Migrator().HasIndex(&ShortLink{}, searchIndex) {
+			return nil
+		}
+		return d.db.Exec("CREATE FULLTEXT INDEX " + searchIndex + " ON short_links (url, title)").Error
+	case "postgres":
+		return d.db.Exec("CREATE INDEX IF NOT EXISTS " + searchIndex +
+			" ON short_links USING GIN (to_tsvector('simple', url || ' ' || title))").Error
+	}
+	return nil
 }
 
 func (d *shortLinkDao) Create(shortLink *ShortLink) error {
-	err := d.db.Create(shortLink).Error
-	return err
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(shortLink).Error; err != nil {
+			return err
+		}
+		if err := createTags(tx, shortLink); err != nil {
+			return err
+		}
+		if err := createAuditEvents(tx, d.actor, AuditCreate, nil, []*ShortLink{shortLink}); err != nil {
+			return err
+		}
+		return createLinkEvents(tx, EventLinkCreated, 0, shortLink)
+	})
+}
+
+func (d *shortLinkDao) CreateBatch(shortLinks []*ShortLink) error {
+	if len(shortLinks) == 0 {
+		return nil
+	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&shortLinks).Error; err != nil {
+			return err
+		}
+		if err := createTags(tx, shortLinks...); err != nil {
+			return err
+		}
+		if err := createAuditEvents(tx, d.actor, AuditCreate, nil, shortLinks); err != nil {
+			return err
+		}
+		return createLinkEvents(tx, EventLinkCreated, 0, shortLinks...)
+	})
+}
+
+func (d *shortLinkDao) Update(shortLink *ShortLink, fields ...string) error {
+	for _, field := range fields {
+		if !editableFields[field] {
+			return fmt.Errorf("field %s is not editable", field)
+		}
+	}
+	return d.update(shortLink, fields, false)
+}
+
+func (d *shortLinkDao) Overwrite(shortLink *ShortLink) error {
+	// deleted short links are restored by overwriting
+	shortLink.DeletedAt = nil
+	shortLink.StatusBeforeDelete = ""
+	fields := []string{"DeletedAt", "StatusBeforeDelete"}
+	for field := range editableFields {
+		fields = append(fields, field)
+	}
+	return d.update(shortLink, fields, true)
 }
 
-func (d *shortLinkDao) GetByURLID(urlID string) (*ShortLink, error) {
+// update writes fields of shortLink to the locked row, deleted short links are written only if overwrite.
+func (d *shortLinkDao) update(shortLink *ShortLink, fields []string, overwrite bool) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var before ShortLink
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before, shortLink.ID).Error; err != nil {
+			return err
+		}
+		if before.Status == StatusDeleted && !overwrite {
+			return ErrShortLinkDeleted
+		}
+		columns := append(append([]string{}, fields...), "UpdatedAt")
+		// the broken flag and the expired event are reset only when the destination and the expiry are changed
+		if containsField(fields, "URL") && shortLink.URL != before.URL {
+			shortLink.Broken = false
+			columns = append(columns, "Broken")
+		}
+		if containsField(fields, "ExpireAt") && !shortLink.ExpireAt.Equal(before.ExpireAt) {
+			shortLink.ExpireNotified = false
+			columns = append(columns, "ExpireNotified")
+		}
+		if err := tx.Model(shortLink).Select(columns).Updates(shortLink).Error; err != nil {
+			return err
+		}
+		// fields not given and written by others since shortLink is loaded are returned as well
+		if err := tx.First(shortLink, shortLink.ID).Error; err != nil {
+			return err
+		}
+		if containsField(fields, "Tags") {
+			if err := tx.Where("short_link_id = ?", shortLink.ID).Delete(&ShortLinkTag{}).Error; err != nil {
+				return err
+			}
+			if err := createTags(tx, shortLink); err != nil {
+				return err
+			}
+		}
+		if err := createAuditEvents(tx, d.actor, AuditUpdate, []*ShortLink{&before}, []*ShortLink{shortLink}); err != nil {
+			return err
+		}
+		return createLinkEvents(tx, EventLinkUpdated, 0, shortLink)
+	})
+}
+
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *shortLinkDao) Delete(id uint64, deletedAt time.Time) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var before ShortLink
+		if err := tx.First(&before, id).Error; IsErrRecordNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if before.Status == StatusDeleted {
+			return nil
+		}
+
+		shortLink := before
+		shortLink.StatusBeforeDelete = before.Status
+		shortLink.Status = StatusDeleted
+		shortLink.DeletedAt = &deletedAt
+		err := tx.Model(&shortLink).Select("status", "deleted_at", "status_before_delete").Updates(&shortLink).Error
+		if err != nil {
+			return err
+		}
+		if err := createLinkEvents(tx, EventLinkDeleted, 0, &shortLink); err != nil {
+			return err
+		}
+		return createAuditEvents(tx, d.actor, AuditDelete, []*ShortLink{&before}, []*ShortLink{&shortLink})
+	})
+}
+
+func (d *shortLinkDao) Restore(id uint64) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var before ShortLink
+		if err := tx.First(&before, id).Error; err != nil {
+			return err
+		}
+		if before.Status != StatusDeleted {
+			return nil
+		}
+
+		shortLink := before
+		shortLink.Status = before.RestoredStatus()
+		shortLink.StatusBeforeDelete = ""
+		shortLink.DeletedAt = nil
+		err := tx.Model(&shortLink).Select("status", "deleted_at", "status_before_delete").Updates(&shortLink).Error
+		if err != nil {
+			return err
+		}
+		if err := createLinkEvents(tx, EventLinkUpdated, 0, &shortLink); err != nil {
+			return err
+		}
+		return createAuditEvents(tx, d.actor, AuditRestore, []*ShortLink{&before}, []*ShortLink{&shortLink})
+	})
+}
+
+func (d *shortLinkDao) Purge(id uint64) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		// the deleted event carries the short link before deletion, it's raised already if it's soft deleted
+		var shortLink ShortLink
+		if err := tx.First(&shortLink, id).Error; IsErrRecordNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if shortLink.Status != StatusDeleted {
+			if err := createLinkEvents(tx, EventLinkDeleted, 0, &shortLink); err != nil {
+				return err
+			}
+		}
+		if err := createAuditEvents(tx, d.actor, AuditPurge, []*ShortLink{&shortLink}, nil); err != nil {
+			return err
+		}
+		if err := tx.Where("short_link_id = ?", id).Delete(&ShortLinkTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("short_link_id = ?", id).Delete(&LinkHealth{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&ShortLink{}, id).Error
+	})
+}
+
+// createTags writes ShortLinkTag rows of tags of short links.
+func createTags(tx *gorm.DB, shortLinks ...*ShortLink) error {
+	var tags []ShortLinkTag
+	for _, shortLink := range shortLinks {
+		for _, tag := range shortLink.Tags {
+			tags = append(tags, ShortLinkTag{ShortLinkID: shortLink.ID, Tag: tag})
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return tx.Create(&tags).Error
+}
+
+func (d *shortLinkDao) GetByURLID(domainID uint64, urlID string) (*ShortLink, error) {
 	var shortLink ShortLink
-	if err := d.db.Where("url_id = ?", urlID).First(&shortLink).Error; err != nil {
+	if err := d.db.Where("domain_id = ? AND url_id = ?", domainID, urlID).First(&shortLink).Error; err != nil {
 		return nil, err
 	}
 	return &shortLink, nil
 }
 
-func (d *shortLinkDao) Exists(urlID string) (bool, error) {
+func (d *shortLinkDao) Exists(domainID uint64, urlID string) (bool, error) {
 	var exists int
 	if err :=
 		d.db.
 			Model(&ShortLink{}).
-			Where("url_id = ?", urlID).
+			Where("domain_id = ? AND url_id = ?", domainID, urlID).
 			Select("1 AS one").
 			Limit(1).
 			Scan(&exists).Error; err != nil {
@@ -59,3 +463,220 @@ func (d *shortLinkDao) Exists(urlID string) (bool, error) {
 
 	return exists == 1, nil
 }
+
+func (d *shortLinkDao) List(filter ListFilter) ([]*ShortLink, error) {
+	var shortLinks []*ShortLink
+	query := d.db.Where("owner_id = ?", filter.OwnerID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		query = query.Where("status <> ?", StatusDeleted)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	for _, tag := range filter.Tags {
+		query = query.Where("id IN (?)", d.db.Model(&ShortLinkTag{}).Select("short_link_id").Where("tag = ?", tag))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if !filter.ExpireFrom.IsZero() {
+		query = query.Where("expire_at >= ?", filter.ExpireFrom)
+	}
+	if !filter.ExpireTo.IsZero() {
+		query = query.Where("expire_at < ?", filter.ExpireTo)
+	}
+	if filter.Query != "" {
+		query = d.search(query, filter.Query, filter.FullText)
+	}
+	if filter.Broken {
+		query = query.Where("broken = ?", true)
+	}
+
+	if err := query.Order("id DESC").Limit(filter.Limit).Find(&shortLinks).Error; err != nil {
+		return nil, err
+	}
+	return shortLinks, nil
+}
+
+// search matches URL or title of short links by q.
+func (d *shortLinkDao) search(query *gorm.DB, q string, fullText bool) *gorm.DB {
+	if fullText {
+		switch d.db.Dialector.Name() {
+		case "mysql":
+			return query.Where("MATCH (url, title) AGAINST (? IN NATURAL LANGUAGE MODE)", q)
+		case "postgres":
+			return query.Where("to_tsvector('simple', url || ' ' || title) @@ plainto_tsquery('simple', ?)", q)
+		}
+	}
+
+	pattern := "%" + likeEscaper.Replace(q) + "%"
+	return query.Where("(url LIKE ? ESCAPE '!' OR title LIKE ? ESCAPE '!')", pattern, pattern)
+}
+
+// likeEscaper escapes wildcards of LIKE with escape character "!", which is the same in all databases unlike
+// backslash.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (d *shortLinkDao) GetByURLIDs(domainID uint64, urlIDs []string) ([]*ShortLink, error) {
+	var shortLinks []*ShortLink
+	if len(urlIDs) == 0 {
+		return shortLinks, nil
+	}
+	if err := d.db.Where("domain_id = ? AND url_id IN ?", domainID, urlIDs).Find(&shortLinks).Error; err != nil {
+		return nil, err
+	}
+	return shortLinks, nil
+}
+
+func (d *shortLinkDao) IterateByOwner(ownerID uint64, batchSize int, fn func(shortLinks []*ShortLink) error) error {
+	// repeatable read keeps the snapshot of the first read for the whole transaction
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var shortLinks []*ShortLink
+		return tx.
+			Where("owner_id = ? AND status <> ?", ownerID, StatusDeleted).
+			FindInBatches(&shortLinks, batchSize, func(tx *gorm.DB, batch int) error {
+				return fn(shortLinks)
+			}).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+func (d *shortLinkDao) Transaction(fn func(dao ShortLinkDao) error) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&shortLinkDao{db: tx, actor: d.actor})
+	})
+}
+
+func (d *shortLinkDao) WithActor(actor *Actor) ShortLinkDao {
+	return &shortLinkDao{db: d.db, actor: actor}
+}
+
+func (d *shortLinkDao) UpdateMetadata(id uint64, url string, metadata Metadata) (bool, error) {
+	result := d.db.Model(&ShortLink{}).Where("id = ? AND url = ?", id, url).UpdateColumns(map[string]interface{}{
+		// titles and descriptions given by owners are kept, and updated_at is not changed since owners do not
+		// update it
+		"title":               gorm.Expr("CASE WHEN title = '' THEN ? ELSE title END", metadata.Title),
+		"description":         gorm.Expr("CASE WHEN description = '' THEN ? ELSE description END", metadata.Description),
+		"image_url":           metadata.ImageURL,
+		"favicon_url":         metadata.FaviconURL,
+		"metadata_fetched_at": metadata.FetchedAt,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (d *shortLinkDao) ListActive(afterID uint64, now time.Time, limit int) ([]*ShortLink, error) {
+	var shortLinks []*ShortLink
+	if err := d.db.
+		Where("id > ? AND status = ? AND expire_at > ?", afterID, StatusActive, now).
+		Order("id").
+		Limit(limit).
+		Find(&shortLinks).Error; err != nil {
+		return nil, err
+	}
+	return shortLinks, nil
+}
+
+func (d *shortLinkDao) ListRecent(now time.Time, limit int) ([]*ShortLink, error) {
+	var shortLinks []*ShortLink
+	if err := d.db.
+		Where("status = ? AND expire_at > ?", StatusActive, now).
+		Order("id DESC").
+		Limit(limit).
+		Find(&shortLinks).Error; err != nil {
+		return nil, err
+	}
+	return shortLinks, nil
+}
+
+func (d *shortLinkDao) ListMostClicked(clickedSince, now time.Time, limit int) ([]*ShortLink, error) {
+	// updated_at of click counts is time of the last click of a variant
+	clicks := d.db.
+		Model(&ClickCount{}).
+		Select("short_link_id, SUM(clicks) AS total").
+		Where("updated_at >= ?", clickedSince).
+		Group("short_link_id")
+	var shortLinks []*ShortLink
+	if err := d.db.
+		Joins("JOIN (?) AS clicks ON clicks.short_link_id = short_links.id", clicks).
+		Where("short_links.status = ? AND short_links.expire_at > ?", StatusActive, now).
+		Order("clicks.total DESC, short_links.id DESC").
+		Limit(limit).
+		Find(&shortLinks).Error; err != nil {
+		return nil, err
+	}
+	return shortLinks, nil
+}
+
+func (d *shortLinkDao) SetBroken(id uint64, url string, broken bool) (bool, error) {
+	// updated_at is not changed like UpdateMetadata, owners do not update it
+	result := d.db.Model(&ShortLink{}).
+		Where("id = ? AND url = ? AND broken = ?", id, url, !broken).
+		UpdateColumn("broken", broken)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (d *shortLinkDao) CreateExpiredEvents(now time.Time, limit int) (int, error) {
+	var n int
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var shortLinks []*ShortLink
+		if err := tx.
+			Where("expire_notified = ? AND expire_at <= ? AND status <> ?", false, now, StatusDeleted).
+			Order("expire_at").
+			Limit(limit).
+			Find(&shortLinks).Error; err != nil {
+			return err
+		}
+		if len(shortLinks) == 0 {
+			return nil
+		}
+
+		ids := make([]uint64, 0, len(shortLinks))
+		for _, shortLink := range shortLinks {
+			ids = append(ids, shortLink.ID)
+		}
+		// updated_at is not changed like SetBroken
+		if err := tx.Model(&ShortLink{}).Where("id IN ?", ids).UpdateColumn("expire_notified", true).Error; err != nil {
+			return err
+		}
+		n = len(shortLinks)
+		return createLinkEvents(tx, EventLinkExpired, 0, shortLinks...)
+	})
+	return n, err
+}
+
+func (d *shortLinkDao) GetHealth(shortLinkID uint64) (*LinkHealth, error) {
+	var health LinkHealth
+	if err := d.db.Where("short_link_id = ?", shortLinkID).First(&health).Error; err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+func (d *shortLinkDao) GetHealths(shortLinkIDs []uint64) ([]*LinkHealth, error) {
+	var healths []*LinkHealth
+	if len(shortLinkIDs) == 0 {
+		return healths, nil
+	}
+	if err := d.db.Where("short_link_id IN ?", shortLinkIDs).Find(&healths).Error; err != nil {
+		return nil, err
+	}
+	return healths, nil
+}
+
+func (d *shortLinkDao) SaveHealth(health *LinkHealth) error {
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "short_link_id"}},
+		UpdateAll: true,
+	}).Create(health).Error
+}
+
+func (d *shortLinkDao) AssignDomain(domainID uint64) error {
+	return d.db.
+		Model(&ShortLink{}).
+		Where("domain_id = ?", 0).
+		Update("domain_id", domainID).Error
+}
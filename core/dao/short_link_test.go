@@ -1,9 +1,13 @@
 package dao
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/georgechang0117/url-shortener/core/rules"
+
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -16,6 +20,7 @@ const (
 
 var (
 	testShortLink1 = ShortLink{
+		DomainID: 1,
 		URLID:    "shortLink1",
 		URL:      testURL,
 		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
@@ -59,7 +64,7 @@ func (s *shortLinkTestSuite) TestCreate() {
 }
 
 func (s *shortLinkTestSuite) TestGetByURLID() {
-	shortLink, err := s.impl.GetByURLID(testShortLink1.URLID)
+	shortLink, err := s.impl.GetByURLID(testShortLink1.DomainID, testShortLink1.URLID)
 	s.Require().NoError(err)
 	s.Equal(testShortLink1.ID, shortLink.ID)
 	s.Equal(testShortLink1.URL, shortLink.URL)
@@ -67,7 +72,573 @@ func (s *shortLinkTestSuite) TestGetByURLID() {
 }
 
 func (s *shortLinkTestSuite) TestExists() {
-	exists, err := s.impl.Exists(testShortLink1.URLID)
+	exists, err := s.impl.Exists(testShortLink1.DomainID, testShortLink1.URLID)
+	s.Require().NoError(err)
+	s.True(exists)
+
+	exists, err = s.impl.Exists(100, testShortLink1.URLID)
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+func (s *shortLinkTestSuite) TestCreateSameURLIDInAnotherDomain() {
+	shortLink := ShortLink{
+		DomainID: testShortLink1.DomainID + 1,
+		URLID:    testShortLink1.URLID,
+		URL:      testURL,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+
+	duplicated := shortLink
+	duplicated.ID = 0
+	err := s.impl.Create(&duplicated)
+	s.True(IsErrDuplicatedKey(err))
+	s.False(IsErrDuplicatedKey(gorm.ErrRecordNotFound))
+}
+
+func (s *shortLinkTestSuite) TestAssignDomain() {
+	legacy := ShortLink{
+		URLID:    "legacyLink1",
+		URL:      testURL,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&legacy))
+
+	s.Require().NoError(s.impl.AssignDomain(5))
+	shortLink, err := s.impl.GetByURLID(5, legacy.URLID)
+	s.Require().NoError(err)
+	s.Equal(legacy.ID, shortLink.ID)
+}
+
+func (s *shortLinkTestSuite) TestCreateWithRules() {
+	set, err := rules.Compile([]rules.Rule{{Platforms: []string{rules.PlatformIOS}, URL: testURL}})
+	s.Require().NoError(err)
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "rulesLink1",
+		URL:      testURL,
+		Rules:    set,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal(set, sl.Rules)
+
+	sl, err = s.impl.GetByURLID(testShortLink1.DomainID, testShortLink1.URLID)
+	s.Require().NoError(err)
+	s.Nil(sl.Rules)
+}
+
+func (s *shortLinkTestSuite) TestCreateBatch() {
+	shortLinks := []*ShortLink{
+		{DomainID: 1, URLID: "batchLink1", URL: testURL, ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)},
+		{DomainID: 1, URLID: "batchLink2", URL: testURL, ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)},
+	}
+	s.Require().NoError(s.impl.CreateBatch(shortLinks))
+	s.NotZero(shortLinks[0].ID)
+	s.NotZero(shortLinks[1].ID)
+
+	exists, err := s.impl.Exists(1, "batchLink2")
+	s.Require().NoError(err)
+	s.True(exists)
+}
+
+func (s *shortLinkTestSuite) TestUpdate() {
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "updateLink1",
+		URL:      testURL,
+		Preview:  true,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+
+	shortLink.URL = "https://example.com"
+	shortLink.Preview = false
+	s.Require().NoError(s.impl.Update(&shortLink, "URL", "Preview"))
+
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal("https://example.com", sl.URL)
+	s.False(sl.Preview)
+}
+
+func (s *shortLinkTestSuite) TestUpdateStale() {
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "updateStale1",
+		URL:      testURL,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+	stale := shortLink
+
+	// written by metadata fetching, health checks and expiry notifications after stale is loaded
+	fetchedAt := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ok, err := s.impl.UpdateMetadata(shortLink.ID, testURL, Metadata{
+		Title:       "Example",
+		Description: "An example",
+		ImageURL:    "https://example.com/image.png",
+		FetchedAt:   fetchedAt,
+	})
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	ok, err = s.impl.SetBroken(shortLink.ID, testURL, true)
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	s.Require().NoError(s.db.Model(&ShortLink{}).Where("id = ?", shortLink.ID).UpdateColumn("expire_notified", true).Error)
+
+	stale.Title = "Edited"
+	s.Require().NoError(s.impl.Update(&stale, "Title"))
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal("Edited", sl.Title)
+	// fields not given are kept though they're stale
+	s.Equal("An example", sl.Description)
+	s.Equal("https://example.com/image.png", sl.ImageURL)
+	s.Require().NotNil(sl.MetadataFetchedAt)
+	s.True(fetchedAt.Equal(*sl.MetadataFetchedAt))
+	s.True(sl.Broken)
+	s.True(sl.ExpireNotified)
+	// the updated short link is returned as stored
+	s.True(stale.Broken)
+	s.Equal("https://example.com/image.png", stale.ImageURL)
+
+	// the broken flag and the expired event are reset once the destination and the expiry are changed
+	stale.URL = "https://example.com"
+	stale.ExpireAt = stale.ExpireAt.Add(time.Hour)
+	s.Require().NoError(s.impl.Update(&stale, "URL", "ExpireAt"))
+	sl, err = s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.False(sl.Broken)
+	s.False(sl.ExpireNotified)
+
+	s.Error(s.impl.Update(&stale, "Broken"))
+}
+
+func (s *shortLinkTestSuite) TestUpdateDeleted() {
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "updateDeleted1",
+		URL:      testURL,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+	stale := shortLink
+	s.Require().NoError(s.impl.Delete(shortLink.ID, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	// deletion after the short link is loaded is not undone
+	stale.Status = StatusDisabled
+	s.ErrorIs(s.impl.Update(&stale, "Status"), ErrShortLinkDeleted)
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal(StatusDeleted, sl.Status)
+}
+
+func (s *shortLinkTestSuite) TestDelete() {
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "deleteLink1",
+		URL:      testURL,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+
+	s.Equal(StatusActive, shortLink.Status)
+
+	deletedAt := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.impl.Delete(shortLink.ID, deletedAt))
+	// deleted short links are kept and their url_ids are still taken
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal(StatusDeleted, sl.Status)
+	s.Require().NotNil(sl.DeletedAt)
+	s.True(deletedAt.Equal(*sl.DeletedAt))
+	exists, err := s.impl.Exists(shortLink.DomainID, shortLink.URLID)
 	s.Require().NoError(err)
 	s.True(exists)
+	// deleting again does not change deletedAt
+	s.Require().NoError(s.impl.Delete(shortLink.ID, deletedAt.Add(time.Hour)))
+	sl, err = s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.True(deletedAt.Equal(*sl.DeletedAt))
+
+	s.Require().NoError(s.impl.Restore(shortLink.ID))
+	sl, err = s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal(StatusActive, sl.Status)
+	s.Nil(sl.DeletedAt)
+
+	s.Require().NoError(s.impl.Purge(shortLink.ID))
+	_, err = s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.True(IsErrRecordNotFound(err))
+	s.NoError(s.impl.Purge(shortLink.ID))
+}
+
+func (s *shortLinkTestSuite) TestRestoreDisabled() {
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "restoreLink1",
+		URL:      testURL,
+		Status:   StatusDisabled,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+
+	s.Require().NoError(s.impl.Delete(shortLink.ID, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)))
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal(StatusDeleted, sl.Status)
+	s.Equal(StatusDisabled, sl.StatusBeforeDelete)
+
+	// the short link is still disabled once restored
+	s.Require().NoError(s.impl.Restore(shortLink.ID))
+	sl, err = s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal(StatusDisabled, sl.Status)
+	s.Empty(sl.StatusBeforeDelete)
+	s.Nil(sl.DeletedAt)
+}
+
+func (s *shortLinkTestSuite) TestOverwriteDeleted() {
+	shortLink := ShortLink{
+		DomainID: 1,
+		URLID:    "overwriteLink1",
+		URL:      testURL,
+		Status:   StatusDisabled,
+		ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+	}
+	s.Require().NoError(s.impl.Create(&shortLink))
+	s.Require().NoError(s.impl.Delete(shortLink.ID, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	imported := shortLink
+	imported.URL = "https://example.com"
+	imported.Status = StatusActive
+	s.Require().NoError(s.impl.Overwrite(&imported))
+	sl, err := s.impl.GetByURLID(shortLink.DomainID, shortLink.URLID)
+	s.Require().NoError(err)
+	s.Equal("https://example.com", sl.URL)
+	s.Equal(StatusActive, sl.Status)
+	s.Nil(sl.DeletedAt)
+	s.Empty(sl.StatusBeforeDelete)
+}
+
+func (s *shortLinkTestSuite) TestListStatus() {
+	statuses := []string{StatusActive, StatusDisabled, StatusDeleted}
+	for i, status := range statuses {
+		shortLink := ShortLink{DomainID: 1, OwnerID: 14, URLID: fmt.Sprintf("statusLink%d", i), URL: testURL, Status: status}
+		s.Require().NoError(s.impl.Create(&shortLink))
+	}
+
+	// deleted short links are excluded unless they're listed by status
+	shortLinks, err := s.impl.List(ListFilter{OwnerID: 14, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 2)
+	s.Equal(StatusDisabled, shortLinks[0].Status)
+	s.Equal(StatusActive, shortLinks[1].Status)
+	for _, status := range statuses {
+		shortLinks, err := s.impl.List(ListFilter{OwnerID: 14, Status: status, Limit: 10})
+		s.Require().NoError(err)
+		s.Require().Len(shortLinks, 1)
+		s.Equal(status, shortLinks[0].Status)
+	}
+
+	var exported int
+	s.Require().NoError(s.impl.IterateByOwner(14, 10, func(shortLinks []*ShortLink) error {
+		exported += len(shortLinks)
+		return nil
+	}))
+	s.Equal(2, exported)
+}
+
+func (s *shortLinkTestSuite) TestList() {
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		shortLink := ShortLink{
+			DomainID: 1,
+			OwnerID:  9,
+			URLID:    fmt.Sprintf("ownedLink%d", i),
+			URL:      testURL,
+			ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+		}
+		s.Require().NoError(s.impl.Create(&shortLink))
+		ids = append(ids, shortLink.ID)
+	}
+
+	shortLinks, err := s.impl.List(ListFilter{OwnerID: 9, Limit: 2})
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 2)
+	s.Equal(ids[2], shortLinks[0].ID)
+	s.Equal(ids[1], shortLinks[1].ID)
+
+	shortLinks, err = s.impl.List(ListFilter{OwnerID: 9, BeforeID: shortLinks[1].ID, Limit: 2})
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 1)
+	s.Equal(ids[0], shortLinks[0].ID)
+}
+
+func (s *shortLinkTestSuite) TestListFilter() {
+	createdAt := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uint64
+	for i, shortLink := range []ShortLink{
+		{URL: "https://example.com/summer", Title: "Summer sale", Tags: Tags{"campaign", "summer"}},
+		{URL: "https://example.com/winter", Title: "Winter 100% off", Tags: Tags{"campaign"}},
+		{URL: "https://docs.example.com", Title: "Docs"},
+	} {
+		shortLink.DomainID = 1
+		shortLink.OwnerID = 11
+		shortLink.URLID = fmt.Sprintf("filterLink%d", i)
+		shortLink.ExpireAt = createdAt.AddDate(0, i+1, 0)
+		shortLink.CreatedAt = createdAt.AddDate(0, 0, i)
+		s.Require().NoError(s.impl.Create(&shortLink))
+		ids = append(ids, shortLink.ID)
+	}
+
+	for name, c := range map[string]struct {
+		filter   ListFilter
+		expected []uint64
+	}{
+		"tag":          {ListFilter{Tags: []string{"campaign"}}, []uint64{ids[1], ids[0]}},
+		"all tags":     {ListFilter{Tags: []string{"campaign", "summer"}}, []uint64{ids[0]}},
+		"unknown tag":  {ListFilter{Tags: []string{"fall"}}, nil},
+		"created":      {ListFilter{CreatedFrom: createdAt.AddDate(0, 0, 1), CreatedTo: createdAt.AddDate(0, 0, 2)}, []uint64{ids[1]}},
+		"expire":       {ListFilter{ExpireFrom: createdAt.AddDate(0, 2, 0)}, []uint64{ids[2], ids[1]}},
+		"url":          {ListFilter{Query: "docs"}, []uint64{ids[2]}},
+		"title":        {ListFilter{Query: "Sale"}, []uint64{ids[0]}},
+		"wildcard":     {ListFilter{Query: "100%"}, []uint64{ids[1]}},
+		"not wildcard": {ListFilter{Query: "_"}, nil},
+		// sqlite falls back to substring
+		"full text": {ListFilter{Query: "winter", FullText: true}, []uint64{ids[1]}},
+	} {
+		c.filter.OwnerID = 11
+		c.filter.Limit = 10
+		shortLinks, err := s.impl.List(c.filter)
+		s.Require().NoError(err, name)
+		var listed []uint64
+		for _, shortLink := range shortLinks {
+			listed = append(listed, shortLink.ID)
+		}
+		s.Equal(c.expected, listed, name)
+	}
+}
+
+func (s *shortLinkTestSuite) TestTags() {
+	shortLink := ShortLink{DomainID: 1, URLID: "tagLink1", URL: testURL, Tags: Tags{"a", "b"}}
+	s.Require().NoError(s.impl.Create(&shortLink))
+
+	loaded, err := s.impl.GetByURLID(1, "tagLink1")
+	s.Require().NoError(err)
+	s.Equal(Tags{"a", "b"}, loaded.Tags)
+
+	loaded.Tags = Tags{"b", "c"}
+	s.Require().NoError(s.impl.Update(loaded, "Tags"))
+	var tags []string
+	s.Require().NoError(s.db.Model(&ShortLinkTag{}).Where("short_link_id = ?", shortLink.ID).Order("tag").Pluck("tag", &tags).Error)
+	s.Equal([]string{"b", "c"}, tags)
+
+	s.Require().NoError(s.impl.Purge(shortLink.ID))
+	var count int64
+	s.Require().NoError(s.db.Model(&ShortLinkTag{}).Where("short_link_id = ?", shortLink.ID).Count(&count).Error)
+	s.Zero(count)
+}
+
+func (s *shortLinkTestSuite) TestGetByURLIDs() {
+	shortLinks, err := s.impl.GetByURLIDs(testShortLink1.DomainID, []string{testShortLink1.URLID, "absentLink1"})
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 1)
+	s.Equal(testShortLink1.ID, shortLinks[0].ID)
+
+	shortLinks, err = s.impl.GetByURLIDs(testShortLink1.DomainID, nil)
+	s.Require().NoError(err)
+	s.Empty(shortLinks)
+}
+
+func (s *shortLinkTestSuite) TestIterateByOwner() {
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		shortLink := ShortLink{
+			DomainID: 1,
+			OwnerID:  10,
+			URLID:    fmt.Sprintf("iterLink%d", i),
+			URL:      testURL,
+			ExpireAt: time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC),
+		}
+		s.Require().NoError(s.impl.Create(&shortLink))
+		ids = append(ids, shortLink.ID)
+	}
+
+	var iterated []uint64
+	var batches int
+	s.Require().NoError(s.impl.IterateByOwner(10, 2, func(shortLinks []*ShortLink) error {
+		batches++
+		for _, shortLink := range shortLinks {
+			iterated = append(iterated, shortLink.ID)
+		}
+		return nil
+	}))
+	s.Equal(ids, iterated)
+	s.Equal(3, batches)
+}
+
+func (s *shortLinkTestSuite) TestTransaction() {
+	shortLink := ShortLink{DomainID: 1, URLID: "txLink1", URL: testURL}
+	err := s.impl.Transaction(func(dao ShortLinkDao) error {
+		s.Require().NoError(dao.Create(&shortLink))
+		return errors.New("rollback")
+	})
+	s.Error(err)
+
+	exists, err := s.impl.Exists(1, "txLink1")
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+func (s *shortLinkTestSuite) TestUpdateMetadata() {
+	shortLink := ShortLink{DomainID: 1, URLID: "metaLink1", URL: testURL, Title: "Careers"}
+	s.Require().NoError(s.impl.Create(&shortLink))
+	fetchedAt := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	metadata := Metadata{
+		Title:       "Jobs at Dcard",
+		Description: "Join us",
+		ImageURL:    "https://example.com/og.png",
+		FaviconURL:  "https://example.com/favicon.ico",
+		FetchedAt:   fetchedAt,
+	}
+
+	updated, err := s.impl.UpdateMetadata(shortLink.ID, "https://example.com/changed", metadata)
+	s.Require().NoError(err)
+	s.False(updated)
+
+	updated, err = s.impl.UpdateMetadata(shortLink.ID, testURL, metadata)
+	s.Require().NoError(err)
+	s.True(updated)
+	loaded, err := s.impl.GetByURLID(1, "metaLink1")
+	s.Require().NoError(err)
+	s.Equal("Careers", loaded.Title)
+	s.Equal("Join us", loaded.Description)
+	s.Equal(metadata.ImageURL, loaded.ImageURL)
+	s.Equal(metadata.FaviconURL, loaded.FaviconURL)
+	s.Require().NotNil(loaded.MetadataFetchedAt)
+	s.True(fetchedAt.Equal(*loaded.MetadataFetchedAt))
+	s.True(shortLink.UpdatedAt.Equal(loaded.UpdatedAt))
+}
+
+func (s *shortLinkTestSuite) TestListActive() {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uint64
+	for i, expireAt := range []time.Time{now.Add(time.Hour), now, now.AddDate(0, 1, 0)} {
+		shortLink := ShortLink{DomainID: 1, URLID: fmt.Sprintf("activeLink%d", i), URL: testURL, ExpireAt: expireAt}
+		s.Require().NoError(s.impl.Create(&shortLink))
+		ids = append(ids, shortLink.ID)
+	}
+	disabled := ShortLink{DomainID: 1, URLID: "disabledLink", URL: testURL, ExpireAt: now.Add(time.Hour), Status: StatusDisabled}
+	s.Require().NoError(s.impl.Create(&disabled))
+
+	shortLinks, err := s.impl.ListActive(ids[0]-1, now, 1)
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 1)
+	s.Equal(ids[0], shortLinks[0].ID)
+
+	// the second one expires at now and the disabled one is not active
+	shortLinks, err = s.impl.ListActive(ids[0], now, 10)
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 1)
+	s.Equal(ids[2], shortLinks[0].ID)
+}
+
+func (s *shortLinkTestSuite) TestListRecent() {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uint64
+	for i, expireAt := range []time.Time{now.Add(time.Hour), now, now.AddDate(0, 1, 0)} {
+		shortLink := ShortLink{DomainID: 1, URLID: fmt.Sprintf("recentLink%d", i), URL: testURL, ExpireAt: expireAt}
+		s.Require().NoError(s.impl.Create(&shortLink))
+		ids = append(ids, shortLink.ID)
+	}
+	disabled := ShortLink{DomainID: 1, URLID: "recentDisabled", URL: testURL, ExpireAt: now.Add(time.Hour), Status: StatusDisabled}
+	s.Require().NoError(s.impl.Create(&disabled))
+
+	// the second one expires at now and the disabled one is not active
+	shortLinks, err := s.impl.ListRecent(now, 2)
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 2)
+	s.Equal(ids[2], shortLinks[0].ID)
+	s.Equal(ids[0], shortLinks[1].ID)
+}
+
+func (s *shortLinkTestSuite) TestListMostClicked() {
+	s.Require().NoError(s.db.AutoMigrate(&ClickCount{}))
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uint64
+	for i, expireAt := range []time.Time{now.Add(time.Hour), now, now.Add(time.Hour), now.Add(time.Hour)} {
+		shortLink := ShortLink{DomainID: 1, URLID: fmt.Sprintf("clickedLink%d", i), URL: testURL, ExpireAt: expireAt}
+		s.Require().NoError(s.impl.Create(&shortLink))
+		ids = append(ids, shortLink.ID)
+	}
+	for _, clickCount := range []ClickCount{
+		{ShortLinkID: ids[0], Variant: "a", Clicks: 3, UpdatedAt: now},
+		{ShortLinkID: ids[0], Variant: "b", Clicks: 4, UpdatedAt: now},
+		{ShortLinkID: ids[1], Clicks: 100, UpdatedAt: now},
+		{ShortLinkID: ids[2], Clicks: 5, UpdatedAt: now.Add(-time.Minute)},
+		// clicks before clickedSince are not counted
+		{ShortLinkID: ids[3], Clicks: 100, UpdatedAt: now.Add(-2 * time.Hour)},
+	} {
+		clickCount := clickCount
+		s.Require().NoError(s.db.Create(&clickCount).Error)
+	}
+
+	// the second one expires at now
+	shortLinks, err := s.impl.ListMostClicked(now.Add(-time.Hour), now, 10)
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 2)
+	s.Equal(ids[0], shortLinks[0].ID)
+	s.Equal(testURL, shortLinks[0].URL)
+	s.Equal(ids[2], shortLinks[1].ID)
+
+	shortLinks, err = s.impl.ListMostClicked(now.Add(-time.Hour), now, 1)
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 1)
+}
+
+func (s *shortLinkTestSuite) TestHealth() {
+	shortLink := ShortLink{DomainID: 1, OwnerID: 12, URLID: "healthLink1", URL: testURL}
+	s.Require().NoError(s.impl.Create(&shortLink))
+	checkedAt := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.impl.GetHealth(shortLink.ID)
+	s.True(IsErrRecordNotFound(err))
+
+	health := LinkHealth{ShortLinkID: shortLink.ID, URL: testURL, StatusCode: 502, ConsecutiveFailures: 1, CheckedAt: checkedAt}
+	s.Require().NoError(s.impl.SaveHealth(&health))
+	health.ConsecutiveFailures = 2
+	health.BrokenSince = &checkedAt
+	s.Require().NoError(s.impl.SaveHealth(&health))
+
+	healths, err := s.impl.GetHealths([]uint64{shortLink.ID, 0})
+	s.Require().NoError(err)
+	s.Require().Len(healths, 1)
+	s.Equal(2, healths[0].ConsecutiveFailures)
+	s.Require().NotNil(healths[0].BrokenSince)
+	s.Equal(checkedAt.UnixNano(), healths[0].BrokenSince.UnixNano())
+
+	updated, err := s.impl.SetBroken(shortLink.ID, "https://example.com/changed", true)
+	s.Require().NoError(err)
+	s.False(updated)
+	updated, err = s.impl.SetBroken(shortLink.ID, testURL, true)
+	s.Require().NoError(err)
+	s.True(updated)
+	updated, err = s.impl.SetBroken(shortLink.ID, testURL, true)
+	s.Require().NoError(err)
+	s.False(updated)
+
+	shortLinks, err := s.impl.List(ListFilter{OwnerID: 12, Broken: true, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(shortLinks, 1)
+	s.True(shortLinks[0].Broken)
+
+	s.Require().NoError(s.impl.Purge(shortLink.ID))
+	_, err = s.impl.GetHealth(shortLink.ID)
+	s.True(IsErrRecordNotFound(err))
 }
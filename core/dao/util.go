@@ -1,8 +1,27 @@
 package dao
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// mysqlErrDuplicateEntry is error number of MySQL for violating unique index.
+const mysqlErrDuplicateEntry = 1062
 
 // IsErrRecordNotFound checks if error equals to record not found.
 func IsErrRecordNotFound(err error) bool {
 	return err == gorm.ErrRecordNotFound
 }
+
+// IsErrDuplicatedKey checks if error is caused by violating unique index, e.g. url_id is used in the domain.
+func IsErrDuplicatedKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDuplicateEntry
+	}
+	// sqlite used in tests is matched by message, which doesn't need to import the cgo driver
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,301 @@
+package dao
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// EventLinkCreated is raised when a short link is created, including imports.
+	EventLinkCreated = "link.created"
+	// EventLinkUpdated is raised when a short link is updated by its owner, including imports overwriting it.
+	EventLinkUpdated = "link.updated"
+	// EventLinkExpired is raised once a short link expires, again if it expires after its expiry is extended.
+	EventLinkExpired = "link.expired"
+	// EventLinkDeleted is raised when a short link is deleted.
+	EventLinkDeleted = "link.deleted"
+	// EventLinkMilestone is raised when total clicks of a short link reach a milestone, see ClickMilestone.
+	EventLinkMilestone = "link.milestone"
+
+	// DeliveryPending indicates the event is waiting to be delivered at NextAttemptAt.
+	DeliveryPending = "pending"
+	// DeliveryDelivered indicates the endpoint accepted the event.
+	DeliveryDelivered = "delivered"
+	// DeliveryDead indicates the event is not delivered after all attempts, it's delivered again only if it's
+	// replayed.
+	DeliveryDead = "dead"
+
+	// firstClickMilestone is the first milestone of clicks, the next ones are ten times the previous one.
+	firstClickMilestone = 100
+)
+
+// EventTypes are types of events which webhooks can subscribe to.
+var EventTypes = []string{EventLinkCreated, EventLinkUpdated, EventLinkExpired, EventLinkDeleted, EventLinkMilestone}
+
+// Webhook defines model for a subscription of an owner to events of its short links.
+type Webhook struct {
+	ID      uint64 `gorm:"primary_key,AUTO_INCREMENT"`
+	OwnerID uint64 `gorm:"not null;index"`
+	URL     string `gorm:"type:varchar(512);not null"`
+	// Secret signs deliveries by HMAC-SHA256, it's shared with the endpoint to verify them.
+	Secret string `gorm:"type:varchar(64);not null"`
+	// Events are comma-separated types of events subscribed, see EventTypes.
+	Events    string `gorm:"type:varchar(256);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribes checks if the webhook subscribes to events of eventType.
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, t := range w.EventTypes() {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// EventTypes returns types of events subscribed.
+func (w *Webhook) EventTypes() []string {
+	if w.Events == "" {
+		return nil
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// OutboxEvent defines model for an event to deliver to a webhook. It's written in the transaction of the change
+// raising it, so events are neither lost nor raised by changes rolled back.
+type OutboxEvent struct {
+	ID        uint64 `gorm:"primary_key,AUTO_INCREMENT"`
+	WebhookID uint64 `gorm:"not null;index"`
+	OwnerID   uint64 `gorm:"not null;default:0"`
+	Type      string `gorm:"type:varchar(32);not null"`
+	// Payload is data of the event in JSON, see LinkEventData.
+	Payload       string    `gorm:"type:text"`
+	Status        string    `gorm:"type:varchar(16);not null;index:idx_status_next_attempt_at,priority:1"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index:idx_status_next_attempt_at,priority:2"`
+	LastError     string    `gorm:"type:varchar(256);not null;default:''"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// LinkEventData defines payload of events of a short link, which is a snapshot when the event is raised.
+type LinkEventData struct {
+	ID       string    `json:"id"`
+	Domain   string    `json:"domain"`
+	URL      string    `json:"url"`
+	ExpireAt time.Time `json:"expireAt"`
+	// Clicks is the milestone reached, only for EventLinkMilestone.
+	Clicks int64 `json:"clicks,omitempty"`
+}
+
+// EventFilter defines conditions of listing events of a webhook, zero values are not filtered.
+type EventFilter struct {
+	WebhookID uint64
+	Status    string
+	// BeforeID lists events with ID less than it, 0 lists from the newest one.
+	BeforeID uint64
+	Limit    int
+}
+
+// ClickMilestone returns the largest milestone reached by clicks increasing from before to after, which is 0
+// if none is reached. Milestones are 100, 1000, 10000 and so on.
+func ClickMilestone(before, after int64) int64 {
+	var milestone int64
+	for m := int64(firstClickMilestone); m <= after; m *= 10 {
+		if m > before {
+			milestone = m
+		}
+	}
+	return milestone
+}
+
+type webhookDao struct {
+	db *gorm.DB
+}
+
+// NewWebhookDao creates an instance of WebhookDao.
+func NewWebhookDao(db *gorm.DB) (WebhookDao, error) {
+	dao := &webhookDao{
+		db: db,
+	}
+	return dao, dao.migrate()
+}
+
+func (d *webhookDao) migrate() error {
+	return d.db.AutoMigrate(&Webhook{}, &OutboxEvent{})
+}
+
+func (d *webhookDao) Create(webhook *Webhook) error {
+	return d.db.Create(webhook).Error
+}
+
+func (d *webhookDao) Get(id uint64) (*Webhook, error) {
+	var webhook Webhook
+	if err := d.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (d *webhookDao) GetByIDs(ids []uint64) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	if len(ids) == 0 {
+		return webhooks, nil
+	}
+	if err := d.db.Where("id IN ?", ids).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (d *webhookDao) ListByOwner(ownerID uint64) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	if err := d.db.Where("owner_id = ?", ownerID).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (d *webhookDao) Delete(id uint64) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&OutboxEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Webhook{}, id).Error
+	})
+}
+
+func (d *webhookDao) ListDueEvents(now time.Time, limit int) ([]*OutboxEvent, error) {
+	var events []*OutboxEvent
+	if err := d.db.
+		Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (d *webhookDao) GetEvent(id uint64) (*OutboxEvent, error) {
+	var event OutboxEvent
+	if err := d.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (d *webhookDao) ListEvents(filter EventFilter) ([]*OutboxEvent, error) {
+	var events []*OutboxEvent
+	query := d.db.Where("webhook_id = ?", filter.WebhookID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if err := query.Order("id DESC").Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (d *webhookDao) UpdateEvent(event *OutboxEvent) error {
+	return d.db.Save(event).Error
+}
+
+// createLinkEvents writes events of eventType of shortLinks to the outbox of webhooks of their owners
+// subscribing to it by tx. clicks is the milestone of EventLinkMilestone.
+func createLinkEvents(tx *gorm.DB, eventType string, clicks int64, shortLinks ...*ShortLink) error {
+	var ownerIDs []uint64
+	for _, shortLink := range shortLinks {
+		// anonymous short links have no webhooks
+		if shortLink.OwnerID != 0 {
+			ownerIDs = append(ownerIDs, shortLink.OwnerID)
+		}
+	}
+	if len(ownerIDs) == 0 {
+		return nil
+	}
+
+	var webhooks []*Webhook
+	if err := tx.Where("owner_id IN ?", ownerIDs).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	subscribers := map[uint64][]*Webhook{}
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(eventType) {
+			subscribers[webhook.OwnerID] = append(subscribers[webhook.OwnerID], webhook)
+		}
+	}
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	hosts, err := domainHosts(tx, shortLinks)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var events []*OutboxEvent
+	for _, shortLink := range shortLinks {
+		if len(subscribers[shortLink.OwnerID]) == 0 {
+			continue
+		}
+		payload, err := json.Marshal(LinkEventData{
+			ID:       shortLink.URLID,
+			Domain:   hosts[shortLink.DomainID],
+			URL:      shortLink.URL,
+			ExpireAt: shortLink.ExpireAt,
+			Clicks:   clicks,
+		})
+		if err != nil {
+			return err
+		}
+		for _, webhook := range subscribers[shortLink.OwnerID] {
+			events = append(events, &OutboxEvent{
+				WebhookID:     webhook.ID,
+				OwnerID:       shortLink.OwnerID,
+				Type:          eventType,
+				Payload:       string(payload),
+				Status:        DeliveryPending,
+				NextAttemptAt: now,
+			})
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+// domainHosts returns hosts of domains of shortLinks by domain ID, domains not set on short links are read by tx.
+func domainHosts(tx *gorm.DB, shortLinks []*ShortLink) (map[uint64]string, error) {
+	hosts := map[uint64]string{}
+	var missing []uint64
+	for _, shortLink := range shortLinks {
+		if shortLink.Domain != nil {
+			hosts[shortLink.DomainID] = shortLink.Domain.Host
+		} else {
+			missing = append(missing, shortLink.DomainID)
+		}
+	}
+	if len(missing) == 0 {
+		return hosts, nil
+	}
+
+	var domains []Domain
+	if err := tx.Where("id IN ?", missing).Find(&domains).Error; err != nil {
+		return nil, err
+	}
+	for _, domain := range domains {
+		hosts[domain.ID] = domain.Host
+	}
+	return hosts, nil
+}
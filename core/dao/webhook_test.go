@@ -0,0 +1,192 @@
+package dao
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type webhookTestSuite struct {
+	suite.Suite
+	impl         *webhookDao
+	shortLinkDao ShortLinkDao
+	clickDao     ClickDao
+	db           *gorm.DB
+	domain       Domain
+}
+
+func TestWebhookSuite(t *testing.T) {
+	suite.Run(t, new(webhookTestSuite))
+}
+
+func (s *webhookTestSuite) SetupTest() {
+	var err error
+	s.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	s.Require().NoError(err)
+
+	dao, err := NewWebhookDao(s.db)
+	s.Require().NoError(err)
+	s.impl = dao.(*webhookDao)
+	s.shortLinkDao, err = NewShortLinkDao(s.db)
+	s.Require().NoError(err)
+	s.clickDao, err = NewClickDao(s.db)
+	s.Require().NoError(err)
+	domainDao, err := NewDomainDao(s.db)
+	s.Require().NoError(err)
+	s.domain = Domain{Host: "go.example.com", Scheme: "https"}
+	s.Require().NoError(domainDao.Create(&s.domain))
+}
+
+func (s *webhookTestSuite) TearDownTest() {
+	db, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(db.Close())
+}
+
+func (s *webhookTestSuite) events(webhookID uint64) []*OutboxEvent {
+	events, err := s.impl.ListEvents(EventFilter{WebhookID: webhookID, Limit: 100})
+	s.Require().NoError(err)
+	return events
+}
+
+func (s *webhookTestSuite) TestLinkEvents() {
+	all := Webhook{OwnerID: 1, URL: "https://example.com/all", Secret: "secret", Events: "link.created,link.updated,link.deleted"}
+	created := Webhook{OwnerID: 1, URL: "https://example.com/created", Secret: "secret", Events: EventLinkCreated}
+	others := Webhook{OwnerID: 2, URL: "https://example.com/others", Secret: "secret", Events: EventLinkCreated}
+	for _, webhook := range []*Webhook{&all, &created, &others} {
+		s.Require().NoError(s.impl.Create(webhook))
+	}
+
+	expireAt := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	shortLink := ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "events", URL: testURL, ExpireAt: expireAt}
+	s.Require().NoError(s.shortLinkDao.Create(&shortLink))
+	anonymous := ShortLink{DomainID: s.domain.ID, URLID: "anonymous", URL: testURL, ExpireAt: expireAt}
+	s.Require().NoError(s.shortLinkDao.Create(&anonymous))
+	shortLink.URL = "https://example.com/new"
+	s.Require().NoError(s.shortLinkDao.Update(&shortLink, "URL"))
+	s.Require().NoError(s.shortLinkDao.Delete(shortLink.ID, expireAt))
+	// the deleted event is raised once by soft deletion
+	s.Require().NoError(s.shortLinkDao.Purge(shortLink.ID))
+
+	events := s.events(all.ID)
+	s.Require().Len(events, 3)
+	s.Equal(EventLinkDeleted, events[0].Type)
+	s.Equal(EventLinkUpdated, events[1].Type)
+	s.Equal(EventLinkCreated, events[2].Type)
+	s.Equal(DeliveryPending, events[2].Status)
+	s.Equal(uint64(1), events[2].OwnerID)
+	var data LinkEventData
+	s.Require().NoError(json.Unmarshal([]byte(events[0].Payload), &data))
+	s.Equal(LinkEventData{ID: "events", Domain: "go.example.com", URL: "https://example.com/new", ExpireAt: expireAt}, data)
+
+	s.Len(s.events(created.ID), 1)
+	s.Empty(s.events(others.ID))
+}
+
+func (s *webhookTestSuite) TestEventsRolledBack() {
+	webhook := Webhook{OwnerID: 1, URL: "https://example.com/", Secret: "secret", Events: EventLinkCreated}
+	s.Require().NoError(s.impl.Create(&webhook))
+	shortLink := ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "conflict", URL: testURL}
+	s.Require().NoError(s.shortLinkDao.Create(&shortLink))
+
+	// the event of the conflicting short link is not written either
+	s.Error(s.shortLinkDao.Create(&ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "conflict", URL: testURL}))
+	s.Len(s.events(webhook.ID), 1)
+}
+
+func (s *webhookTestSuite) TestExpiredEvents() {
+	webhook := Webhook{OwnerID: 1, URL: "https://example.com/", Secret: "secret", Events: EventLinkExpired}
+	s.Require().NoError(s.impl.Create(&webhook))
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	expired := ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "expired", URL: testURL, ExpireAt: now.Add(-time.Hour)}
+	active := ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "active", URL: testURL, ExpireAt: now.Add(time.Hour)}
+	s.Require().NoError(s.shortLinkDao.CreateBatch([]*ShortLink{&expired, &active}))
+
+	n, err := s.shortLinkDao.CreateExpiredEvents(now, 10)
+	s.Require().NoError(err)
+	s.Equal(1, n)
+	// expired short links are notified once
+	n, err = s.shortLinkDao.CreateExpiredEvents(now, 10)
+	s.Require().NoError(err)
+	s.Equal(0, n)
+
+	events := s.events(webhook.ID)
+	s.Require().Len(events, 1)
+	s.Equal(EventLinkExpired, events[0].Type)
+	s.Contains(events[0].Payload, `"id":"expired"`)
+}
+
+func (s *webhookTestSuite) TestMilestoneEvents() {
+	webhook := Webhook{OwnerID: 1, URL: "https://example.com/", Secret: "secret", Events: EventLinkMilestone}
+	s.Require().NoError(s.impl.Create(&webhook))
+	shortLink := ShortLink{DomainID: s.domain.ID, OwnerID: 1, URLID: "popular", URL: testURL}
+	s.Require().NoError(s.shortLinkDao.Create(&shortLink))
+
+	s.Require().NoError(s.clickDao.Increase(shortLink.ID, "a", 60))
+	s.Require().NoError(s.clickDao.Increase(shortLink.ID, "b", 50))
+	s.Require().NoError(s.clickDao.Increase(shortLink.ID, "a", 50))
+
+	events := s.events(webhook.ID)
+	s.Require().Len(events, 1)
+	var data LinkEventData
+	s.Require().NoError(json.Unmarshal([]byte(events[0].Payload), &data))
+	s.Equal(int64(100), data.Clicks)
+}
+
+func (s *webhookTestSuite) TestClickMilestone() {
+	s.Equal(int64(0), ClickMilestone(0, 99))
+	s.Equal(int64(100), ClickMilestone(99, 100))
+	s.Equal(int64(0), ClickMilestone(100, 999))
+	s.Equal(int64(10000), ClickMilestone(500, 12000))
+}
+
+func (s *webhookTestSuite) TestDueEvents() {
+	webhook := Webhook{OwnerID: 1, URL: "https://example.com/", Secret: "secret", Events: EventLinkCreated}
+	s.Require().NoError(s.impl.Create(&webhook))
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	due := OutboxEvent{WebhookID: webhook.ID, Type: EventLinkCreated, Status: DeliveryPending, NextAttemptAt: now}
+	later := OutboxEvent{WebhookID: webhook.ID, Type: EventLinkCreated, Status: DeliveryPending, NextAttemptAt: now.Add(time.Minute)}
+	dead := OutboxEvent{WebhookID: webhook.ID, Type: EventLinkCreated, Status: DeliveryDead, NextAttemptAt: now}
+	for _, event := range []*OutboxEvent{&due, &later, &dead} {
+		s.Require().NoError(s.db.Create(event).Error)
+	}
+
+	events, err := s.impl.ListDueEvents(now, 10)
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(due.ID, events[0].ID)
+
+	events[0].Status = DeliveryDelivered
+	events[0].Attempts = 1
+	s.Require().NoError(s.impl.UpdateEvent(events[0]))
+	event, err := s.impl.GetEvent(due.ID)
+	s.Require().NoError(err)
+	s.Equal(DeliveryDelivered, event.Status)
+
+	events, err = s.impl.ListEvents(EventFilter{WebhookID: webhook.ID, Status: DeliveryDead, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(dead.ID, events[0].ID)
+	events, err = s.impl.ListEvents(EventFilter{WebhookID: webhook.ID, BeforeID: later.ID, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(due.ID, events[0].ID)
+
+	// events are deleted with the webhook
+	s.Require().NoError(s.impl.Delete(webhook.ID))
+	s.Empty(s.events(webhook.ID))
+	webhooks, err := s.impl.ListByOwner(1)
+	s.Require().NoError(err)
+	s.Empty(webhooks)
+}
+
+func (s *webhookTestSuite) TestSubscribes() {
+	webhook := Webhook{Events: "link.created,link.deleted"}
+	s.True(webhook.Subscribes(EventLinkDeleted))
+	s.False(webhook.Subscribes(EventLinkUpdated))
+	s.Nil((&Webhook{}).EventTypes())
+}
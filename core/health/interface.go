@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/safehttp"
+)
+
+// ErrBlocked indicates the destination resolves to an address which is not allowed to probe, e.g. loopback or
+// private networks. Such destinations are not known to be broken.
+var ErrBlocked = safehttp.ErrBlocked
+
+// Result defines result of probing a destination.
+type Result struct {
+	// StatusCode is status of the last response, 0 if no response is received.
+	StatusCode int
+	// Latency is duration of the last request until response headers are received.
+	Latency time.Duration
+	// Err is error of the last request, nil if a response is received.
+	Err error
+}
+
+// Healthy returns whether the destination is up. Responses like 401 or 429 still show the destination exists,
+// only 404, 410, 5xx and failed requests are not healthy.
+func (r Result) Healthy() bool {
+	return r.Err == nil &&
+		r.StatusCode != http.StatusNotFound &&
+		r.StatusCode != http.StatusGone &&
+		r.StatusCode < http.StatusInternalServerError
+}
+
+// ProberConfig defines limits of probing destinations, zero values use defaults.
+type ProberConfig struct {
+	// Timeout limits a request including redirects, defaultTimeout if zero.
+	Timeout time.Duration
+	// MaxRedirects limits redirects followed, defaultMaxRedirects if zero.
+	MaxRedirects int
+	UserAgent    string
+}
+
+// Prober defines interface of probing whether destinations are up.
+type Prober interface {
+	// Probe requests rawURL by HEAD, and by GET if HEAD fails since some servers do not support it.
+	Probe(ctx context.Context, rawURL string) Result
+}
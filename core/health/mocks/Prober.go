@@ -0,0 +1,29 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	health "github.com/georgechang0117/url-shortener/core/health"
+	mock "github.com/stretchr/testify/mock"
+
+	context "context"
+)
+
+// Prober is an autogenerated mock type for the Prober type
+type Prober struct {
+	mock.Mock
+}
+
+// Probe provides a mock function with given fields: ctx, rawURL
+func (_m *Prober) Probe(ctx context.Context, rawURL string) health.Result {
+	ret := _m.Called(ctx, rawURL)
+
+	var r0 health.Result
+	if rf, ok := ret.Get(0).(func(context.Context, string) health.Result); ok {
+		r0 = rf(ctx, rawURL)
+	} else {
+		r0 = ret.Get(0).(health.Result)
+	}
+
+	return r0
+}
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/safehttp"
+)
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRedirects = 5
+	defaultUserAgent    = "url-shortener-health/1.0"
+)
+
+type proberImpl struct {
+	config ProberConfig
+	client *http.Client
+	// blocked returns whether ip is not allowed to connect, overridden by tests probing loopback.
+	blocked func(ip net.IP) bool
+}
+
+// NewProber creates an instance of Prober. Destinations in blocked networks are not probed, see
+// safehttp.NewClient.
+func NewProber(config ProberConfig) Prober {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = defaultMaxRedirects
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+
+	p := &proberImpl{config: config, blocked: safehttp.IsBlocked}
+	p.client = safehttp.NewClient(safehttp.Config{
+		Timeout:      config.Timeout,
+		MaxRedirects: config.MaxRedirects,
+		Blocked:      func(ip net.IP) bool { return p.blocked(ip) },
+	})
+	return p
+}
+
+func (p *proberImpl) Probe(ctx context.Context, rawURL string) Result {
+	result := p.request(ctx, http.MethodHead, rawURL)
+	if errors.Is(result.Err, ErrBlocked) || ctx.Err() != nil {
+		return result
+	}
+	// servers may reject HEAD by 405 or even 404, so failures are confirmed by GET
+	if result.Err != nil || result.StatusCode >= http.StatusBadRequest {
+		result = p.request(ctx, http.MethodGet, rawURL)
+	}
+	return result
+}
+
+func (p *proberImpl) request(ctx context.Context, method, rawURL string) Result {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("User-Agent", p.config.UserAgent)
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency, Err: err}
+	}
+	// body is not needed, closing it without reading drops the connection instead of downloading the page
+	resp.Body.Close()
+
+	return Result{StatusCode: resp.StatusCode, Latency: latency}
+}
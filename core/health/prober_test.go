@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type proberTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	impl   *proberImpl
+}
+
+func TestProberTestSuite(t *testing.T) {
+	suite.Run(t, new(proberTestSuite))
+}
+
+func (s *proberTestSuite) SetupSuite() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/get_only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	s.server = httptest.NewServer(mux)
+}
+
+func (s *proberTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *proberTestSuite) SetupTest() {
+	s.impl = NewProber(ProberConfig{Timeout: 100 * time.Millisecond}).(*proberImpl)
+	// the test destination listens on loopback
+	s.impl.blocked = func(ip net.IP) bool { return false }
+}
+
+func (s *proberTestSuite) TestProbe() {
+	for path, expected := range map[string]int{
+		"/ok":       http.StatusOK,
+		"/get_only": http.StatusOK,
+		"/gone":     http.StatusGone,
+		"/login":    http.StatusUnauthorized,
+	} {
+		result := s.impl.Probe(context.Background(), s.server.URL+path)
+		s.NoError(result.Err, path)
+		s.Equal(expected, result.StatusCode, path)
+		s.Equal(expected != http.StatusGone, result.Healthy(), path)
+		s.Positive(int64(result.Latency), path)
+	}
+}
+
+func (s *proberTestSuite) TestProbeTimeout() {
+	result := s.impl.Probe(context.Background(), s.server.URL+"/slow")
+	s.Error(result.Err)
+	s.Zero(result.StatusCode)
+	s.False(result.Healthy())
+}
+
+func (s *proberTestSuite) TestProbeBlocked() {
+	result := NewProber(ProberConfig{Timeout: 100 * time.Millisecond}).Probe(context.Background(), s.server.URL+"/ok")
+	s.True(errors.Is(result.Err, ErrBlocked), result.Err)
+}
+
+func (s *proberTestSuite) TestHealthy() {
+	s.True(Result{StatusCode: http.StatusTooManyRequests}.Healthy())
+	s.False(Result{StatusCode: http.StatusNotFound}.Healthy())
+	s.False(Result{StatusCode: http.StatusBadGateway}.Healthy())
+	s.False(Result{Err: errors.New("connection refused")}.Healthy())
+}
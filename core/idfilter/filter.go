@@ -0,0 +1,130 @@
+package idfilter
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+)
+
+// DefaultReservedIDs are first path segments of routes served besides short links.
+var DefaultReservedIDs = []string{"api"}
+
+//go:embed blocklist.txt
+var embeddedBlocklist []byte
+
+// leetReplacer maps letters and leetspeak digits looking alike to the same letter. Words and IDs are both
+// normalized by it, so "sh1t" and "5hit" match "shit", and "l" matches "I" in mixed case IDs.
+var leetReplacer = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"l", "i",
+	"2", "z",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"6", "g",
+	"7", "t",
+	"8", "b",
+	"9", "g",
+)
+
+// Config defines configuration of Filter.
+type Config struct {
+	// Words are blocked as substrings of IDs in addition to the embedded block list, which are matched
+	// case-insensitively and leetspeak-aware.
+	Words []string
+	// ReservedIDs are reserved in addition to DefaultReservedIDs, which are matched as whole IDs
+	// case-insensitively. IDs only starting with them do not clash with routes.
+	ReservedIDs []string
+}
+
+type filterImpl struct {
+	words []string
+	// reservedIDs are in lower case.
+	reservedIDs map[string]bool
+	// blockedIDs are blocked by administrators, which are not checked if nil.
+	blockedIDs dao.BlockedIDDao
+}
+
+// NewFilter creates an instance of Filter, blockedIDs is optional.
+func NewFilter(config Config, blockedIDs dao.BlockedIDDao) Filter {
+	f := &filterImpl{reservedIDs: map[string]bool{}, blockedIDs: blockedIDs}
+
+	words, _ := readWords(bytes.NewReader(embeddedBlocklist))
+	for _, word := range append(words, config.Words...) {
+		if word = normalize(strings.TrimSpace(word)); word != "" {
+			f.words = append(f.words, word)
+		}
+	}
+	for _, id := range append(DefaultReservedIDs, config.ReservedIDs...) {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			f.reservedIDs[id] = true
+		}
+	}
+	return f
+}
+
+func (f *filterImpl) Check(id string) error {
+	if f.reservedIDs[strings.ToLower(id)] {
+		return ErrReserved
+	}
+
+	normalized := normalize(id)
+	for _, word := range f.words {
+		if strings.Contains(normalized, word) {
+			return ErrBlocked
+		}
+	}
+
+	if f.blockedIDs == nil {
+		return nil
+	}
+	blocked, err := f.blockedIDs.Exists(id)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// normalize returns s in lower case with leetspeak digits replaced.
+func normalize(s string) string {
+	return leetReplacer.Replace(strings.ToLower(s))
+}
+
+// LoadWords reads words of a block list file, one word per line. Empty lines and lines starting with # are
+// ignored.
+func LoadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words, err := readWords(f)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read %s: %w", path, err)
+	}
+	return words, nil
+}
+
+func readWords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
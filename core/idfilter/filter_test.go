@@ -0,0 +1,107 @@
+package idfilter
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type filterTestSuite struct {
+	suite.Suite
+}
+
+func TestFilterSuite(t *testing.T) {
+	suite.Run(t, new(filterTestSuite))
+}
+
+func (s *filterTestSuite) TestReserved() {
+	f := NewFilter(Config{ReservedIDs: []string{" Admin "}}, nil)
+	for _, id := range []string{"api", "API", "admin", "aDMIN"} {
+		s.Equal(ErrReserved, f.Check(id), id)
+	}
+	// only whole IDs clash with routes
+	for _, id := range []string{"apiAbcdefgh", "APIabcdefgh", "adminXyz", "xapiAbcdefg"} {
+		s.NoError(f.Check(id), id)
+	}
+}
+
+func (s *filterTestSuite) TestBlockedWords() {
+	f := NewFilter(Config{Words: []string{"darn"}}, nil)
+	for _, id := range []string{"xxFuCkxxxxx", "Sh1tAbcdefg", "Xy5HITabcde", "b1tchXyzQwe", "PI55abcdefg", "D4RNabcdefg"} {
+		s.Equal(ErrBlocked, f.Check(id), id)
+	}
+	for _, id := range []string{"abcdefghijk", "Qw3rtyZxcvB"} {
+		s.NoError(f.Check(id), id)
+	}
+}
+
+func (s *filterTestSuite) TestBase62Alphabet() {
+	// digit 0 is encoded only in numbers with more digits, e.g. 62 is "01" in little-endian
+	alphabet := base62.Encode(62)[:1]
+	for i := uint64(1); i < 62; i++ {
+		alphabet += base62.Encode(i)
+	}
+	s.Len(alphabet, 62)
+	// every character of IDs is normalized to a lower-case letter
+	for _, c := range alphabet {
+		n := normalize(string(c))
+		s.Len(n, 1)
+		s.True(n[0] >= 'a' && n[0] <= 'z', string(c))
+	}
+
+	// generated IDs are rarely rejected, and those passing contain no blocked words
+	f := NewFilter(Config{}, nil).(*filterImpl)
+	r := rand.New(rand.NewSource(1))
+	blocked := 0
+	for i := 0; i < 10000; i++ {
+		id := base62.Encode(r.Uint64())
+		if err := f.Check(id); err != nil {
+			blocked++
+			continue
+		}
+		for _, word := range f.words {
+			s.False(strings.Contains(normalize(id), word), id)
+		}
+	}
+	s.Less(blocked, 500)
+}
+
+func (s *filterTestSuite) TestBlockedIDs() {
+	blockedIDs := &daomocks.BlockedIDDao{}
+	f := NewFilter(Config{}, blockedIDs)
+
+	blockedIDs.On("Exists", "abcdefghijk").Return(true, nil).Once()
+	s.Equal(ErrBlocked, f.Check("abcdefghijk"))
+	blockedIDs.On("Exists", "abcdefghijk").Return(false, nil).Once()
+	s.NoError(f.Check("abcdefghijk"))
+	blockedIDs.On("Exists", "abcdefghijk").Return(false, errors.New("connection refused")).Once()
+	s.EqualError(f.Check("abcdefghijk"), "connection refused")
+
+	// words are checked before loading blocked IDs
+	s.Equal(ErrBlocked, f.Check("fuckabcdefg"))
+	blockedIDs.AssertExpectations(s.T())
+}
+
+func (s *filterTestSuite) TestLoadWords() {
+	dir, err := ioutil.TempDir("", "idfilter")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "words.txt")
+	s.Require().NoError(ioutil.WriteFile(path, []byte("# comment\n\nheck\n  darn \n"), 0600))
+
+	words, err := LoadWords(path)
+	s.Require().NoError(err)
+	s.Equal([]string{"heck", "darn"}, words)
+
+	_, err = LoadWords(filepath.Join(dir, "missing.txt"))
+	s.Error(err)
+}
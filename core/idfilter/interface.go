@@ -0,0 +1,17 @@
+package idfilter
+
+import "errors"
+
+var (
+	// ErrReserved indicates the ID is reserved, e.g. a route name.
+	ErrReserved = errors.New("id is reserved")
+	// ErrBlocked indicates the ID contains a blocked word or is blocked by administrators.
+	ErrBlocked = errors.New("id is blocked")
+)
+
+// Filter defines interface of checking IDs of short links, which is used for both generated and given IDs.
+type Filter interface {
+	// Check returns ErrReserved or ErrBlocked if id should not be used, or other errors if blocked IDs fail to
+	// load.
+	Check(id string) error
+}
@@ -0,0 +1,24 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Filter is an autogenerated mock type for the Filter type
+type Filter struct {
+	mock.Mock
+}
+
+// Check provides a mock function with given fields: id
+func (_m *Filter) Check(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/safehttp"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxBodySize  = 512 * 1024
+	defaultMaxRedirects = 3
+	defaultUserAgent    = "url-shortener-metadata/1.0"
+)
+
+type fetcherImpl struct {
+	config FetcherConfig
+	client *http.Client
+	// blocked returns whether ip is not allowed to connect, overridden by tests fetching from loopback.
+	blocked func(ip net.IP) bool
+}
+
+// NewFetcher creates an instance of Fetcher. Destinations in blocked networks are not fetched, see safehttp.NewClient.
+func NewFetcher(config FetcherConfig) Fetcher {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = defaultMaxRedirects
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+
+	f := &fetcherImpl{config: config, blocked: safehttp.IsBlocked}
+	f.client = safehttp.NewClient(safehttp.Config{
+		Timeout:      config.Timeout,
+		MaxRedirects: config.MaxRedirects,
+		Blocked:      func(ip net.IP) bool { return f.blocked(ip) },
+	})
+	return f
+}
+
+func (f *fetcherImpl) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Code: resp.StatusCode}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, fmt.Errorf("%w: %s", ErrNotHTML, contentType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.config.MaxBodySize), contentType)
+	if err != nil {
+		return nil, err
+	}
+	// resp.Request is the last request of redirects, relative URLs in page are resolved against it
+	return parseHead(body, resp.Request.URL)
+}
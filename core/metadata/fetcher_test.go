@@ -0,0 +1,154 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>
+    Jobs at Dcard
+  </title>
+  <meta property="og:title" content="Dcard Careers">
+  <meta name="description" content="Join us">
+  <meta property="og:image" content="/images/og.png">
+  <link rel="apple-touch-icon" href="/touch.png">
+  <link rel="shortcut icon" href="https://cdn.example.com/favicon.png">
+</head>
+<body><title>Not a title</title></body>
+</html>`
+
+type fetcherTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	impl   *fetcherImpl
+}
+
+func TestFetcherTestSuite(t *testing.T) {
+	suite.Run(t, new(fetcherTestSuite))
+}
+
+func (s *fetcherTestSuite) SetupSuite() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/big5", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=big5")
+		// 測試 in Big5
+		w.Write([]byte("<title>\xb4\xfa\xb8\xd5</title>"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<head><title>Large</title>" + strings.Repeat("<!-- padding -->", 1024) +
+			`<meta property="og:image" content="/late.png"></head>`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	mux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	s.server = httptest.NewServer(mux)
+}
+
+func (s *fetcherTestSuite) TearDownSuite() {
+	s.server.Close()
+}
+
+func (s *fetcherTestSuite) SetupTest() {
+	s.impl = NewFetcher(FetcherConfig{Timeout: 100 * time.Millisecond, MaxBodySize: 4096}).(*fetcherImpl)
+	// the test origin listens on loopback
+	s.impl.blocked = func(ip net.IP) bool { return false }
+}
+
+func (s *fetcherTestSuite) TestFetch() {
+	metadata, err := s.impl.Fetch(context.Background(), s.server.URL+"/page")
+	s.Require().NoError(err)
+	s.Equal(&Metadata{
+		Title:       "Jobs at Dcard",
+		Description: "Join us",
+		Image:       s.server.URL + "/images/og.png",
+		Favicon:     "https://cdn.example.com/favicon.png",
+	}, metadata)
+}
+
+func (s *fetcherTestSuite) TestFetchRedirect() {
+	metadata, err := s.impl.Fetch(context.Background(), s.server.URL+"/redirect")
+	s.Require().NoError(err)
+	s.Equal("Jobs at Dcard", metadata.Title)
+}
+
+func (s *fetcherTestSuite) TestFetchCharset() {
+	metadata, err := s.impl.Fetch(context.Background(), s.server.URL+"/big5")
+	s.Require().NoError(err)
+	s.Equal("測試", metadata.Title)
+	s.Equal(s.server.URL+"/favicon.ico", metadata.Favicon)
+}
+
+func (s *fetcherTestSuite) TestFetchSizeCap() {
+	metadata, err := s.impl.Fetch(context.Background(), s.server.URL+"/large")
+	s.Require().NoError(err)
+	s.Equal("Large", metadata.Title)
+	s.Empty(metadata.Image)
+}
+
+func (s *fetcherTestSuite) TestFetchErrors() {
+	for path, retryable := range map[string]bool{
+		"/json":        false,
+		"/missing":     false,
+		"/unavailable": true,
+		"/slow":        true,
+	} {
+		_, err := s.impl.Fetch(context.Background(), s.server.URL+path)
+		s.Error(err, path)
+		s.Equal(retryable, IsRetryable(err), path)
+	}
+
+	_, err := s.impl.Fetch(context.Background(), "ftp://example.com/file")
+	s.True(errors.Is(err, ErrInvalidURL))
+}
+
+func (s *fetcherTestSuite) TestFetchBlocked() {
+	impl := NewFetcher(FetcherConfig{Timeout: 100 * time.Millisecond})
+	_, err := impl.Fetch(context.Background(), s.server.URL+"/page")
+	s.True(errors.Is(err, ErrBlocked), err)
+	s.False(IsRetryable(err))
+}
+
+func (s *fetcherTestSuite) TestFetchRedirectBlocked() {
+	// the origin is allowed, but it redirects to a blocked address
+	s.impl.blocked = func(ip net.IP) bool { return !ip.Equal(net.ParseIP("127.0.0.1")) }
+	origin := httptest.NewServer(http.RedirectHandler("http://[::1]:1/", http.StatusFound))
+	defer origin.Close()
+
+	_, err := s.impl.Fetch(context.Background(), origin.URL)
+	s.True(errors.Is(err, ErrBlocked), err)
+}
+
+func (s *fetcherTestSuite) TestIsRetryable() {
+	s.True(IsRetryable(errors.New("connection reset")))
+	s.True(IsRetryable(&StatusError{Code: http.StatusTooManyRequests}))
+	s.False(IsRetryable(&StatusError{Code: http.StatusNotFound}))
+	s.False(IsRetryable(ErrNotHTML))
+	s.False(IsRetryable(nil))
+}
@@ -0,0 +1,72 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/safehttp"
+)
+
+var (
+	// ErrBlocked indicates the destination resolves to an address which is not allowed to fetch, e.g. loopback
+	// or private networks.
+	ErrBlocked = safehttp.ErrBlocked
+	// ErrNotHTML indicates the destination is not an HTML page.
+	ErrNotHTML = errors.New("content is not html")
+	// ErrInvalidURL indicates the destination is not an absolute http or https URL, or redirects to one.
+	ErrInvalidURL = errors.New("invalid url")
+)
+
+// StatusError indicates the destination responds a status other than 200.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %d", e.Code)
+}
+
+// IsRetryable returns whether fetching could succeed later, e.g. on timeout or 5xx status.
+func IsRetryable(err error) bool {
+	var statusErr *StatusError
+	switch {
+	case errors.Is(err, ErrBlocked), errors.Is(err, ErrNotHTML), errors.Is(err, ErrInvalidURL),
+		errors.Is(err, safehttp.ErrRedirect):
+		return false
+	case errors.As(err, &statusErr):
+		return statusErr.Code >= http.StatusInternalServerError || statusErr.Code == http.StatusTooManyRequests
+	}
+	return err != nil
+}
+
+// Metadata defines metadata of a page parsed from its HTML head, URLs are absolute and empty if absent.
+type Metadata struct {
+	// Title is <title> of the page, or og:title if absent.
+	Title string
+	// Description is og:description of the page, or <meta name="description"> if absent.
+	Description string
+	// Image is og:image of the page.
+	Image string
+	// Favicon is icon of the page in <link rel="icon">, or /favicon.ico if absent.
+	Favicon string
+}
+
+// FetcherConfig defines limits of fetching pages, zero values use defaults.
+type FetcherConfig struct {
+	// Timeout limits a fetch including redirects and reading body, defaultTimeout if zero.
+	Timeout time.Duration
+	// MaxBodySize limits bytes read from body, metadata after it is ignored. defaultMaxBodySize if zero.
+	MaxBodySize int64
+	// MaxRedirects limits redirects followed, defaultMaxRedirects if zero.
+	MaxRedirects int
+	UserAgent    string
+}
+
+// Fetcher defines interface of fetching metadata of pages.
+type Fetcher interface {
+	// Fetch returns metadata of page at rawURL, check errors by IsRetryable.
+	Fetch(ctx context.Context, rawURL string) (*Metadata, error)
+}
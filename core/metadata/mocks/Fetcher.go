@@ -0,0 +1,38 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	metadata "github.com/georgechang0117/url-shortener/core/metadata"
+	mock "github.com/stretchr/testify/mock"
+
+	context "context"
+)
+
+// Fetcher is an autogenerated mock type for the Fetcher type
+type Fetcher struct {
+	mock.Mock
+}
+
+// Fetch provides a mock function with given fields: ctx, rawURL
+func (_m *Fetcher) Fetch(ctx context.Context, rawURL string) (*metadata.Metadata, error) {
+	ret := _m.Called(ctx, rawURL)
+
+	var r0 *metadata.Metadata
+	if rf, ok := ret.Get(0).(func(context.Context, string) *metadata.Metadata); ok {
+		r0 = rf(ctx, rawURL)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*metadata.Metadata)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, rawURL)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
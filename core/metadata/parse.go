@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// parseHead returns metadata in HTML head read from r, which stops at <body> or the end of head. Relative URLs
+// are resolved against base, or <base href> in head.
+func parseHead(r io.Reader, base *url.URL) (*Metadata, error) {
+	var metadata Metadata
+	var title, ogTitle, ogDescription, description, icon, touchIcon string
+	z := html.NewTokenizer(r)
+
+parse:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// truncated by size cap or malformed, metadata parsed so far is kept
+			if err := z.Err(); err != io.EOF && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			break parse
+		case html.EndTagToken:
+			if name, _ := z.TagName(); atom.Lookup(name) == atom.Head {
+				break parse
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = z.TagAttr()
+				attrs[string(key)] = string(value)
+			}
+
+			switch tag {
+			case atom.Body:
+				break parse
+			case atom.Title:
+				// text of title is raw, the tokenizer returns it as a single text token
+				if z.Next() == html.TextToken && title == "" {
+					title = string(z.Text())
+				}
+			case atom.Base:
+				if u, err := base.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = u
+				}
+			case atom.Meta:
+				key := strings.ToLower(attrs["property"])
+				if key == "" {
+					key = strings.ToLower(attrs["name"])
+				}
+				content := attrs["content"]
+				switch {
+				case key == "og:title" && ogTitle == "":
+					ogTitle = content
+				case key == "og:description" && ogDescription == "":
+					ogDescription = content
+				case key == "description" && description == "":
+					description = content
+				case (key == "og:image" || key == "og:image:url" || key == "og:image:secure_url") && metadata.Image == "":
+					metadata.Image = content
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch {
+					case rel == "icon" && icon == "":
+						icon = attrs["href"]
+					case rel == "apple-touch-icon" && touchIcon == "":
+						touchIcon = attrs["href"]
+					}
+				}
+			}
+		}
+	}
+
+	metadata.Title = firstNonEmpty(title, ogTitle)
+	metadata.Description = firstNonEmpty(ogDescription, description)
+	metadata.Image = resolve(base, metadata.Image)
+	metadata.Favicon = resolve(base, firstNonEmpty(icon, touchIcon, "/favicon.ico"))
+	return &metadata, nil
+}
+
+// firstNonEmpty returns the first value with non-space characters, whose spaces are collapsed.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolve returns ref resolved against base, or empty if it's not an http or https URL.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,17 @@
+package redirect
+
+import "github.com/georgechang0117/url-shortener/core/dao"
+
+// Resolution defines resolved destination of a short link.
+type Resolution struct {
+	URL string
+	// Variant is name of the chosen variant, empty if short link has no variants or a rule matched.
+	Variant string
+	// Fallback indicates URL is the fallback URL since the destination of short link is broken.
+	Fallback bool
+}
+
+// Resolver defines interface of resolving destination of short links.
+type Resolver interface {
+	Resolve(shortLink *dao.ShortLink, req Request) (*Resolution, error)
+}
@@ -0,0 +1,181 @@
+package redirect
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+)
+
+const (
+	placeholderPath        = "path"
+	placeholderQueryPrefix = "query."
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{(path|query\.[A-Za-z0-9_.\-]+)\}`)
+
+// Request defines incoming request information used to resolve destination.
+type Request struct {
+	// Path is the path following url_id, without leading slash.
+	Path           string
+	Query          url.Values
+	UserAgent      string
+	AcceptLanguage string
+	IP             net.IP
+	Time           time.Time
+	// Variant is name of the variant assigned to client before, for sticky assignment.
+	Variant string
+}
+
+type resolverImpl struct {
+	geoIP rules.GeoIP
+}
+
+// NewResolver creates an instance of Resolver, geoIP can be nil if rules never match countries.
+func NewResolver(geoIP rules.GeoIP) Resolver {
+	return &resolverImpl{
+		geoIP: geoIP,
+	}
+}
+
+// Resolve returns destination of shortLink for the request. Rules are evaluated first, then variants
+// are picked if no rule matches, and URL of short link is the default, which is replaced by the fallback URL
+// while the short link is broken.
+func (r *resolverImpl) Resolve(shortLink *dao.ShortLink, req Request) (*Resolution, error) {
+	resolution := Resolution{URL: shortLink.URL}
+	if ruleURL, ok := shortLink.Rules.Evaluate(rules.Request{
+		UserAgent:      req.UserAgent,
+		AcceptLanguage: req.AcceptLanguage,
+		IP:             req.IP,
+		Time:           req.Time,
+	}, r.geoIP); ok {
+		resolution.URL = ruleURL
+	} else if variant, ok := pickVariant(shortLink, req); ok {
+		resolution.URL = variant.URL
+		resolution.Variant = variant.Name
+	} else if shortLink.Broken && shortLink.FallbackURL != "" {
+		resolution.URL = shortLink.FallbackURL
+		resolution.Fallback = true
+	}
+
+	destination, err := destination(shortLink, resolution.URL, req)
+	if err != nil {
+		return nil, err
+	}
+	resolution.URL = destination
+
+	return &resolution, nil
+}
+
+// pickVariant returns the variant assigned to client before if it still exists, otherwise picks one by
+// hash of client, so the same client gets the same variant even without cookie.
+func pickVariant(shortLink *dao.ShortLink, req Request) (split.Variant, bool) {
+	if len(shortLink.Variants) == 0 {
+		return split.Variant{}, false
+	}
+	if variant, ok := shortLink.Variants.Get(req.Variant); ok {
+		return variant, true
+	}
+	return shortLink.Variants.Pick(split.Seed(shortLink.URLID, req.IP.String(), req.UserAgent))
+}
+
+// destination returns target with placeholders expanded and query parameters merged.
+func destination(shortLink *dao.ShortLink, target string, req Request) (string, error) {
+	u, err := url.Parse(expand(target, req))
+	if err != nil {
+		return "", err
+	}
+
+	switch shortLink.QueryMode {
+	case dao.QueryModeOverride:
+		u.RawQuery = mergeQuery(u.RawQuery, req.Query, true)
+	case dao.QueryModePreserve:
+		u.RawQuery = mergeQuery(u.RawQuery, req.Query, false)
+	}
+
+	if shortLink.UTM != "" {
+		utm, err := url.ParseQuery(shortLink.UTM)
+		if err != nil {
+			return "", err
+		}
+		// UTM defaults never override parameters from destination or request
+		u.RawQuery = mergeQuery(u.RawQuery, utm, false)
+	}
+
+	return u.String(), nil
+}
+
+// HasPlaceholders returns whether rawURL has placeholders, whose destination depends on requests.
+func HasPlaceholders(rawURL string) bool {
+	return placeholderRegexp.MatchString(rawURL)
+}
+
+// expand replaces placeholders in rawURL by request path and query values.
+func expand(rawURL string, req Request) string {
+	return placeholderRegexp.ReplaceAllStringFunc(rawURL, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		if name == placeholderPath {
+			segments := strings.Split(strings.Trim(req.Path, "/"), "/")
+			for i, segment := range segments {
+				segments[i] = escape(segment)
+			}
+			return strings.Join(segments, "/")
+		}
+		return escape(req.Query.Get(strings.TrimPrefix(name, placeholderQueryPrefix)))
+	})
+}
+
+// escape escapes s so that it's safe in both path and query of URL.
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// mergeQuery appends values to rawQuery without re-encoding existing parameters, so their order and
+// encoding are kept. Conflicting parameters in rawQuery are replaced if override, otherwise values are ignored.
+func mergeQuery(rawQuery string, values url.Values, override bool) string {
+	if len(values) == 0 {
+		return rawQuery
+	}
+
+	existing := map[string]bool{}
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key = pair[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if override && len(values[key]) > 0 {
+			continue
+		}
+		existing[key] = true
+		pairs = append(pairs, pair)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if existing[key] {
+			continue
+		}
+		for _, value := range values[key] {
+			pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
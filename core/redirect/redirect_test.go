@@ -0,0 +1,182 @@
+package redirect
+
+import (
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeGeoIP map[string]string
+
+func (g fakeGeoIP) Country(ip net.IP) (string, error) {
+	return g[ip.String()], nil
+}
+
+type redirectTestSuite struct {
+	suite.Suite
+	impl *resolverImpl
+}
+
+func (s *redirectTestSuite) SetupSuite() {
+	s.impl = NewResolver(fakeGeoIP{"1.2.3.4": "TW"}).(*resolverImpl)
+}
+
+func TestRedirectSuite(t *testing.T) {
+	suite.Run(t, new(redirectTestSuite))
+}
+
+func (s *redirectTestSuite) TestDestinationDropQuery() {
+	shortLink := dao.ShortLink{URL: "https://example.com/a?b=1#top"}
+
+	res, err := s.impl.Resolve(&shortLink, Request{Query: url.Values{"gclid": {"x"}}})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/a?b=1#top", res.URL)
+}
+
+func (s *redirectTestSuite) TestDestinationOverride() {
+	shortLink := dao.ShortLink{
+		URL:       "https://example.com/a?z=1&b=%2F&c=3#top",
+		QueryMode: dao.QueryModeOverride,
+	}
+
+	res, err := s.impl.Resolve(&shortLink, Request{Query: url.Values{"c": {"new value"}, "a": {"1"}}})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/a?z=1&b=%2F&a=1&c=new+value#top", res.URL)
+}
+
+func (s *redirectTestSuite) TestDestinationPreserve() {
+	shortLink := dao.ShortLink{
+		URL:       "https://example.com/a?c=3",
+		QueryMode: dao.QueryModePreserve,
+	}
+
+	res, err := s.impl.Resolve(&shortLink, Request{Query: url.Values{"c": {"4"}, "utm_source": {"ads"}}})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/a?c=3&utm_source=ads", res.URL)
+}
+
+func (s *redirectTestSuite) TestDestinationUTM() {
+	shortLink := dao.ShortLink{
+		URL:       "https://example.com/a#top",
+		QueryMode: dao.QueryModeOverride,
+		UTM:       "utm_medium=email&utm_source=newsletter",
+	}
+
+	res, err := s.impl.Resolve(&shortLink, Request{Query: url.Values{"utm_source": {"ads"}}})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/a?utm_source=ads&utm_medium=email#top", res.URL)
+}
+
+func (s *redirectTestSuite) TestDestinationPlaceholders() {
+	shortLink := dao.ShortLink{URL: "https://example.com/docs/{path}?lang={query.lang}&q={query.q}"}
+
+	res, err := s.impl.Resolve(&shortLink, Request{
+		Path:  "guide/getting started/",
+		Query: url.Values{"lang": {"zh-TW"}, "q": {"a&b=c"}},
+	})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/docs/guide/getting%20started?lang=zh-TW&q=a%26b%3Dc", res.URL)
+
+	res, err = s.impl.Resolve(&shortLink, Request{})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/docs/?lang=&q=", res.URL)
+}
+
+func (s *redirectTestSuite) TestDestinationRules() {
+	set, err := rules.Compile([]rules.Rule{
+		{Platforms: []string{rules.PlatformIOS}, URL: "https://apps.apple.com/app/id1"},
+		{Countries: []string{"tw"}, URL: "https://example.com/tw?ref={query.ref}"},
+	})
+	s.Require().NoError(err)
+	shortLink := dao.ShortLink{URL: "https://example.com/", Rules: set}
+
+	res, err := s.impl.Resolve(&shortLink, Request{
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X)",
+		IP:        net.ParseIP("1.2.3.4"),
+	})
+	s.Require().NoError(err)
+	s.Equal("https://apps.apple.com/app/id1", res.URL)
+
+	res, err = s.impl.Resolve(&shortLink, Request{
+		IP:    net.ParseIP("1.2.3.4"),
+		Query: url.Values{"ref": {"qr"}},
+	})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/tw?ref=qr", res.URL)
+
+	res, err = s.impl.Resolve(&shortLink, Request{IP: net.ParseIP("5.6.7.8")})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/", res.URL)
+}
+
+func (s *redirectTestSuite) TestResolveVariants() {
+	set, err := split.Compile([]split.Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/?v={query.v}", Weight: 1},
+	})
+	s.Require().NoError(err)
+	shortLink := dao.ShortLink{URLID: "ejLqV3Wkyd6", URL: "https://example.com/", Variants: set}
+
+	res, err := s.impl.Resolve(&shortLink, Request{Variant: "b", Query: url.Values{"v": {"1"}}})
+	s.Require().NoError(err)
+	s.Equal("b", res.Variant)
+	s.Equal("https://b.example.com/?v=1", res.URL)
+
+	// the same client is assigned to the same variant without cookie
+	req := Request{IP: net.ParseIP("5.6.7.8"), UserAgent: "curl/7.64.1", Variant: "removed"}
+	first, err := s.impl.Resolve(&shortLink, req)
+	s.Require().NoError(err)
+	s.NotEmpty(first.Variant)
+	for i := 0; i < 10; i++ {
+		res, err := s.impl.Resolve(&shortLink, req)
+		s.Require().NoError(err)
+		s.Equal(first, res)
+	}
+
+	// rules take precedence over variants
+	shortLink.Rules, err = rules.Compile([]rules.Rule{{Platforms: []string{rules.PlatformIOS}, URL: "https://apps.apple.com/app/id1"}})
+	s.Require().NoError(err)
+	res, err = s.impl.Resolve(&shortLink, Request{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X)"})
+	s.Require().NoError(err)
+	s.Equal("https://apps.apple.com/app/id1", res.URL)
+	s.Empty(res.Variant)
+}
+
+func (s *redirectTestSuite) TestResolveFallback() {
+	shortLink := dao.ShortLink{
+		URL:         "https://example.com/",
+		FallbackURL: "https://example.com/maintenance",
+		UTM:         "utm_source=qr",
+	}
+
+	res, err := s.impl.Resolve(&shortLink, Request{})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/?utm_source=qr", res.URL)
+	s.False(res.Fallback)
+
+	shortLink.Broken = true
+	res, err = s.impl.Resolve(&shortLink, Request{})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/maintenance?utm_source=qr", res.URL)
+	s.True(res.Fallback)
+
+	// destinations of rules are chosen for the request, they do not fall back
+	shortLink.Rules, err = rules.Compile([]rules.Rule{{Platforms: []string{rules.PlatformIOS}, URL: "https://apps.apple.com/app/id1"}})
+	s.Require().NoError(err)
+	res, err = s.impl.Resolve(&shortLink, Request{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X)"})
+	s.Require().NoError(err)
+	s.Equal("https://apps.apple.com/app/id1?utm_source=qr", res.URL)
+	s.False(res.Fallback)
+}
+
+func (s *redirectTestSuite) TestHasPlaceholders() {
+	s.True(HasPlaceholders("https://example.com/docs/{path}"))
+	s.True(HasPlaceholders("https://example.com/?q={query.q}"))
+	s.False(HasPlaceholders("https://example.com/{unknown}"))
+}
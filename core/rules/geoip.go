@@ -0,0 +1,34 @@
+package rules
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type mmdbGeoIPImpl struct {
+	reader *maxminddb.Reader
+}
+
+type mmdbCountryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// NewMMDB creates an instance of GeoIP reading a local MaxMind DB file, e.g. GeoLite2-Country.mmdb.
+func NewMMDB(path string) (GeoIP, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbGeoIPImpl{reader: reader}, nil
+}
+
+func (g *mmdbGeoIPImpl) Country(ip net.IP) (string, error) {
+	var record mmdbCountryRecord
+	if err := g.reader.Lookup(ip, &record); err != nil {
+		return "", err
+	}
+	return record.Country.ISOCode, nil
+}
@@ -0,0 +1,9 @@
+package rules
+
+import "net"
+
+// GeoIP defines interface of looking up country of IP address.
+type GeoIP interface {
+	// Country returns ISO 3166-1 alpha-2 country code of ip, or empty string if unknown.
+	Country(ip net.IP) (string, error)
+}
@@ -0,0 +1,270 @@
+package rules
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PlatformIOS matches iPhone, iPad and iPod.
+	PlatformIOS = "ios"
+	// PlatformAndroid matches Android devices.
+	PlatformAndroid = "android"
+	// PlatformWindows matches Windows desktops.
+	PlatformWindows = "windows"
+	// PlatformMacOS matches Mac desktops.
+	PlatformMacOS = "macos"
+	// PlatformLinux matches Linux desktops.
+	PlatformLinux = "linux"
+)
+
+// platformPatterns is ordered, mobile platforms are checked first since their user agents mention desktop ones.
+var platformPatterns = []struct {
+	platform string
+	patterns []string
+}{
+	{PlatformIOS, []string{"iphone", "ipad", "ipod"}},
+	{PlatformAndroid, []string{"android"}},
+	{PlatformWindows, []string{"windows"}},
+	{PlatformMacOS, []string{"macintosh", "mac os x"}},
+	{PlatformLinux, []string{"linux"}},
+}
+
+// Rule defines matchers and target URL of a routing rule. A rule matches if all its non-empty
+// matchers match, and each matcher matches if any of its values matches.
+type Rule struct {
+	// Platforms matches platform detected from User-Agent, see Platform*.
+	Platforms []string `json:"platforms,omitempty" validate:"omitempty,dive,oneof=ios android windows macos linux"`
+	// Languages matches the most preferred language of Accept-Language, "en" matches "en-US" as well.
+	Languages []string `json:"languages,omitempty" validate:"omitempty,dive,bcp47_language_tag"`
+	// Countries matches ISO 3166-1 alpha-2 country code of client IP.
+	Countries []string `json:"countries,omitempty" validate:"omitempty,dive,iso3166_1_alpha2"`
+	// StartAt and EndAt matches time in [StartAt, EndAt).
+	StartAt *time.Time `json:"startAt,omitempty"`
+	EndAt   *time.Time `json:"endAt,omitempty"`
+	URL     string     `json:"url" validate:"required,uri"`
+}
+
+// Request defines client information matched by rules.
+type Request struct {
+	UserAgent      string
+	AcceptLanguage string
+	IP             net.IP
+	Time           time.Time
+}
+
+// Set defines ordered rules of a short link, stored as JSON.
+type Set []Rule
+
+// Compile validates rules and normalizes matcher values, so the set can be evaluated without parsing.
+func Compile(rules []Rule) (Set, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	set := make(Set, 0, len(rules))
+	for i, rule := range rules {
+		if rule.URL == "" {
+			return nil, fmt.Errorf("rule %d: url is empty", i)
+		}
+		if len(rule.Platforms) == 0 && len(rule.Languages) == 0 && len(rule.Countries) == 0 &&
+			rule.StartAt == nil && rule.EndAt == nil {
+			return nil, fmt.Errorf("rule %d: no matcher", i)
+		}
+		if rule.StartAt != nil && rule.EndAt != nil && !rule.StartAt.Before(*rule.EndAt) {
+			return nil, fmt.Errorf("rule %d: startAt should be before endAt", i)
+		}
+
+		compiled := Rule{
+			Platforms: normalize(rule.Platforms, strings.ToLower),
+			Languages: normalize(rule.Languages, strings.ToLower),
+			Countries: normalize(rule.Countries, strings.ToUpper),
+			StartAt:   rule.StartAt,
+			EndAt:     rule.EndAt,
+			URL:       rule.URL,
+		}
+		for _, platform := range compiled.Platforms {
+			if !isPlatform(platform) {
+				return nil, fmt.Errorf("rule %d: invalid platform: %s", i, platform)
+			}
+		}
+		set = append(set, compiled)
+	}
+
+	return set, nil
+}
+
+// Evaluate returns URL of the first rule matching req, or false if no rule matches.
+func (s Set) Evaluate(req Request, geoIP GeoIP) (string, bool) {
+	if len(s) == 0 {
+		return "", false
+	}
+
+	platform := Platform(req.UserAgent)
+	language := PreferredLanguage(req.AcceptLanguage)
+	// country is looked up lazily, only rules with countries need it
+	var country *string
+	lookupCountry := func() string {
+		if country == nil {
+			c := ""
+			if geoIP != nil && req.IP != nil {
+				if code, err := geoIP.Country(req.IP); err == nil {
+					c = strings.ToUpper(code)
+				}
+			}
+			country = &c
+		}
+		return *country
+	}
+
+	for _, rule := range s {
+		if len(rule.Platforms) > 0 && !contains(rule.Platforms, platform) {
+			continue
+		}
+		if len(rule.Languages) > 0 && !matchLanguage(rule.Languages, language) {
+			continue
+		}
+		if len(rule.Countries) > 0 && !contains(rule.Countries, lookupCountry()) {
+			continue
+		}
+		if rule.StartAt != nil && req.Time.Before(*rule.StartAt) {
+			continue
+		}
+		if rule.EndAt != nil && !req.Time.Before(*rule.EndAt) {
+			continue
+		}
+		return rule.URL, true
+	}
+
+	return "", false
+}
+
+// Value implements driver.Valuer, empty set is stored as NULL.
+func (s Set) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (s *Set) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("invalid type of rules")
+	}
+	return json.Unmarshal(b, s)
+}
+
+// Platform returns platform detected from userAgent, or empty string if unknown.
+func Platform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	for _, p := range platformPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(ua, pattern) {
+				return p.platform
+			}
+		}
+	}
+	return ""
+}
+
+// PreferredLanguage returns the language with highest quality in acceptLanguage header, in lower case.
+func PreferredLanguage(acceptLanguage string) string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	var languages []language
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+	if len(languages) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	return languages[0].tag
+}
+
+func matchLanguage(languages []string, language string) bool {
+	if language == "" {
+		return false
+	}
+	for _, l := range languages {
+		if language == l || strings.HasPrefix(language, l+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func isPlatform(platform string) bool {
+	for _, p := range platformPatterns {
+		if p.platform == platform {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(values []string, f func(string) string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	normalized := make([]string, len(values))
+	for i, v := range values {
+		normalized[i] = f(strings.TrimSpace(v))
+	}
+	return normalized
+}
+
+func contains(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testIPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Mobile/15E148 Safari/604.1"
+	testAndroidUA = "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36"
+	testMacUA     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36"
+)
+
+type fakeGeoIP struct {
+	countries map[string]string
+	lookups   int
+}
+
+func (g *fakeGeoIP) Country(ip net.IP) (string, error) {
+	g.lookups++
+	country, ok := g.countries[ip.String()]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return country, nil
+}
+
+type rulesTestSuite struct {
+	suite.Suite
+}
+
+func TestRulesSuite(t *testing.T) {
+	suite.Run(t, new(rulesTestSuite))
+}
+
+func (s *rulesTestSuite) TestPlatform() {
+	s.Equal(PlatformIOS, Platform(testIPhoneUA))
+	s.Equal(PlatformAndroid, Platform(testAndroidUA))
+	s.Equal(PlatformMacOS, Platform(testMacUA))
+	s.Equal(PlatformWindows, Platform("Mozilla/5.0 (Windows NT 10.0; Win64; x64)"))
+	s.Equal("", Platform("curl/7.64.1"))
+}
+
+func (s *rulesTestSuite) TestPreferredLanguage() {
+	s.Equal("zh-tw", PreferredLanguage("zh-TW,zh;q=0.9,en-US;q=0.8,en;q=0.7"))
+	s.Equal("en", PreferredLanguage("fr;q=0.5, en"))
+	s.Equal("", PreferredLanguage("*"))
+	s.Equal("", PreferredLanguage("de;q=0"))
+}
+
+func (s *rulesTestSuite) TestCompile() {
+	set, err := Compile([]Rule{
+		{Platforms: []string{"iOS"}, Languages: []string{"zh-TW"}, Countries: []string{"tw"}, URL: "https://example.com/"},
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{PlatformIOS}, set[0].Platforms)
+	s.Equal([]string{"zh-tw"}, set[0].Languages)
+	s.Equal([]string{"TW"}, set[0].Countries)
+
+	_, err = Compile([]Rule{{URL: "https://example.com/"}})
+	s.Error(err)
+	_, err = Compile([]Rule{{Platforms: []string{PlatformIOS}}})
+	s.Error(err)
+	_, err = Compile([]Rule{{Platforms: []string{"blackberry"}, URL: "https://example.com/"}})
+	s.Error(err)
+
+	now := time.Now()
+	_, err = Compile([]Rule{{StartAt: &now, EndAt: &now, URL: "https://example.com/"}})
+	s.Error(err)
+}
+
+func (s *rulesTestSuite) TestEvaluate() {
+	start := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC)
+	set, err := Compile([]Rule{
+		{Platforms: []string{PlatformIOS}, URL: "ios"},
+		{Platforms: []string{PlatformAndroid}, Countries: []string{"TW"}, URL: "android-tw"},
+		{Languages: []string{"ja"}, URL: "ja"},
+		{StartAt: &start, EndAt: &end, URL: "campaign"},
+	})
+	s.Require().NoError(err)
+	geoIP := &fakeGeoIP{countries: map[string]string{"1.2.3.4": "TW"}}
+
+	cases := []struct {
+		req Request
+		url string
+		ok  bool
+	}{
+		{Request{UserAgent: testIPhoneUA, IP: net.ParseIP("1.2.3.4")}, "ios", true},
+		{Request{UserAgent: testAndroidUA, IP: net.ParseIP("1.2.3.4")}, "android-tw", true},
+		{Request{UserAgent: testAndroidUA, IP: net.ParseIP("5.6.7.8")}, "", false},
+		{Request{UserAgent: testMacUA, AcceptLanguage: "ja-JP,ja;q=0.9"}, "ja", true},
+		{Request{UserAgent: testMacUA, Time: start}, "campaign", true},
+		{Request{UserAgent: testMacUA, Time: end}, "", false},
+	}
+	for _, c := range cases {
+		url, ok := set.Evaluate(c.req, geoIP)
+		s.Equal(c.ok, ok, c.req)
+		s.Equal(c.url, url, c.req)
+	}
+
+	// country is looked up only if a rule needs it
+	geoIP.lookups = 0
+	set.Evaluate(Request{UserAgent: testIPhoneUA, IP: net.ParseIP("1.2.3.4")}, geoIP)
+	s.Equal(0, geoIP.lookups)
+}
+
+func (s *rulesTestSuite) TestValueAndScan() {
+	set, err := Compile([]Rule{{Platforms: []string{PlatformIOS}, URL: "https://example.com/"}})
+	s.Require().NoError(err)
+
+	v, err := set.Value()
+	s.Require().NoError(err)
+	var scanned Set
+	s.Require().NoError(scanned.Scan(v))
+	s.Equal(set, scanned)
+
+	v, err = Set{}.Value()
+	s.Require().NoError(err)
+	s.Nil(v)
+	s.Require().NoError(scanned.Scan(nil))
+	s.Nil(scanned)
+
+	b, err := json.Marshal(set)
+	s.Require().NoError(err)
+	s.JSONEq(`[{"platforms":["ios"],"url":"https://example.com/"}]`, string(b))
+}
+
+func (s *rulesTestSuite) TestNewMMDBNotFound() {
+	_, err := NewMMDB("testdata/not-found.mmdb")
+	s.Error(err)
+}
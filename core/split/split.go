@@ -0,0 +1,117 @@
+package split
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// Variant defines a weighted destination of a short link.
+type Variant struct {
+	Name   string `json:"name" validate:"required,alphanum,max=32"`
+	URL    string `json:"url" validate:"required,uri"`
+	Weight int    `json:"weight" validate:"min=1,max=10000"`
+}
+
+// Set defines variants of a short link, stored as JSON.
+type Set []Variant
+
+// Compile validates variants, names should be unique and weights should be positive.
+func Compile(variants []Variant) (Set, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) == 1 {
+		return nil, errors.New("at least 2 variants are required")
+	}
+
+	names := map[string]bool{}
+	for i, variant := range variants {
+		if variant.Name == "" {
+			return nil, fmt.Errorf("variant %d: name is empty", i)
+		}
+		if names[variant.Name] {
+			return nil, fmt.Errorf("variant %d: duplicated name: %s", i, variant.Name)
+		}
+		if variant.URL == "" {
+			return nil, fmt.Errorf("variant %d: url is empty", i)
+		}
+		if variant.Weight <= 0 {
+			return nil, fmt.Errorf("variant %d: weight should be positive", i)
+		}
+		names[variant.Name] = true
+	}
+
+	return append(Set(nil), variants...), nil
+}
+
+// Pick returns variant chosen by weights, the same seed always picks the same variant.
+func (s Set) Pick(seed uint64) (Variant, bool) {
+	total := 0
+	for _, variant := range s {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return Variant{}, false
+	}
+
+	n := int(seed % uint64(total))
+	for _, variant := range s {
+		if n < variant.Weight {
+			return variant, true
+		}
+		n -= variant.Weight
+	}
+	return Variant{}, false
+}
+
+// Get returns variant of the name.
+func (s Set) Get(name string) (Variant, bool) {
+	for _, variant := range s {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+// Value implements driver.Valuer, empty set is stored as NULL.
+func (s Set) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (s *Set) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("invalid type of variants")
+	}
+	return json.Unmarshal(b, s)
+}
+
+// Seed returns seed hashed from keys, used to pick the same variant for the same client.
+func Seed(keys ...string) uint64 {
+	h := fnv.New64a()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,83 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type splitTestSuite struct {
+	suite.Suite
+}
+
+func TestSplitSuite(t *testing.T) {
+	suite.Run(t, new(splitTestSuite))
+}
+
+func (s *splitTestSuite) TestCompile() {
+	set, err := Compile(nil)
+	s.NoError(err)
+	s.Nil(set)
+
+	_, err = Compile([]Variant{{Name: "a", URL: "https://a.example.com/", Weight: 1}})
+	s.Error(err)
+	_, err = Compile([]Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "a", URL: "https://b.example.com/", Weight: 1},
+	})
+	s.Error(err)
+	_, err = Compile([]Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/", Weight: 0},
+	})
+	s.Error(err)
+}
+
+func (s *splitTestSuite) TestPick() {
+	set, err := Compile([]Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/", Weight: 3},
+	})
+	s.Require().NoError(err)
+
+	picked := map[string]int{}
+	for seed := uint64(0); seed < 400; seed++ {
+		variant, ok := set.Pick(seed)
+		s.Require().True(ok)
+		picked[variant.Name]++
+	}
+	s.Equal(map[string]int{"a": 100, "b": 300}, picked)
+
+	a, _ := set.Pick(Seed("1.2.3.4", "curl"))
+	b, _ := set.Pick(Seed("1.2.3.4", "curl"))
+	s.Equal(a, b)
+}
+
+func (s *splitTestSuite) TestGet() {
+	set, err := Compile([]Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/", Weight: 3},
+	})
+	s.Require().NoError(err)
+
+	variant, ok := set.Get("b")
+	s.True(ok)
+	s.Equal("https://b.example.com/", variant.URL)
+
+	_, ok = set.Get("c")
+	s.False(ok)
+}
+
+func (s *splitTestSuite) TestValueAndScan() {
+	set, err := Compile([]Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/", Weight: 3},
+	})
+	s.Require().NoError(err)
+
+	v, err := set.Value()
+	s.Require().NoError(err)
+	var scanned Set
+	s.Require().NoError(scanned.Scan(v))
+	s.Equal(set, scanned)
+}
@@ -0,0 +1,19 @@
+package stats
+
+import "time"
+
+// Summary defines click stats of a short link.
+type Summary struct {
+	Clicks int64
+	// Variants defines clicks per variant, empty if short link has no variants.
+	Variants map[string]int64
+}
+
+// Stats defines interface of recording and querying clicks of short links.
+type Stats interface {
+	// Record records a click without blocking, clicks are buffered and flushed to db periodically.
+	Record(shortLinkID uint64, variant string)
+	Get(shortLinkID uint64) (*Summary, error)
+	Start(flushInterval time.Duration)
+	Stop()
+}
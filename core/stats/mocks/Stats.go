@@ -0,0 +1,53 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	stats "github.com/georgechang0117/url-shortener/core/stats"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// Stats is an autogenerated mock type for the Stats type
+type Stats struct {
+	mock.Mock
+}
+
+// Get provides a mock function with given fields: shortLinkID
+func (_m *Stats) Get(shortLinkID uint64) (*stats.Summary, error) {
+	ret := _m.Called(shortLinkID)
+
+	var r0 *stats.Summary
+	if rf, ok := ret.Get(0).(func(uint64) *stats.Summary); ok {
+		r0 = rf(shortLinkID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*stats.Summary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(shortLinkID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Record provides a mock function with given fields: shortLinkID, variant
+func (_m *Stats) Record(shortLinkID uint64, variant string) {
+	_m.Called(shortLinkID, variant)
+}
+
+// Start provides a mock function with given fields: flushInterval
+func (_m *Stats) Start(flushInterval time.Duration) {
+	_m.Called(flushInterval)
+}
+
+// Stop provides a mock function with given fields:
+func (_m *Stats) Stop() {
+	_m.Called()
+}
@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+)
+
+type clickKey struct {
+	shortLinkID uint64
+	variant     string
+}
+
+type statsImpl struct {
+	clickDao dao.ClickDao
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	pending map[clickKey]int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStats creates an instance of Stats.
+func NewStats(clickDao dao.ClickDao, clock clock.Clock) Stats {
+	return &statsImpl{
+		clickDao: clickDao,
+		clock:    clock,
+		pending:  map[clickKey]int64{},
+	}
+}
+
+func (s *statsImpl) Record(shortLinkID uint64, variant string) {
+	s.mu.Lock()
+	s.pending[clickKey{shortLinkID: shortLinkID, variant: variant}]++
+	s.mu.Unlock()
+}
+
+func (s *statsImpl) Get(shortLinkID uint64) (*Summary, error) {
+	clickCounts, err := s.clickDao.ListByShortLinkID(shortLinkID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := Summary{Variants: map[string]int64{}}
+	add := func(variant string, clicks int64) {
+		summary.Clicks += clicks
+		if variant != "" {
+			summary.Variants[variant] += clicks
+		}
+	}
+	for _, clickCount := range clickCounts {
+		add(clickCount.Variant, clickCount.Clicks)
+	}
+
+	// clicks not flushed yet are counted as well
+	s.mu.Lock()
+	for key, clicks := range s.pending {
+		if key.shortLinkID == shortLinkID {
+			add(key.variant, clicks)
+		}
+	}
+	s.mu.Unlock()
+
+	return &summary, nil
+}
+
+func (s *statsImpl) Start(flushInterval time.Duration) {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := s.clock.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				s.flush()
+			case <-s.stop:
+				s.flush()
+				return
+			}
+		}
+	}()
+}
+
+func (s *statsImpl) Stop() {
+	if s.stop == nil {
+		s.flush()
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// flush writes pending clicks to db, clicks failed to write are kept for the next flush.
+func (s *statsImpl) flush() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[clickKey]int64{}
+	s.mu.Unlock()
+
+	for key, clicks := range pending {
+		if err := s.clickDao.Increase(key.shortLinkID, key.variant, clicks); err != nil {
+			zap.S().Warnf("fail to flush clicks, short_link_id: %d, err: %v", key.shortLinkID, err)
+			s.mu.Lock()
+			s.pending[key] += clicks
+			s.mu.Unlock()
+		}
+	}
+}
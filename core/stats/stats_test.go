@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/suite"
+)
+
+var testNow = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+
+type statsTestSuite struct {
+	suite.Suite
+	impl         *statsImpl
+	clock        *fakeclock.FakeClock
+	mockClickDao *daomocks.ClickDao
+}
+
+func TestStatsSuite(t *testing.T) {
+	suite.Run(t, new(statsTestSuite))
+}
+
+func (s *statsTestSuite) SetupTest() {
+	s.clock = fakeclock.NewFakeClock(testNow)
+	s.mockClickDao = &daomocks.ClickDao{}
+	s.impl = NewStats(s.mockClickDao, s.clock).(*statsImpl)
+}
+
+func (s *statsTestSuite) TestFlush() {
+	s.impl.Record(1, "a")
+	s.impl.Record(1, "a")
+	s.impl.Record(2, "")
+
+	s.mockClickDao.On("Increase", uint64(1), "a", int64(2)).Return(nil).Once()
+	s.mockClickDao.On("Increase", uint64(2), "", int64(1)).Return(nil).Once()
+
+	s.impl.Start(time.Minute)
+	s.clock.WaitForWatcherAndIncrement(time.Minute)
+	s.impl.Stop()
+
+	s.mockClickDao.AssertExpectations(s.T())
+	s.Empty(s.impl.pending)
+}
+
+func (s *statsTestSuite) TestFlushFailed() {
+	s.impl.Record(1, "a")
+	s.mockClickDao.On("Increase", uint64(1), "a", int64(1)).Return(errors.New("db is down")).Once()
+	s.impl.flush()
+	s.Equal(int64(1), s.impl.pending[clickKey{shortLinkID: 1, variant: "a"}])
+
+	s.impl.Record(1, "a")
+	s.mockClickDao.On("Increase", uint64(1), "a", int64(2)).Return(nil).Once()
+	s.impl.Stop()
+	s.Empty(s.impl.pending)
+}
+
+func (s *statsTestSuite) TestGet() {
+	s.mockClickDao.On("ListByShortLinkID", uint64(1)).Return([]dao.ClickCount{
+		{ShortLinkID: 1, Variant: "a", Clicks: 3},
+		{ShortLinkID: 1, Variant: "b", Clicks: 5},
+	}, nil).Once()
+	s.impl.Record(1, "b")
+	s.impl.Record(2, "a")
+
+	summary, err := s.impl.Get(1)
+	s.Require().NoError(err)
+	s.Equal(int64(9), summary.Clicks)
+	s.Equal(map[string]int64{"a": 3, "b": 6}, summary.Variants)
+}
@@ -0,0 +1,88 @@
+package urlshortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/core/dao"
+)
+
+// apiKeyHashLength is number of hex digits of SHA-256 of API keys recorded in audit events.
+const apiKeyHashLength = 16
+
+type auditContextKey struct{}
+
+// auditSource is where a request comes from, see NewAuditContext.
+type auditSource struct {
+	sourceIP  string
+	requestID string
+}
+
+// NewAuditContext returns a copy of ctx carrying source IP and ID of the request, which are recorded in audit
+// events of changes made with ctx.
+func NewAuditContext(ctx context.Context, sourceIP, requestID string) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, auditSource{sourceIP: sourceIP, requestID: requestID})
+}
+
+// auditedDao returns ShortLinkDao recording changes of owner with the source of the request in ctx.
+func (s *urlShortenerImpl) auditedDao(ctx context.Context, owner *dao.Owner) dao.ShortLinkDao {
+	source, _ := ctx.Value(auditContextKey{}).(auditSource)
+	actor := &dao.Actor{
+		SourceIP:  source.sourceIP,
+		RequestID: source.requestID,
+	}
+	if owner != nil {
+		actor.OwnerID = owner.ID
+		actor.APIKeyHash = hashAPIKey(owner.APIKey)
+	}
+	return s.shortLinkDao.WithActor(actor)
+}
+
+// hashAPIKey returns the prefix of SHA-256 of apiKey, which identifies the key without revealing it.
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:apiKeyHashLength]
+}
+
+func (s *urlShortenerImpl) ListAuditEvents(params ListAuditEventsParams) ([]*dao.AuditEvent, string, error) {
+	if err := validate.Struct(params); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+
+	var beforeID uint64
+	if params.Cursor != "" {
+		id, err := base62.Decode(params.Cursor)
+		if err != nil || id == 0 {
+			return nil, "", fmt.Errorf("%w: cursor is invalid", ErrInvalidParams)
+		}
+		beforeID = id
+	}
+
+	events, err := s.auditDao.List(dao.AuditFilter{
+		OwnerID:     params.OwnerID,
+		Domain:      params.Domain,
+		URLID:       params.URLID,
+		Action:      params.Action,
+		RequestID:   params.RequestID,
+		CreatedFrom: params.CreatedFrom,
+		CreatedTo:   params.CreatedTo,
+		BeforeID:    beforeID,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(events) == limit {
+		next = base62.Encode(events[len(events)-1].ID)
+	}
+	return events, next, nil
+}
@@ -0,0 +1,52 @@
+package urlshortener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/core/dao"
+)
+
+func (s *urlShortenerTestSuite) TestAuditedDao() {
+	ctx := NewAuditContext(context.Background(), "203.0.113.7", "req-1")
+
+	s.Equal(s.mockShortLinkDao, s.impl.auditedDao(ctx, &dao.Owner{ID: 3, APIKey: "test-api-key"}))
+	s.mockShortLinkDao.AssertCalled(s.T(), "WithActor", &dao.Actor{
+		OwnerID:    3,
+		APIKeyHash: hashAPIKey("test-api-key"),
+		SourceIP:   "203.0.113.7",
+		RequestID:  "req-1",
+	})
+
+	// anonymous changes without the source are recorded as well
+	s.impl.auditedDao(context.Background(), nil)
+	s.mockShortLinkDao.AssertCalled(s.T(), "WithActor", &dao.Actor{})
+}
+
+func (s *urlShortenerTestSuite) TestHashAPIKey() {
+	s.Len(hashAPIKey("test-api-key"), apiKeyHashLength)
+	s.Equal(hashAPIKey("test-api-key"), hashAPIKey("test-api-key"))
+	s.NotEqual(hashAPIKey("test-api-key"), hashAPIKey("other-api-key"))
+}
+
+func (s *urlShortenerTestSuite) TestListAuditEvents() {
+	s.mockAuditDao.On("List", dao.AuditFilter{OwnerID: 3, Action: dao.AuditDelete, BeforeID: 100, Limit: 2}).
+		Return([]*dao.AuditEvent{{ID: 99}, {ID: 98}}, nil).
+		Once()
+
+	events, next, err := s.impl.ListAuditEvents(ListAuditEventsParams{
+		OwnerID: 3,
+		Action:  dao.AuditDelete,
+		Cursor:  base62.Encode(100),
+		Limit:   2,
+	})
+	s.Require().NoError(err)
+	s.Len(events, 2)
+	s.Equal(base62.Encode(98), next)
+
+	_, _, err = s.impl.ListAuditEvents(ListAuditEventsParams{Action: "read"})
+	s.True(errors.Is(err, ErrInvalidParams))
+	_, _, err = s.impl.ListAuditEvents(ListAuditEventsParams{Cursor: "-"})
+	s.True(errors.Is(err, ErrInvalidParams))
+}
@@ -0,0 +1,35 @@
+package urlshortener
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+)
+
+func (s *urlShortenerImpl) ListBlockedIDs() ([]*dao.BlockedID, error) {
+	return s.blockedIDDao.List()
+}
+
+func (s *urlShortenerImpl) BlockID(urlID, reason string) (*dao.BlockedID, error) {
+	if !IsValidURLID(urlID) {
+		return nil, fmt.Errorf("%w: id is invalid", ErrInvalidParams)
+	}
+	reason = strings.TrimSpace(reason)
+	if len(reason) > 256 {
+		return nil, fmt.Errorf("%w: reason should be at most 256 characters", ErrInvalidParams)
+	}
+
+	blockedID := &dao.BlockedID{URLID: urlID, Reason: reason}
+	if err := s.blockedIDDao.Block(blockedID); err != nil {
+		return nil, err
+	}
+	return blockedID, nil
+}
+
+func (s *urlShortenerImpl) UnblockID(urlID string) error {
+	if !IsValidURLID(urlID) {
+		return fmt.Errorf("%w: id is invalid", ErrInvalidParams)
+	}
+	return s.blockedIDDao.Unblock(urlID)
+}
@@ -0,0 +1,371 @@
+package urlshortener
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/georgechang0117/url-shortener/core/bulk"
+	"github.com/georgechang0117/url-shortener/core/dao"
+)
+
+const (
+	importBatchSize = 500
+	exportBatchSize = 500
+	maxImportErrors = 100
+)
+
+// errRollback rolls back import transaction without failing import.
+var errRollback = errors.New("rollback")
+
+// importItem is a validated record waiting to be written in batch, which is buffered in a spool file.
+type importItem struct {
+	Line      int
+	ShortLink *dao.ShortLink
+}
+
+func (s *urlShortenerImpl) Import(ctx context.Context, params ImportParams) (*ImportResult, error) {
+	if params.Owner == nil {
+		return nil, ErrOwnerRequired
+	}
+	switch params.Conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, fmt.Errorf("%w: conflict should be one of skip, overwrite and fail", ErrInvalidParams)
+	}
+
+	spool, err := ioutil.TempFile("", "import-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	// the upload is read and validated before the transaction begins, and records to write are buffered in spool
+	// instead of memory
+	result, err := s.spoolImport(params, spool)
+	if err != nil {
+		return nil, err
+	}
+	if result.Failed > 0 || params.DryRun {
+		return result, nil
+	}
+
+	// conflicts are checked again in the transaction since short links could be changed after the first pass
+	checked := &ImportResult{Total: result.Total, Skipped: result.Skipped}
+	err = s.auditedDao(ctx, params.Owner).Transaction(func(tx dao.ShortLinkDao) error {
+		err := readSpool(spool, func(batch []importItem) error {
+			return s.importBatch(tx, params, batch, checked)
+		})
+		if err != nil {
+			return err
+		}
+		if checked.Failed > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return checked, nil
+	} else if err != nil {
+		return nil, err
+	}
+	checked.Committed = true
+
+	// cache is invalidated after commit, or the short links could be cached again before they're written
+	err = readSpool(spool, func(batch []importItem) error {
+		for _, item := range batch {
+			if err := s.remoteCache.Delete(shortLinkCacheKey(item.ShortLink.DomainID, item.ShortLink.URLID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return checked, nil
+}
+
+// spoolImport reads and validates all records of params, checks their conflicts without locking and writes
+// the ones to create or overwrite to spool.
+func (s *urlShortenerImpl) spoolImport(params ImportParams, spool io.Writer) (*ImportResult, error) {
+	result := &ImportResult{}
+	w := bufio.NewWriter(spool)
+	enc := gob.NewEncoder(w)
+	seen := map[string]bool{}
+	batch := make([]importItem, 0, importBatchSize)
+	flush := func() error {
+		items, err := s.checkImportBatch(s.shortLinkDao, params, batch, result)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		record, err := params.Reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *bulk.ParseError
+		if errors.As(err, &parseErr) {
+			result.Total++
+			result.fail(parseErr.Line, "", parseErr.Err.Error())
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		result.Total++
+
+		shortLink, err := s.importShortLink(params.Owner, record)
+		if err != nil {
+			result.fail(record.Line, record.ID, err.Error())
+			continue
+		}
+		key := shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)
+		if seen[key] {
+			result.fail(record.Line, record.ID, "duplicated id in file")
+			continue
+		}
+		seen[key] = true
+
+		batch = append(batch, importItem{Line: record.Line, ShortLink: shortLink})
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return result, w.Flush()
+}
+
+// readSpool reads items written by spoolImport from the beginning of spool, and calls fn with every
+// importBatchSize items.
+func readSpool(spool io.ReadSeeker, fn func([]importItem) error) error {
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dec := gob.NewDecoder(bufio.NewReader(spool))
+	batch := make([]importItem, 0, importBatchSize)
+	for {
+		var item importItem
+		err := dec.Decode(&item)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		batch = append(batch, item)
+		if len(batch) == importBatchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return fn(batch)
+}
+
+// importShortLink validates record and returns the short link of it. Expired records are allowed to keep
+// links which are expired in the source.
+func (s *urlShortenerImpl) importShortLink(owner *dao.Owner, record *bulk.Record) (*dao.ShortLink, error) {
+	if !IsValidURLID(record.ID) {
+		return nil, errors.New("id is invalid")
+	}
+	if err := s.idFilter.Check(record.ID); err != nil {
+		return nil, err
+	}
+	params := UploadParams{
+		Owner:       owner,
+		Domain:      record.Domain,
+		URL:         record.URL,
+		ExpireAt:    record.ExpireAt,
+		Preview:     record.Preview,
+		QueryMode:   record.QueryMode,
+		UTM:         record.UTM,
+		Rules:       record.Rules,
+		Variants:    record.Variants,
+		Title:       record.Title,
+		Description: record.Description,
+		Tags:        record.Tags,
+		FallbackURL: record.FallbackURL,
+	}
+	if err := validate.Struct(params); err != nil {
+		return nil, err
+	}
+
+	shortLink, err := s.compileShortLink(params)
+	if err != nil {
+		return nil, err
+	}
+	shortLink.URLID = record.ID
+	shortLink.CreatedAt = record.CreatedAt
+
+	return shortLink, nil
+}
+
+// checkImportBatch resolves conflicts of batch with existing short links loaded by d, and returns the items to
+// create or overwrite.
+func (s *urlShortenerImpl) checkImportBatch(
+	d dao.ShortLinkDao,
+	params ImportParams,
+	batch []importItem,
+	result *ImportResult,
+) ([]importItem, error) {
+	urlIDs := map[uint64][]string{}
+	for _, item := range batch {
+		urlIDs[item.ShortLink.DomainID] = append(urlIDs[item.ShortLink.DomainID], item.ShortLink.URLID)
+	}
+	existing := map[string]*dao.ShortLink{}
+	for domainID, ids := range urlIDs {
+		shortLinks, err := d.GetByURLIDs(domainID, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, shortLink := range shortLinks {
+			existing[shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)] = shortLink
+		}
+	}
+
+	var items []importItem
+	for _, item := range batch {
+		shortLink := item.ShortLink
+		old, ok := existing[shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)]
+		switch {
+		case !ok:
+			// the short link could be purged since it's checked
+			shortLink.ID = 0
+			result.Created++
+			items = append(items, item)
+		case params.Conflict == ConflictSkip:
+			result.Skipped++
+		case params.Conflict == ConflictFail:
+			result.fail(item.Line, shortLink.URLID, "id already exists")
+		case old.OwnerID != params.Owner.ID:
+			result.fail(item.Line, shortLink.URLID, "id is used by another owner")
+		default:
+			// overwriting restores deleted and disabled short links like uploading them again, see dao.Overwrite
+			shortLink.ID = old.ID
+			if shortLink.CreatedAt.IsZero() {
+				shortLink.CreatedAt = old.CreatedAt
+			}
+			// the destination is still broken unless it's changed
+			shortLink.Broken = old.Broken && old.URL == shortLink.URL
+			// so is the expired event unless the expiry is changed
+			shortLink.ExpireNotified = old.ExpireNotified && old.ExpireAt.Equal(shortLink.ExpireAt)
+			result.Updated++
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// importBatch checks conflicts of batch again and writes it by tx. Nothing is written once any record fails.
+func (s *urlShortenerImpl) importBatch(
+	tx dao.ShortLinkDao,
+	params ImportParams,
+	batch []importItem,
+	result *ImportResult,
+) error {
+	items, err := s.checkImportBatch(tx, params, batch, result)
+	if err != nil {
+		return err
+	}
+	if result.Failed > 0 {
+		return nil
+	}
+
+	var creates, updates []*dao.ShortLink
+	for _, item := range items {
+		if item.ShortLink.ID == 0 {
+			creates = append(creates, item.ShortLink)
+		} else {
+			updates = append(updates, item.ShortLink)
+		}
+	}
+	if err := tx.CreateBatch(creates); dao.IsErrDuplicatedKey(err) {
+		// created by others after conflicts are checked
+		return ErrConflict
+	} else if err != nil {
+		return err
+	}
+	for _, shortLink := range updates {
+		if err := tx.Overwrite(shortLink); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ImportResult) fail(line int, id, message string) {
+	r.Failed++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, ImportError{Line: line, ID: id, Message: message})
+	}
+}
+
+func (s *urlShortenerImpl) Export(owner *dao.Owner, w bulk.Writer) error {
+	if owner == nil {
+		return ErrOwnerRequired
+	}
+
+	domains := s.ownerDomains(owner)
+	err := s.shortLinkDao.IterateByOwner(owner.ID, exportBatchSize, func(shortLinks []*dao.ShortLink) error {
+		for _, shortLink := range shortLinks {
+			var host string
+			if domain, ok := domains[shortLink.DomainID]; ok {
+				host = domain.Host
+			}
+			err := w.Write(&bulk.Record{
+				ID:          shortLink.URLID,
+				Domain:      host,
+				URL:         shortLink.URL,
+				ExpireAt:    shortLink.ExpireAt,
+				Preview:     shortLink.Preview,
+				QueryMode:   shortLink.QueryMode,
+				UTM:         shortLink.UTMParams(),
+				Rules:       shortLink.Rules,
+				Variants:    shortLink.Variants,
+				CreatedAt:   shortLink.CreatedAt,
+				Title:       shortLink.Title,
+				Description: shortLink.Description,
+				Tags:        shortLink.Tags,
+				FallbackURL: shortLink.FallbackURL,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	// header of CSV is not flushed yet if there is no short link
+	return w.Flush()
+}
@@ -0,0 +1,211 @@
+package urlshortener
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"strings"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/bulk"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/idfilter"
+
+	"github.com/stretchr/testify/mock"
+)
+
+const testImportFile = `{"id":"custom-id","url":"https://example.com/a","expireAt":"2021-07-30T00:00:00Z"}
+{"id":"ejLqV3Wkyd6","url":"https://example.com/b","expireAt":"2021-07-30T00:00:00Z","preview":true}
+`
+
+func (s *urlShortenerTestSuite) runTransaction() interface{} {
+	return func(fn func(dao.ShortLinkDao) error) error {
+		return fn(s.mockShortLinkDao)
+	}
+}
+
+func (s *urlShortenerTestSuite) newImportReader(data string) bulk.Reader {
+	reader, err := bulk.NewReader(strings.NewReader(data), bulk.FormatJSONL)
+	s.Require().NoError(err)
+	return reader
+}
+
+func (s *urlShortenerTestSuite) TestImport() {
+	owner := dao.Owner{ID: 3}
+	createdAt := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	s.mockShortLinkDao.On("Transaction", mock.Anything).Return(s.runTransaction()).Once()
+	// checked before and in the transaction
+	s.mockShortLinkDao.On("GetByURLIDs", testDefaultDomain.ID, []string{"custom-id", testURLID}).Return([]*dao.ShortLink{
+		{ID: 10, DomainID: testDefaultDomain.ID, OwnerID: owner.ID, URLID: testURLID, CreatedAt: createdAt},
+	}, nil).Twice()
+	s.mockShortLinkDao.On("CreateBatch", mock.MatchedBy(func(shortLinks []*dao.ShortLink) bool {
+		return len(shortLinks) == 1 && shortLinks[0].URLID == "custom-id" && shortLinks[0].OwnerID == owner.ID
+	})).Return(nil).Once()
+	s.mockShortLinkDao.On("Overwrite", mock.MatchedBy(func(shortLink *dao.ShortLink) bool {
+		return shortLink.ID == 10 && shortLink.Preview && shortLink.CreatedAt.Equal(createdAt)
+	})).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, "custom-id")).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+
+	result, err := s.impl.Import(context.Background(), ImportParams{
+		Owner:    &owner,
+		Reader:   s.newImportReader(testImportFile),
+		Conflict: ConflictOverwrite,
+	})
+	s.Require().NoError(err)
+	s.Equal(&ImportResult{Total: 2, Created: 1, Updated: 1, Committed: true}, result)
+}
+
+func (s *urlShortenerTestSuite) TestImportConflict() {
+	owner := dao.Owner{ID: 3}
+	existing := []*dao.ShortLink{{ID: 10, DomainID: testDefaultDomain.ID, OwnerID: 4, URLID: testURLID}}
+	s.mockShortLinkDao.On("GetByURLIDs", testDefaultDomain.ID, []string{"custom-id", testURLID}).Return(existing, nil).Twice()
+
+	result, err := s.impl.Import(context.Background(), ImportParams{
+		Owner:    &owner,
+		Reader:   s.newImportReader(testImportFile),
+		Conflict: ConflictFail,
+	})
+	s.Require().NoError(err)
+	s.False(result.Committed)
+	s.Equal(1, result.Failed)
+	s.Equal([]ImportError{{Line: 2, ID: testURLID, Message: "id already exists"}}, result.Errors)
+
+	result, err = s.impl.Import(context.Background(), ImportParams{
+		Owner:    &owner,
+		Reader:   s.newImportReader(testImportFile),
+		Conflict: ConflictOverwrite,
+	})
+	s.Require().NoError(err)
+	s.False(result.Committed)
+	s.Equal("id is used by another owner", result.Errors[0].Message)
+}
+
+func (s *urlShortenerTestSuite) TestImportConflictInTransaction() {
+	owner := dao.Owner{ID: 3}
+	s.mockShortLinkDao.On("GetByURLIDs", testDefaultDomain.ID, []string{"custom-id", testURLID}).Return(nil, nil).Once()
+	s.mockShortLinkDao.On("Transaction", mock.Anything).Return(s.runTransaction()).Once()
+	// created by another owner after the upload is checked
+	s.mockShortLinkDao.On("GetByURLIDs", testDefaultDomain.ID, []string{"custom-id", testURLID}).Return([]*dao.ShortLink{
+		{ID: 10, DomainID: testDefaultDomain.ID, OwnerID: 4, URLID: testURLID},
+	}, nil).Once()
+
+	result, err := s.impl.Import(context.Background(), ImportParams{
+		Owner:    &owner,
+		Reader:   s.newImportReader(testImportFile),
+		Conflict: ConflictOverwrite,
+	})
+	s.Require().NoError(err)
+	s.False(result.Committed)
+	s.Equal(&ImportResult{
+		Total:   2,
+		Created: 1,
+		Failed:  1,
+		Errors:  []ImportError{{Line: 2, ID: testURLID, Message: "id is used by another owner"}},
+	}, result)
+}
+
+func (s *urlShortenerTestSuite) TestReadSpool() {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	for i := 0; i <= importBatchSize; i++ {
+		s.Require().NoError(enc.Encode(importItem{Line: i + 1, ShortLink: &dao.ShortLink{URLID: testURLID}}))
+	}
+
+	var sizes []int
+	var last importItem
+	err := readSpool(bytes.NewReader(buf.Bytes()), func(batch []importItem) error {
+		sizes = append(sizes, len(batch))
+		last = batch[len(batch)-1]
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Equal([]int{importBatchSize, 1}, sizes)
+	s.Equal(importBatchSize+1, last.Line)
+	s.Equal(testURLID, last.ShortLink.URLID)
+}
+
+func (s *urlShortenerTestSuite) TestImportDryRun() {
+	owner := dao.Owner{ID: 3}
+	data := testImportFile + `not json
+{"id":"invalid id","url":"https://example.com/c","expireAt":"2021-07-30T00:00:00Z"}
+{"id":"custom-id","url":"https://example.com/d","expireAt":"2021-07-30T00:00:00Z"}
+{"id":"other-id","url":"https://example.com/e"}
+`
+	s.mockShortLinkDao.On("GetByURLIDs", testDefaultDomain.ID, []string{"custom-id", testURLID}).Return([]*dao.ShortLink{
+		{ID: 10, DomainID: testDefaultDomain.ID, OwnerID: owner.ID, URLID: testURLID},
+	}, nil).Once()
+
+	result, err := s.impl.Import(context.Background(), ImportParams{
+		Owner:    &owner,
+		Reader:   s.newImportReader(data),
+		Conflict: ConflictSkip,
+		DryRun:   true,
+	})
+	s.Require().NoError(err)
+	s.Equal(6, result.Total)
+	s.Equal(1, result.Created)
+	s.Equal(1, result.Skipped)
+	s.Equal(4, result.Failed)
+	s.False(result.Committed)
+	s.Equal(3, result.Errors[0].Line)
+	s.Equal("id is invalid", result.Errors[1].Message)
+	s.Equal("duplicated id in file", result.Errors[2].Message)
+	s.Equal("other-id", result.Errors[3].ID)
+}
+
+func (s *urlShortenerTestSuite) TestImportFilteredID() {
+	owner := dao.Owner{ID: 3}
+	data := `{"id":"API","url":"https://example.com/a","expireAt":"2021-07-30T00:00:00Z"}
+{"id":"oh-sh1t","url":"https://example.com/b","expireAt":"2021-07-30T00:00:00Z"}
+`
+
+	result, err := s.impl.Import(context.Background(), ImportParams{
+		Owner:    &owner,
+		Reader:   s.newImportReader(data),
+		Conflict: ConflictSkip,
+		DryRun:   true,
+	})
+	s.Require().NoError(err)
+	s.Equal(2, result.Failed)
+	s.Equal(idfilter.ErrReserved.Error(), result.Errors[0].Message)
+	s.Equal(idfilter.ErrBlocked.Error(), result.Errors[1].Message)
+}
+
+func (s *urlShortenerTestSuite) TestImportInvalid() {
+	_, err := s.impl.Import(context.Background(), ImportParams{Conflict: ConflictSkip})
+	s.Equal(ErrOwnerRequired, err)
+
+	_, err = s.impl.Import(context.Background(), ImportParams{Owner: &dao.Owner{ID: 3}, Conflict: "merge"})
+	s.ErrorIs(err, ErrInvalidParams)
+}
+
+func (s *urlShortenerTestSuite) TestExport() {
+	owner := dao.Owner{ID: 3, Domains: []dao.Domain{testDomain}}
+	expireAt := time.Date(2021, 7, 30, 0, 0, 0, 0, time.UTC)
+	s.mockShortLinkDao.On("IterateByOwner", owner.ID, exportBatchSize, mock.Anything).Return(
+		func(ownerID uint64, batchSize int, fn func([]*dao.ShortLink) error) error {
+			return fn([]*dao.ShortLink{
+				{ID: 10, DomainID: testDefaultDomain.ID, URLID: testURLID, URL: testUploadURL, ExpireAt: expireAt},
+				{ID: 12, DomainID: testDomain.ID, URLID: "custom-id", URL: testUploadURL, ExpireAt: expireAt, UTM: "utm_source=a"},
+			})
+		},
+	).Once()
+
+	var buf bytes.Buffer
+	w, err := bulk.NewWriter(&buf, bulk.FormatJSONL)
+	s.Require().NoError(err)
+	s.Require().NoError(s.impl.Export(&owner, w))
+
+	reader := s.newImportReader(buf.String())
+	record, err := reader.Read()
+	s.Require().NoError(err)
+	s.Equal(testURLID, record.ID)
+	s.Equal(testDefaultDomain.Host, record.Domain)
+	record, err = reader.Read()
+	s.Require().NoError(err)
+	s.Equal(testHost, record.Domain)
+	s.Equal(map[string]string{"utm_source": "a"}, record.UTM)
+
+	s.Equal(ErrOwnerRequired, s.impl.Export(nil, w))
+}
@@ -0,0 +1,179 @@
+package urlshortener
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/cache"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	"github.com/georgechang0117/url-shortener/core/dao"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+)
+
+const (
+	cacheWarmLockKey = "cache_warm"
+	// cacheWarmedKey never expires, so its absence means the cache is flushed or has never been warmed.
+	cacheWarmedKey   = "cache_warmed"
+	cacheWarmLockTTL = time.Minute
+
+	defaultCacheWarmClickWindow   = 7 * 24 * time.Hour
+	defaultCacheWarmCheckInterval = time.Minute
+)
+
+type cacheWarmerImpl struct {
+	shortLinkDao dao.ShortLinkDao
+	remoteCache  cache.RemoteCache
+	locker       lock.DistributedLocker
+	clock        clock.Clock
+	config       CacheWarmerConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCacheWarmer creates an instance of CacheWarmer.
+func NewCacheWarmer(
+	shortLinkDao dao.ShortLinkDao,
+	remoteCache cache.RemoteCache,
+	locker lock.DistributedLocker,
+	clock clock.Clock,
+	config CacheWarmerConfig,
+) CacheWarmer {
+	if config.ClickWindow == 0 {
+		config.ClickWindow = defaultCacheWarmClickWindow
+	}
+	if config.CheckInterval == 0 {
+		config.CheckInterval = defaultCacheWarmCheckInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cacheWarmerImpl{
+		shortLinkDao: shortLinkDao,
+		remoteCache:  remoteCache,
+		locker:       locker,
+		clock:        clock,
+		config:       config,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (w *cacheWarmerImpl) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		// a deploy warms the cache even if it isn't flushed, short links popular now may not be cached
+		w.warm()
+		for {
+			select {
+			case <-w.clock.After(w.config.CheckInterval):
+				if w.flushed() {
+					w.warm()
+				}
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (w *cacheWarmerImpl) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// flushed reports whether the mark of warming is gone.
+func (w *cacheWarmerImpl) flushed() bool {
+	ok, err := w.remoteCache.Exists(cacheWarmedKey)
+	if err != nil {
+		zap.S().Warnf("fail to check cache warmed, err: %v", err)
+		return false
+	}
+	return !ok
+}
+
+// warm preloads short links by one of replicas holding the lock, and marks the cache warmed. Short links already
+// cached are kept, and the others are loaded from db again before caching.
+func (w *cacheWarmerImpl) warm() {
+	l, err := w.locker.Lock(cacheWarmLockKey, cacheWarmLockTTL, lock.DefaultRetryDelay, 0)
+	if err != nil {
+		zap.S().Debugf("skip cache warming, err: %v", err)
+		return
+	}
+	defer l.Unlock()
+
+	now := w.clock.Now()
+	var shortLinks []*dao.ShortLink
+	if w.config.MostClicked > 0 {
+		mostClicked, err := w.shortLinkDao.ListMostClicked(now.Add(-w.config.ClickWindow), now, w.config.MostClicked)
+		if err != nil {
+			zap.S().Warnf("fail to list most clicked short links for cache warming, err: %v", err)
+			return
+		}
+		shortLinks = append(shortLinks, mostClicked...)
+	}
+	if w.config.Recent > 0 {
+		recent, err := w.shortLinkDao.ListRecent(now, w.config.Recent)
+		if err != nil {
+			zap.S().Warnf("fail to list recent short links for cache warming, err: %v", err)
+			return
+		}
+		shortLinks = append(shortLinks, recent...)
+	}
+
+	seen := make(map[uint64]bool, len(shortLinks))
+	warmed := 0
+	for _, shortLink := range shortLinks {
+		if w.ctx.Err() != nil {
+			return
+		}
+		if seen[shortLink.ID] {
+			continue
+		}
+		seen[shortLink.ID] = true
+		// entries loaded since the listing are kept, and absent ones are read again right before caching, since
+		// the entries of short links changed since the listing are deleted and the snapshot is stale
+		key := shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)
+		cached, err := w.remoteCache.Exists(key)
+		if err != nil {
+			zap.S().Warnf("fail to warm cache, err: %v", err)
+			return
+		}
+		if cached {
+			continue
+		}
+		fresh, err := w.shortLinkDao.GetByURLID(shortLink.DomainID, shortLink.URLID)
+		if dao.IsErrRecordNotFound(err) {
+			// purged since the listing
+			continue
+		} else if err != nil {
+			zap.S().Warnf("fail to warm cache, err: %v", err)
+			return
+		}
+		b, err := json.Marshal(fresh)
+		if err != nil {
+			zap.S().Warnf("fail to warm cache, err: %v", err)
+			return
+		}
+		// only absent keys are filled, entries loaded by redirects since the read are not overwritten
+		ok, err := w.remoteCache.SetNX(key, b, shortLinkCacheTTL(fresh, now))
+		if err != nil {
+			zap.S().Warnf("fail to warm cache, err: %v", err)
+			return
+		}
+		if ok {
+			warmed++
+		}
+	}
+
+	if err := w.remoteCache.Set(cacheWarmedKey, now.Format(time.RFC3339), 0); err != nil {
+		zap.S().Warnf("fail to mark cache warmed, err: %v", err)
+		return
+	}
+	zap.S().Infof("cache warmed with %d short links", warmed)
+}
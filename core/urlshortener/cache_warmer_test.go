@@ -0,0 +1,159 @@
+package urlshortener
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/cache"
+	cachemocks "github.com/georgechang0117/url-shortener/base/cache/mocks"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	lockmocks "github.com/georgechang0117/url-shortener/base/lock/mocks"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type cacheWarmerTestSuite struct {
+	suite.Suite
+	impl             *cacheWarmerImpl
+	clock            *fakeclock.FakeClock
+	mockShortLinkDao *daomocks.ShortLinkDao
+	mockRemoteCache  *cachemocks.RemoteCache
+	mockLocker       *lockmocks.DistributedLocker
+}
+
+func TestCacheWarmerTestSuite(t *testing.T) {
+	suite.Run(t, new(cacheWarmerTestSuite))
+}
+
+func (s *cacheWarmerTestSuite) SetupTest() {
+	s.clock = fakeclock.NewFakeClock(testNow)
+	s.mockShortLinkDao = &daomocks.ShortLinkDao{}
+	s.mockRemoteCache = &cachemocks.RemoteCache{}
+	s.mockLocker = &lockmocks.DistributedLocker{}
+	impl := NewCacheWarmer(s.mockShortLinkDao, s.mockRemoteCache, s.mockLocker, s.clock, CacheWarmerConfig{
+		MostClicked: 2,
+		ClickWindow: 24 * time.Hour,
+		Recent:      2,
+	})
+	s.impl = impl.(*cacheWarmerImpl)
+}
+
+func (s *cacheWarmerTestSuite) TearDownTest() {
+	s.impl.Stop()
+}
+
+func (s *cacheWarmerTestSuite) TestWarm() {
+	expireAt := testNow.Add(time.Hour)
+	popular := &dao.ShortLink{ID: 1, DomainID: 1, URLID: "popular", URL: testUploadURL, ExpireAt: expireAt}
+	both := &dao.ShortLink{ID: 3, DomainID: 2, URLID: "both", URL: testUploadURL, ExpireAt: expireAt}
+	recent := &dao.ShortLink{ID: 4, DomainID: 1, URLID: "recent", URL: testUploadURL, ExpireAt: expireAt}
+	mockLock := &lockmocks.Lock{}
+	mockLock.On("Unlock").Return(nil).Once()
+	s.mockLocker.On("Lock", cacheWarmLockKey, cacheWarmLockTTL, lock.DefaultRetryDelay, 0).Return(mockLock, nil).Once()
+	s.mockShortLinkDao.On("ListMostClicked", testNow.Add(-24*time.Hour), testNow, 2).
+		Return([]*dao.ShortLink{popular, both}, nil).
+		Once()
+	s.mockShortLinkDao.On("ListRecent", testNow, 2).Return([]*dao.ShortLink{recent, both}, nil).Once()
+	for _, shortLink := range []*dao.ShortLink{popular, both, recent} {
+		key := shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)
+		s.mockRemoteCache.On("Exists", key).Return(false, nil).Once()
+		s.mockShortLinkDao.On("GetByURLID", shortLink.DomainID, shortLink.URLID).Return(shortLink, nil).Once()
+		s.mockRemoteCache.On("SetNX", shortLinkCacheKey(shortLink.DomainID, shortLink.URLID), mock.AnythingOfType("[]uint8"), time.Hour+notFoundCacheTTL).
+			Return(true, nil).
+			Once()
+	}
+	s.mockRemoteCache.On("Set", cacheWarmedKey, testNow.Format(time.RFC3339), time.Duration(0)).Return(nil).Once()
+
+	s.impl.warm()
+	s.mockRemoteCache.AssertExpectations(s.T())
+	mockLock.AssertExpectations(s.T())
+}
+
+func (s *cacheWarmerTestSuite) TestWarmFailed() {
+	mockLock := &lockmocks.Lock{}
+	mockLock.On("Unlock").Return(nil).Once()
+	s.mockLocker.On("Lock", cacheWarmLockKey, cacheWarmLockTTL, lock.DefaultRetryDelay, 0).Return(mockLock, nil).Once()
+	s.mockShortLinkDao.On("ListMostClicked", mock.Anything, testNow, 2).Return(nil, errors.New("db down")).Once()
+
+	// the cache isn't marked warmed, so it's warmed again in the next check
+	s.impl.warm()
+	s.mockRemoteCache.AssertNotCalled(s.T(), "SetNX", mock.Anything, mock.Anything, mock.Anything)
+	s.mockRemoteCache.AssertNotCalled(s.T(), "Set", mock.Anything, mock.Anything, mock.Anything)
+	mockLock.AssertExpectations(s.T())
+}
+
+func (s *cacheWarmerTestSuite) TestWarmUpdatedSinceListed() {
+	mr := miniredis.RunT(s.T())
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	s.impl.remoteCache = cache.NewRedis(client)
+	s.impl.config.MostClicked = 0
+	s.impl.config.Recent = 4
+
+	expireAt := testNow.Add(time.Hour)
+	loaded := &dao.ShortLink{ID: 1, DomainID: 1, URLID: "loaded", URL: testUploadURL, ExpireAt: expireAt}
+	loadedFresh := *loaded
+	loadedFresh.URL = "https://example.com/loaded"
+	updated := &dao.ShortLink{ID: 2, DomainID: 1, URLID: "updated", URL: testUploadURL, ExpireAt: expireAt}
+	updatedFresh := *updated
+	updatedFresh.URL = "https://example.com/updated"
+	purged := &dao.ShortLink{ID: 3, DomainID: 1, URLID: "purged", URL: testUploadURL, ExpireAt: expireAt}
+	other := &dao.ShortLink{ID: 4, DomainID: 1, URLID: "other", URL: testUploadURL, ExpireAt: expireAt}
+	mockLock := &lockmocks.Lock{}
+	mockLock.On("Unlock").Return(nil).Once()
+	s.mockLocker.On("Lock", cacheWarmLockKey, cacheWarmLockTTL, lock.DefaultRetryDelay, 0).Return(mockLock, nil).Once()
+	s.mockShortLinkDao.On("ListRecent", testNow, 4).
+		Run(func(mock.Arguments) {
+			// the short link is updated and loaded into cache by a redirect after listed
+			s.Require().NoError(setShortLinkCache(s.impl.remoteCache, &loadedFresh, testNow))
+		}).
+		Return([]*dao.ShortLink{loaded, updated, purged, other}, nil).
+		Once()
+	// the others are updated or purged after listed, whose entries are deleted
+	s.mockShortLinkDao.On("GetByURLID", updated.DomainID, updated.URLID).Return(&updatedFresh, nil).Once()
+	s.mockShortLinkDao.On("GetByURLID", purged.DomainID, purged.URLID).Return(nil, gorm.ErrRecordNotFound).Once()
+	s.mockShortLinkDao.On("GetByURLID", other.DomainID, other.URLID).Return(other, nil).Once()
+
+	s.impl.warm()
+	for _, shortLink := range []*dao.ShortLink{&loadedFresh, &updatedFresh, other} {
+		var cached dao.ShortLink
+		b, err := s.impl.remoteCache.Get(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID))
+		s.Require().NoError(err)
+		s.Require().NoError(json.Unmarshal(b, &cached))
+		s.Equal(shortLink.URL, cached.URL)
+	}
+	ok, err := s.impl.remoteCache.Exists(shortLinkCacheKey(purged.DomainID, purged.URLID))
+	s.NoError(err)
+	s.False(ok)
+	ok, err = s.impl.remoteCache.Exists(cacheWarmedKey)
+	s.NoError(err)
+	s.True(ok)
+}
+
+func (s *cacheWarmerTestSuite) TestWarmFlushed() {
+	warmed := make(chan struct{})
+	s.mockLocker.On("Lock", cacheWarmLockKey, cacheWarmLockTTL, lock.DefaultRetryDelay, 0).
+		Run(func(mock.Arguments) { warmed <- struct{}{} }).
+		Return(nil, errors.New("lock timeout")).
+		Twice()
+	s.mockRemoteCache.On("Exists", cacheWarmedKey).Return(true, nil).Once()
+	s.mockRemoteCache.On("Exists", cacheWarmedKey).Return(false, nil).Once()
+
+	s.impl.Start()
+	// warmed once started
+	<-warmed
+	s.clock.WaitForWatcherAndIncrement(defaultCacheWarmCheckInterval)
+	// warmed again once the cache is flushed
+	s.clock.WaitForWatcherAndIncrement(defaultCacheWarmCheckInterval)
+	<-warmed
+	s.mockRemoteCache.AssertExpectations(s.T())
+}
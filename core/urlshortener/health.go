@@ -0,0 +1,279 @@
+package urlshortener
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/cache"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/health"
+	"github.com/georgechang0117/url-shortener/core/redirect"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+)
+
+const (
+	healthCheckLockKey = "health_check"
+
+	defaultHealthCheckInterval  = time.Hour
+	defaultHealthCheckWorkers   = 8
+	defaultHealthHostDelay      = time.Second
+	defaultHealthCheckThreshold = 3
+	defaultHealthCheckBatchSize = 500
+
+	maxHealthErrorLength = 256
+)
+
+type healthCheckerImpl struct {
+	prober       health.Prober
+	shortLinkDao dao.ShortLinkDao
+	remoteCache  cache.RemoteCache
+	locker       lock.DistributedLocker
+	clock        clock.Clock
+	config       HealthCheckerConfig
+
+	// lastProbes is start time of the last probe of each host in the current round.
+	mu         sync.Mutex
+	lastProbes map[string]time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewHealthChecker creates an instance of HealthChecker.
+func NewHealthChecker(
+	prober health.Prober,
+	shortLinkDao dao.ShortLinkDao,
+	remoteCache cache.RemoteCache,
+	locker lock.DistributedLocker,
+	clock clock.Clock,
+	config HealthCheckerConfig,
+) HealthChecker {
+	if config.Interval == 0 {
+		config.Interval = defaultHealthCheckInterval
+	}
+	if config.Workers == 0 {
+		config.Workers = defaultHealthCheckWorkers
+	}
+	if config.HostDelay == 0 {
+		config.HostDelay = defaultHealthHostDelay
+	}
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = defaultHealthCheckThreshold
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultHealthCheckBatchSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &healthCheckerImpl{
+		prober:       prober,
+		shortLinkDao: shortLinkDao,
+		remoteCache:  remoteCache,
+		locker:       locker,
+		clock:        clock,
+		config:       config,
+		lastProbes:   map[string]time.Time{},
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (c *healthCheckerImpl) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			select {
+			case <-c.clock.After(c.config.Interval):
+				c.checkAll()
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (c *healthCheckerImpl) Stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// checkAll checks all active short links in batches. The lock is held until it expires after an interval
+// instead of being released, so each round is run by one of replicas.
+func (c *healthCheckerImpl) checkAll() {
+	if _, err := c.locker.Lock(healthCheckLockKey, c.config.Interval, lock.DefaultRetryDelay, 0); err != nil {
+		zap.S().Debugf("skip health check, err: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.lastProbes = map[string]time.Time{}
+	c.mu.Unlock()
+
+	now := c.clock.Now()
+	var afterID uint64
+	for c.ctx.Err() == nil {
+		shortLinks, err := c.shortLinkDao.ListActive(afterID, now, c.config.BatchSize)
+		if err != nil {
+			zap.S().Warnf("fail to list short links for health check, err: %v", err)
+			return
+		}
+		if len(shortLinks) == 0 {
+			return
+		}
+		c.checkBatch(shortLinks)
+		afterID = shortLinks[len(shortLinks)-1].ID
+	}
+}
+
+// checkBatch probes short links by workers. Short links are grouped by host and each group is probed in order
+// by a worker, so a host is never probed concurrently.
+func (c *healthCheckerImpl) checkBatch(shortLinks []*dao.ShortLink) {
+	ids := make([]uint64, 0, len(shortLinks))
+	for _, shortLink := range shortLinks {
+		ids = append(ids, shortLink.ID)
+	}
+	healths, err := c.shortLinkDao.GetHealths(ids)
+	if err != nil {
+		zap.S().Warnf("fail to get health checks, err: %v", err)
+		return
+	}
+	previous := make(map[uint64]*dao.LinkHealth, len(healths))
+	for _, h := range healths {
+		previous[h.ShortLinkID] = h
+	}
+
+	var hosts []string
+	groups := map[string][]*dao.ShortLink{}
+	for _, shortLink := range shortLinks {
+		// destinations with placeholders depend on requests
+		if redirect.HasPlaceholders(shortLink.URL) {
+			continue
+		}
+		host := hostOf(shortLink.URL)
+		if _, ok := groups[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		groups[host] = append(groups[host], shortLink)
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				for _, shortLink := range groups[host] {
+					if c.ctx.Err() != nil {
+						break
+					}
+					c.check(host, shortLink, previous[shortLink.ID])
+				}
+			}
+		}()
+	}
+	for _, host := range hosts {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// check probes the destination of shortLink after HostDelay since the last probe of its host, and records the
+// result.
+func (c *healthCheckerImpl) check(host string, shortLink *dao.ShortLink, previous *dao.LinkHealth) {
+	c.mu.Lock()
+	last, ok := c.lastProbes[host]
+	c.mu.Unlock()
+	if wait := last.Add(c.config.HostDelay).Sub(c.clock.Now()); ok && wait > 0 {
+		select {
+		case <-c.clock.After(wait):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+	c.mu.Lock()
+	c.lastProbes[host] = c.clock.Now()
+	c.mu.Unlock()
+
+	result := c.prober.Probe(c.ctx, shortLink.URL)
+	// failures of stopping or blocked addresses do not show the destination is down
+	if c.ctx.Err() != nil || errors.Is(result.Err, health.ErrBlocked) {
+		return
+	}
+	c.record(shortLink, previous, result)
+}
+
+// record stores result of shortLink, and flags or unflags it broken when the result changes it.
+func (c *healthCheckerImpl) record(shortLink *dao.ShortLink, previous *dao.LinkHealth, result health.Result) {
+	logger := zap.S().With("shortLinkId", shortLink.ID)
+	now := c.clock.Now()
+
+	h := dao.LinkHealth{
+		ShortLinkID: shortLink.ID,
+		URL:         shortLink.URL,
+		StatusCode:  result.StatusCode,
+		LatencyMS:   int64(result.Latency / time.Millisecond),
+		CheckedAt:   now,
+	}
+	if result.Err != nil {
+		h.Error = truncate(result.Err.Error(), maxHealthErrorLength)
+	}
+	if previous != nil && previous.URL == shortLink.URL {
+		h.ConsecutiveFailures = previous.ConsecutiveFailures
+		h.BrokenSince = previous.BrokenSince
+	}
+
+	broken := false
+	if !result.Healthy() {
+		h.ConsecutiveFailures++
+		broken = h.ConsecutiveFailures >= c.config.FailureThreshold
+	} else {
+		h.ConsecutiveFailures = 0
+	}
+	if !broken {
+		h.BrokenSince = nil
+	} else if h.BrokenSince == nil {
+		h.BrokenSince = &now
+	}
+
+	if err := c.shortLinkDao.SaveHealth(&h); err != nil {
+		logger.Warnf("fail to save health check, err: %v", err)
+		return
+	}
+	if broken == shortLink.Broken {
+		return
+	}
+
+	updated, err := c.shortLinkDao.SetBroken(shortLink.ID, shortLink.URL, broken)
+	if err != nil {
+		logger.Warnf("fail to set broken, err: %v", err)
+		return
+	}
+	// the short link is deleted, updated to another destination or set by another replica
+	if !updated {
+		return
+	}
+	logger.Infof("short link broken: %t, status: %d, err: %v", broken, result.StatusCode, result.Err)
+	// redirect reads broken from cache to fall back
+	if err := c.remoteCache.Delete(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)); err != nil {
+		logger.Warnf("fail to delete cache of broken short link, err: %v", err)
+	}
+}
+
+// hostOf returns host of rawURL, or empty if it's invalid.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
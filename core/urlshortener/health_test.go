@@ -0,0 +1,166 @@
+package urlshortener
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	cachemocks "github.com/georgechang0117/url-shortener/base/cache/mocks"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	lockmocks "github.com/georgechang0117/url-shortener/base/lock/mocks"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+	"github.com/georgechang0117/url-shortener/core/health"
+	healthmocks "github.com/georgechang0117/url-shortener/core/health/mocks"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type healthCheckerTestSuite struct {
+	suite.Suite
+	impl             *healthCheckerImpl
+	clock            *fakeclock.FakeClock
+	mockProber       *healthmocks.Prober
+	mockShortLinkDao *daomocks.ShortLinkDao
+	mockRemoteCache  *cachemocks.RemoteCache
+	mockLocker       *lockmocks.DistributedLocker
+}
+
+func TestHealthCheckerTestSuite(t *testing.T) {
+	suite.Run(t, new(healthCheckerTestSuite))
+}
+
+func (s *healthCheckerTestSuite) SetupTest() {
+	s.clock = fakeclock.NewFakeClock(testNow)
+	s.mockProber = &healthmocks.Prober{}
+	s.mockShortLinkDao = &daomocks.ShortLinkDao{}
+	s.mockRemoteCache = &cachemocks.RemoteCache{}
+	s.mockLocker = &lockmocks.DistributedLocker{}
+	impl := NewHealthChecker(s.mockProber, s.mockShortLinkDao, s.mockRemoteCache, s.mockLocker, s.clock, HealthCheckerConfig{
+		Interval:         time.Hour,
+		Workers:          2,
+		HostDelay:        time.Second,
+		FailureThreshold: 2,
+		BatchSize:        10,
+	})
+	s.impl = impl.(*healthCheckerImpl)
+}
+
+func (s *healthCheckerTestSuite) TearDownTest() {
+	s.impl.Stop()
+}
+
+// expectRound expects a round listing shortLinks in a batch with their previous health checks.
+func (s *healthCheckerTestSuite) expectRound(shortLinks []*dao.ShortLink, previous []*dao.LinkHealth) {
+	var ids []uint64
+	for _, shortLink := range shortLinks {
+		ids = append(ids, shortLink.ID)
+	}
+	s.mockLocker.On("Lock", healthCheckLockKey, time.Hour, lock.DefaultRetryDelay, 0).Return(&lockmocks.Lock{}, nil).Once()
+	s.mockShortLinkDao.On("ListActive", uint64(0), testNow, 10).Return(shortLinks, nil).Once()
+	s.mockShortLinkDao.On("ListActive", ids[len(ids)-1], testNow, 10).Return([]*dao.ShortLink{}, nil).Once()
+	s.mockShortLinkDao.On("GetHealths", ids).Return(previous, nil).Once()
+}
+
+func (s *healthCheckerTestSuite) TestCheckAll() {
+	down := &dao.ShortLink{ID: 1, DomainID: 1, URLID: "down", URL: "https://down.example.com/"}
+	up := &dao.ShortLink{ID: 2, DomainID: 1, URLID: "up", URL: "https://up.example.com/", Broken: true}
+	placeholder := &dao.ShortLink{ID: 3, URL: "https://example.com/{path}"}
+	s.expectRound([]*dao.ShortLink{down, up, placeholder}, []*dao.LinkHealth{
+		{ShortLinkID: down.ID, URL: down.URL, ConsecutiveFailures: 1},
+		{ShortLinkID: up.ID, URL: up.URL, ConsecutiveFailures: 5},
+	})
+	s.mockProber.On("Probe", mock.Anything, down.URL).
+		Return(health.Result{StatusCode: http.StatusBadGateway, Latency: 30 * time.Millisecond}).
+		Once()
+	s.mockProber.On("Probe", mock.Anything, up.URL).Return(health.Result{StatusCode: http.StatusOK}).Once()
+
+	now := testNow
+	s.mockShortLinkDao.On("SaveHealth", &dao.LinkHealth{
+		ShortLinkID:         down.ID,
+		URL:                 down.URL,
+		StatusCode:          http.StatusBadGateway,
+		LatencyMS:           30,
+		ConsecutiveFailures: 2,
+		BrokenSince:         &now,
+		CheckedAt:           testNow,
+	}).Return(nil).Once()
+	s.mockShortLinkDao.On("SaveHealth", &dao.LinkHealth{
+		ShortLinkID: up.ID,
+		URL:         up.URL,
+		StatusCode:  http.StatusOK,
+		CheckedAt:   testNow,
+	}).Return(nil).Once()
+	s.mockShortLinkDao.On("SetBroken", down.ID, down.URL, true).Return(true, nil).Once()
+	s.mockShortLinkDao.On("SetBroken", up.ID, up.URL, false).Return(true, nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(1, "down")).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(1, "up")).Return(nil).Once()
+
+	s.impl.checkAll()
+	s.mockProber.AssertNumberOfCalls(s.T(), "Probe", 2)
+	s.mockShortLinkDao.AssertExpectations(s.T())
+	s.mockRemoteCache.AssertExpectations(s.T())
+}
+
+func (s *healthCheckerTestSuite) TestCheckURLChanged() {
+	shortLink := &dao.ShortLink{ID: 1, URL: "https://new.example.com/"}
+	s.expectRound([]*dao.ShortLink{shortLink}, []*dao.LinkHealth{
+		{ShortLinkID: shortLink.ID, URL: "https://old.example.com/", ConsecutiveFailures: 1},
+	})
+	s.mockProber.On("Probe", mock.Anything, shortLink.URL).Return(health.Result{Err: errors.New("connection refused")}).Once()
+	// failures of the old destination are not counted, so it's not broken yet
+	s.mockShortLinkDao.On("SaveHealth", mock.MatchedBy(func(h *dao.LinkHealth) bool {
+		return h.ConsecutiveFailures == 1 && h.BrokenSince == nil && h.Error == "connection refused"
+	})).Return(nil).Once()
+
+	s.impl.checkAll()
+	s.mockShortLinkDao.AssertExpectations(s.T())
+	s.mockShortLinkDao.AssertNotCalled(s.T(), "SetBroken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *healthCheckerTestSuite) TestCheckBlocked() {
+	shortLink := &dao.ShortLink{ID: 1, URL: "http://10.0.0.1/"}
+	s.expectRound([]*dao.ShortLink{shortLink}, nil)
+	s.mockProber.On("Probe", mock.Anything, shortLink.URL).Return(health.Result{Err: health.ErrBlocked}).Once()
+
+	s.impl.checkAll()
+	s.mockShortLinkDao.AssertNotCalled(s.T(), "SaveHealth", mock.Anything)
+}
+
+func (s *healthCheckerTestSuite) TestHostDelay() {
+	first := &dao.ShortLink{ID: 1, URL: "https://example.com/a"}
+	second := &dao.ShortLink{ID: 2, URL: "https://example.com/b"}
+	s.expectRound([]*dao.ShortLink{first, second}, nil)
+	probed := make(chan string, 2)
+	for _, shortLink := range []*dao.ShortLink{first, second} {
+		s.mockProber.On("Probe", mock.Anything, shortLink.URL).
+			Return(health.Result{StatusCode: http.StatusOK}).
+			Run(func(args mock.Arguments) { probed <- args.String(1) }).
+			Once()
+	}
+	s.mockShortLinkDao.On("SaveHealth", mock.Anything).Return(nil).Twice()
+
+	done := make(chan struct{})
+	go func() {
+		s.impl.checkAll()
+		close(done)
+	}()
+	s.Equal(first.URL, <-probed)
+	// the second probe of the host waits for HostDelay
+	s.clock.WaitForWatcherAndIncrement(time.Second)
+	s.Equal(second.URL, <-probed)
+	<-done
+}
+
+func (s *healthCheckerTestSuite) TestSkipLocked() {
+	s.mockLocker.On("Lock", healthCheckLockKey, time.Hour, lock.DefaultRetryDelay, 0).Return(nil, errors.New("lock timeout"))
+
+	s.impl.Start()
+	s.clock.WaitForWatcherAndIncrement(time.Hour)
+	// the next round is scheduled after the skipped one
+	s.clock.WaitForWatcherAndIncrement(time.Hour)
+	s.mockShortLinkDao.AssertNotCalled(s.T(), "ListActive", mock.Anything, mock.Anything, mock.Anything)
+}
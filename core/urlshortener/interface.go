@@ -1,13 +1,395 @@
 package urlshortener
 
 import (
+	"context"
+	"errors"
 	"time"
 
+	"github.com/georgechang0117/url-shortener/core/bulk"
 	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
 )
 
-// URLShortener defines interface of URL shortener operations.
+var (
+	// ErrDomainNotFound indicates the domain is not registered.
+	ErrDomainNotFound = errors.New("domain not found")
+	// ErrDomainNotAllowed indicates the owner is not allowed to use the domain.
+	ErrDomainNotAllowed = errors.New("domain not allowed")
+	// ErrOwnerNotFound indicates no owner matches the API key.
+	ErrOwnerNotFound = errors.New("owner not found")
+	// ErrInvalidRules indicates routing rules are invalid.
+	ErrInvalidRules = errors.New("invalid rules")
+	// ErrInvalidVariants indicates weighted variants are invalid.
+	ErrInvalidVariants = errors.New("invalid variants")
+	// ErrInvalidParams indicates parameters of the operation are invalid.
+	ErrInvalidParams = errors.New("invalid params")
+	// ErrOwnerRequired indicates the operation is not allowed for anonymous requests.
+	ErrOwnerRequired = errors.New("owner required")
+	// ErrNotFound indicates the short link does not exist or is not visible to the owner.
+	ErrNotFound = errors.New("short link not found")
+	// ErrExpired indicates the short link is expired.
+	ErrExpired = errors.New("short link expired")
+	// ErrDisabled indicates the short link is disabled by its owner.
+	ErrDisabled = errors.New("short link disabled")
+	// ErrDeleted indicates the short link is deleted, it could be restored by its owner.
+	ErrDeleted = errors.New("short link deleted")
+	// ErrConflict indicates the url_id is taken by another short link.
+	ErrConflict = errors.New("short link conflict")
+	// ErrInvalidURL indicates the destination is not an absolute http or https URL.
+	ErrInvalidURL = errors.New("invalid url")
+	// ErrUnavailable indicates cache, lock or db is unavailable, the operation could be retried later.
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrWebhookNotFound indicates the webhook or its delivery does not exist or is not visible to the owner.
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// MaxBatchUploadSize is max number of URLs uploaded by BatchUpload at a time.
+const MaxBatchUploadSize = 100
+
+const (
+	// ConflictSkip keeps existing short links with the same url_id on import.
+	ConflictSkip = "skip"
+	// ConflictOverwrite overwrites existing short links with the same url_id on import, which should be
+	// owned by the importer.
+	ConflictOverwrite = "overwrite"
+	// ConflictFail fails import if any short link with the same url_id exists.
+	ConflictFail = "fail"
+)
+
+const (
+	// SearchSubstring matches URL or title of short links containing the query.
+	SearchSubstring = "substring"
+	// SearchFullText matches URL or title of short links by full-text index, which falls back to substring if
+	// the database does not support it.
+	SearchFullText = "fulltext"
+)
+
+// UploadParams defines parameters of uploading a URL.
+type UploadParams struct {
+	// Owner is nil for anonymous upload, which can only use the default domain.
+	Owner *dao.Owner `validate:"-"`
+	// Domain is host of the short link domain, default domain is used if empty.
+	Domain string `validate:"omitempty,hostname_port|hostname"`
+	// URL is validated by validateURL instead of tags, which fails with ErrInvalidURL.
+	URL      string    `validate:"required"`
+	ExpireAt time.Time `validate:"required"`
+	// Preview renders preview page instead of redirecting.
+	Preview bool
+	// QueryMode defines how query parameters of request are merged into destination, see dao.QueryMode*.
+	QueryMode string `validate:"omitempty,oneof=override preserve"`
+	// UTM defines default UTM parameters added to destination.
+	UTM map[string]string `validate:"omitempty,dive,keys,oneof=utm_source utm_medium utm_campaign utm_term utm_content,endkeys,required"`
+	// Rules defines ordered routing rules, URL is the default if no rule matches.
+	Rules []rules.Rule `validate:"omitempty,max=20,dive"`
+	// Variants defines weighted destinations for A/B testing, used if no rule matches.
+	Variants []split.Variant `validate:"omitempty,max=10,dive"`
+	Title    string          `validate:"max=256"`
+	// Description is shown with the short link, it's not searched.
+	Description string `validate:"max=1024"`
+	// Tags are free-form labels for filtering short links, duplicates and surrounding spaces are removed. Tags
+	// can not contain comma, which separates them in CSV.
+	Tags []string `validate:"omitempty,max=20,dive,required,max=64,excludesall=0x2C"`
+	// FallbackURL is redirected to while URL is broken, it's validated like URL if not empty.
+	FallbackURL string
+}
+
+// UpdateParams defines parameters of updating a short link, nil fields are left unchanged.
+type UpdateParams struct {
+	// Owner should be the owner of the short link, anonymous short links can not be updated.
+	Owner *dao.Owner `validate:"-"`
+	// Domain is host of the short link domain, default domain is used if empty.
+	Domain    string     `validate:"omitempty,hostname_port|hostname"`
+	URLID     string     `validate:"required"`
+	URL       *string    `validate:"omitempty"`
+	ExpireAt  *time.Time `validate:"omitempty"`
+	Preview   *bool
+	QueryMode *string `validate:"omitempty,oneof='' override preserve"`
+	// UTM replaces all default UTM parameters, an empty map removes them.
+	UTM map[string]string `validate:"omitempty,dive,keys,oneof=utm_source utm_medium utm_campaign utm_term utm_content,endkeys,required"`
+	// Rules replaces all routing rules, an empty slice removes them.
+	Rules *[]rules.Rule `validate:"omitempty,max=20,dive"`
+	// Variants replaces all variants, an empty slice removes them.
+	Variants    *[]split.Variant `validate:"omitempty,max=10,dive"`
+	Title       *string          `validate:"omitempty,max=256"`
+	Description *string          `validate:"omitempty,max=1024"`
+	// Tags replaces all tags, an empty slice removes them.
+	Tags *[]string `validate:"omitempty,max=20,dive,required,max=64,excludesall=0x2C"`
+	// FallbackURL replaces the fallback URL, an empty string removes it.
+	FallbackURL *string
+	// Status disables or enables the short link, deleted ones are restored by Restore instead.
+	Status *string `validate:"omitempty,oneof=active disabled"`
+}
+
+// ExpiryParams defines parameters of changing expiry of a short link, exactly one of ExpireAt, ExtendBy and Never
+// should be given.
+type ExpiryParams struct {
+	// Owner should be the owner of the short link, anonymous short links can not be changed.
+	Owner *dao.Owner `validate:"-"`
+	// Domain is host of the short link domain, default domain is used if empty.
+	Domain string `validate:"omitempty,hostname_port|hostname"`
+	URLID  string `validate:"required"`
+	// ExpireAt extends or shortens the expiry to the time, which should be greater than now.
+	ExpireAt *time.Time
+	// ExtendBy extends the expiry by the duration, from now if the short link is expired.
+	ExtendBy time.Duration `validate:"min=0"`
+	// Never removes the expiry, see dao.NeverExpire.
+	Never bool
+}
+
+// ExpiryPolicy limits expiry of short links set by owners.
+type ExpiryPolicy struct {
+	// MaxLifetime limits expiry to creation time plus MaxLifetime, and forbids removing the expiry. It's unlimited
+	// if 0.
+	MaxLifetime time.Duration
+	// GracePeriod is how long expired short links could be revived by a new expiry, they are never revived if 0.
+	GracePeriod time.Duration
+}
+
+// ListParams defines parameters of listing short links of an owner.
+type ListParams struct {
+	Owner *dao.Owner `validate:"-"`
+	// Cursor is returned with the previous page, empty for the first page.
+	Cursor string
+	// Limit is max number of short links in a page, defaultListLimit is used if zero.
+	Limit int `validate:"min=0,max=100"`
+	// Tags lists short links having all of them.
+	Tags []string `validate:"omitempty,max=10,dive,required,max=64"`
+	// CreatedFrom and CreatedTo list short links created in [CreatedFrom, CreatedTo), zero values are not
+	// bounded. So are ExpireFrom and ExpireTo.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	ExpireFrom  time.Time
+	ExpireTo    time.Time
+	// Query matches URL or title of short links by Search, which is SearchSubstring if empty.
+	Query  string `validate:"max=256"`
+	Search string `validate:"omitempty,oneof=substring fulltext"`
+	// Broken lists short links whose destinations are broken only.
+	Broken bool
+	// Status lists short links in the status only, see dao.Status*. Deleted short links are excluded if empty.
+	Status string `validate:"omitempty,oneof=active disabled deleted"`
+}
+
+// ImportParams defines parameters of importing short links of an owner.
+type ImportParams struct {
+	Owner  *dao.Owner
+	Reader bulk.Reader
+	// Conflict defines how records with url_id of existing short links are handled, see Conflict*.
+	Conflict string
+	// DryRun validates records and checks conflicts without writing.
+	DryRun bool
+}
+
+// ImportError defines an invalid or conflicting record in import file.
+type ImportError struct {
+	Line    int
+	ID      string
+	Message string
+}
+
+// ImportResult defines result of importing short links.
+type ImportResult struct {
+	Total   int
+	Created int
+	Updated int
+	Skipped int
+	// Failed is number of invalid or conflicting records, nothing is imported if it's not zero.
+	Failed int
+	// Errors defines the first maxImportErrors failed records.
+	Errors []ImportError
+	// Committed indicates short links are written to db, which is false for dry run or if any record fails.
+	Committed bool
+}
+
+// WebhookParams defines parameters of creating a webhook.
+type WebhookParams struct {
+	Owner *dao.Owner `validate:"-"`
+	// URL is validated like destinations of short links, which fails with ErrInvalidURL.
+	URL string `validate:"required,max=512"`
+	// Events are types of events subscribed, see dao.EventTypes.
+	Events []string `validate:"required,dive,oneof=link.created link.updated link.expired link.deleted link.milestone"`
+	// Secret signs deliveries, a random one is generated if empty.
+	Secret string `validate:"omitempty,min=16,max=64"`
+}
+
+// ListDeliveriesParams defines parameters of listing deliveries of a webhook.
+type ListDeliveriesParams struct {
+	Owner     *dao.Owner `validate:"-"`
+	WebhookID uint64
+	// Status lists deliveries in the status only, see dao.Delivery*.
+	Status string `validate:"omitempty,oneof=pending delivered dead"`
+	// Cursor is returned with the previous page, empty for the first page.
+	Cursor string
+	// Limit is max number of deliveries in a page, defaultListLimit is used if zero.
+	Limit int `validate:"min=0,max=100"`
+}
+
+// ListAuditEventsParams defines parameters of listing audit events of all owners, zero values are not
+// filtered.
+type ListAuditEventsParams struct {
+	// OwnerID is the owner making changes.
+	OwnerID uint64
+	Domain  string
+	URLID   string
+	Action  string `validate:"omitempty,oneof=create update delete restore purge"`
+	// RequestID lists changes made by the request only.
+	RequestID string
+	// CreatedFrom is inclusive and CreatedTo is exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Cursor is returned with the previous page, empty for the first page.
+	Cursor string
+	// Limit is max number of audit events in a page, defaultListLimit is used if zero.
+	Limit int `validate:"min=0,max=100"`
+}
+
+// MetadataWorkerConfig defines config of MetadataWorker, zero values use defaults.
+type MetadataWorkerConfig struct {
+	// Workers is number of concurrent fetches.
+	Workers int
+	// QueueSize limits short links waiting to be fetched, new ones are dropped if it's full.
+	QueueSize int
+	// MaxAttempts limits fetches of a short link including retries.
+	MaxAttempts int
+	// Backoff is delay before the first retry, which is doubled for each retry.
+	Backoff time.Duration
+}
+
+// MetadataWorker defines interface of fetching title, description, image and favicon of destinations in
+// background, which are stored on short links.
+type MetadataWorker interface {
+	// Enqueue schedules fetching metadata of shortLink without blocking, it's dropped if the queue is full.
+	Enqueue(shortLink *dao.ShortLink)
+	Start()
+	// Stop cancels fetches in progress and waits for workers, short links in queue are dropped.
+	Stop()
+}
+
+// HealthCheckerConfig defines config of HealthChecker, zero values use defaults.
+type HealthCheckerConfig struct {
+	// Interval is delay between rounds of checking all active short links.
+	Interval time.Duration
+	// Workers is number of concurrent probes.
+	Workers int
+	// HostDelay is min delay between probes of the same host, a host is never probed concurrently.
+	HostDelay time.Duration
+	// FailureThreshold is number of consecutive failures before a short link is flagged broken.
+	FailureThreshold int
+	// BatchSize is number of short links read from db at a time.
+	BatchSize int
+}
+
+// HealthChecker defines interface of periodically probing destinations of active short links, which flags
+// short links broken after consecutive failures and unflags them once they're up again.
+type HealthChecker interface {
+	Start()
+	// Stop cancels the round in progress and waits for it.
+	Stop()
+}
+
+// WebhookDispatcherConfig defines config of WebhookDispatcher, zero values use defaults.
+type WebhookDispatcherConfig struct {
+	// PollInterval is delay between rounds of delivering due events.
+	PollInterval time.Duration
+	// BatchSize is max number of events delivered in a round.
+	BatchSize int
+	// Workers is number of concurrent deliveries.
+	Workers int
+	// MaxAttempts limits deliveries of an event, it's dead after all of them fail.
+	MaxAttempts int
+	// Backoff is delay before the first retry, which is doubled for each retry.
+	Backoff time.Duration
+}
+
+// WebhookDispatcher defines interface of delivering events in the outbox to webhooks in background, and raising
+// events of short links expired. Events are delivered at least once and not in order.
+type WebhookDispatcher interface {
+	Start()
+	// Stop cancels the round in progress and waits for it, events being delivered are retried later.
+	Stop()
+}
+
+// CacheWarmerConfig defines config of CacheWarmer, zero values use defaults.
+type CacheWarmerConfig struct {
+	// MostClicked is number of most clicked short links preloaded, none if 0.
+	MostClicked int
+	// ClickWindow limits most clicked short links to those clicked in it.
+	ClickWindow time.Duration
+	// Recent is number of recently created short links preloaded, none if 0.
+	Recent int
+	// CheckInterval is delay between checks of whether the cache is flushed.
+	CheckInterval time.Duration
+}
+
+// CacheWarmer defines interface of preloading most clicked and recently created short links into cache in
+// background, once it starts and whenever the cache is found flushed.
+type CacheWarmer interface {
+	Start()
+	// Stop cancels warming in progress and waits for it.
+	Stop()
+}
+
+// URLShortener defines interface of URL shortener operations. Upload, BatchUpload, Update, Delete, Restore, Purge
+// and Import record changes in the audit log with the source of the request in ctx, see NewAuditContext.
 type URLShortener interface {
-	Upload(url string, expireAt time.Time) (*dao.ShortLink, error)
-	Load(urlID string) (*dao.ShortLink, error)
+	Upload(ctx context.Context, params UploadParams) (*dao.ShortLink, error)
+	// BatchUpload uploads all URLs in a single transaction, nothing is uploaded if any of params is invalid. It
+	// returns ErrInvalidParams if there are more than MaxBatchUploadSize params.
+	BatchUpload(ctx context.Context, params []UploadParams) ([]*dao.ShortLink, error)
+	// Load returns the short link of urlID in domain of host, default domain is used if host is empty. It returns
+	// ErrNotFound if the short link does not exist, expired, disabled and deleted ones are returned for managing
+	// them. Logs are written by the logger in ctx, see logging.FromContext.
+	Load(ctx context.Context, host, urlID string) (*dao.ShortLink, error)
+	// LoadActive returns the short link like Load for serving it, and ErrDeleted, ErrDisabled or ErrExpired if
+	// it's not active.
+	LoadActive(ctx context.Context, host, urlID string) (*dao.ShortLink, error)
+	// OwnerFallbackURL returns fallback URL of the owner of short link urlID in host, where browsers are redirected
+	// to while the short link is not active, or empty if the short link is anonymous or its owner has none.
+	OwnerFallbackURL(ctx context.Context, host, urlID string) (string, error)
+	// Update updates a short link owned by owner, which returns ErrDeleted if it's deleted. A new expiry is
+	// limited by ExpiryPolicy like SetExpiry.
+	Update(ctx context.Context, params UpdateParams) (*dao.ShortLink, error)
+	// SetExpiry extends, shortens or removes the expiry of a short link owned by owner within ExpiryPolicy, and
+	// refreshes its cache entry. Expired short links are revived within the grace period, and ErrExpired is returned
+	// after that. It returns ErrDeleted if the short link is deleted.
+	SetExpiry(ctx context.Context, params ExpiryParams) (*dao.ShortLink, error)
+	// Delete soft deletes a short link owned by owner, which could be restored until it's purged.
+	Delete(ctx context.Context, owner *dao.Owner, host, urlID string) error
+	// Restore makes a deleted short link owned by owner active again, others are returned unchanged.
+	Restore(ctx context.Context, owner *dao.Owner, host, urlID string) (*dao.ShortLink, error)
+	// Purge deletes a short link owned by owner permanently, whether it's deleted or not.
+	Purge(ctx context.Context, owner *dao.Owner, host, urlID string) error
+	// List returns a page of short links owned by owner newest first, and cursor of the next page which is
+	// empty if it's the last page.
+	List(params ListParams) ([]*dao.ShortLink, string, error)
+	// Import imports short links with their url_ids in a transaction, all records are imported or none of them.
+	// Records are validated and buffered in a temp file before the transaction begins.
+	Import(ctx context.Context, params ImportParams) (*ImportResult, error)
+	// Export writes all short links of owner to w from a consistent snapshot, w is flushed after each batch.
+	Export(owner *dao.Owner, w bulk.Writer) error
+	// Health returns the latest health check of the destination of a short link owned by owner, which is zero
+	// except ShortLinkID if the destination is not checked yet.
+	Health(owner *dao.Owner, host, urlID string) (*dao.LinkHealth, error)
+	// CreateWebhook subscribes the owner to events of its short links.
+	CreateWebhook(params WebhookParams) (*dao.Webhook, error)
+	ListWebhooks(owner *dao.Owner) ([]*dao.Webhook, error)
+	// DeleteWebhook deletes a webhook owned by owner, its deliveries not delivered yet are dropped.
+	DeleteWebhook(owner *dao.Owner, webhookID uint64) error
+	// ListDeliveries returns a page of events of a webhook owned by owner newest first, and cursor of the next
+	// page which is empty if it's the last page.
+	ListDeliveries(params ListDeliveriesParams) ([]*dao.OutboxEvent, string, error)
+	// ReplayDelivery schedules delivering an event of a webhook owned by owner again now, including delivered
+	// and dead ones.
+	ReplayDelivery(owner *dao.Owner, webhookID, eventID uint64) (*dao.OutboxEvent, error)
+	// ListAuditEvents returns a page of audit events of all owners newest first, and cursor of the next page
+	// which is empty if it's the last page. It's for administrators, callers should authorize them.
+	ListAuditEvents(params ListAuditEventsParams) ([]*dao.AuditEvent, string, error)
+	// ListBlockedIDs returns IDs blocked by administrators ordered by ID, callers should authorize them.
+	ListBlockedIDs() ([]*dao.BlockedID, error)
+	// BlockID blocks urlID from being generated or imported in any domain, short links already using it are left
+	// unchanged. Blocking a blocked ID returns the existing one. Callers should authorize administrators.
+	BlockID(urlID, reason string) (*dao.BlockedID, error)
+	// UnblockID unblocks urlID, callers should authorize administrators.
+	UnblockID(urlID string) error
+	Authenticate(apiKey string) (*dao.Owner, error)
 }
@@ -0,0 +1,184 @@
+package urlshortener
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/georgechang0117/url-shortener/base/cache"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/metadata"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMetadataWorkers     = 4
+	defaultMetadataQueueSize   = 1000
+	defaultMetadataMaxAttempts = 3
+	defaultMetadataBackoff     = 5 * time.Second
+
+	// limits of columns, fetched values longer than them are truncated or dropped
+	maxTitleLength       = 256
+	maxDescriptionLength = 1024
+	maxMetadataURLLength = 512
+)
+
+type metadataJob struct {
+	shortLinkID uint64
+	domainID    uint64
+	urlID       string
+	url         string
+	attempt     int
+}
+
+type metadataWorkerImpl struct {
+	fetcher      metadata.Fetcher
+	shortLinkDao dao.ShortLinkDao
+	remoteCache  cache.RemoteCache
+	clock        clock.Clock
+	config       MetadataWorkerConfig
+
+	jobs   chan metadataJob
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMetadataWorker creates an instance of MetadataWorker.
+func NewMetadataWorker(
+	fetcher metadata.Fetcher,
+	shortLinkDao dao.ShortLinkDao,
+	remoteCache cache.RemoteCache,
+	clock clock.Clock,
+	config MetadataWorkerConfig,
+) MetadataWorker {
+	if config.Workers == 0 {
+		config.Workers = defaultMetadataWorkers
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultMetadataQueueSize
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = defaultMetadataMaxAttempts
+	}
+	if config.Backoff == 0 {
+		config.Backoff = defaultMetadataBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &metadataWorkerImpl{
+		fetcher:      fetcher,
+		shortLinkDao: shortLinkDao,
+		remoteCache:  remoteCache,
+		clock:        clock,
+		config:       config,
+		jobs:         make(chan metadataJob, config.QueueSize),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (w *metadataWorkerImpl) Enqueue(shortLink *dao.ShortLink) {
+	w.enqueue(metadataJob{
+		shortLinkID: shortLink.ID,
+		domainID:    shortLink.DomainID,
+		urlID:       shortLink.URLID,
+		url:         shortLink.URL,
+	})
+}
+
+func (w *metadataWorkerImpl) enqueue(job metadataJob) {
+	select {
+	case w.jobs <- job:
+	default:
+		zap.S().Warnf("metadata queue is full, drop short_link_id: %d", job.shortLinkID)
+	}
+}
+
+func (w *metadataWorkerImpl) Start() {
+	for i := 0; i < w.config.Workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for {
+				select {
+				case job := <-w.jobs:
+					w.process(job)
+				case <-w.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (w *metadataWorkerImpl) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// process fetches metadata of job and stores it, retryable failures are enqueued again after backoff.
+func (w *metadataWorkerImpl) process(job metadataJob) {
+	logger := zap.S().With("shortLinkId", job.shortLinkID)
+
+	fetched, err := w.fetcher.Fetch(w.ctx, job.url)
+	if err != nil {
+		job.attempt++
+		if !metadata.IsRetryable(err) || job.attempt >= w.config.MaxAttempts || w.ctx.Err() != nil {
+			logger.Infof("fail to fetch metadata, attempts: %d, err: %v", job.attempt, err)
+			return
+		}
+		w.retry(job, w.config.Backoff<<(job.attempt-1))
+		return
+	}
+
+	updated, err := w.shortLinkDao.UpdateMetadata(job.shortLinkID, job.url, dao.Metadata{
+		Title:       truncate(fetched.Title, maxTitleLength),
+		Description: truncate(fetched.Description, maxDescriptionLength),
+		ImageURL:    limitURL(fetched.Image),
+		FaviconURL:  limitURL(fetched.Favicon),
+		FetchedAt:   w.clock.Now(),
+	})
+	if err != nil {
+		logger.Warnf("fail to store metadata, err: %v", err)
+		return
+	}
+	// the short link is deleted or its destination is changed, which is fetched by another job
+	if !updated {
+		return
+	}
+	if err := w.remoteCache.Delete(shortLinkCacheKey(job.domainID, job.urlID)); err != nil {
+		logger.Warnf("fail to delete cache of metadata, err: %v", err)
+	}
+}
+
+func (w *metadataWorkerImpl) retry(job metadataJob, backoff time.Duration) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		select {
+		case <-w.clock.After(backoff):
+			w.enqueue(job)
+		case <-w.ctx.Done():
+		}
+	}()
+}
+
+// truncate returns s in at most n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// limitURL returns empty for URLs not fitting in columns, truncated URLs would be broken.
+func limitURL(u string) string {
+	if len(u) > maxMetadataURLLength {
+		return ""
+	}
+	return u
+}
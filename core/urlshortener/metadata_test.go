@@ -0,0 +1,149 @@
+package urlshortener
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cachemocks "github.com/georgechang0117/url-shortener/base/cache/mocks"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+	"github.com/georgechang0117/url-shortener/core/metadata"
+	metadatamocks "github.com/georgechang0117/url-shortener/core/metadata/mocks"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingMetadataWorker records enqueued short links, mocks of this package can not be used in its tests.
+type recordingMetadataWorker struct {
+	mu       sync.Mutex
+	enqueued []*dao.ShortLink
+}
+
+func (w *recordingMetadataWorker) Enqueue(shortLink *dao.ShortLink) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enqueued = append(w.enqueued, shortLink)
+}
+
+func (w *recordingMetadataWorker) Start() {}
+
+func (w *recordingMetadataWorker) Stop() {}
+
+func (w *recordingMetadataWorker) last() *dao.ShortLink {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.enqueued) == 0 {
+		return nil
+	}
+	return w.enqueued[len(w.enqueued)-1]
+}
+
+var testShortLink = dao.ShortLink{ID: 10, DomainID: testDefaultDomain.ID, URLID: testURLID, URL: testUploadURL}
+
+type metadataWorkerTestSuite struct {
+	suite.Suite
+	impl             *metadataWorkerImpl
+	clock            *fakeclock.FakeClock
+	mockFetcher      *metadatamocks.Fetcher
+	mockShortLinkDao *daomocks.ShortLinkDao
+	mockRemoteCache  *cachemocks.RemoteCache
+}
+
+func TestMetadataWorkerTestSuite(t *testing.T) {
+	suite.Run(t, new(metadataWorkerTestSuite))
+}
+
+func (s *metadataWorkerTestSuite) SetupTest() {
+	s.clock = fakeclock.NewFakeClock(testNow)
+	s.mockFetcher = &metadatamocks.Fetcher{}
+	s.mockShortLinkDao = &daomocks.ShortLinkDao{}
+	s.mockRemoteCache = &cachemocks.RemoteCache{}
+	impl := NewMetadataWorker(s.mockFetcher, s.mockShortLinkDao, s.mockRemoteCache, s.clock, MetadataWorkerConfig{
+		Workers:     1,
+		QueueSize:   1,
+		MaxAttempts: 2,
+		Backoff:     time.Second,
+	})
+	s.impl = impl.(*metadataWorkerImpl)
+}
+
+func (s *metadataWorkerTestSuite) TearDownTest() {
+	s.impl.Stop()
+}
+
+// expectStored expects metadata stored and returns a channel closed after cache of the short link is deleted.
+func (s *metadataWorkerTestSuite) expectStored(expected dao.Metadata) chan struct{} {
+	done := make(chan struct{})
+	s.mockShortLinkDao.On("UpdateMetadata", testShortLink.ID, testShortLink.URL, expected).Return(true, nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testShortLink.DomainID, testShortLink.URLID)).
+		Return(nil).
+		Run(func(mock.Arguments) { close(done) }).
+		Once()
+	return done
+}
+
+func (s *metadataWorkerTestSuite) wait(done chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.FailNow("metadata is not stored")
+	}
+}
+
+func (s *metadataWorkerTestSuite) TestProcess() {
+	s.mockFetcher.On("Fetch", mock.Anything, testShortLink.URL).Return(&metadata.Metadata{
+		Title:   strings.Repeat("t", maxTitleLength+1),
+		Image:   "https://example.com/" + strings.Repeat("i", maxMetadataURLLength),
+		Favicon: "https://example.com/favicon.ico",
+	}, nil).Once()
+	done := s.expectStored(dao.Metadata{
+		Title:      strings.Repeat("t", maxTitleLength),
+		FaviconURL: "https://example.com/favicon.ico",
+		FetchedAt:  testNow,
+	})
+
+	s.impl.Start()
+	s.impl.Enqueue(&testShortLink)
+	s.wait(done)
+}
+
+func (s *metadataWorkerTestSuite) TestRetry() {
+	s.mockFetcher.On("Fetch", mock.Anything, testShortLink.URL).
+		Return(nil, &metadata.StatusError{Code: http.StatusServiceUnavailable}).
+		Once()
+	s.mockFetcher.On("Fetch", mock.Anything, testShortLink.URL).Return(&metadata.Metadata{Title: "Jobs"}, nil).Once()
+	done := s.expectStored(dao.Metadata{Title: "Jobs", FetchedAt: testNow.Add(time.Second)})
+
+	s.impl.Start()
+	s.impl.Enqueue(&testShortLink)
+	s.clock.WaitForWatcherAndIncrement(time.Second)
+	s.wait(done)
+}
+
+func (s *metadataWorkerTestSuite) TestNotRetryable() {
+	fetched := make(chan struct{})
+	s.mockFetcher.On("Fetch", mock.Anything, testShortLink.URL).
+		Return(nil, metadata.ErrBlocked).
+		Run(func(mock.Arguments) { close(fetched) }).
+		Once()
+
+	s.impl.Start()
+	s.impl.Enqueue(&testShortLink)
+	s.wait(fetched)
+	s.impl.Stop()
+	s.Zero(s.clock.WatcherCount())
+	s.mockShortLinkDao.AssertNotCalled(s.T(), "UpdateMetadata", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *metadataWorkerTestSuite) TestQueueFull() {
+	// workers are not started, the second short link is dropped
+	s.impl.Enqueue(&testShortLink)
+	s.impl.Enqueue(&dao.ShortLink{ID: 11})
+	s.Len(s.impl.jobs, 1)
+	s.Equal(testShortLink.ID, (<-s.impl.jobs).shortLinkID)
+}
@@ -3,10 +3,12 @@
 package mocks
 
 import (
+	bulk "github.com/georgechang0117/url-shortener/core/bulk"
 	dao "github.com/georgechang0117/url-shortener/core/dao"
+	urlshortener "github.com/georgechang0117/url-shortener/core/urlshortener"
 	mock "github.com/stretchr/testify/mock"
 
-	time "time"
+	context "context"
 )
 
 // URLShortener is an autogenerated mock type for the URLShortener type
@@ -14,13 +16,493 @@ type URLShortener struct {
 	mock.Mock
 }
 
-// Load provides a mock function with given fields: urlID
-func (_m *URLShortener) Load(urlID string) (*dao.ShortLink, error) {
-	ret := _m.Called(urlID)
+// Authenticate provides a mock function with given fields: apiKey
+func (_m *URLShortener) Authenticate(apiKey string) (*dao.Owner, error) {
+	ret := _m.Called(apiKey)
+
+	var r0 *dao.Owner
+	if rf, ok := ret.Get(0).(func(string) *dao.Owner); ok {
+		r0 = rf(apiKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.Owner)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(apiKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchUpload provides a mock function with given fields: ctx, params
+func (_m *URLShortener) BatchUpload(ctx context.Context, params []urlshortener.UploadParams) ([]*dao.ShortLink, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(context.Context, []urlshortener.UploadParams) []*dao.ShortLink); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []urlshortener.UploadParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BlockID provides a mock function with given fields: urlID, reason
+func (_m *URLShortener) BlockID(urlID string, reason string) (*dao.BlockedID, error) {
+	ret := _m.Called(urlID, reason)
+
+	var r0 *dao.BlockedID
+	if rf, ok := ret.Get(0).(func(string, string) *dao.BlockedID); ok {
+		r0 = rf(urlID, reason)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.BlockedID)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(urlID, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateWebhook provides a mock function with given fields: params
+func (_m *URLShortener) CreateWebhook(params urlshortener.WebhookParams) (*dao.Webhook, error) {
+	ret := _m.Called(params)
+
+	var r0 *dao.Webhook
+	if rf, ok := ret.Get(0).(func(urlshortener.WebhookParams) *dao.Webhook); ok {
+		r0 = rf(params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(urlshortener.WebhookParams) error); ok {
+		r1 = rf(params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, owner, host, urlID
+func (_m *URLShortener) Delete(ctx context.Context, owner *dao.Owner, host string, urlID string) error {
+	ret := _m.Called(ctx, owner, host, urlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dao.Owner, string, string) error); ok {
+		r0 = rf(ctx, owner, host, urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteWebhook provides a mock function with given fields: owner, webhookID
+func (_m *URLShortener) DeleteWebhook(owner *dao.Owner, webhookID uint64) error {
+	ret := _m.Called(owner, webhookID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.Owner, uint64) error); ok {
+		r0 = rf(owner, webhookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Export provides a mock function with given fields: owner, w
+func (_m *URLShortener) Export(owner *dao.Owner, w bulk.Writer) error {
+	ret := _m.Called(owner, w)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dao.Owner, bulk.Writer) error); ok {
+		r0 = rf(owner, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Health provides a mock function with given fields: owner, host, urlID
+func (_m *URLShortener) Health(owner *dao.Owner, host string, urlID string) (*dao.LinkHealth, error) {
+	ret := _m.Called(owner, host, urlID)
+
+	var r0 *dao.LinkHealth
+	if rf, ok := ret.Get(0).(func(*dao.Owner, string, string) *dao.LinkHealth); ok {
+		r0 = rf(owner, host, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.LinkHealth)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dao.Owner, string, string) error); ok {
+		r1 = rf(owner, host, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Import provides a mock function with given fields: ctx, params
+func (_m *URLShortener) Import(ctx context.Context, params urlshortener.ImportParams) (*urlshortener.ImportResult, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *urlshortener.ImportResult
+	if rf, ok := ret.Get(0).(func(context.Context, urlshortener.ImportParams) *urlshortener.ImportResult); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*urlshortener.ImportResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, urlshortener.ImportParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: params
+func (_m *URLShortener) List(params urlshortener.ListParams) ([]*dao.ShortLink, string, error) {
+	ret := _m.Called(params)
+
+	var r0 []*dao.ShortLink
+	if rf, ok := ret.Get(0).(func(urlshortener.ListParams) []*dao.ShortLink); ok {
+		r0 = rf(params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.ShortLink)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(urlshortener.ListParams) string); ok {
+		r1 = rf(params)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(urlshortener.ListParams) error); ok {
+		r2 = rf(params)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListAuditEvents provides a mock function with given fields: params
+func (_m *URLShortener) ListAuditEvents(params urlshortener.ListAuditEventsParams) ([]*dao.AuditEvent, string, error) {
+	ret := _m.Called(params)
+
+	var r0 []*dao.AuditEvent
+	if rf, ok := ret.Get(0).(func(urlshortener.ListAuditEventsParams) []*dao.AuditEvent); ok {
+		r0 = rf(params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.AuditEvent)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(urlshortener.ListAuditEventsParams) string); ok {
+		r1 = rf(params)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(urlshortener.ListAuditEventsParams) error); ok {
+		r2 = rf(params)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListBlockedIDs provides a mock function with given fields:
+func (_m *URLShortener) ListBlockedIDs() ([]*dao.BlockedID, error) {
+	ret := _m.Called()
+
+	var r0 []*dao.BlockedID
+	if rf, ok := ret.Get(0).(func() []*dao.BlockedID); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.BlockedID)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeliveries provides a mock function with given fields: params
+func (_m *URLShortener) ListDeliveries(params urlshortener.ListDeliveriesParams) ([]*dao.OutboxEvent, string, error) {
+	ret := _m.Called(params)
+
+	var r0 []*dao.OutboxEvent
+	if rf, ok := ret.Get(0).(func(urlshortener.ListDeliveriesParams) []*dao.OutboxEvent); ok {
+		r0 = rf(params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.OutboxEvent)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(urlshortener.ListDeliveriesParams) string); ok {
+		r1 = rf(params)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(urlshortener.ListDeliveriesParams) error); ok {
+		r2 = rf(params)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListWebhooks provides a mock function with given fields: owner
+func (_m *URLShortener) ListWebhooks(owner *dao.Owner) ([]*dao.Webhook, error) {
+	ret := _m.Called(owner)
+
+	var r0 []*dao.Webhook
+	if rf, ok := ret.Get(0).(func(*dao.Owner) []*dao.Webhook); ok {
+		r0 = rf(owner)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*dao.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dao.Owner) error); ok {
+		r1 = rf(owner)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Load provides a mock function with given fields: ctx, host, urlID
+func (_m *URLShortener) Load(ctx context.Context, host string, urlID string) (*dao.ShortLink, error) {
+	ret := _m.Called(ctx, host, urlID)
 
 	var r0 *dao.ShortLink
-	if rf, ok := ret.Get(0).(func(string) *dao.ShortLink); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.ShortLink); ok {
+		r0 = rf(ctx, host, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, host, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoadActive provides a mock function with given fields: ctx, host, urlID
+func (_m *URLShortener) LoadActive(ctx context.Context, host string, urlID string) (*dao.ShortLink, error) {
+	ret := _m.Called(ctx, host, urlID)
+
+	var r0 *dao.ShortLink
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.ShortLink); ok {
+		r0 = rf(ctx, host, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, host, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OwnerFallbackURL provides a mock function with given fields: ctx, host, urlID
+func (_m *URLShortener) OwnerFallbackURL(ctx context.Context, host string, urlID string) (string, error) {
+	ret := _m.Called(ctx, host, urlID)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, host, urlID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, host, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: ctx, owner, host, urlID
+func (_m *URLShortener) Purge(ctx context.Context, owner *dao.Owner, host string, urlID string) error {
+	ret := _m.Called(ctx, owner, host, urlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dao.Owner, string, string) error); ok {
+		r0 = rf(ctx, owner, host, urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplayDelivery provides a mock function with given fields: owner, webhookID, eventID
+func (_m *URLShortener) ReplayDelivery(owner *dao.Owner, webhookID uint64, eventID uint64) (*dao.OutboxEvent, error) {
+	ret := _m.Called(owner, webhookID, eventID)
+
+	var r0 *dao.OutboxEvent
+	if rf, ok := ret.Get(0).(func(*dao.Owner, uint64, uint64) *dao.OutboxEvent); ok {
+		r0 = rf(owner, webhookID, eventID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.OutboxEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dao.Owner, uint64, uint64) error); ok {
+		r1 = rf(owner, webhookID, eventID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: ctx, owner, host, urlID
+func (_m *URLShortener) Restore(ctx context.Context, owner *dao.Owner, host string, urlID string) (*dao.ShortLink, error) {
+	ret := _m.Called(ctx, owner, host, urlID)
+
+	var r0 *dao.ShortLink
+	if rf, ok := ret.Get(0).(func(context.Context, *dao.Owner, string, string) *dao.ShortLink); ok {
+		r0 = rf(ctx, owner, host, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *dao.Owner, string, string) error); ok {
+		r1 = rf(ctx, owner, host, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetExpiry provides a mock function with given fields: ctx, params
+func (_m *URLShortener) SetExpiry(ctx context.Context, params urlshortener.ExpiryParams) (*dao.ShortLink, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *dao.ShortLink
+	if rf, ok := ret.Get(0).(func(context.Context, urlshortener.ExpiryParams) *dao.ShortLink); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.ShortLink)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, urlshortener.ExpiryParams) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UnblockID provides a mock function with given fields: urlID
+func (_m *URLShortener) UnblockID(urlID string) error {
+	ret := _m.Called(urlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
 		r0 = rf(urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, params
+func (_m *URLShortener) Update(ctx context.Context, params urlshortener.UpdateParams) (*dao.ShortLink, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *dao.ShortLink
+	if rf, ok := ret.Get(0).(func(context.Context, urlshortener.UpdateParams) *dao.ShortLink); ok {
+		r0 = rf(ctx, params)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*dao.ShortLink)
@@ -28,8 +510,8 @@ func (_m *URLShortener) Load(urlID string) (*dao.ShortLink, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(urlID)
+	if rf, ok := ret.Get(1).(func(context.Context, urlshortener.UpdateParams) error); ok {
+		r1 = rf(ctx, params)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -37,13 +519,13 @@ func (_m *URLShortener) Load(urlID string) (*dao.ShortLink, error) {
 	return r0, r1
 }
 
-// Upload provides a mock function with given fields: url, expireAt
-func (_m *URLShortener) Upload(url string, expireAt time.Time) (*dao.ShortLink, error) {
-	ret := _m.Called(url, expireAt)
+// Upload provides a mock function with given fields: ctx, params
+func (_m *URLShortener) Upload(ctx context.Context, params urlshortener.UploadParams) (*dao.ShortLink, error) {
+	ret := _m.Called(ctx, params)
 
 	var r0 *dao.ShortLink
-	if rf, ok := ret.Get(0).(func(string, time.Time) *dao.ShortLink); ok {
-		r0 = rf(url, expireAt)
+	if rf, ok := ret.Get(0).(func(context.Context, urlshortener.UploadParams) *dao.ShortLink); ok {
+		r0 = rf(ctx, params)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*dao.ShortLink)
@@ -51,8 +533,8 @@ func (_m *URLShortener) Upload(url string, expireAt time.Time) (*dao.ShortLink,
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
-		r1 = rf(url, expireAt)
+	if rf, ok := ret.Get(1).(func(context.Context, urlshortener.UploadParams) error); ok {
+		r1 = rf(ctx, params)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1,118 +1,792 @@
 package urlshortener
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/georgechang0117/url-shortener/base/base62"
 	"github.com/georgechang0117/url-shortener/base/cache"
 	"github.com/georgechang0117/url-shortener/base/lock"
+	"github.com/georgechang0117/url-shortener/base/logging"
 	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/idfilter"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
 
 	"code.cloudfoundry.org/clock"
-	"go.uber.org/zap"
+	"github.com/go-playground/validator/v10"
 )
 
 const (
 	lockerKeyPrefix = "get_url_shortener_"
+	domainKeyPrefix = "domain_"
 	lockRetryCount  = 3
+	urlIDLength     = 11
+	maxURLIDLength  = 20
+	urlIDCharset    = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_"
+	// maxURLIDAttempts limits generated url_ids of a short link, which are rarely filtered or used.
+	maxURLIDAttempts = 10
+
+	defaultListLimit = 20
 )
 
 var (
 	defaultCacheTTL  = 24 * time.Hour
+	domainCacheTTL   = 10 * time.Minute
 	cacheRandMax     = 5
 	notFoundCacheTTL = 1 * time.Minute
 	lockTTL          = time.Duration(10 * time.Second)
+
+	validate = validator.New()
 )
 
 type urlShortenerImpl struct {
-	locker       lock.DistributedLocker
-	remoteCache  cache.RemoteCache
-	shortLinkDao dao.ShortLinkDao
-	clock        clock.Clock
+	locker        lock.DistributedLocker
+	remoteCache   cache.RemoteCache
+	shortLinkDao  dao.ShortLinkDao
+	domainDao     dao.DomainDao
+	ownerDao      dao.OwnerDao
+	webhookDao    dao.WebhookDao
+	auditDao      dao.AuditDao
+	blockedIDDao  dao.BlockedIDDao
+	defaultDomain *dao.Domain
+	clock         clock.Clock
+	// metadataWorker fetches metadata of destinations of created and updated short links, nil disables it.
+	metadataWorker MetadataWorker
+	expiryPolicy   ExpiryPolicy
+	idFilterConfig idfilter.Config
+	idFilter       idfilter.Filter
+	// writeThrough caches uploaded short links.
+	writeThrough bool
+}
+
+// Option defines optional configuration of URLShortener.
+type Option func(s *urlShortenerImpl)
+
+// WithExpiryPolicy limits expiry of short links by policy, which is unlimited and never revives expired short links
+// by default.
+func WithExpiryPolicy(policy ExpiryPolicy) Option {
+	return func(s *urlShortenerImpl) {
+		s.expiryPolicy = policy
+	}
+}
+
+// WithIDFilterConfig filters generated and imported IDs by config besides IDs blocked by administrators, the
+// embedded block list and idfilter.DefaultReservedIDs are used by default.
+func WithIDFilterConfig(config idfilter.Config) Option {
+	return func(s *urlShortenerImpl) {
+		s.idFilterConfig = config
+	}
+}
+
+// WithMetadataWorker fetches metadata of destinations of created and updated short links by worker, which is
+// not fetched by default.
+func WithMetadataWorker(worker MetadataWorker) Option {
+	return func(s *urlShortenerImpl) {
+		s.metadataWorker = worker
+	}
+}
+
+// WithWriteThrough caches short links once they're uploaded, so first redirects of new short links don't take the
+// lock and load them from db.
+func WithWriteThrough() Option {
+	return func(s *urlShortenerImpl) {
+		s.writeThrough = true
+	}
+}
+
+// Daos defines daos used by URLShortener.
+type Daos struct {
+	ShortLink dao.ShortLinkDao
+	Domain    dao.DomainDao
+	Owner     dao.OwnerDao
+	Webhook   dao.WebhookDao
+	Audit     dao.AuditDao
+	BlockedID dao.BlockedIDDao
 }
 
 // NewURLShortener creates an instance of URLShortener.
 func NewURLShortener(
 	locker lock.DistributedLocker,
 	remoteCache cache.RemoteCache,
-	shortLinkDao dao.ShortLinkDao,
+	daos Daos,
+	defaultDomain *dao.Domain,
 	clock clock.Clock,
+	opts ...Option,
 ) URLShortener {
-	return &urlShortenerImpl{
-		locker:       locker,
-		remoteCache:  remoteCache,
-		shortLinkDao: shortLinkDao,
-		clock:        clock,
+	s := &urlShortenerImpl{
+		locker:        locker,
+		remoteCache:   remoteCache,
+		shortLinkDao:  daos.ShortLink,
+		domainDao:     daos.Domain,
+		ownerDao:      daos.Owner,
+		webhookDao:    daos.Webhook,
+		auditDao:      daos.Audit,
+		blockedIDDao:  daos.BlockedID,
+		defaultDomain: defaultDomain,
+		clock:         clock,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.idFilter = idfilter.NewFilter(s.idFilterConfig, daos.BlockedID)
+	return s
 }
 
-func (s *urlShortenerImpl) Upload(url string, expireAt time.Time) (*dao.ShortLink, error) {
-	var id uint64
-	var urlID string
-	for used := true; used; used = s.isUsed(urlID) {
-		id = rand.Uint64()
-		urlID = base62.Encode(id)
+func (s *urlShortenerImpl) Upload(ctx context.Context, params UploadParams) (*dao.ShortLink, error) {
+	shortLink, err := s.newShortLink(params)
+	if err != nil {
+		return nil, err
 	}
 
-	shortLink := dao.ShortLink{
-		URLID:    urlID,
-		URL:      url,
-		ExpireAt: expireAt,
+	if err := s.auditedDao(ctx, params.Owner).Create(shortLink); dao.IsErrDuplicatedKey(err) {
+		return nil, ErrConflict
+	} else if err != nil {
+		return nil, err
+	}
+	s.fetchMetadata(shortLink)
+	s.writeThroughCache(ctx, shortLink)
+
+	return shortLink, nil
+}
+
+func (s *urlShortenerImpl) BatchUpload(ctx context.Context, params []UploadParams) ([]*dao.ShortLink, error) {
+	if len(params) > MaxBatchUploadSize {
+		return nil, fmt.Errorf("%w: at most %d URLs could be uploaded at a time", ErrInvalidParams, MaxBatchUploadSize)
+	}
+	shortLinks := make([]*dao.ShortLink, 0, len(params))
+	// all params are given by the same owner
+	var owner *dao.Owner
+	for i, p := range params {
+		shortLink, err := s.newShortLink(p)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		shortLinks = append(shortLinks, shortLink)
+		owner = p.Owner
+	}
+
+	if err := s.auditedDao(ctx, owner).CreateBatch(shortLinks); dao.IsErrDuplicatedKey(err) {
+		return nil, ErrConflict
+	} else if err != nil {
+		return nil, err
 	}
+	s.fetchMetadata(shortLinks...)
+	s.writeThroughCache(ctx, shortLinks...)
+
+	return shortLinks, nil
+}
 
-	if err := s.shortLinkDao.Create(&shortLink); err != nil {
+// newShortLink validates params and returns a short link with an unused url_id, which is not created yet.
+func (s *urlShortenerImpl) newShortLink(params UploadParams) (*dao.ShortLink, error) {
+	if err := validate.Struct(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	if !params.ExpireAt.After(s.clock.Now()) {
+		return nil, fmt.Errorf("%w: expireAt should be greater than now", ErrInvalidParams)
+	}
+	if err := s.checkLifetime(s.clock.Now(), params.ExpireAt); err != nil {
 		return nil, err
 	}
 
+	shortLink, err := s.compileShortLink(params)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxURLIDAttempts; i++ {
+		shortLink.URLID = newURLID()
+		// IDs spelling blocked words or clashing with routes are discarded like used ones
+		err := s.idFilter.Check(shortLink.URLID)
+		if errors.Is(err, idfilter.ErrReserved) || errors.Is(err, idfilter.ErrBlocked) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		if !s.isUsed(shortLink.DomainID, shortLink.URLID) {
+			return shortLink, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no available url_id in %d attempts", ErrUnavailable, maxURLIDAttempts)
+}
+
+// compileShortLink returns a short link of validated params without url_id.
+func (s *urlShortenerImpl) compileShortLink(params UploadParams) (*dao.ShortLink, error) {
+	if err := validateURL(params.URL); err != nil {
+		return nil, err
+	}
+	if err := validateFallbackURL(params.FallbackURL); err != nil {
+		return nil, err
+	}
+
+	domain, err := s.uploadDomain(params.Owner, params.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSet, err := compileRules(params.Rules)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := compileVariants(params.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	shortLink := dao.ShortLink{
+		DomainID:    domain.ID,
+		URL:         params.URL,
+		Preview:     params.Preview,
+		QueryMode:   params.QueryMode,
+		UTM:         encodeUTM(params.UTM),
+		Rules:       ruleSet,
+		Variants:    variants,
+		Title:       strings.TrimSpace(params.Title),
+		Description: strings.TrimSpace(params.Description),
+		Tags:        normalizeTags(params.Tags),
+		FallbackURL: params.FallbackURL,
+		Status:      dao.StatusActive,
+		ExpireAt:    params.ExpireAt,
+		Domain:      domain,
+	}
+	if params.Owner != nil {
+		shortLink.OwnerID = params.Owner.ID
+	}
+
 	return &shortLink, nil
 }
 
-func (s *urlShortenerImpl) Load(urlID string) (*dao.ShortLink, error) {
+func (s *urlShortenerImpl) Load(ctx context.Context, host, urlID string) (*dao.ShortLink, error) {
 	var shortLink dao.ShortLink
 
-	b, err := s.remoteCache.Get(urlID)
-	if cache.IsErrKeyNotExist(err) {
-		// do noting
-	} else if err != nil {
+	domain, err := s.loadDomain(ctx, host)
+	if err != nil {
 		return nil, err
-	} else {
-		if err := json.Unmarshal(b, &shortLink); err != nil {
-			return nil, err
-		}
-		zap.S().Debugf("get shortLink from cache in the beginning, url_id: %s", urlID)
-		return &shortLink, nil
+	}
+
+	b, err := s.cachedShortLink(ctx, domain.ID, urlID)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &shortLink); err != nil {
+		return nil, err
+	}
+	// non-existent short link is cached as an empty one
+	if shortLink.URL == "" {
+		return nil, ErrNotFound
+	}
+	shortLink.Domain = domain
+
+	return &shortLink, nil
+}
+
+func (s *urlShortenerImpl) LoadActive(ctx context.Context, host, urlID string) (*dao.ShortLink, error) {
+	shortLink, err := s.Load(ctx, host, urlID)
+	if err != nil {
+		return nil, err
+	}
+	switch shortLink.Status {
+	case dao.StatusDeleted:
+		return nil, ErrDeleted
+	case dao.StatusDisabled:
+		return nil, ErrDisabled
+	}
+	if shortLink.ExpireAt.Before(s.clock.Now()) {
+		return nil, ErrExpired
+	}
+
+	return shortLink, nil
+}
+
+func (s *urlShortenerImpl) OwnerFallbackURL(ctx context.Context, host, urlID string) (string, error) {
+	shortLink, err := s.Load(ctx, host, urlID)
+	if err != nil {
+		return "", err
+	}
+	if shortLink.OwnerID == 0 {
+		return "", nil
+	}
+
+	owner, err := s.ownerDao.Get(shortLink.OwnerID)
+	if dao.IsErrRecordNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return owner.FallbackURL, nil
+}
+
+// cachedShortLink returns short link in JSON from cache, or loads it from db into cache.
+func (s *urlShortenerImpl) cachedShortLink(ctx context.Context, domainID uint64, urlID string) ([]byte, error) {
+	logger := logging.FromContext(ctx).Sugar()
+	key := shortLinkCacheKey(domainID, urlID)
+
+	b, err := s.remoteCache.Get(key)
+	if err == nil {
+		logger.Debugf("get shortLink from cache in the beginning, url_id: %s", urlID)
+		return b, nil
+	} else if !cache.IsErrKeyNotExist(err) {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
 	}
 
 	// use distributed lock to prevent cache stampede
 	lock, err := s.locker.Lock(
-		lockerKeyPrefix+urlID,
+		lockerKeyPrefix+key,
 		lockTTL,
 		lock.DefaultRetryDelay,
 		lockRetryCount,
 	)
 	if err != nil {
-		zap.S().Warnf("fail to lock, err: %v", err)
-		return nil, err
+		logger.Warnf("fail to lock, err: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
 	}
 	defer lock.Unlock()
 
-	b, err = s.remoteCache.GetOrSet(urlID, s.shortLinkRemoteEntryGen(urlID))
+	b, err = s.remoteCache.GetOrSet(key, s.shortLinkRemoteEntryGen(ctx, domainID, urlID))
 	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	logger.Debugf("get shortLink from cache or db, url_id: %s", urlID)
+
+	return b, nil
+}
+
+func (s *urlShortenerImpl) Authenticate(apiKey string) (*dao.Owner, error) {
+	owner, err := s.ownerDao.GetByAPIKey(apiKey)
+	if dao.IsErrRecordNotFound(err) {
+		return nil, ErrOwnerNotFound
+	} else if err != nil {
 		return nil, err
 	}
-	zap.S().Debugf("get shortLink from cache or db, url_id: %s", urlID)
 
-	if err := json.Unmarshal(b, &shortLink); err != nil {
+	return owner, nil
+}
+
+func (s *urlShortenerImpl) Update(ctx context.Context, params UpdateParams) (*dao.ShortLink, error) {
+	if err := validate.Struct(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	if params.ExpireAt != nil && !params.ExpireAt.After(s.clock.Now()) {
+		return nil, fmt.Errorf("%w: expireAt should be greater than now", ErrInvalidParams)
+	}
+
+	shortLink, err := s.ownedShortLink(params.Owner, params.Domain, params.URLID)
+	if err != nil {
 		return nil, err
 	}
+	if shortLink.Status == dao.StatusDeleted {
+		return nil, ErrDeleted
+	}
+	if params.ExpireAt != nil {
+		if err := s.checkExpiry(shortLink, *params.ExpireAt); err != nil {
+			return nil, err
+		}
+	}
 
-	return &shortLink, nil
+	urlChanged := params.URL != nil && *params.URL != shortLink.URL
+	// only given fields are written, so concurrent updates of other fields are kept
+	var fields []string
+	if params.URL != nil {
+		if err := validateURL(*params.URL); err != nil {
+			return nil, err
+		}
+		shortLink.URL = *params.URL
+		fields = append(fields, "URL")
+	}
+	if params.ExpireAt != nil {
+		shortLink.ExpireAt = *params.ExpireAt
+		fields = append(fields, "ExpireAt")
+	}
+	if params.Preview != nil {
+		shortLink.Preview = *params.Preview
+		fields = append(fields, "Preview")
+	}
+	if params.QueryMode != nil {
+		shortLink.QueryMode = *params.QueryMode
+		fields = append(fields, "QueryMode")
+	}
+	if params.UTM != nil {
+		shortLink.UTM = encodeUTM(params.UTM)
+		fields = append(fields, "UTM")
+	}
+	if params.Rules != nil {
+		ruleSet, err := compileRules(*params.Rules)
+		if err != nil {
+			return nil, err
+		}
+		shortLink.Rules = ruleSet
+		fields = append(fields, "Rules")
+	}
+	if params.Variants != nil {
+		variants, err := compileVariants(*params.Variants)
+		if err != nil {
+			return nil, err
+		}
+		shortLink.Variants = variants
+		fields = append(fields, "Variants")
+	}
+	if params.Title != nil {
+		shortLink.Title = strings.TrimSpace(*params.Title)
+		fields = append(fields, "Title")
+	}
+	if params.Description != nil {
+		shortLink.Description = strings.TrimSpace(*params.Description)
+		fields = append(fields, "Description")
+	}
+	if params.Tags != nil {
+		shortLink.Tags = normalizeTags(*params.Tags)
+		fields = append(fields, "Tags")
+	}
+	if params.FallbackURL != nil {
+		if err := validateFallbackURL(*params.FallbackURL); err != nil {
+			return nil, err
+		}
+		shortLink.FallbackURL = *params.FallbackURL
+		fields = append(fields, "FallbackURL")
+	}
+	if params.Status != nil {
+		shortLink.Status = *params.Status
+		fields = append(fields, "Status")
+	}
+
+	// the broken flag and the expired event are reset by the dao if URL and ExpireAt are changed
+	if err := s.auditedDao(ctx, params.Owner).Update(shortLink, fields...); errors.Is(err, dao.ErrShortLinkDeleted) {
+		// deleted after it's loaded
+		return nil, ErrDeleted
+	} else if err != nil {
+		return nil, err
+	}
+	if err := s.remoteCache.Delete(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)); err != nil {
+		return nil, err
+	}
+	if urlChanged {
+		s.fetchMetadata(shortLink)
+	}
+
+	return shortLink, nil
+}
+
+func (s *urlShortenerImpl) SetExpiry(ctx context.Context, params ExpiryParams) (*dao.ShortLink, error) {
+	if err := validate.Struct(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	given := 0
+	for _, ok := range []bool{params.ExpireAt != nil, params.ExtendBy > 0, params.Never} {
+		if ok {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("%w: exactly one of expireAt, extendBy and never should be given", ErrInvalidParams)
+	}
+
+	shortLink, err := s.ownedShortLink(params.Owner, params.Domain, params.URLID)
+	if err != nil {
+		return nil, err
+	}
+	if shortLink.Status == dao.StatusDeleted {
+		return nil, ErrDeleted
+	}
+
+	var expireAt time.Time
+	switch {
+	case params.ExpireAt != nil:
+		expireAt = *params.ExpireAt
+	case params.Never:
+		expireAt = dao.NeverExpire
+	default:
+		from := shortLink.ExpireAt
+		if now := s.clock.Now(); from.Before(now) {
+			from = now
+		}
+		expireAt = from.Add(params.ExtendBy)
+	}
+	if err := s.checkExpiry(shortLink, expireAt); err != nil {
+		return nil, err
+	}
+	shortLink.ExpireAt = expireAt
+
+	if err := s.auditedDao(ctx, params.Owner).Update(shortLink, "ExpireAt"); errors.Is(err, dao.ErrShortLinkDeleted) {
+		return nil, ErrDeleted
+	} else if err != nil {
+		return nil, err
+	}
+	if err := s.cacheShortLink(shortLink); err != nil {
+		return nil, err
+	}
+
+	return shortLink, nil
 }
 
-func (s *urlShortenerImpl) isUsed(urlID string) bool {
-	exists, err := s.shortLinkDao.Exists(urlID)
+// checkExpiry checks if the expiry of shortLink could be changed to expireAt by ExpiryPolicy.
+func (s *urlShortenerImpl) checkExpiry(shortLink *dao.ShortLink, expireAt time.Time) error {
+	now := s.clock.Now()
+	if !expireAt.After(now) {
+		return fmt.Errorf("%w: expireAt should be greater than now", ErrInvalidParams)
+	}
+	if shortLink.ExpireAt.Before(now) && now.Sub(shortLink.ExpireAt) > s.expiryPolicy.GracePeriod {
+		return ErrExpired
+	}
+	return s.checkLifetime(shortLink.CreatedAt, expireAt)
+}
+
+// checkLifetime checks if a short link created at createdAt could live until expireAt by ExpiryPolicy. expireAt
+// could not be after dao.NeverExpire, like extending a short link which never expires.
+func (s *urlShortenerImpl) checkLifetime(createdAt, expireAt time.Time) error {
+	if expireAt.After(dao.NeverExpire) {
+		return fmt.Errorf("%w: expireAt should not be after %s", ErrInvalidParams, dao.NeverExpire.Format(time.RFC3339))
+	}
+	maxLifetime := s.expiryPolicy.MaxLifetime
+	if maxLifetime > 0 && expireAt.Sub(createdAt) > maxLifetime {
+		return fmt.Errorf("%w: expireAt should be within %s after creation", ErrInvalidParams, maxLifetime)
+	}
+	return nil
+}
+
+// cacheShortLink writes shortLink into cache, replacing the entry loaded before it's changed.
+func (s *urlShortenerImpl) cacheShortLink(shortLink *dao.ShortLink) error {
+	return setShortLinkCache(s.remoteCache, shortLink, s.clock.Now())
+}
+
+// writeThroughCache caches uploaded short links if writeThrough is enabled. Failures are only logged since the
+// short links are loaded from db on cache misses.
+func (s *urlShortenerImpl) writeThroughCache(ctx context.Context, shortLinks ...*dao.ShortLink) {
+	if !s.writeThrough {
+		return
+	}
+	for _, shortLink := range shortLinks {
+		if err := s.cacheShortLink(shortLink); err != nil {
+			logging.FromContext(ctx).Sugar().Warnf("fail to cache uploaded short link, url_id: %s, err: %v", shortLink.URLID, err)
+		}
+	}
+}
+
+// setShortLinkCache writes shortLink into cache with TTL cut at its expiry.
+func setShortLinkCache(remoteCache cache.RemoteCache, shortLink *dao.ShortLink, now time.Time) error {
+	b, err := json.Marshal(shortLink)
+	if err != nil {
+		return err
+	}
+	key := shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)
+	return remoteCache.Set(key, b, shortLinkCacheTTL(shortLink, now))
+}
+
+func (s *urlShortenerImpl) Delete(ctx context.Context, owner *dao.Owner, host, urlID string) error {
+	shortLink, err := s.ownedShortLink(owner, host, urlID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auditedDao(ctx, owner).Delete(shortLink.ID, s.clock.Now()); err != nil {
+		return err
+	}
+
+	return s.remoteCache.Delete(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID))
+}
+
+func (s *urlShortenerImpl) Restore(ctx context.Context, owner *dao.Owner, host, urlID string) (*dao.ShortLink, error) {
+	shortLink, err := s.ownedShortLink(owner, host, urlID)
+	if err != nil {
+		return nil, err
+	}
+	if shortLink.Status != dao.StatusDeleted {
+		return shortLink, nil
+	}
+
+	if err := s.auditedDao(ctx, owner).Restore(shortLink.ID); err != nil {
+		return nil, err
+	}
+	if err := s.remoteCache.Delete(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID)); err != nil {
+		return nil, err
+	}
+	shortLink.Status = shortLink.RestoredStatus()
+	shortLink.StatusBeforeDelete = ""
+	shortLink.DeletedAt = nil
+
+	return shortLink, nil
+}
+
+func (s *urlShortenerImpl) Purge(ctx context.Context, owner *dao.Owner, host, urlID string) error {
+	shortLink, err := s.ownedShortLink(owner, host, urlID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auditedDao(ctx, owner).Purge(shortLink.ID); err != nil {
+		return err
+	}
+
+	return s.remoteCache.Delete(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID))
+}
+
+func (s *urlShortenerImpl) List(params ListParams) ([]*dao.ShortLink, string, error) {
+	if params.Owner == nil {
+		return nil, "", ErrOwnerRequired
+	}
+	if err := validate.Struct(params); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+
+	var beforeID uint64
+	if params.Cursor != "" {
+		id, err := base62.Decode(params.Cursor)
+		if err != nil || id == 0 {
+			return nil, "", fmt.Errorf("%w: cursor is invalid", ErrInvalidParams)
+		}
+		beforeID = id
+	}
+
+	shortLinks, err := s.shortLinkDao.List(dao.ListFilter{
+		OwnerID:     params.Owner.ID,
+		BeforeID:    beforeID,
+		Limit:       limit,
+		Tags:        normalizeTags(params.Tags),
+		CreatedFrom: params.CreatedFrom,
+		CreatedTo:   params.CreatedTo,
+		ExpireFrom:  params.ExpireFrom,
+		ExpireTo:    params.ExpireTo,
+		Query:       strings.TrimSpace(params.Query),
+		FullText:    params.Search == SearchFullText,
+		Broken:      params.Broken,
+		Status:      params.Status,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	domains := s.ownerDomains(params.Owner)
+	for _, shortLink := range shortLinks {
+		shortLink.Domain = domains[shortLink.DomainID]
+	}
+
+	var next string
+	if len(shortLinks) == limit {
+		next = base62.Encode(shortLinks[len(shortLinks)-1].ID)
+	}
+
+	return shortLinks, next, nil
+}
+
+func (s *urlShortenerImpl) Health(owner *dao.Owner, host, urlID string) (*dao.LinkHealth, error) {
+	shortLink, err := s.ownedShortLink(owner, host, urlID)
+	if err != nil {
+		return nil, err
+	}
+
+	health, err := s.shortLinkDao.GetHealth(shortLink.ID)
+	if dao.IsErrRecordNotFound(err) {
+		return &dao.LinkHealth{ShortLinkID: shortLink.ID}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// the check is of the previous destination
+	if health.URL != shortLink.URL {
+		return &dao.LinkHealth{ShortLinkID: shortLink.ID}, nil
+	}
+
+	return health, nil
+}
+
+// fetchMetadata schedules fetching metadata of destinations of shortLinks if metadataWorker is set.
+func (s *urlShortenerImpl) fetchMetadata(shortLinks ...*dao.ShortLink) {
+	if s.metadataWorker == nil {
+		return
+	}
+	for _, shortLink := range shortLinks {
+		s.metadataWorker.Enqueue(shortLink)
+	}
+}
+
+// ownedShortLink returns the short link from db if owner owns it.
+func (s *urlShortenerImpl) ownedShortLink(owner *dao.Owner, host, urlID string) (*dao.ShortLink, error) {
+	if owner == nil {
+		return nil, ErrOwnerRequired
+	}
+
+	domain, err := s.loadDomain(context.Background(), host)
+	if err != nil {
+		return nil, err
+	}
+
+	shortLink, err := s.shortLinkDao.GetByURLID(domain.ID, urlID)
+	if dao.IsErrRecordNotFound(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	// not revealing existence of short links owned by others
+	if shortLink.OwnerID != owner.ID {
+		return nil, ErrNotFound
+	}
+	shortLink.Domain = domain
+
+	return shortLink, nil
+}
+
+// ownerDomains returns domains which owner can use by id, including the default domain.
+func (s *urlShortenerImpl) ownerDomains(owner *dao.Owner) map[uint64]*dao.Domain {
+	domains := map[uint64]*dao.Domain{s.defaultDomain.ID: s.defaultDomain}
+	for i := range owner.Domains {
+		domains[owner.Domains[i].ID] = &owner.Domains[i]
+	}
+	return domains
+}
+
+// uploadDomain returns the domain which owner uploads to, anyone can use the default domain.
+func (s *urlShortenerImpl) uploadDomain(owner *dao.Owner, host string) (*dao.Domain, error) {
+	if host == "" || host == s.defaultDomain.Host {
+		return s.defaultDomain, nil
+	}
+	if owner == nil {
+		return nil, ErrDomainNotAllowed
+	}
+
+	domain, err := s.loadDomain(context.Background(), host)
+	if err != nil {
+		return nil, err
+	}
+	if !owner.AllowsDomain(domain.ID) {
+		return nil, ErrDomainNotAllowed
+	}
+
+	return domain, nil
+}
+
+// loadDomain returns the domain of host, or the default domain if host is empty.
+func (s *urlShortenerImpl) loadDomain(ctx context.Context, host string) (*dao.Domain, error) {
+	var domain dao.Domain
+
+	if host == "" {
+		return s.defaultDomain, nil
+	}
+
+	b, err := s.remoteCache.GetOrSet(domainKeyPrefix+host, s.domainRemoteEntryGen(ctx, host))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err := json.Unmarshal(b, &domain); err != nil {
+		return nil, err
+	}
+	if domain.ID == 0 {
+		return nil, ErrDomainNotFound
+	}
+
+	return &domain, nil
+}
+
+func (s *urlShortenerImpl) isUsed(domainID uint64, urlID string) bool {
+	exists, err := s.shortLinkDao.Exists(domainID, urlID)
 	if err != nil {
 		return false
 	}
@@ -120,13 +794,19 @@ func (s *urlShortenerImpl) isUsed(urlID string) bool {
 	return exists
 }
 
-func (s *urlShortenerImpl) shortLinkRemoteEntryGen(urlID string) cache.RemoteEntryGenerator {
+func (s *urlShortenerImpl) shortLinkRemoteEntryGen(
+	ctx context.Context,
+	domainID uint64,
+	urlID string,
+) cache.RemoteEntryGenerator {
+	logger := logging.FromContext(ctx).Sugar()
 	gen := func() ([]byte, time.Duration, error) {
-		shortLink, err := s.shortLinkDao.GetByURLID(urlID)
+		shortLink, err := s.shortLinkDao.GetByURLID(domainID, urlID)
 		if dao.IsErrRecordNotFound(err) {
-			zap.S().Debugf("shortLink not found in db, url_id: %s", urlID)
+			logger.Debugf("shortLink not found in db, url_id: %s", urlID)
 			// handle request with non-existent shorten URL to prevent cache penetration
 			emptyShortLink := dao.ShortLink{
+				DomainID: domainID,
 				URLID:    urlID,
 				URL:      "",
 				ExpireAt: s.clock.Now().Add(time.Duration(-1)),
@@ -141,18 +821,156 @@ func (s *urlShortenerImpl) shortLinkRemoteEntryGen(urlID string) cache.RemoteEnt
 			return nil, 0, err
 		}
 
-		zap.S().Debugf("get shortLink from db, url_id: %s", urlID)
+		logger.Debugf("get shortLink from db, url_id: %s", urlID)
 
 		b, err := json.Marshal(shortLink)
 		if err != nil {
 			return nil, 0, err
 		}
 
-		// add rand time duration to cacheTTL to prevent cache expired at same time.
-		cacheTTL := defaultCacheTTL + time.Duration(rand.Intn(cacheRandMax)*int(time.Minute))
+		return b, shortLinkCacheTTL(shortLink, s.clock.Now()), nil
+	}
 
-		return b, cacheTTL, nil
+	return gen
+}
+
+// shortLinkCacheTTL returns TTL of cached shortLink, which is cut at its expiry plus notFoundCacheTTL so expired
+// short links do not stay in cache long.
+func shortLinkCacheTTL(shortLink *dao.ShortLink, now time.Time) time.Duration {
+	// add rand time duration to cacheTTL to prevent cache expired at same time.
+	ttl := defaultCacheTTL + time.Duration(rand.Intn(cacheRandMax)*int(time.Minute))
+	if evictAt := shortLink.ExpireAt.Add(notFoundCacheTTL); evictAt.Before(now.Add(ttl)) {
+		ttl = evictAt.Sub(now)
+	}
+	if ttl < notFoundCacheTTL {
+		ttl = notFoundCacheTTL
+	}
+	return ttl
+}
+
+func (s *urlShortenerImpl) domainRemoteEntryGen(ctx context.Context, host string) cache.RemoteEntryGenerator {
+	logger := logging.FromContext(ctx).Sugar()
+	gen := func() ([]byte, time.Duration, error) {
+		domain, err := s.domainDao.GetByHost(host)
+		if dao.IsErrRecordNotFound(err) {
+			logger.Debugf("domain not found in db, host: %s", host)
+			// cache empty domain as well, requests with unknown Host header should not reach db
+			b, err := json.Marshal(dao.Domain{Host: host})
+			if err != nil {
+				return nil, 0, err
+			}
+
+			return b, notFoundCacheTTL, nil
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		b, err := json.Marshal(domain)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return b, domainCacheTTL, nil
 	}
 
 	return gen
 }
+
+// normalizeTags returns tags without surrounding spaces, empty ones and duplicates in their order.
+func normalizeTags(tags []string) dao.Tags {
+	var normalized dao.Tags
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// newURLID returns a random url_id of urlIDLength characters, small numbers encoded in fewer characters
+// are skipped since they are rejected as invalid url_id on redirect.
+func newURLID() string {
+	for {
+		if urlID := base62.Encode(rand.Uint64()); len(urlID) == urlIDLength {
+			return urlID
+		}
+	}
+}
+
+// validateURL checks url is an absolute http or https URL, others like javascript: URLs are rejected.
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url should be an absolute http or https URL", ErrInvalidURL)
+	}
+	return nil
+}
+
+// validateFallbackURL checks fallbackURL like validateURL, empty means no fallback.
+func validateFallbackURL(fallbackURL string) error {
+	if fallbackURL == "" {
+		return nil
+	}
+	if err := validateURL(fallbackURL); err != nil {
+		return fmt.Errorf("%w: fallbackUrl should be an absolute http or https URL", ErrInvalidURL)
+	}
+	return nil
+}
+
+// compileRules checks URLs of rs like validateURL and compiles rs, since browsers are redirected to them as well.
+func compileRules(rs []rules.Rule) (rules.Set, error) {
+	for i, rule := range rs {
+		if err := validateURL(rule.URL); err != nil {
+			return nil, fmt.Errorf("%w: url of rule %d should be an absolute http or https URL", ErrInvalidURL, i)
+		}
+	}
+	ruleSet, err := rules.Compile(rs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	return ruleSet, nil
+}
+
+// compileVariants checks URLs of variants like validateURL and compiles variants.
+func compileVariants(variants []split.Variant) (split.Set, error) {
+	for _, variant := range variants {
+		if err := validateURL(variant.URL); err != nil {
+			return nil, fmt.Errorf("%w: url of variant %s should be an absolute http or https URL", ErrInvalidURL, variant.Name)
+		}
+	}
+	variantSet, err := split.Compile(variants)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVariants, err)
+	}
+	return variantSet, nil
+}
+
+// IsValidURLID checks if urlID is a valid url_id. Generated url_ids are urlIDLength base62 characters, and
+// imported ones could be up to maxURLIDLength characters of base62, '-' and '_'.
+func IsValidURLID(urlID string) bool {
+	if len(urlID) == 0 || len(urlID) > maxURLIDLength {
+		return false
+	}
+	for _, c := range urlID {
+		if !strings.ContainsRune(urlIDCharset, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeUTM(utm map[string]string) string {
+	values := url.Values{}
+	for k, v := range utm {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+func shortLinkCacheKey(domainID uint64, urlID string) string {
+	return fmt.Sprintf("%d_%s", domainID, urlID)
+}
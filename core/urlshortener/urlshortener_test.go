@@ -1,7 +1,9 @@
 package urlshortener
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"math/rand"
 	"testing"
 	"time"
@@ -11,6 +13,10 @@ import (
 	lockmocks "github.com/georgechang0117/url-shortener/base/lock/mocks"
 	"github.com/georgechang0117/url-shortener/core/dao"
 	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+	"github.com/georgechang0117/url-shortener/core/idfilter"
+	idfiltermocks "github.com/georgechang0117/url-shortener/core/idfilter/mocks"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	"github.com/go-redis/redis"
@@ -22,9 +28,15 @@ import (
 const (
 	testUploadURL = "https://boards.greenhouse.io/dcard/jobs/871123?gh_src=ok8y1h1/"
 	testURLID     = "ejLqV3Wkyd6"
+	testHost      = "go.example.com"
+	testAPIKey    = "test-api-key"
 )
 
-var testNow = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+var (
+	testNow           = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+	testDefaultDomain = dao.Domain{ID: 1, Host: "localhost", Scheme: "http"}
+	testDomain        = dao.Domain{ID: 2, Host: testHost, Scheme: "https"}
+)
 
 type urlShortenerTestSuite struct {
 	suite.Suite
@@ -32,6 +44,12 @@ type urlShortenerTestSuite struct {
 	mockLocker       *lockmocks.DistributedLocker
 	mockRemoteCache  *cachemocks.RemoteCache
 	mockShortLinkDao *daomocks.ShortLinkDao
+	mockDomainDao    *daomocks.DomainDao
+	mockOwnerDao     *daomocks.OwnerDao
+	mockWebhookDao   *daomocks.WebhookDao
+	mockAuditDao     *daomocks.AuditDao
+	mockBlockedIDDao *daomocks.BlockedIDDao
+	metadataWorker   *recordingMetadataWorker
 }
 
 func (s *urlShortenerTestSuite) SetupSuite() {
@@ -39,11 +57,30 @@ func (s *urlShortenerTestSuite) SetupSuite() {
 	s.mockLocker = &lockmocks.DistributedLocker{}
 	s.mockRemoteCache = &cachemocks.RemoteCache{}
 	s.mockShortLinkDao = &daomocks.ShortLinkDao{}
+	s.mockDomainDao = &daomocks.DomainDao{}
+	s.mockOwnerDao = &daomocks.OwnerDao{}
+	s.mockWebhookDao = &daomocks.WebhookDao{}
+	s.mockAuditDao = &daomocks.AuditDao{}
+	s.mockBlockedIDDao = &daomocks.BlockedIDDao{}
+	// no ID is blocked by administrators unless the test blocks it
+	s.mockBlockedIDDao.On("Exists", mock.AnythingOfType("string")).Return(false, nil)
+	// changes are recorded by the same dao
+	s.mockShortLinkDao.On("WithActor", mock.AnythingOfType("*dao.Actor")).Return(s.mockShortLinkDao)
+	s.metadataWorker = &recordingMetadataWorker{}
 	impl := NewURLShortener(
 		s.mockLocker,
 		s.mockRemoteCache,
-		s.mockShortLinkDao,
+		Daos{
+			ShortLink: s.mockShortLinkDao,
+			Domain:    s.mockDomainDao,
+			Owner:     s.mockOwnerDao,
+			Webhook:   s.mockWebhookDao,
+			Audit:     s.mockAuditDao,
+			BlockedID: s.mockBlockedIDDao,
+		},
+		&testDefaultDomain,
 		fakeclock.NewFakeClock(testNow),
+		WithMetadataWorker(s.metadataWorker),
 	)
 	s.impl = impl.(*urlShortenerImpl)
 }
@@ -55,14 +92,115 @@ func TestURLShortenerTestSuite(t *testing.T) {
 func (s *urlShortenerTestSuite) TestUpload() {
 	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
 
-	s.mockShortLinkDao.On("Exists", mock.Anything).Return(false, nil).Once()
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Once()
 	s.mockShortLinkDao.On("Create", mock.Anything).Return(nil).Once()
 
-	shortLink, err := s.impl.Upload(testUploadURL, expireAt)
+	shortLink, err := s.impl.Upload(context.Background(), UploadParams{
+		URL:      testUploadURL,
+		ExpireAt: expireAt,
+		Title:    " Careers ",
+		Tags:     []string{"jobs", " jobs ", "dcard"},
+	})
 	s.NoError(err)
+	s.Equal("Careers", shortLink.Title)
+	s.Equal(dao.Tags{"jobs", "dcard"}, shortLink.Tags)
 	s.NotNil(shortLink.ID)
 	s.NotNil(shortLink.URL)
+	s.Equal(testDefaultDomain.ID, shortLink.DomainID)
+	s.Equal(&testDefaultDomain, shortLink.Domain)
 	s.Equal(expireAt.Unix(), shortLink.ExpireAt.Unix())
+	s.Equal(shortLink, s.metadataWorker.last())
+}
+
+func (s *urlShortenerTestSuite) TestUploadWithDomain() {
+	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+	owner := dao.Owner{ID: 3, APIKey: testAPIKey, Domains: []dao.Domain{testDomain}}
+	b, _ := json.Marshal(testDomain)
+
+	s.mockRemoteCache.On("GetOrSet", domainKeyPrefix+testHost, mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(b, nil).Once()
+	s.mockShortLinkDao.On("Exists", testDomain.ID, mock.Anything).Return(false, nil).Once()
+	s.mockShortLinkDao.On("Create", mock.Anything).Return(nil).Once()
+
+	shortLink, err := s.impl.Upload(context.Background(), UploadParams{
+		Owner:    &owner,
+		Domain:   testHost,
+		URL:      testUploadURL,
+		ExpireAt: expireAt,
+		Preview:  true,
+	})
+	s.Require().NoError(err)
+	s.True(shortLink.Preview)
+	s.Equal(testDomain.ID, shortLink.DomainID)
+	s.Equal(owner.ID, shortLink.OwnerID)
+	s.Equal(testDomain.Host, shortLink.Domain.Host)
+}
+
+func (s *urlShortenerTestSuite) TestUploadDomainNotAllowed() {
+	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+
+	_, err := s.impl.Upload(context.Background(), UploadParams{Domain: testHost, URL: testUploadURL, ExpireAt: expireAt})
+	s.Equal(ErrDomainNotAllowed, err)
+
+	owner := dao.Owner{ID: 3, APIKey: testAPIKey}
+	b, _ := json.Marshal(testDomain)
+	s.mockRemoteCache.On("GetOrSet", domainKeyPrefix+testHost, mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(b, nil).Once()
+
+	_, err = s.impl.Upload(context.Background(), UploadParams{Owner: &owner, Domain: testHost, URL: testUploadURL, ExpireAt: expireAt})
+	s.Equal(ErrDomainNotAllowed, err)
+}
+
+func (s *urlShortenerTestSuite) TestUploadInvalidRules() {
+	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+
+	_, err := s.impl.Upload(context.Background(), UploadParams{
+		URL:      testUploadURL,
+		ExpireAt: expireAt,
+		Rules:    []rules.Rule{{URL: testUploadURL}},
+	})
+	s.True(errors.Is(err, ErrInvalidRules))
+
+	// browsers are redirected to URLs of rules, which are checked like the destination
+	for _, url := range []string{"javascript:alert(1)", "data:text/html,hi", "file:///etc/passwd"} {
+		_, err := s.impl.Upload(context.Background(), UploadParams{
+			URL:      testUploadURL,
+			ExpireAt: expireAt,
+			Rules:    []rules.Rule{{Platforms: []string{rules.PlatformIOS}, URL: url}},
+		})
+		s.True(errors.Is(err, ErrInvalidURL), url)
+	}
+}
+
+func (s *urlShortenerTestSuite) TestUploadInvalidVariants() {
+	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+
+	_, err := s.impl.Upload(context.Background(), UploadParams{
+		URL:      testUploadURL,
+		ExpireAt: expireAt,
+		Variants: []split.Variant{{Name: "a", URL: testUploadURL, Weight: 1}},
+	})
+	s.True(errors.Is(err, ErrInvalidVariants))
+
+	for _, url := range []string{"javascript:alert(1)", "data:text/html,hi", "file:///etc/passwd"} {
+		_, err := s.impl.Upload(context.Background(), UploadParams{
+			URL:      testUploadURL,
+			ExpireAt: expireAt,
+			Variants: []split.Variant{{Name: "a", URL: testUploadURL, Weight: 1}, {Name: "b", URL: url, Weight: 1}},
+		})
+		s.True(errors.Is(err, ErrInvalidURL), url)
+	}
+}
+
+func (s *urlShortenerTestSuite) TestAuthenticate() {
+	owner := dao.Owner{ID: 3, APIKey: testAPIKey}
+	s.mockOwnerDao.On("GetByAPIKey", testAPIKey).Return(&owner, nil).Once()
+
+	o, err := s.impl.Authenticate(testAPIKey)
+	s.Require().NoError(err)
+	s.Equal(owner.ID, o.ID)
+
+	s.mockOwnerDao.On("GetByAPIKey", "unknown").Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.impl.Authenticate("unknown")
+	s.Equal(ErrOwnerNotFound, err)
 }
 
 func (s *urlShortenerTestSuite) TestLoad() {
@@ -72,14 +210,17 @@ func (s *urlShortenerTestSuite) TestLoad() {
 		ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC),
 	}
 	b, _ := json.Marshal(shortLink)
-	s.mockRemoteCache.On("Get", testURLID).Return(nil, redis.Nil).Once()
+	db, _ := json.Marshal(testDomain)
+	key := shortLinkCacheKey(testDomain.ID, testURLID)
+	s.mockRemoteCache.On("GetOrSet", domainKeyPrefix+testHost, mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(db, nil).Once()
+	s.mockRemoteCache.On("Get", key).Return(nil, redis.Nil).Once()
 	mockLock := lockmocks.Lock{}
-	s.mockLocker.On("Lock", lockerKeyPrefix+testURLID, lockTTL, lock.DefaultRetryDelay, lockRetryCount).Return(&mockLock, nil).Once()
+	s.mockLocker.On("Lock", lockerKeyPrefix+key, lockTTL, lock.DefaultRetryDelay, lockRetryCount).Return(&mockLock, nil).Once()
 	mockLock.On("Unlock").Return(nil).Once()
-	s.mockRemoteCache.On("GetOrSet", testURLID, mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(b, nil).Once()
-	s.mockRemoteCache.On("Set", testURLID, &shortLink, mock.AnythingOfType("int64")).Return(nil).Once()
+	s.mockRemoteCache.On("GetOrSet", key, mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(b, nil).Once()
+	s.mockRemoteCache.On("Set", key, &shortLink, mock.AnythingOfType("int64")).Return(nil).Once()
 
-	sl, err := s.impl.Load(testURLID)
+	sl, err := s.impl.Load(context.Background(), testHost, testURLID)
 	s.NoError(err)
 	s.Equal(shortLink.URL, sl.URL)
 }
@@ -91,13 +232,23 @@ func (s *urlShortenerTestSuite) TestLoadCache() {
 		ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC),
 	}
 	b, _ := json.Marshal(shortLink)
-	s.mockRemoteCache.On("Get", testURLID).Return(b, nil)
+	db, _ := json.Marshal(testDomain)
+	s.mockRemoteCache.On("GetOrSet", domainKeyPrefix+testHost, mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(db, nil).Once()
+	s.mockRemoteCache.On("Get", shortLinkCacheKey(testDomain.ID, testURLID)).Return(b, nil).Once()
 
-	sl, err := s.impl.Load(testURLID)
+	sl, err := s.impl.Load(context.Background(), testHost, testURLID)
 	s.NoError(err)
 	s.Equal(shortLink.URL, sl.URL)
 }
 
+func (s *urlShortenerTestSuite) TestLoadDomainNotFound() {
+	b, _ := json.Marshal(dao.Domain{Host: "unknown.example.com"})
+	s.mockRemoteCache.On("GetOrSet", domainKeyPrefix+"unknown.example.com", mock.AnythingOfType("cache.RemoteEntryGenerator")).Return(b, nil).Once()
+
+	_, err := s.impl.Load(context.Background(), "unknown.example.com", testURLID)
+	s.Equal(ErrDomainNotFound, err)
+}
+
 func (s *urlShortenerTestSuite) TestRemoteEntryGen() {
 
 	shortLink := dao.ShortLink{
@@ -107,9 +258,9 @@ func (s *urlShortenerTestSuite) TestRemoteEntryGen() {
 	}
 	b, _ := json.Marshal(shortLink)
 
-	s.mockShortLinkDao.On("GetByURLID", testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("GetByURLID", testDomain.ID, testURLID).Return(&shortLink, nil).Once()
 
-	gen := s.impl.shortLinkRemoteEntryGen(testURLID)
+	gen := s.impl.shortLinkRemoteEntryGen(context.Background(), testDomain.ID, testURLID)
 	v, ttl, err := gen()
 	s.NoError(err)
 	s.GreaterOrEqual(ttl, defaultCacheTTL)
@@ -118,17 +269,694 @@ func (s *urlShortenerTestSuite) TestRemoteEntryGen() {
 
 func (s *urlShortenerTestSuite) TestRemoteEntryGenRecordNotFound() {
 	shortLink := dao.ShortLink{
+		DomainID: testDomain.ID,
 		URLID:    testURLID,
 		URL:      "",
 		ExpireAt: s.impl.clock.Now().Add(time.Duration(-1)),
 	}
 	b, _ := json.Marshal(shortLink)
 
-	s.mockShortLinkDao.On("GetByURLID", testURLID).Return(nil, gorm.ErrRecordNotFound).Once()
+	s.mockShortLinkDao.On("GetByURLID", testDomain.ID, testURLID).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	gen := s.impl.shortLinkRemoteEntryGen(context.Background(), testDomain.ID, testURLID)
+	v, ttl, err := gen()
+	s.NoError(err)
+	s.Equal(notFoundCacheTTL, ttl)
+	s.Equal(b, v)
+}
+
+func (s *urlShortenerTestSuite) TestDomainRemoteEntryGenRecordNotFound() {
+	b, _ := json.Marshal(dao.Domain{Host: "unknown.example.com"})
+
+	s.mockDomainDao.On("GetByHost", "unknown.example.com").Return(nil, gorm.ErrRecordNotFound).Once()
 
-	gen := s.impl.shortLinkRemoteEntryGen(testURLID)
+	gen := s.impl.domainRemoteEntryGen(context.Background(), "unknown.example.com")
 	v, ttl, err := gen()
 	s.NoError(err)
 	s.Equal(notFoundCacheTTL, ttl)
 	s.Equal(b, v)
 }
+
+func (s *urlShortenerTestSuite) TestLoadDefaultDomain() {
+	shortLink := dao.ShortLink{
+		DomainID: testDefaultDomain.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+		ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC),
+	}
+	b, _ := json.Marshal(shortLink)
+	s.mockRemoteCache.On("Get", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(b, nil).Once()
+
+	sl, err := s.impl.Load(context.Background(), "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(&testDefaultDomain, sl.Domain)
+}
+
+func (s *urlShortenerTestSuite) TestLoadNotFound() {
+	b, _ := json.Marshal(dao.ShortLink{DomainID: testDefaultDomain.ID, URLID: testURLID})
+	s.mockRemoteCache.On("Get", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(b, nil).Once()
+
+	_, err := s.impl.Load(context.Background(), "", testURLID)
+	s.Equal(ErrNotFound, err)
+}
+
+func (s *urlShortenerTestSuite) TestLoadActiveExpired() {
+	b, _ := json.Marshal(dao.ShortLink{
+		DomainID: testDefaultDomain.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+		ExpireAt: testNow.Add(-time.Second),
+	})
+	s.mockRemoteCache.On("Get", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(b, nil).Twice()
+
+	_, err := s.impl.LoadActive(context.Background(), "", testURLID)
+	s.Equal(ErrExpired, err)
+
+	sl, err := s.impl.Load(context.Background(), "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(testUploadURL, sl.URL)
+}
+
+func (s *urlShortenerTestSuite) TestLoadActiveStatus() {
+	for status, expected := range map[string]error{
+		dao.StatusDisabled: ErrDisabled,
+		dao.StatusDeleted:  ErrDeleted,
+		// short links cached before status is added are active
+		"": nil,
+	} {
+		b, _ := json.Marshal(dao.ShortLink{
+			DomainID: testDefaultDomain.ID,
+			URLID:    testURLID,
+			URL:      testUploadURL,
+			Status:   status,
+			ExpireAt: testNow.Add(time.Hour),
+		})
+		s.mockRemoteCache.On("Get", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(b, nil).Once()
+
+		_, err := s.impl.LoadActive(context.Background(), "", testURLID)
+		s.Equal(expected, err, status)
+	}
+}
+
+func (s *urlShortenerTestSuite) TestOwnerFallbackURL() {
+	key := shortLinkCacheKey(testDefaultDomain.ID, testURLID)
+	b, _ := json.Marshal(dao.ShortLink{DomainID: testDefaultDomain.ID, URLID: testURLID, OwnerID: 3, URL: testUploadURL})
+	s.mockRemoteCache.On("Get", key).Return(b, nil).Once()
+	s.mockOwnerDao.On("Get", uint64(3)).Return(&dao.Owner{ID: 3, FallbackURL: "https://example.com/gone"}, nil).Once()
+
+	fallbackURL, err := s.impl.OwnerFallbackURL(context.Background(), "", testURLID)
+	s.Require().NoError(err)
+	s.Equal("https://example.com/gone", fallbackURL)
+
+	// anonymous short links have no owner
+	b, _ = json.Marshal(dao.ShortLink{DomainID: testDefaultDomain.ID, URLID: testURLID, URL: testUploadURL})
+	s.mockRemoteCache.On("Get", key).Return(b, nil).Once()
+	fallbackURL, err = s.impl.OwnerFallbackURL(context.Background(), "", testURLID)
+	s.Require().NoError(err)
+	s.Empty(fallbackURL)
+}
+
+func (s *urlShortenerTestSuite) TestLoadUnavailable() {
+	key := shortLinkCacheKey(testDefaultDomain.ID, testURLID)
+	s.mockRemoteCache.On("Get", key).Return(nil, errors.New("connection refused")).Once()
+	_, err := s.impl.Load(context.Background(), "", testURLID)
+	s.True(errors.Is(err, ErrUnavailable))
+
+	s.mockRemoteCache.On("Get", key).Return(nil, redis.Nil).Once()
+	s.mockLocker.On("Lock", lockerKeyPrefix+key, lockTTL, lock.DefaultRetryDelay, lockRetryCount).Return(nil, errors.New("lock not obtained")).Once()
+	_, err = s.impl.Load(context.Background(), "", testURLID)
+	s.True(errors.Is(err, ErrUnavailable))
+}
+
+func (s *urlShortenerTestSuite) TestUploadFilteredID() {
+	mockFilter := &idfiltermocks.Filter{}
+	s.impl.idFilter = mockFilter
+	defer func() { s.impl.idFilter = idfilter.NewFilter(idfilter.Config{}, s.mockBlockedIDDao) }()
+
+	// rejected IDs are regenerated
+	mockFilter.On("Check", mock.AnythingOfType("string")).Return(idfilter.ErrBlocked).Once()
+	mockFilter.On("Check", mock.AnythingOfType("string")).Return(idfilter.ErrReserved).Once()
+	mockFilter.On("Check", mock.AnythingOfType("string")).Return(nil).Once()
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Once()
+	s.mockShortLinkDao.On("Create", mock.Anything).Return(nil).Once()
+
+	_, err := s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(time.Hour)})
+	s.Require().NoError(err)
+	mockFilter.AssertNumberOfCalls(s.T(), "Check", 3)
+
+	mockFilter.On("Check", mock.AnythingOfType("string")).Return(errors.New("connection refused")).Once()
+	_, err = s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(time.Hour)})
+	s.True(errors.Is(err, ErrUnavailable))
+}
+
+func (s *urlShortenerTestSuite) TestUploadIDsRunOut() {
+	mockFilter := &idfiltermocks.Filter{}
+	s.impl.idFilter = mockFilter
+	defer func() { s.impl.idFilter = idfilter.NewFilter(idfilter.Config{}, s.mockBlockedIDDao) }()
+
+	// generated IDs are either filtered or used
+	mockFilter.On("Check", mock.AnythingOfType("string")).Return(idfilter.ErrBlocked).Times(maxURLIDAttempts / 2)
+	mockFilter.On("Check", mock.AnythingOfType("string")).Return(nil).Times(maxURLIDAttempts / 2)
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(true, nil).Times(maxURLIDAttempts / 2)
+
+	_, err := s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(time.Hour)})
+	s.True(errors.Is(err, ErrUnavailable))
+	mockFilter.AssertExpectations(s.T())
+}
+
+func (s *urlShortenerTestSuite) TestBlockID() {
+	s.mockBlockedIDDao.On("Block", &dao.BlockedID{URLID: testURLID, Reason: "phishing"}).Return(nil).Once()
+	blockedID, err := s.impl.BlockID(testURLID, " phishing ")
+	s.Require().NoError(err)
+	s.Equal(testURLID, blockedID.URLID)
+
+	_, err = s.impl.BlockID("invalid id", "")
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	s.mockBlockedIDDao.On("Unblock", testURLID).Return(nil).Once()
+	s.NoError(s.impl.UnblockID(testURLID))
+}
+
+func (s *urlShortenerTestSuite) TestUploadConflict() {
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Once()
+	s.mockShortLinkDao.On("Create", mock.Anything).Return(errors.New("UNIQUE constraint failed: short_links.domain_id, short_links.url_id")).Once()
+
+	_, err := s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)})
+	s.Equal(ErrConflict, err)
+}
+
+func (s *urlShortenerTestSuite) TestUploadInvalidParams() {
+	_, err := s.impl.Upload(context.Background(), UploadParams{ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)})
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	for _, url := range []string{"not a url", "javascript:alert(1)", "/relative/path"} {
+		_, err = s.impl.Upload(context.Background(), UploadParams{URL: url, ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)})
+		s.True(errors.Is(err, ErrInvalidURL), url)
+	}
+
+	_, err = s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(-time.Hour)})
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	_, err = s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(time.Hour), Tags: []string{"a,b"}})
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	_, err = s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(time.Hour), FallbackURL: "javascript:alert(1)"})
+	s.True(errors.Is(err, ErrInvalidURL))
+
+	_, err = s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: dao.NeverExpire.Add(time.Second)})
+	s.True(errors.Is(err, ErrInvalidParams))
+}
+
+func (s *urlShortenerTestSuite) TestBatchUpload() {
+	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Twice()
+	s.mockShortLinkDao.On("CreateBatch", mock.AnythingOfType("[]*dao.ShortLink")).Return(nil).Once()
+
+	shortLinks, err := s.impl.BatchUpload(context.Background(), []UploadParams{
+		{URL: testUploadURL, ExpireAt: expireAt},
+		{URL: "https://example.com", ExpireAt: expireAt},
+	})
+	s.Require().NoError(err)
+	s.Len(shortLinks, 2)
+	s.Equal("https://example.com", shortLinks[1].URL)
+	s.NotEqual(shortLinks[0].URLID, shortLinks[1].URLID)
+}
+
+func (s *urlShortenerTestSuite) TestUploadWriteThrough() {
+	s.impl.writeThrough = true
+	defer func() { s.impl.writeThrough = false }()
+	expireAt := testNow.Add(time.Hour)
+
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Times(3)
+	s.mockShortLinkDao.On("Create", mock.Anything).Return(nil).Once()
+	s.mockShortLinkDao.On("CreateBatch", mock.AnythingOfType("[]*dao.ShortLink")).Return(nil).Once()
+	var cached []*dao.ShortLink
+	s.mockRemoteCache.On("Set", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).
+		Run(func(args mock.Arguments) {
+			var shortLink dao.ShortLink
+			s.Require().NoError(json.Unmarshal(args.Get(1).([]byte), &shortLink))
+			s.Equal(shortLinkCacheKey(shortLink.DomainID, shortLink.URLID), args.String(0))
+			// TTL is cut at the expiry
+			s.Equal(time.Hour+notFoundCacheTTL, args.Get(2))
+			cached = append(cached, &shortLink)
+		}).
+		Return(nil).
+		Times(3)
+
+	shortLink, err := s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: expireAt})
+	s.Require().NoError(err)
+	s.Require().Len(cached, 1)
+	s.Equal(shortLink.URLID, cached[0].URLID)
+	s.Equal(testUploadURL, cached[0].URL)
+
+	shortLinks, err := s.impl.BatchUpload(context.Background(), []UploadParams{
+		{URL: testUploadURL, ExpireAt: expireAt},
+		{URL: "https://example.com", ExpireAt: expireAt},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(cached, 3)
+	s.Equal(shortLinks[1].URLID, cached[2].URLID)
+}
+
+func (s *urlShortenerTestSuite) TestUploadWriteThroughFailed() {
+	s.impl.writeThrough = true
+	defer func() { s.impl.writeThrough = false }()
+
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Once()
+	s.mockShortLinkDao.On("Create", mock.Anything).Return(nil).Once()
+	s.mockRemoteCache.On("Set", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).
+		Return(errors.New("redis down")).
+		Once()
+
+	// the short link is loaded from db on cache misses
+	_, err := s.impl.Upload(context.Background(), UploadParams{URL: testUploadURL, ExpireAt: testNow.Add(time.Hour)})
+	s.NoError(err)
+}
+
+func (s *urlShortenerTestSuite) TestBatchUploadWriteThroughPartiallyFailed() {
+	s.impl.writeThrough = true
+	defer func() { s.impl.writeThrough = false }()
+	expireAt := testNow.Add(time.Hour)
+
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Times(3)
+	s.mockShortLinkDao.On("CreateBatch", mock.AnythingOfType("[]*dao.ShortLink")).Return(nil).Once()
+	s.mockRemoteCache.On("Set", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).
+		Return(errors.New("redis timeout")).
+		Once()
+	var cached []string
+	s.mockRemoteCache.On("Set", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).
+		Run(func(args mock.Arguments) { cached = append(cached, args.String(0)) }).
+		Return(nil).
+		Twice()
+
+	// the rest are cached after the first failure
+	shortLinks, err := s.impl.BatchUpload(context.Background(), []UploadParams{
+		{URL: testUploadURL, ExpireAt: expireAt},
+		{URL: "https://example.com", ExpireAt: expireAt},
+		{URL: "https://example.org", ExpireAt: expireAt},
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{
+		shortLinkCacheKey(shortLinks[1].DomainID, shortLinks[1].URLID),
+		shortLinkCacheKey(shortLinks[2].DomainID, shortLinks[2].URLID),
+	}, cached)
+	s.mockRemoteCache.AssertExpectations(s.T())
+}
+
+func (s *urlShortenerTestSuite) TestBatchUploadInvalid() {
+	expireAt := time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+
+	s.mockShortLinkDao.On("Exists", testDefaultDomain.ID, mock.Anything).Return(false, nil).Once()
+
+	_, err := s.impl.BatchUpload(context.Background(), []UploadParams{
+		{URL: testUploadURL, ExpireAt: expireAt},
+		{URL: "", ExpireAt: expireAt},
+	})
+	s.True(errors.Is(err, ErrInvalidParams))
+	s.Contains(err.Error(), "item 1")
+
+	params := make([]UploadParams, MaxBatchUploadSize+1)
+	for i := range params {
+		params[i] = UploadParams{URL: testUploadURL, ExpireAt: expireAt}
+	}
+	_, err = s.impl.BatchUpload(context.Background(), params)
+	s.True(errors.Is(err, ErrInvalidParams))
+}
+
+func (s *urlShortenerTestSuite) TestUpdate() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{
+		ID:       10,
+		DomainID: testDefaultDomain.ID,
+		OwnerID:  owner.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+		Preview:  true,
+		Broken:   true,
+		ExpireAt: time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC),
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("Update", mock.AnythingOfType("*dao.ShortLink"), "URL", "Preview", "Variants", "Tags", "FallbackURL").Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+
+	url := "https://example.com"
+	preview := false
+	variants := []split.Variant{{Name: "a", URL: "https://a.example.com", Weight: 1}, {Name: "b", URL: "https://b.example.com", Weight: 1}}
+	tags := []string{"jobs"}
+	fallbackURL := "https://example.com/maintenance"
+	sl, err := s.impl.Update(context.Background(), UpdateParams{
+		Owner:       &owner,
+		URLID:       testURLID,
+		URL:         &url,
+		Preview:     &preview,
+		Variants:    &variants,
+		Tags:        &tags,
+		FallbackURL: &fallbackURL,
+	})
+	s.Require().NoError(err)
+	s.Equal(dao.Tags{"jobs"}, sl.Tags)
+	s.Equal(fallbackURL, sl.FallbackURL)
+	s.Equal(sl, s.metadataWorker.last())
+	s.Equal(url, sl.URL)
+	s.False(sl.Preview)
+	s.Len(sl.Variants, 2)
+	s.Equal(shortLink.ExpireAt, sl.ExpireAt)
+	s.Equal(&testDefaultDomain, sl.Domain)
+}
+
+func (s *urlShortenerTestSuite) TestUpdateNotOwner() {
+	shortLink := dao.ShortLink{
+		ID:       10,
+		DomainID: testDefaultDomain.ID,
+		OwnerID:  3,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+
+	url := "https://example.com"
+	_, err := s.impl.Update(context.Background(), UpdateParams{Owner: &dao.Owner{ID: 4}, URLID: testURLID, URL: &url})
+	s.Equal(ErrNotFound, err)
+
+	_, err = s.impl.Update(context.Background(), UpdateParams{URLID: testURLID, URL: &url})
+	s.Equal(ErrOwnerRequired, err)
+}
+
+func (s *urlShortenerTestSuite) TestUpdateInvalidRules() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{
+		ID:       10,
+		DomainID: testDefaultDomain.ID,
+		OwnerID:  owner.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+
+	ruleSet := []rules.Rule{{Platforms: []string{rules.PlatformIOS}, URL: "javascript:alert(1)"}}
+	_, err := s.impl.Update(context.Background(), UpdateParams{Owner: &owner, URLID: testURLID, Rules: &ruleSet})
+	s.True(errors.Is(err, ErrInvalidURL))
+}
+
+func (s *urlShortenerTestSuite) TestUpdateInvalidVariants() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{
+		ID:       10,
+		DomainID: testDefaultDomain.ID,
+		OwnerID:  owner.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+
+	variants := []split.Variant{{Name: "a", URL: testUploadURL, Weight: 1}, {Name: "b", URL: "data:text/html,hi", Weight: 1}}
+	_, err := s.impl.Update(context.Background(), UpdateParams{Owner: &owner, URLID: testURLID, Variants: &variants})
+	s.True(errors.Is(err, ErrInvalidURL))
+}
+
+func (s *urlShortenerTestSuite) TestDelete() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{
+		ID:       11,
+		DomainID: testDefaultDomain.ID,
+		OwnerID:  owner.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("Delete", shortLink.ID, testNow).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+
+	s.Require().NoError(s.impl.Delete(context.Background(), &owner, "", testURLID))
+
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(nil, gorm.ErrRecordNotFound).Once()
+	s.Equal(ErrNotFound, s.impl.Delete(context.Background(), &owner, "", testURLID))
+}
+
+func (s *urlShortenerTestSuite) TestRestore() {
+	owner := dao.Owner{ID: 3}
+	deletedAt := testNow.Add(-time.Hour)
+	shortLink := dao.ShortLink{
+		ID:        12,
+		DomainID:  testDefaultDomain.ID,
+		OwnerID:   owner.ID,
+		URLID:     testURLID,
+		URL:       testUploadURL,
+		Status:    dao.StatusDeleted,
+		DeletedAt: &deletedAt,
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("Restore", shortLink.ID).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+
+	sl, err := s.impl.Restore(context.Background(), &owner, "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(dao.StatusActive, sl.Status)
+	s.Nil(sl.DeletedAt)
+
+	// short links not deleted are left unchanged
+	disabled := dao.ShortLink{ID: 12, DomainID: testDefaultDomain.ID, OwnerID: owner.ID, URLID: testURLID, Status: dao.StatusDisabled}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&disabled, nil).Once()
+	sl, err = s.impl.Restore(context.Background(), &owner, "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(dao.StatusDisabled, sl.Status)
+	s.mockShortLinkDao.AssertNumberOfCalls(s.T(), "Restore", 1)
+
+	// short links disabled before deletion are restored disabled
+	deletedDisabled := shortLink
+	deletedDisabled.Status = dao.StatusDeleted
+	deletedDisabled.StatusBeforeDelete = dao.StatusDisabled
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&deletedDisabled, nil).Once()
+	s.mockShortLinkDao.On("Restore", shortLink.ID).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+	sl, err = s.impl.Restore(context.Background(), &owner, "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(dao.StatusDisabled, sl.Status)
+	s.Empty(sl.StatusBeforeDelete)
+}
+
+func (s *urlShortenerTestSuite) TestSetExpiry() {
+	s.impl.expiryPolicy = ExpiryPolicy{MaxLifetime: 365 * 24 * time.Hour, GracePeriod: 7 * 24 * time.Hour}
+	defer func() { s.impl.expiryPolicy = ExpiryPolicy{} }()
+
+	owner := dao.Owner{ID: 3}
+	newShortLink := func(expireAt time.Time) *dao.ShortLink {
+		return &dao.ShortLink{
+			ID:             14,
+			DomainID:       testDefaultDomain.ID,
+			OwnerID:        owner.ID,
+			URLID:          testURLID,
+			URL:            testUploadURL,
+			ExpireNotified: expireAt.Before(testNow),
+			ExpireAt:       expireAt,
+			CreatedAt:      testNow.AddDate(0, -1, 0),
+		}
+	}
+	key := shortLinkCacheKey(testDefaultDomain.ID, testURLID)
+
+	// extended from the current expiry, and the cache entry is refreshed
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(newShortLink(testNow.Add(time.Hour)), nil).Once()
+	s.mockShortLinkDao.On("Update", mock.AnythingOfType("*dao.ShortLink"), "ExpireAt").Return(nil).Once()
+	s.mockRemoteCache.On("Set", key, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).Return(nil).Once()
+	sl, err := s.impl.SetExpiry(context.Background(), ExpiryParams{Owner: &owner, URLID: testURLID, ExtendBy: 24 * time.Hour})
+	s.Require().NoError(err)
+	s.Equal(testNow.Add(25*time.Hour), sl.ExpireAt)
+
+	// expired short links are revived within the grace period, extended from now
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(newShortLink(testNow.AddDate(0, 0, -1)), nil).Once()
+	s.mockShortLinkDao.On("Update", mock.AnythingOfType("*dao.ShortLink"), "ExpireAt").Return(nil).Once()
+	s.mockRemoteCache.On("Set", key, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).Return(nil).Once()
+	sl, err = s.impl.SetExpiry(context.Background(), ExpiryParams{Owner: &owner, URLID: testURLID, ExtendBy: 24 * time.Hour})
+	s.Require().NoError(err)
+	s.Equal(testNow.Add(24*time.Hour), sl.ExpireAt)
+
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(newShortLink(testNow.AddDate(0, 0, -8)), nil).Once()
+	_, err = s.impl.SetExpiry(context.Background(), ExpiryParams{Owner: &owner, URLID: testURLID, ExtendBy: 24 * time.Hour})
+	s.Equal(ErrExpired, err)
+
+	// the expiry could not be removed or exceed max lifetime
+	for _, params := range []ExpiryParams{
+		{Owner: &owner, URLID: testURLID, Never: true},
+		{Owner: &owner, URLID: testURLID, ExtendBy: 365 * 24 * time.Hour},
+	} {
+		s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(newShortLink(testNow.Add(time.Hour)), nil).Once()
+		_, err = s.impl.SetExpiry(context.Background(), params)
+		s.True(errors.Is(err, ErrInvalidParams))
+	}
+
+	s.impl.expiryPolicy.MaxLifetime = 0
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(newShortLink(testNow.Add(time.Hour)), nil).Once()
+	s.mockShortLinkDao.On("Update", mock.AnythingOfType("*dao.ShortLink"), "ExpireAt").Return(nil).Once()
+	s.mockRemoteCache.On("Set", key, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Duration")).Return(nil).Once()
+	sl, err = s.impl.SetExpiry(context.Background(), ExpiryParams{Owner: &owner, URLID: testURLID, Never: true})
+	s.Require().NoError(err)
+	s.Equal(dao.NeverExpire, sl.ExpireAt)
+
+	// short links which never expire could not be extended
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(newShortLink(dao.NeverExpire), nil).Once()
+	_, err = s.impl.SetExpiry(context.Background(), ExpiryParams{Owner: &owner, URLID: testURLID, ExtendBy: 24 * time.Hour})
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	// exactly one of expireAt, extendBy and never
+	expireAt := testNow.Add(time.Hour)
+	for _, params := range []ExpiryParams{
+		{Owner: &owner, URLID: testURLID},
+		{Owner: &owner, URLID: testURLID, ExpireAt: &expireAt, Never: true},
+		{Owner: &owner, URLID: testURLID, ExtendBy: -time.Hour},
+	} {
+		_, err = s.impl.SetExpiry(context.Background(), params)
+		s.True(errors.Is(err, ErrInvalidParams))
+	}
+}
+
+func (s *urlShortenerTestSuite) TestShortLinkCacheTTL() {
+	ttl := shortLinkCacheTTL(&dao.ShortLink{ExpireAt: dao.NeverExpire}, testNow)
+	s.True(ttl >= defaultCacheTTL)
+
+	ttl = shortLinkCacheTTL(&dao.ShortLink{ExpireAt: testNow.Add(time.Hour)}, testNow)
+	s.Equal(time.Hour+notFoundCacheTTL, ttl)
+
+	ttl = shortLinkCacheTTL(&dao.ShortLink{ExpireAt: testNow.AddDate(0, 0, -1)}, testNow)
+	s.Equal(notFoundCacheTTL, ttl)
+}
+
+func (s *urlShortenerTestSuite) TestPurge() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{
+		ID:       13,
+		DomainID: testDefaultDomain.ID,
+		OwnerID:  owner.ID,
+		URLID:    testURLID,
+		URL:      testUploadURL,
+		Status:   dao.StatusDeleted,
+	}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("Purge", shortLink.ID).Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+
+	s.Require().NoError(s.impl.Purge(context.Background(), &owner, "", testURLID))
+
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.Equal(ErrNotFound, s.impl.Purge(context.Background(), &dao.Owner{ID: 4}, "", testURLID))
+}
+
+func (s *urlShortenerTestSuite) TestUpdateStatus() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{ID: 14, DomainID: testDefaultDomain.ID, OwnerID: owner.ID, URLID: testURLID, URL: testUploadURL, Status: dao.StatusActive}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("Update", mock.AnythingOfType("*dao.ShortLink"), "Status").Return(nil).Once()
+	s.mockRemoteCache.On("Delete", shortLinkCacheKey(testDefaultDomain.ID, testURLID)).Return(nil).Once()
+
+	status := dao.StatusDisabled
+	sl, err := s.impl.Update(context.Background(), UpdateParams{Owner: &owner, URLID: testURLID, Status: &status})
+	s.Require().NoError(err)
+	s.Equal(dao.StatusDisabled, sl.Status)
+
+	// deleted short links are restored instead
+	status = dao.StatusDeleted
+	_, err = s.impl.Update(context.Background(), UpdateParams{Owner: &owner, URLID: testURLID, Status: &status})
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	deleted := dao.ShortLink{ID: 14, DomainID: testDefaultDomain.ID, OwnerID: owner.ID, URLID: testURLID, Status: dao.StatusDeleted}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&deleted, nil).Once()
+	status = dao.StatusActive
+	_, err = s.impl.Update(context.Background(), UpdateParams{Owner: &owner, URLID: testURLID, Status: &status})
+	s.Equal(ErrDeleted, err)
+
+	// deleted after being loaded
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Once()
+	s.mockShortLinkDao.On("Update", mock.AnythingOfType("*dao.ShortLink"), "Status").Return(dao.ErrShortLinkDeleted).Once()
+	_, err = s.impl.Update(context.Background(), UpdateParams{Owner: &owner, URLID: testURLID, Status: &status})
+	s.Equal(ErrDeleted, err)
+}
+
+func (s *urlShortenerTestSuite) TestNewURLID() {
+	for i := 0; i < 1000; i++ {
+		s.Len(newURLID(), urlIDLength)
+	}
+}
+
+func (s *urlShortenerTestSuite) TestList() {
+	owner := dao.Owner{ID: 3, Domains: []dao.Domain{testDomain}}
+	s.mockShortLinkDao.On("List", dao.ListFilter{OwnerID: owner.ID, Limit: 2}).Return([]*dao.ShortLink{
+		{ID: 12, DomainID: testDomain.ID, URLID: testURLID},
+		{ID: 10, DomainID: testDefaultDomain.ID, URLID: "bcdefghijkl"},
+	}, nil).Once()
+
+	shortLinks, next, err := s.impl.List(ListParams{Owner: &owner, Limit: 2})
+	s.Require().NoError(err)
+	s.Len(shortLinks, 2)
+	s.Equal(testDomain.ID, shortLinks[0].Domain.ID)
+	s.Equal(&testDefaultDomain, shortLinks[1].Domain)
+	s.NotEmpty(next)
+
+	s.mockShortLinkDao.On("List", dao.ListFilter{OwnerID: owner.ID, BeforeID: 10, Limit: 2}).Return([]*dao.ShortLink{
+		{ID: 8, DomainID: testDefaultDomain.ID, URLID: "cdefghijklm"},
+	}, nil).Once()
+
+	shortLinks, next, err = s.impl.List(ListParams{Owner: &owner, Cursor: next, Limit: 2})
+	s.Require().NoError(err)
+	s.Len(shortLinks, 1)
+	s.Empty(next)
+}
+
+func (s *urlShortenerTestSuite) TestListFilter() {
+	owner := dao.Owner{ID: 3}
+	createdFrom := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	s.mockShortLinkDao.On("List", dao.ListFilter{
+		OwnerID:     owner.ID,
+		Limit:       defaultListLimit,
+		Tags:        []string{"campaign"},
+		CreatedFrom: createdFrom,
+		Query:       "summer sale",
+		FullText:    true,
+	}).Return([]*dao.ShortLink{}, nil).Once()
+
+	shortLinks, next, err := s.impl.List(ListParams{
+		Owner:       &owner,
+		Tags:        []string{" campaign", "campaign"},
+		CreatedFrom: createdFrom,
+		Query:       " summer sale ",
+		Search:      SearchFullText,
+	})
+	s.Require().NoError(err)
+	s.Empty(shortLinks)
+	s.Empty(next)
+
+	_, _, err = s.impl.List(ListParams{Owner: &owner, Search: "regexp"})
+	s.True(errors.Is(err, ErrInvalidParams))
+}
+
+func (s *urlShortenerTestSuite) TestHealth() {
+	owner := dao.Owner{ID: 3}
+	shortLink := dao.ShortLink{ID: 12, DomainID: testDefaultDomain.ID, OwnerID: owner.ID, URLID: testURLID, URL: testUploadURL}
+	health := dao.LinkHealth{ShortLinkID: shortLink.ID, URL: testUploadURL, StatusCode: 200, CheckedAt: testNow}
+	s.mockShortLinkDao.On("GetByURLID", testDefaultDomain.ID, testURLID).Return(&shortLink, nil).Twice()
+	s.mockShortLinkDao.On("GetHealth", shortLink.ID).Return(&health, nil).Once()
+
+	loaded, err := s.impl.Health(&owner, "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(&health, loaded)
+
+	// the check is of the previous destination
+	health.URL = "https://example.com"
+	s.mockShortLinkDao.On("GetHealth", shortLink.ID).Return(&health, nil).Once()
+	loaded, err = s.impl.Health(&owner, "", testURLID)
+	s.Require().NoError(err)
+	s.Equal(&dao.LinkHealth{ShortLinkID: shortLink.ID}, loaded)
+
+	_, err = s.impl.Health(nil, "", testURLID)
+	s.Equal(ErrOwnerRequired, err)
+}
+
+func (s *urlShortenerTestSuite) TestListInvalid() {
+	_, _, err := s.impl.List(ListParams{})
+	s.Equal(ErrOwnerRequired, err)
+
+	_, _, err = s.impl.List(ListParams{Owner: &dao.Owner{ID: 3}, Cursor: "!"})
+	s.True(errors.Is(err, ErrInvalidParams))
+
+	_, _, err = s.impl.List(ListParams{Owner: &dao.Owner{ID: 3}, Limit: 1000})
+	s.True(errors.Is(err, ErrInvalidParams))
+}
@@ -0,0 +1,368 @@
+package urlshortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/webhook"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookDispatchLockKey = "webhook_dispatch"
+	// webhookDispatchLockTTL also limits a round, so events are not delivered by another replica while being
+	// delivered.
+	webhookDispatchLockTTL = time.Minute
+
+	defaultWebhookPollInterval = 5 * time.Second
+	defaultWebhookBatchSize    = 100
+	defaultWebhookWorkers      = 4
+	defaultWebhookMaxAttempts  = 10
+	defaultWebhookBackoff      = 30 * time.Second
+	maxWebhookBackoff          = 24 * time.Hour
+
+	maxWebhooks         = 10
+	webhookSecretLength = 24
+	maxDeliveryError    = 256
+)
+
+func (s *urlShortenerImpl) CreateWebhook(params WebhookParams) (*dao.Webhook, error) {
+	if params.Owner == nil {
+		return nil, ErrOwnerRequired
+	}
+	if err := validate.Struct(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	if err := validateURL(params.URL); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookDao.ListByOwner(params.Owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) >= maxWebhooks {
+		return nil, fmt.Errorf("%w: at most %d webhooks are allowed", ErrInvalidParams, maxWebhooks)
+	}
+
+	secret := params.Secret
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+	// events are deduplicated in order of dao.EventTypes
+	var events []string
+	for _, eventType := range dao.EventTypes {
+		for _, e := range params.Events {
+			if e == eventType {
+				events = append(events, eventType)
+				break
+			}
+		}
+	}
+
+	w := &dao.Webhook{
+		OwnerID: params.Owner.ID,
+		URL:     params.URL,
+		Secret:  secret,
+		Events:  strings.Join(events, ","),
+	}
+	if err := s.webhookDao.Create(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *urlShortenerImpl) ListWebhooks(owner *dao.Owner) ([]*dao.Webhook, error) {
+	if owner == nil {
+		return nil, ErrOwnerRequired
+	}
+	return s.webhookDao.ListByOwner(owner.ID)
+}
+
+func (s *urlShortenerImpl) DeleteWebhook(owner *dao.Owner, webhookID uint64) error {
+	w, err := s.ownedWebhook(owner, webhookID)
+	if err != nil {
+		return err
+	}
+	return s.webhookDao.Delete(w.ID)
+}
+
+func (s *urlShortenerImpl) ListDeliveries(params ListDeliveriesParams) ([]*dao.OutboxEvent, string, error) {
+	if err := validate.Struct(params); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	w, err := s.ownedWebhook(params.Owner, params.WebhookID)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+
+	var beforeID uint64
+	if params.Cursor != "" {
+		id, err := base62.Decode(params.Cursor)
+		if err != nil || id == 0 {
+			return nil, "", fmt.Errorf("%w: cursor is invalid", ErrInvalidParams)
+		}
+		beforeID = id
+	}
+
+	events, err := s.webhookDao.ListEvents(dao.EventFilter{
+		WebhookID: w.ID,
+		Status:    params.Status,
+		BeforeID:  beforeID,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(events) == limit {
+		next = base62.Encode(events[len(events)-1].ID)
+	}
+	return events, next, nil
+}
+
+func (s *urlShortenerImpl) ReplayDelivery(owner *dao.Owner, webhookID, eventID uint64) (*dao.OutboxEvent, error) {
+	w, err := s.ownedWebhook(owner, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := s.webhookDao.GetEvent(eventID)
+	if dao.IsErrRecordNotFound(err) {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if event.WebhookID != w.ID {
+		return nil, ErrWebhookNotFound
+	}
+
+	// attempts are counted from zero, so dead events are retried as many times as new ones
+	event.Status = dao.DeliveryPending
+	event.Attempts = 0
+	event.NextAttemptAt = s.clock.Now()
+	event.LastError = ""
+	event.DeliveredAt = nil
+	if err := s.webhookDao.UpdateEvent(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ownedWebhook returns the webhook from db if owner owns it.
+func (s *urlShortenerImpl) ownedWebhook(owner *dao.Owner, webhookID uint64) (*dao.Webhook, error) {
+	if owner == nil {
+		return nil, ErrOwnerRequired
+	}
+
+	w, err := s.webhookDao.Get(webhookID)
+	if dao.IsErrRecordNotFound(err) {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if w.OwnerID != owner.ID {
+		return nil, ErrWebhookNotFound
+	}
+	return w, nil
+}
+
+// newWebhookSecret returns a random secret in hex.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type webhookDispatcherImpl struct {
+	sender       webhook.Sender
+	shortLinkDao dao.ShortLinkDao
+	webhookDao   dao.WebhookDao
+	locker       lock.DistributedLocker
+	clock        clock.Clock
+	config       WebhookDispatcherConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWebhookDispatcher creates an instance of WebhookDispatcher.
+func NewWebhookDispatcher(
+	sender webhook.Sender,
+	shortLinkDao dao.ShortLinkDao,
+	webhookDao dao.WebhookDao,
+	locker lock.DistributedLocker,
+	clock clock.Clock,
+	config WebhookDispatcherConfig,
+) WebhookDispatcher {
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultWebhookPollInterval
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultWebhookBatchSize
+	}
+	if config.Workers == 0 {
+		config.Workers = defaultWebhookWorkers
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if config.Backoff == 0 {
+		config.Backoff = defaultWebhookBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookDispatcherImpl{
+		sender:       sender,
+		shortLinkDao: shortLinkDao,
+		webhookDao:   webhookDao,
+		locker:       locker,
+		clock:        clock,
+		config:       config,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (d *webhookDispatcherImpl) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-d.clock.After(d.config.PollInterval):
+				d.dispatch()
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (d *webhookDispatcherImpl) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// dispatch raises expired events and delivers due events by one of replicas holding the lock.
+func (d *webhookDispatcherImpl) dispatch() {
+	l, err := d.locker.Lock(webhookDispatchLockKey, webhookDispatchLockTTL, lock.DefaultRetryDelay, 0)
+	if err != nil {
+		zap.S().Debugf("skip webhook dispatch, err: %v", err)
+		return
+	}
+	defer l.Unlock()
+
+	now := d.clock.Now()
+	if _, err := d.shortLinkDao.CreateExpiredEvents(now, d.config.BatchSize); err != nil {
+		zap.S().Warnf("fail to create expired events, err: %v", err)
+	}
+
+	events, err := d.webhookDao.ListDueEvents(now, d.config.BatchSize)
+	if err != nil {
+		zap.S().Warnf("fail to list due events, err: %v", err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+	webhookIDs := make([]uint64, 0, len(events))
+	for _, event := range events {
+		webhookIDs = append(webhookIDs, event.WebhookID)
+	}
+	webhooks, err := d.webhookDao.GetByIDs(webhookIDs)
+	if err != nil {
+		zap.S().Warnf("fail to get webhooks, err: %v", err)
+		return
+	}
+	byID := make(map[uint64]*dao.Webhook, len(webhooks))
+	for _, w := range webhooks {
+		byID[w.ID] = w
+	}
+
+	// deliveries stop before the lock expires, unfinished ones are retried in the next round
+	ctx, cancel := context.WithTimeout(d.ctx, webhookDispatchLockTTL)
+	defer cancel()
+	jobs := make(chan *dao.OutboxEvent)
+	var wg sync.WaitGroup
+	for i := 0; i < d.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range jobs {
+				// events of deleted webhooks are deleted with them
+				if w, ok := byID[event.WebhookID]; ok {
+					d.deliver(ctx, w, event)
+				}
+			}
+		}()
+	}
+	for _, event := range events {
+		jobs <- event
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// deliver sends event to w and records the result, the event is retried with exponential backoff until
+// MaxAttempts.
+func (d *webhookDispatcherImpl) deliver(ctx context.Context, w *dao.Webhook, event *dao.OutboxEvent) {
+	if ctx.Err() != nil {
+		return
+	}
+	logger := zap.S().With("webhookId", w.ID, "eventId", event.ID)
+
+	err := d.sender.Send(ctx, w.URL, w.Secret, &webhook.Event{
+		ID:        base62.Encode(event.ID),
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      json.RawMessage(event.Payload),
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := d.clock.Now()
+	event.Attempts++
+	if err == nil {
+		event.Status = dao.DeliveryDelivered
+		event.LastError = ""
+		event.DeliveredAt = &now
+	} else {
+		event.LastError = truncate(err.Error(), maxDeliveryError)
+		if !webhook.IsRetryable(err) || event.Attempts >= d.config.MaxAttempts {
+			event.Status = dao.DeliveryDead
+			logger.Infof("webhook event is dead after %d attempts, err: %v", event.Attempts, err)
+		} else {
+			backoff := d.config.Backoff << (event.Attempts - 1)
+			if backoff <= 0 || backoff > maxWebhookBackoff {
+				backoff = maxWebhookBackoff
+			}
+			event.NextAttemptAt = now.Add(backoff)
+		}
+	}
+	if err := d.webhookDao.UpdateEvent(event); err != nil {
+		logger.Warnf("fail to update webhook event, err: %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package urlshortener
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/base/lock"
+	lockmocks "github.com/georgechang0117/url-shortener/base/lock/mocks"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	daomocks "github.com/georgechang0117/url-shortener/core/dao/mocks"
+	"github.com/georgechang0117/url-shortener/core/webhook"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+func (s *urlShortenerTestSuite) TestCreateWebhook() {
+	owner := dao.Owner{ID: 3}
+	s.mockWebhookDao.On("ListByOwner", owner.ID).Return([]*dao.Webhook{}, nil).Once()
+	s.mockWebhookDao.On("Create", mock.AnythingOfType("*dao.Webhook")).Return(nil).Once()
+
+	w, err := s.impl.CreateWebhook(WebhookParams{
+		Owner:  &owner,
+		URL:    "https://example.com/hook",
+		Events: []string{dao.EventLinkDeleted, dao.EventLinkCreated, dao.EventLinkDeleted},
+	})
+	s.Require().NoError(err)
+	s.Equal(owner.ID, w.OwnerID)
+	s.Equal("link.created,link.deleted", w.Events)
+	// a random secret is generated
+	s.Len(w.Secret, webhookSecretLength*2)
+}
+
+func (s *urlShortenerTestSuite) TestCreateWebhookInvalidParams() {
+	owner := dao.Owner{ID: 3}
+	for _, params := range []WebhookParams{
+		{Owner: &owner, URL: "https://example.com/hook"},
+		{Owner: &owner, URL: "https://example.com/hook", Events: []string{"link.clicked"}},
+		{Owner: &owner, URL: "https://example.com/hook", Events: []string{dao.EventLinkCreated}, Secret: "short"},
+	} {
+		_, err := s.impl.CreateWebhook(params)
+		s.True(errors.Is(err, ErrInvalidParams), params)
+	}
+
+	_, err := s.impl.CreateWebhook(WebhookParams{Owner: &owner, URL: "ftp://example.com", Events: []string{dao.EventLinkCreated}})
+	s.True(errors.Is(err, ErrInvalidURL))
+
+	_, err = s.impl.CreateWebhook(WebhookParams{URL: "https://example.com/hook", Events: []string{dao.EventLinkCreated}})
+	s.True(errors.Is(err, ErrOwnerRequired))
+
+	s.mockWebhookDao.On("ListByOwner", owner.ID).Return(make([]*dao.Webhook, maxWebhooks), nil).Once()
+	_, err = s.impl.CreateWebhook(WebhookParams{Owner: &owner, URL: "https://example.com/hook", Events: []string{dao.EventLinkCreated}})
+	s.True(errors.Is(err, ErrInvalidParams))
+}
+
+func (s *urlShortenerTestSuite) TestDeleteWebhookNotOwner() {
+	s.mockWebhookDao.On("Get", uint64(5)).Return(&dao.Webhook{ID: 5, OwnerID: 4}, nil).Once()
+
+	err := s.impl.DeleteWebhook(&dao.Owner{ID: 3}, 5)
+	s.True(errors.Is(err, ErrWebhookNotFound))
+	s.mockWebhookDao.AssertNotCalled(s.T(), "Delete", uint64(5))
+}
+
+func (s *urlShortenerTestSuite) TestListDeliveries() {
+	owner := dao.Owner{ID: 3}
+	s.mockWebhookDao.On("Get", uint64(5)).Return(&dao.Webhook{ID: 5, OwnerID: owner.ID}, nil).Once()
+	s.mockWebhookDao.On("ListEvents", dao.EventFilter{WebhookID: 5, Status: dao.DeliveryDead, BeforeID: 100, Limit: 2}).
+		Return([]*dao.OutboxEvent{{ID: 99}, {ID: 98}}, nil).
+		Once()
+
+	events, next, err := s.impl.ListDeliveries(ListDeliveriesParams{
+		Owner:     &owner,
+		WebhookID: 5,
+		Status:    dao.DeliveryDead,
+		Cursor:    base62.Encode(100),
+		Limit:     2,
+	})
+	s.Require().NoError(err)
+	s.Len(events, 2)
+	s.Equal(base62.Encode(98), next)
+}
+
+func (s *urlShortenerTestSuite) TestReplayDelivery() {
+	owner := dao.Owner{ID: 3}
+	deliveredAt := testNow.Add(-time.Hour)
+	s.mockWebhookDao.On("Get", uint64(5)).Return(&dao.Webhook{ID: 5, OwnerID: owner.ID}, nil).Twice()
+	s.mockWebhookDao.On("GetEvent", uint64(7)).Return(&dao.OutboxEvent{
+		ID:          7,
+		WebhookID:   5,
+		Status:      dao.DeliveryDelivered,
+		Attempts:    2,
+		LastError:   "unexpected status 500",
+		DeliveredAt: &deliveredAt,
+	}, nil).Once()
+	s.mockWebhookDao.On("UpdateEvent", &dao.OutboxEvent{
+		ID:            7,
+		WebhookID:     5,
+		Status:        dao.DeliveryPending,
+		NextAttemptAt: testNow,
+	}).Return(nil).Once()
+
+	event, err := s.impl.ReplayDelivery(&owner, 5, 7)
+	s.Require().NoError(err)
+	s.Equal(dao.DeliveryPending, event.Status)
+
+	// events of other webhooks are not visible
+	s.mockWebhookDao.On("GetEvent", uint64(8)).Return(&dao.OutboxEvent{ID: 8, WebhookID: 6}, nil).Once()
+	_, err = s.impl.ReplayDelivery(&owner, 5, 8)
+	s.True(errors.Is(err, ErrWebhookNotFound))
+}
+
+type webhookDispatcherTestSuite struct {
+	suite.Suite
+	impl             *webhookDispatcherImpl
+	clock            *fakeclock.FakeClock
+	server           *httptest.Server
+	status           int
+	received         chan *http.Request
+	bodies           chan []byte
+	mockShortLinkDao *daomocks.ShortLinkDao
+	mockWebhookDao   *daomocks.WebhookDao
+	mockLocker       *lockmocks.DistributedLocker
+}
+
+func TestWebhookDispatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(webhookDispatcherTestSuite))
+}
+
+func (s *webhookDispatcherTestSuite) SetupTest() {
+	s.status = http.StatusNoContent
+	s.received = make(chan *http.Request, 10)
+	s.bodies = make(chan []byte, 10)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.received <- r
+		s.bodies <- body
+		w.WriteHeader(s.status)
+	}))
+
+	s.clock = fakeclock.NewFakeClock(testNow)
+	s.mockShortLinkDao = &daomocks.ShortLinkDao{}
+	s.mockWebhookDao = &daomocks.WebhookDao{}
+	s.mockLocker = &lockmocks.DistributedLocker{}
+	// the test endpoint listens on loopback
+	sender := webhook.NewSender(webhook.SenderConfig{Timeout: time.Second, AllowPrivateNetworks: true})
+	impl := NewWebhookDispatcher(sender, s.mockShortLinkDao, s.mockWebhookDao, s.mockLocker, s.clock, WebhookDispatcherConfig{
+		BatchSize:   10,
+		Workers:     2,
+		MaxAttempts: 3,
+		Backoff:     time.Minute,
+	})
+	s.impl = impl.(*webhookDispatcherImpl)
+}
+
+func (s *webhookDispatcherTestSuite) TearDownTest() {
+	s.impl.Stop()
+	s.server.Close()
+}
+
+// expectRound expects a round delivering events to w.
+func (s *webhookDispatcherTestSuite) expectRound(w *dao.Webhook, events []*dao.OutboxEvent) {
+	mockLock := &lockmocks.Lock{}
+	mockLock.On("Unlock").Return(nil).Once()
+	s.mockLocker.On("Lock", webhookDispatchLockKey, webhookDispatchLockTTL, lock.DefaultRetryDelay, 0).Return(mockLock, nil).Once()
+	s.mockShortLinkDao.On("CreateExpiredEvents", testNow, 10).Return(0, nil).Once()
+	s.mockWebhookDao.On("ListDueEvents", testNow, 10).Return(events, nil).Once()
+	var ids []uint64
+	for range events {
+		ids = append(ids, w.ID)
+	}
+	s.mockWebhookDao.On("GetByIDs", ids).Return([]*dao.Webhook{w}, nil).Once()
+}
+
+func (s *webhookDispatcherTestSuite) TestDispatch() {
+	w := &dao.Webhook{ID: 5, URL: s.server.URL + "/hook", Secret: testWebhookSecret}
+	event := &dao.OutboxEvent{
+		ID:            1000,
+		WebhookID:     w.ID,
+		Type:          dao.EventLinkCreated,
+		Payload:       `{"id":"abc","domain":"go.example.com","url":"https://example.com","expireAt":"2021-08-01T00:00:00Z"}`,
+		Status:        dao.DeliveryPending,
+		NextAttemptAt: testNow,
+		CreatedAt:     testNow,
+	}
+	s.expectRound(w, []*dao.OutboxEvent{event})
+	s.mockWebhookDao.On("UpdateEvent", mock.MatchedBy(func(e *dao.OutboxEvent) bool {
+		return e.ID == event.ID && e.Status == dao.DeliveryDelivered && e.Attempts == 1 && e.DeliveredAt.Equal(testNow)
+	})).Return(nil).Once()
+
+	s.impl.dispatch()
+	s.mockWebhookDao.AssertExpectations(s.T())
+
+	r, body := <-s.received, <-s.bodies
+	s.Equal("/hook", r.URL.Path)
+	s.Equal(base62.Encode(event.ID), r.Header.Get(webhook.HeaderID))
+	s.Equal(dao.EventLinkCreated, r.Header.Get(webhook.HeaderEvent))
+	timestamp, err := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	s.Require().NoError(err)
+	s.True(webhook.Verify(testWebhookSecret, r.Header.Get(webhook.HeaderSignature), timestamp, body))
+
+	var delivered webhook.Event
+	s.Require().NoError(json.Unmarshal(body, &delivered))
+	s.Equal(dao.EventLinkCreated, delivered.Type)
+	s.Equal(testNow, delivered.CreatedAt)
+	s.JSONEq(event.Payload, string(delivered.Data))
+}
+
+func (s *webhookDispatcherTestSuite) TestDispatchRetry() {
+	s.status = http.StatusInternalServerError
+	w := &dao.Webhook{ID: 5, URL: s.server.URL, Secret: testWebhookSecret}
+	retried := &dao.OutboxEvent{ID: 1, WebhookID: w.ID, Status: dao.DeliveryPending, Attempts: 1, Payload: "{}"}
+	exhausted := &dao.OutboxEvent{ID: 2, WebhookID: w.ID, Status: dao.DeliveryPending, Attempts: 2, Payload: "{}"}
+	s.expectRound(w, []*dao.OutboxEvent{retried, exhausted})
+	// backoff is doubled for the second retry
+	s.mockWebhookDao.On("UpdateEvent", mock.MatchedBy(func(e *dao.OutboxEvent) bool {
+		return e.ID == retried.ID && e.Status == dao.DeliveryPending && e.Attempts == 2 &&
+			e.NextAttemptAt.Equal(testNow.Add(2*time.Minute)) && e.LastError == "unexpected status 500"
+	})).Return(nil).Once()
+	s.mockWebhookDao.On("UpdateEvent", mock.MatchedBy(func(e *dao.OutboxEvent) bool {
+		return e.ID == exhausted.ID && e.Status == dao.DeliveryDead && e.Attempts == 3
+	})).Return(nil).Once()
+
+	s.impl.dispatch()
+	s.mockWebhookDao.AssertExpectations(s.T())
+	s.Len(s.received, 2)
+}
+
+func (s *webhookDispatcherTestSuite) TestDispatchBlocked() {
+	w := &dao.Webhook{ID: 5, URL: s.server.URL, Secret: testWebhookSecret}
+	s.impl.sender = webhook.NewSender(webhook.SenderConfig{Timeout: time.Second})
+	event := &dao.OutboxEvent{ID: 1, WebhookID: w.ID, Status: dao.DeliveryPending, Payload: "{}"}
+	s.expectRound(w, []*dao.OutboxEvent{event})
+	// endpoints in blocked networks are not retried
+	s.mockWebhookDao.On("UpdateEvent", mock.MatchedBy(func(e *dao.OutboxEvent) bool {
+		return e.Status == dao.DeliveryDead && e.Attempts == 1
+	})).Return(nil).Once()
+
+	s.impl.dispatch()
+	s.mockWebhookDao.AssertExpectations(s.T())
+	s.Empty(s.received)
+}
+
+func (s *webhookDispatcherTestSuite) TestSkipLocked() {
+	s.mockLocker.On("Lock", webhookDispatchLockKey, webhookDispatchLockTTL, lock.DefaultRetryDelay, 0).
+		Return(nil, errors.New("lock timeout"))
+
+	s.impl.Start()
+	s.clock.WaitForWatcherAndIncrement(defaultWebhookPollInterval)
+	// the next round is scheduled after the skipped one
+	s.clock.WaitForWatcherAndIncrement(defaultWebhookPollInterval)
+	s.mockWebhookDao.AssertNotCalled(s.T(), "ListDueEvents", mock.Anything, mock.Anything)
+}
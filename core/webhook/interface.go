@@ -0,0 +1,72 @@
+// Package webhook signs and sends events to webhook endpoints.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/safehttp"
+)
+
+const (
+	// HeaderID is the header carrying ID of the event, which is the same for retries and replays so endpoints
+	// can drop duplicates.
+	HeaderID = "X-Webhook-ID"
+	// HeaderEvent is the header carrying type of the event.
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp is the header carrying unix time of sending, which is signed with the body.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is the header carrying signature of the delivery, see Sign.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var (
+	// ErrBlocked indicates the endpoint resolves to an address which is not allowed to connect, e.g. loopback
+	// or private networks.
+	ErrBlocked = safehttp.ErrBlocked
+	// ErrRedirect indicates the endpoint redirects, which is not followed.
+	ErrRedirect = safehttp.ErrRedirect
+)
+
+// StatusError indicates the endpoint responds a status other than 2xx.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// IsRetryable returns whether sending could succeed later. Endpoints in blocked networks or redirecting are
+// misconfigured, they're not retried.
+func IsRetryable(err error) bool {
+	return !errors.Is(err, ErrBlocked) && !errors.Is(err, ErrRedirect)
+}
+
+// Event defines body of deliveries.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SenderConfig defines config of Sender, zero values use defaults.
+type SenderConfig struct {
+	// Timeout limits a delivery, defaultTimeout if zero.
+	Timeout   time.Duration
+	UserAgent string
+	// AllowPrivateNetworks allows endpoints in loopback and private networks, which are blocked by default
+	// since owners could reach internal services by webhooks.
+	AllowPrivateNetworks bool
+}
+
+// Sender defines interface of delivering events to webhook endpoints.
+type Sender interface {
+	// Send posts event to rawURL signed by secret, it fails with StatusError if the endpoint does not respond
+	// 2xx.
+	Send(ctx context.Context, rawURL, secret string, event *Event) error
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery v2.3.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	webhook "github.com/georgechang0117/url-shortener/core/webhook"
+	mock "github.com/stretchr/testify/mock"
+
+	context "context"
+)
+
+// Sender is an autogenerated mock type for the Sender type
+type Sender struct {
+	mock.Mock
+}
+
+// Send provides a mock function with given fields: ctx, rawURL, secret, event
+func (_m *Sender) Send(ctx context.Context, rawURL string, secret string, event *webhook.Event) error {
+	ret := _m.Called(ctx, rawURL, secret, event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *webhook.Event) error); ok {
+		r0 = rf(ctx, rawURL, secret, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
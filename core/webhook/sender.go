@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/safehttp"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second
+	defaultUserAgent = "url-shortener-webhook/1.0"
+
+	signaturePrefix = "sha256="
+	// maxDrainSize limits response body read before closing, which keeps the connection for reuse.
+	maxDrainSize = 4 << 10
+)
+
+type senderImpl struct {
+	config SenderConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender creates an instance of Sender, redirects of endpoints are not followed.
+func NewSender(config SenderConfig) Sender {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+
+	blocked := safehttp.IsBlocked
+	if config.AllowPrivateNetworks {
+		blocked = func(ip net.IP) bool { return false }
+	}
+	return &senderImpl{
+		config: config,
+		client: safehttp.NewClient(safehttp.Config{Timeout: config.Timeout, Blocked: blocked}),
+		now:    time.Now,
+	}
+}
+
+func (s *senderImpl) Send(ctx context.Context, rawURL, secret string, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := s.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	req.Header.Set(HeaderID, event.ID)
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrainSize))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns signature of a delivery sent at timestamp, which is HMAC-SHA256 of "<timestamp>.<body>" by
+// secret in hex, prefixed by "sha256=". Signing timestamp keeps captured deliveries from being replayed later.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature of a delivery sent at timestamp in constant time, endpoints should also reject
+// timestamps too far from now.
+func Verify(secret, signature string, timestamp int64, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testSecret = "test-secret"
+
+var testNow = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+type senderTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	received chan received
+	impl     *senderImpl
+}
+
+func TestSenderTestSuite(t *testing.T) {
+	suite.Run(t, new(senderTestSuite))
+}
+
+func (s *senderTestSuite) SetupTest() {
+	s.received = make(chan received, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.received <- received{header: r.Header, body: body}
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusTemporaryRedirect)
+	})
+	s.server = httptest.NewServer(mux)
+
+	// the test endpoint listens on loopback
+	s.impl = NewSender(SenderConfig{Timeout: time.Second, AllowPrivateNetworks: true}).(*senderImpl)
+	s.impl.now = func() time.Time { return testNow }
+}
+
+func (s *senderTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *senderTestSuite) TestSend() {
+	event := &Event{ID: "1c", Type: "link.created", CreatedAt: testNow, Data: json.RawMessage(`{"id":"abc"}`)}
+	s.Require().NoError(s.impl.Send(context.Background(), s.server.URL+"/ok", testSecret, event))
+
+	r := <-s.received
+	s.Equal("1c", r.header.Get(HeaderID))
+	s.Equal("link.created", r.header.Get(HeaderEvent))
+	s.Equal("application/json", r.header.Get("Content-Type"))
+	timestamp, err := strconv.ParseInt(r.header.Get(HeaderTimestamp), 10, 64)
+	s.Require().NoError(err)
+	s.Equal(testNow.Unix(), timestamp)
+	s.True(Verify(testSecret, r.header.Get(HeaderSignature), timestamp, r.body))
+	s.False(Verify("another-secret", r.header.Get(HeaderSignature), timestamp, r.body))
+	s.False(Verify(testSecret, r.header.Get(HeaderSignature), timestamp+1, r.body))
+
+	var body Event
+	s.Require().NoError(json.Unmarshal(r.body, &body))
+	s.Equal(*event, body)
+}
+
+func (s *senderTestSuite) TestSendFailed() {
+	err := s.impl.Send(context.Background(), s.server.URL+"/fail", testSecret, &Event{})
+	var statusErr *StatusError
+	s.Require().True(errors.As(err, &statusErr))
+	s.Equal(http.StatusServiceUnavailable, statusErr.StatusCode)
+	s.True(IsRetryable(err))
+}
+
+func (s *senderTestSuite) TestSendRedirect() {
+	err := s.impl.Send(context.Background(), s.server.URL+"/moved", testSecret, &Event{})
+	s.True(errors.Is(err, ErrRedirect))
+	s.False(IsRetryable(err))
+	s.Empty(s.received)
+}
+
+func (s *senderTestSuite) TestSendBlocked() {
+	err := NewSender(SenderConfig{}).Send(context.Background(), s.server.URL+"/ok", testSecret, &Event{})
+	s.True(errors.Is(err, ErrBlocked))
+	s.False(IsRetryable(err))
+}
+
+func (s *senderTestSuite) TestSign() {
+	// HMAC-SHA256 of "1625097600.{}" by "test-secret"
+	s.Equal("sha256=b7ce5d3ca35168dec411727f48a35d32eba8a2e029bf5d31ddacd1c44d02244f", Sign(testSecret, 1625097600, []byte("{}")))
+}
@@ -1,19 +1,31 @@
 package main
 
 import (
+	"database/sql"
 	"flag"
 	"fmt"
 	"log"
 	"math/rand"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/clock"
 	"github.com/georgechang0117/url-shortener/base/cache"
 	"github.com/georgechang0117/url-shortener/base/lock"
+	"github.com/georgechang0117/url-shortener/base/logging"
+	"github.com/georgechang0117/url-shortener/base/redisclient"
 	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/health"
+	"github.com/georgechang0117/url-shortener/core/idfilter"
+	"github.com/georgechang0117/url-shortener/core/metadata"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/stats"
 	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/core/webhook"
 	"github.com/georgechang0117/url-shortener/rest"
+	"github.com/georgechang0117/url-shortener/rpc"
 	"github.com/go-redis/redis"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
@@ -24,10 +36,68 @@ var (
 	mysqlConnStr  = os.Getenv("MYSQL_CONN_STR")
 	mysqlUser     = os.Getenv("MYSQL_USER")
 	mysqlPassword = os.Getenv("MYSQL_PASSWORD")
+	redisPassword = os.Getenv("REDIS_PASSWORD")
 
 	restHost  = flag.String("rest_host", "", "rest host")
 	restPort  = flag.Int("rest_port", 80, "rest port")
-	redisAddr = flag.String("redis_addr", "", "redis address")
+	grpcPort  = flag.Int("grpc_port", 0, "grpc port, grpc server is disabled if 0")
+	redisAddr = flag.String("redis_addr", "", "redis address, comma-separated sentinels or seed nodes in sentinel and cluster mode")
+
+	redisMode                  = flag.String("redis_mode", string(redisclient.ModeStandalone), "how redis is deployed, standalone, sentinel or cluster")
+	redisMasterName            = flag.String("redis_master_name", "", "name of the master monitored by sentinels")
+	redisDB                    = flag.Int("redis_db", 0, "redis database, not supported in cluster mode")
+	redisTLS                   = flag.Bool("redis_tls", false, "connect to redis with TLS")
+	redisTLSCAFile             = flag.String("redis_tls_ca_file", "", "PEM file of certificates verifying redis servers, system roots are used if empty")
+	redisTLSServerName         = flag.String("redis_tls_server_name", "", "server name verifying redis servers, host of the address is used if empty")
+	redisTLSInsecureSkipVerify = flag.Bool("redis_tls_insecure_skip_verify", false, "skip verifying redis servers")
+
+	lockBackend  = flag.String("lock_backend", "redis", "backend of distributed locks, redis, redlock or mysql")
+	redlockAddrs = flag.String("redlock_addrs", "", "comma-separated independent redis nodes of redlock, sharing password and TLS of redis")
+	lockMaxConns = flag.Int("lock_max_conns", 10, "max connections of the mysql pool dedicated to locks, each held lock takes one")
+
+	defaultDomainURL = flag.String("default_domain", "", "default short link domain, e.g. https://sho.rt, rest_host is used if empty")
+
+	preview        = flag.Bool("preview", false, "render preview page for every short link instead of redirecting")
+	templateDir    = flag.String("template_dir", "", "directory of page templates overriding embedded ones")
+	geoIPDB        = flag.String("geoip_db", "", "path of MaxMind country database for routing rules matching countries")
+	adminAPIKey    = flag.String("admin_api_key", "", "API key of administrators reading the audit log, admin APIs are forbidden if empty")
+	trustedProxies = flag.String("trusted_proxies", "", "comma-separated IP ranges or IPs of proxies whose X-Forwarded-For is trusted, client IP is the peer address if empty")
+
+	fallbackNotFound = flag.String("fallback_not_found", "", "how browsers are responded for missing short links, page or a URL redirected to")
+	fallbackExpired  = flag.String("fallback_expired", "", "how browsers are responded for expired short links, page, owner, a URL redirected to, or owner and a URL separated by comma")
+	fallbackDisabled = flag.String("fallback_disabled", "", "how browsers are responded for disabled and deleted short links, in the form of fallback_expired")
+
+	expiryMaxLifetime = flag.Duration("expiry_max_lifetime", 0, "max time from creation to expiry of short links, which also forbids removing the expiry, unlimited if 0")
+	expiryGracePeriod = flag.Duration("expiry_grace_period", 7*24*time.Hour, "how long expired short links could be revived by a new expiry, never if 0")
+
+	idBlocklist = flag.String("id_blocklist", "", "file of words blocked in generated and imported ids besides the embedded list, one per line")
+	idReserved  = flag.String("id_reserved", "", "comma-separated ids reserved besides api, e.g. admin,static")
+
+	statsFlushInterval = flag.Duration("stats_flush_interval", 10*time.Second, "interval of flushing buffered clicks to db")
+
+	metadataWorkers = flag.Int("metadata_workers", 4, "number of workers fetching title and image of destinations, fetching is disabled if 0")
+	metadataTimeout = flag.Duration("metadata_timeout", 5*time.Second, "timeout of fetching a destination page")
+
+	cacheWriteThrough      = flag.Bool("cache_write_through", false, "cache short links once they're uploaded")
+	cacheWarmMostClicked   = flag.Int("cache_warm_most_clicked", 1000, "number of most clicked short links preloaded into cache")
+	cacheWarmClickWindow   = flag.Duration("cache_warm_click_window", 7*24*time.Hour, "most clicked short links are those clicked in the window")
+	cacheWarmRecent        = flag.Int("cache_warm_recent", 1000, "number of recently created short links preloaded into cache")
+	cacheWarmCheckInterval = flag.Duration("cache_warm_check_interval", time.Minute, "interval of checking whether cache is flushed, which is warmed on start and once flushed, warming is disabled if 0")
+
+	healthCheckInterval    = flag.Duration("health_check_interval", time.Hour, "interval of checking destinations of active short links, checking is disabled if 0")
+	healthCheckWorkers     = flag.Int("health_check_workers", 8, "number of concurrent probes of destinations")
+	healthHostDelay        = flag.Duration("health_host_delay", time.Second, "min delay between probes of the same host")
+	healthFailureThreshold = flag.Int("health_failure_threshold", 3, "consecutive failures before a short link is flagged broken")
+
+	webhookPollInterval = flag.Duration("webhook_poll_interval", 5*time.Second, "interval of delivering webhook events, delivering is disabled if 0")
+	webhookTimeout      = flag.Duration("webhook_timeout", 10*time.Second, "timeout of delivering a webhook event")
+	webhookMaxAttempts  = flag.Int("webhook_max_attempts", 10, "attempts of delivering a webhook event before it's dead")
+	webhookAllowPrivate = flag.Bool("webhook_allow_private", false, "allow webhook endpoints in loopback and private networks")
+
+	accessLogLevel            = flag.String("access_log_level", "info", "level of access logs")
+	accessLogRouteLevels      = flag.String("access_log_route_levels", "", "levels of routes overriding access_log_level, e.g. /:url_id=debug")
+	accessLogSampleInitial    = flag.Int("access_log_sample_initial", 0, "access logs of each route logged every second before sampling, sampling is disabled if 0")
+	accessLogSampleThereafter = flag.Int("access_log_sample_thereafter", 100, "log every Nth access log of each route after access_log_sample_initial")
 )
 
 func main() {
@@ -63,12 +133,26 @@ func main() {
 		logger.Sugar().Fatalf("fail to connection mysql db, err: %v", err)
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:       *redisAddr,
+	redisConfig := redisclient.Config{
+		Mode:       redisclient.Mode(*redisMode),
+		Addrs:      redisclient.ParseAddrs(*redisAddr),
+		MasterName: *redisMasterName,
+		Password:   redisPassword,
+		DB:         *redisDB,
 		PoolSize:   100,
 		MaxRetries: 2,
-		DB:         0,
-	})
+	}
+	if *redisTLS {
+		redisConfig.TLS = &redisclient.TLSConfig{
+			CAFile:             *redisTLSCAFile,
+			ServerName:         *redisTLSServerName,
+			InsecureSkipVerify: *redisTLSInsecureSkipVerify,
+		}
+	}
+	rdb, err := redisclient.New(redisConfig)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init redis client, err: %v", err)
+	}
 
 	remoteCache := cache.NewRedis(rdb)
 
@@ -76,14 +160,295 @@ func main() {
 	if err != nil {
 		logger.Sugar().Fatalf("fail to init ShortLinkDao, err: %v", err)
 	}
-	locker := lock.NewRedis(rdb)
+	domainDao, err := dao.NewDomainDao(mysqlDB)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init DomainDao, err: %v", err)
+	}
+	ownerDao, err := dao.NewOwnerDao(mysqlDB)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init OwnerDao, err: %v", err)
+	}
+	clickDao, err := dao.NewClickDao(mysqlDB)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init ClickDao, err: %v", err)
+	}
+	webhookDao, err := dao.NewWebhookDao(mysqlDB)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init WebhookDao, err: %v", err)
+	}
+
+	auditDao, err := dao.NewAuditDao(mysqlDB)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init AuditDao, err: %v", err)
+	}
+	blockedIDDao, err := dao.NewBlockedIDDao(mysqlDB)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init BlockedIDDao, err: %v", err)
+	}
+	var blockedWords []string
+	if *idBlocklist != "" {
+		if blockedWords, err = idfilter.LoadWords(*idBlocklist); err != nil {
+			logger.Sugar().Fatalf("fail to load id_blocklist, err: %v", err)
+		}
+	}
+
+	defaultDomain, err := initDefaultDomain(domainDao, shortLinkDao)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init default domain, err: %v", err)
+	}
+
+	var metadataWorker urlshortener.MetadataWorker
+	if *metadataWorkers > 0 {
+		metadataWorker = urlshortener.NewMetadataWorker(
+			metadata.NewFetcher(metadata.FetcherConfig{Timeout: *metadataTimeout}),
+			shortLinkDao,
+			remoteCache,
+			clock.NewClock(),
+			urlshortener.MetadataWorkerConfig{Workers: *metadataWorkers},
+		)
+		metadataWorker.Start()
+		defer metadataWorker.Stop()
+	}
+
+	locker, err := initLocker(rdb, redisConfig, connStr)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init locker, err: %v", err)
+	}
+	// health checks leave locks to expire instead of unlocking them, which needs a locker honoring ttl
+	healthLocker := locker
+	if *lockBackend == "mysql" {
+		healthLocker = lock.NewRedis(rdb)
+	}
+	urlShortenerOpts := []urlshortener.Option{
+		urlshortener.WithExpiryPolicy(urlshortener.ExpiryPolicy{
+			MaxLifetime: *expiryMaxLifetime,
+			GracePeriod: *expiryGracePeriod,
+		}),
+		urlshortener.WithIDFilterConfig(idfilter.Config{
+			Words:       blockedWords,
+			ReservedIDs: strings.Split(*idReserved, ","),
+		}),
+	}
+	if *cacheWriteThrough {
+		urlShortenerOpts = append(urlShortenerOpts, urlshortener.WithWriteThrough())
+	}
+	if metadataWorker != nil {
+		urlShortenerOpts = append(urlShortenerOpts, urlshortener.WithMetadataWorker(metadataWorker))
+	}
 	urlShortener := urlshortener.NewURLShortener(
 		locker,
 		remoteCache,
-		shortLinkDao,
+		urlshortener.Daos{
+			ShortLink: shortLinkDao,
+			Domain:    domainDao,
+			Owner:     ownerDao,
+			Webhook:   webhookDao,
+			Audit:     auditDao,
+			BlockedID: blockedIDDao,
+		},
+		defaultDomain,
 		clock.NewClock(),
+		urlShortenerOpts...,
 	)
 
-	r := rest.NewRest(*restHost, *restPort, urlShortener, clock.NewClock())
+	if *cacheWarmCheckInterval > 0 {
+		cacheWarmer := urlshortener.NewCacheWarmer(
+			shortLinkDao,
+			remoteCache,
+			locker,
+			clock.NewClock(),
+			urlshortener.CacheWarmerConfig{
+				MostClicked:   *cacheWarmMostClicked,
+				ClickWindow:   *cacheWarmClickWindow,
+				Recent:        *cacheWarmRecent,
+				CheckInterval: *cacheWarmCheckInterval,
+			},
+		)
+		cacheWarmer.Start()
+		defer cacheWarmer.Stop()
+	}
+
+	if *healthCheckInterval > 0 {
+		healthChecker := urlshortener.NewHealthChecker(
+			health.NewProber(health.ProberConfig{}),
+			shortLinkDao,
+			remoteCache,
+			healthLocker,
+			clock.NewClock(),
+			urlshortener.HealthCheckerConfig{
+				Interval:         *healthCheckInterval,
+				Workers:          *healthCheckWorkers,
+				HostDelay:        *healthHostDelay,
+				FailureThreshold: *healthFailureThreshold,
+			},
+		)
+		healthChecker.Start()
+		defer healthChecker.Stop()
+	}
+
+	if *webhookPollInterval > 0 {
+		webhookDispatcher := urlshortener.NewWebhookDispatcher(
+			webhook.NewSender(webhook.SenderConfig{
+				Timeout:              *webhookTimeout,
+				AllowPrivateNetworks: *webhookAllowPrivate,
+			}),
+			shortLinkDao,
+			webhookDao,
+			locker,
+			clock.NewClock(),
+			urlshortener.WebhookDispatcherConfig{
+				PollInterval: *webhookPollInterval,
+				MaxAttempts:  *webhookMaxAttempts,
+			},
+		)
+		webhookDispatcher.Start()
+		defer webhookDispatcher.Stop()
+	}
+
+	clickStats := stats.NewStats(clickDao, clock.NewClock())
+	clickStats.Start(*statsFlushInterval)
+	defer clickStats.Stop()
+
+	accessLogConfig := logging.AccessLogConfig{
+		SampleInitial:    *accessLogSampleInitial,
+		SampleThereafter: *accessLogSampleThereafter,
+	}
+	if err := accessLogConfig.Level.Set(*accessLogLevel); err != nil {
+		logger.Sugar().Fatalf("invalid access_log_level, err: %v", err)
+	}
+	accessLogConfig.RouteLevels, err = logging.ParseRouteLevels(*accessLogRouteLevels)
+	if err != nil {
+		logger.Sugar().Fatalf("invalid access_log_route_levels, err: %v", err)
+	}
+	accessLogger := logging.NewAccessLogger(logger, accessLogConfig)
+
+	var fallbacks rest.Fallbacks
+	for _, fallback := range []struct {
+		name     string
+		value    string
+		fallback *rest.Fallback
+	}{
+		{"fallback_not_found", *fallbackNotFound, &fallbacks.NotFound},
+		{"fallback_expired", *fallbackExpired, &fallbacks.Expired},
+		{"fallback_disabled", *fallbackDisabled, &fallbacks.Disabled},
+	} {
+		*fallback.fallback, err = rest.ParseFallback(fallback.value)
+		if err != nil {
+			logger.Sugar().Fatalf("invalid %s, err: %v", fallback.name, err)
+		}
+	}
+
+	proxies, err := rest.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		logger.Sugar().Fatalf("invalid trusted_proxies, err: %v", err)
+	}
+	restOpts := []rest.Option{
+		rest.WithPreview(*preview),
+		rest.WithTemplateDir(*templateDir),
+		rest.WithAccessLogger(accessLogger),
+		rest.WithAdminAPIKey(*adminAPIKey),
+		rest.WithFallbacks(fallbacks),
+		rest.WithTrustedProxies(proxies),
+	}
+	rpcOpts := []rpc.Option{
+		rpc.WithAccessLogger(accessLogger),
+	}
+	if *geoIPDB != "" {
+		geoIP, err := rules.NewMMDB(*geoIPDB)
+		if err != nil {
+			logger.Sugar().Fatalf("fail to open geoip db, err: %v", err)
+		}
+		restOpts = append(restOpts, rest.WithGeoIP(geoIP))
+		rpcOpts = append(rpcOpts, rpc.WithGeoIP(geoIP))
+	}
+
+	if *grpcPort != 0 {
+		g := rpc.NewRPC(*grpcPort, urlShortener, clickStats, clock.NewClock(), rpcOpts...)
+		go func() {
+			if err := g.Start(); err != nil {
+				logger.Sugar().Fatalf("fail to start grpc, err: %v", err)
+			}
+		}()
+		defer g.Stop()
+	}
+
+	r, err := rest.NewRest(
+		*restHost,
+		*restPort,
+		urlShortener,
+		clickStats,
+		clock.NewClock(),
+		restOpts...,
+	)
+	if err != nil {
+		logger.Sugar().Fatalf("fail to init rest, err: %v", err)
+	}
 	r.Start()
 }
+
+// initLocker creates the distributed locker of lock_backend.
+func initLocker(rdb redis.UniversalClient, redisConfig redisclient.Config, mysqlConnStr string) (lock.DistributedLocker, error) {
+	switch *lockBackend {
+	case "redis":
+		return lock.NewRedis(rdb), nil
+	case "redlock":
+		addrs := redisclient.ParseAddrs(*redlockAddrs)
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("redlock_addrs is empty")
+		}
+		nodes := make([]redis.Cmdable, 0, len(addrs))
+		for _, addr := range addrs {
+			node, err := redisclient.New(redisclient.Config{
+				Addrs:    []string{addr},
+				Password: redisConfig.Password,
+				PoolSize: redisConfig.PoolSize,
+				TLS:      redisConfig.TLS,
+			})
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		}
+		return lock.NewRedlock(nodes...), nil
+	case "mysql":
+		// locks hold connections while waiting and holding, a dedicated pool keeps them from exhausting the pool
+		// of queries
+		db, err := sql.Open("mysql", mysqlConnStr)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(*lockMaxConns)
+		db.SetMaxIdleConns(*lockMaxConns)
+		return lock.NewMySQL(db)
+	default:
+		return nil, fmt.Errorf("unknown lock_backend: %s", *lockBackend)
+	}
+}
+
+// initDefaultDomain registers the default domain and assigns short links created before domains to it.
+func initDefaultDomain(domainDao dao.DomainDao, shortLinkDao dao.ShortLinkDao) (*dao.Domain, error) {
+	domainURL := *defaultDomainURL
+	if domainURL == "" {
+		domainURL = *restHost
+	}
+	u, err := url.Parse(domainURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid domain: %s", domainURL)
+	}
+
+	domain := dao.Domain{
+		Host:   u.Host,
+		Scheme: u.Scheme,
+	}
+	if err := domainDao.FirstOrCreate(&domain); err != nil {
+		return nil, err
+	}
+	if err := shortLinkDao.AssignDomain(domain.ID); err != nil {
+		return nil, err
+	}
+
+	return &domain, nil
+}
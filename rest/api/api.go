@@ -0,0 +1,276 @@
+// Package api defines request and response bodies of the Rest API, shared by rest and client.
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+)
+
+// UploadURLRequest defines request body of uploading a URL.
+type UploadURLRequest struct {
+	URL string `json:"url" validate:"required,uri"`
+	// ExpireAt is in RFC3339 format.
+	ExpireAt    string            `json:"expireAt" validate:"required"`
+	Domain      string            `json:"domain,omitempty" validate:"omitempty,hostname_port|hostname"`
+	Preview     bool              `json:"preview,omitempty"`
+	QueryMode   string            `json:"queryMode,omitempty" validate:"omitempty,oneof=override preserve"`
+	UTM         map[string]string `json:"utm,omitempty" validate:"omitempty,dive,keys,oneof=utm_source utm_medium utm_campaign utm_term utm_content,endkeys,required"`
+	Rules       []rules.Rule      `json:"rules,omitempty" validate:"omitempty,max=20,dive"`
+	Variants    []split.Variant   `json:"variants,omitempty" validate:"omitempty,max=10,dive"`
+	Title       string            `json:"title,omitempty" validate:"max=256"`
+	Description string            `json:"description,omitempty" validate:"max=1024"`
+	// Tags are free-form labels for filtering short links, which can not contain comma.
+	Tags []string `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=64,excludesall=0x2C"`
+	// FallbackURL is redirected to while url is detected broken by health checks.
+	FallbackURL string `json:"fallbackUrl,omitempty" validate:"omitempty,uri"`
+}
+
+// UploadURLResponse defines response body of uploading a URL.
+type UploadURLResponse struct {
+	ID       string `json:"id"`
+	ShortURL string `json:"shortUrl"`
+}
+
+// UpdateURLRequest defines request body of updating a short link, absent fields are left unchanged.
+type UpdateURLRequest struct {
+	URL *string `json:"url,omitempty" validate:"omitempty,uri"`
+	// ExpireAt is in RFC3339 format.
+	ExpireAt  *string `json:"expireAt,omitempty"`
+	Preview   *bool   `json:"preview,omitempty"`
+	QueryMode *string `json:"queryMode,omitempty" validate:"omitempty,oneof='' override preserve"`
+	// UTM replaces all default UTM parameters if present, an empty object removes them.
+	UTM map[string]string `json:"utm,omitempty" validate:"omitempty,dive,keys,oneof=utm_source utm_medium utm_campaign utm_term utm_content,endkeys,required"`
+	// Rules replaces all routing rules if present, an empty array removes them.
+	Rules *[]rules.Rule `json:"rules,omitempty" validate:"omitempty,max=20,dive"`
+	// Variants replaces all variants if present, an empty array removes them.
+	Variants    *[]split.Variant `json:"variants,omitempty" validate:"omitempty,max=10,dive"`
+	Title       *string          `json:"title,omitempty" validate:"omitempty,max=256"`
+	Description *string          `json:"description,omitempty" validate:"omitempty,max=1024"`
+	// Tags replaces all tags if present, an empty array removes them.
+	Tags *[]string `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=64,excludesall=0x2C"`
+	// FallbackURL replaces the fallback URL if present, an empty string removes it.
+	FallbackURL *string `json:"fallbackUrl,omitempty"`
+	// Status disables or enables the short link, deleted ones are restored by the restore API instead.
+	Status *string `json:"status,omitempty" validate:"omitempty,oneof=active disabled"`
+}
+
+// ExpiryRequest defines request body of changing expiry of a short link, exactly one of the fields should be
+// present.
+type ExpiryRequest struct {
+	// ExpireAt extends or shortens the expiry to the time in RFC3339 format.
+	ExpireAt string `json:"expireAt,omitempty"`
+	// ExtendSeconds extends the expiry by seconds, from now if the short link is expired.
+	ExtendSeconds int64 `json:"extendSeconds,omitempty" validate:"min=0"`
+	// Never removes the expiry, the short link expires at 9999-12-31T00:00:00Z then.
+	Never bool `json:"never,omitempty"`
+}
+
+// ShortLinkResponse defines response body of a short link.
+type ShortLinkResponse struct {
+	ID          string            `json:"id"`
+	ShortURL    string            `json:"shortUrl"`
+	Domain      string            `json:"domain"`
+	URL         string            `json:"url"`
+	ExpireAt    time.Time         `json:"expireAt"`
+	Preview     bool              `json:"preview"`
+	QueryMode   string            `json:"queryMode,omitempty"`
+	UTM         map[string]string `json:"utm,omitempty"`
+	Rules       []rules.Rule      `json:"rules,omitempty"`
+	Variants    []split.Variant   `json:"variants,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	// ImageURL and FaviconURL are fetched from the destination after creation, MetadataFetchedAt is absent
+	// until then.
+	ImageURL          string     `json:"imageUrl,omitempty"`
+	FaviconURL        string     `json:"faviconUrl,omitempty"`
+	MetadataFetchedAt *time.Time `json:"metadataFetchedAt,omitempty"`
+	FallbackURL       string     `json:"fallbackUrl,omitempty"`
+	// Broken indicates url is down by health checks, redirect uses fallbackUrl instead if it's set.
+	Broken bool `json:"broken"`
+	// Status is one of active, disabled and deleted, redirect responds 410 unless it's active.
+	Status string `json:"status"`
+	// DeletedAt is absent unless status is deleted.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// ListURLsResponse defines response body of listing short links.
+type ListURLsResponse struct {
+	Items []ShortLinkResponse `json:"items"`
+	// NextCursor is the cursor of the next page, empty if it's the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// StatsResponse defines response body of click stats of a short link.
+type StatsResponse struct {
+	ID       string           `json:"id"`
+	Clicks   int64            `json:"clicks"`
+	Variants map[string]int64 `json:"variants,omitempty"`
+}
+
+// HealthResponse defines response body of the latest health check of the destination of a short link, fields
+// other than id, broken and consecutiveFailures are absent if it's not checked yet.
+type HealthResponse struct {
+	ID     string `json:"id"`
+	Broken bool   `json:"broken"`
+	// StatusCode is absent if no response is received, Error describes why.
+	StatusCode          int        `json:"statusCode,omitempty"`
+	LatencyMS           int64      `json:"latencyMs,omitempty"`
+	Error               string     `json:"error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	BrokenSince         *time.Time `json:"brokenSince,omitempty"`
+	CheckedAt           *time.Time `json:"checkedAt,omitempty"`
+}
+
+// ImportResponse defines response body of importing short links.
+type ImportResponse struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+	// Errors defines the first 100 invalid or conflicting records.
+	Errors []ImportError `json:"errors,omitempty"`
+	DryRun bool          `json:"dryRun"`
+	// Committed indicates short links are imported, nothing is imported if any record fails.
+	Committed bool `json:"committed"`
+}
+
+// ImportError defines an invalid or conflicting record in import file.
+type ImportError struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// CreateWebhookRequest defines request body of creating a webhook.
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required,uri,max=512"`
+	// Events are types of events delivered to url, e.g. link.created.
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+	// Secret signs deliveries, a random one is generated if it's empty.
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=16,max=64"`
+}
+
+// WebhookResponse defines response body of a webhook.
+type WebhookResponse struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is responded only when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListWebhooksResponse defines response body of listing webhooks.
+type ListWebhooksResponse struct {
+	Items []WebhookResponse `json:"items"`
+}
+
+// DeliveryResponse defines response body of a delivery of an event to a webhook.
+type DeliveryResponse struct {
+	ID       string `json:"id"`
+	Event    string `json:"event"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// Payload is data of the event.
+	Payload       json.RawMessage `json:"payload"`
+	LastError     string          `json:"lastError,omitempty"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// ListDeliveriesResponse defines response body of listing deliveries of a webhook.
+type ListDeliveriesResponse struct {
+	Items []DeliveryResponse `json:"items"`
+	// NextCursor is the cursor of the next page, empty if it's the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// AuditEventResponse defines response body of a change of a short link in the audit log.
+type AuditEventResponse struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// OwnerID and APIKeyHash are absent if the change is made anonymously.
+	OwnerID    uint64 `json:"ownerId,omitempty"`
+	APIKeyHash string `json:"apiKeyHash,omitempty"`
+	SourceIP   string `json:"sourceIp,omitempty"`
+	RequestID  string `json:"requestId,omitempty"`
+	Domain     string `json:"domain"`
+	URLID      string `json:"urlId"`
+	// Before is absent for create and After is absent for purge.
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// ListAuditEventsResponse defines response body of listing the audit log.
+type ListAuditEventsResponse struct {
+	Items []AuditEventResponse `json:"items"`
+	// NextCursor is the cursor of the next page, empty if it's the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// BlockIDRequest defines request body of blocking an ID.
+type BlockIDRequest struct {
+	Reason string `json:"reason,omitempty" validate:"max=256"`
+}
+
+// BlockedIDResponse defines response body of an ID blocked by administrators.
+type BlockedIDResponse struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListBlockedIDsResponse defines response body of listing blocked IDs.
+type ListBlockedIDsResponse struct {
+	Items []BlockedIDResponse `json:"items"`
+}
+
+// MIMEProblemJSON is content type of Problem.
+const MIMEProblemJSON = "application/problem+json"
+
+// Codes of Problem, which are stable for clients to tell errors apart.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidParams    = "invalid_params"
+	CodeInvalidURL       = "invalid_url"
+	CodeInvalidRules     = "invalid_rules"
+	CodeInvalidVariants  = "invalid_variants"
+	CodeDomainNotFound   = "domain_not_found"
+	CodeAPIKeyRequired   = "api_key_required"
+	CodeInvalidAPIKey    = "invalid_api_key"
+	CodeForbidden        = "forbidden"
+	CodeDomainNotAllowed = "domain_not_allowed"
+	CodeNotFound         = "not_found"
+	CodeExpired          = "expired"
+	CodeDisabled         = "disabled"
+	CodeDeleted          = "deleted"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeUnsupportedMedia = "unsupported_media_type"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal"
+	CodeUnavailable      = "unavailable"
+)
+
+// Problem defines error response body in RFC 7807 problem details.
+type Problem struct {
+	// Type is always about:blank, Code tells the error instead.
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail is human-readable explanation, internal errors are not revealed.
+	Detail string `json:"detail,omitempty"`
+	// Instance is path of the request.
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+var errAdminRequired = newAPIError(http.StatusForbidden, api.CodeForbidden, "admin api key is required")
+
+type listAuditEventsParams struct {
+	OwnerID   uint64 `query:"ownerId"`
+	Domain    string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+	URLID     string `query:"urlId" validate:"max=20"`
+	Action    string `query:"action" validate:"omitempty,oneof=create update delete restore purge"`
+	RequestID string `query:"requestId" validate:"max=64"`
+	// CreatedAfter and CreatedBefore are in RFC3339 format, after is inclusive and before is exclusive.
+	CreatedAfter  string `query:"createdAfter"`
+	CreatedBefore string `query:"createdBefore"`
+	Cursor        string `query:"cursor"`
+	Limit         int    `query:"limit" validate:"min=0,max=100"`
+}
+
+func (r *restImpl) listAuditEvents(c echo.Context) error {
+	if err := r.authorizeAdmin(c); err != nil {
+		return err
+	}
+
+	var params listAuditEventsParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	listParams := urlshortener.ListAuditEventsParams{
+		OwnerID:   params.OwnerID,
+		Domain:    params.Domain,
+		URLID:     params.URLID,
+		Action:    params.Action,
+		RequestID: params.RequestID,
+		Cursor:    params.Cursor,
+		Limit:     params.Limit,
+	}
+	for _, bound := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{
+		{"createdAfter", params.CreatedAfter, &listParams.CreatedFrom},
+		{"createdBefore", params.CreatedBefore, &listParams.CreatedTo},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseTime(bound.value)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, bound.name+" is invalid")
+		}
+		*bound.time = t
+	}
+
+	events, next, err := r.urlShortener.ListAuditEvents(listParams)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListAuditEventsResponse{
+		Items:      make([]api.AuditEventResponse, 0, len(events)),
+		NextCursor: next,
+	}
+	for _, event := range events {
+		resp.Items = append(resp.Items, toAuditEventResponse(event))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// authorizeAdmin checks if the request carries the admin API key.
+func (r *restImpl) authorizeAdmin(c echo.Context) error {
+	apiKey := c.Request().Header.Get(headerAPIKey)
+	if apiKey == "" {
+		return errUnauthorized
+	}
+	if r.adminAPIKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(r.adminAPIKey)) != 1 {
+		return errAdminRequired
+	}
+	return nil
+}
+
+func toAuditEventResponse(event *dao.AuditEvent) api.AuditEventResponse {
+	resp := api.AuditEventResponse{
+		ID:         base62.Encode(event.ID),
+		Action:     event.Action,
+		OwnerID:    event.OwnerID,
+		APIKeyHash: event.APIKeyHash,
+		SourceIP:   event.SourceIP,
+		RequestID:  event.RequestID,
+		Domain:     event.Domain,
+		URLID:      event.URLID,
+		CreatedAt:  event.CreatedAt.UTC(),
+	}
+	if event.Before != "" {
+		resp.Before = json.RawMessage(event.Before)
+	}
+	if event.After != "" {
+		resp.After = json.RawMessage(event.After)
+	}
+	return resp
+}
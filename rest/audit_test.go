@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+const testAdminAPIKey = "test-admin-api-key"
+
+func (s *restTestSuite) TestListAuditEvents() {
+	WithAdminAPIKey(testAdminAPIKey)(s.impl)
+	s.mockURLShortener.On("ListAuditEvents", urlshortener.ListAuditEventsParams{
+		OwnerID:     3,
+		URLID:       testURLID,
+		Action:      dao.AuditUpdate,
+		CreatedFrom: testNow.Add(-time.Hour),
+		Limit:       1,
+	}).Return([]*dao.AuditEvent{{
+		ID:          62,
+		OwnerID:     3,
+		APIKeyHash:  "0123456789abcdef",
+		Action:      dao.AuditUpdate,
+		ShortLinkID: 7,
+		Domain:      testDomain.Host,
+		URLID:       testURLID,
+		Before:      `{"url":"https://example.com/old"}`,
+		After:       `{"url":"https://example.com/new"}`,
+		SourceIP:    "203.0.113.7",
+		RequestID:   "req-1",
+		CreatedAt:   testNow,
+	}}, base62.Encode(62), nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/admin/audit-events?ownerId=3&urlId="+testURLID+
+		"&action=update&createdAfter=2021-06-30T23:00:00Z&limit=1", testAdminAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ListAuditEventsResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(api.ListAuditEventsResponse{
+		Items: []api.AuditEventResponse{{
+			ID:         base62.Encode(62),
+			Action:     dao.AuditUpdate,
+			OwnerID:    3,
+			APIKeyHash: "0123456789abcdef",
+			SourceIP:   "203.0.113.7",
+			RequestID:  "req-1",
+			Domain:     testDomain.Host,
+			URLID:      testURLID,
+			Before:     json.RawMessage(`{"url":"https://example.com/old"}`),
+			After:      json.RawMessage(`{"url":"https://example.com/new"}`),
+			CreatedAt:  testNow,
+		}},
+		NextCursor: base62.Encode(62),
+	}, resp)
+}
+
+func (s *restTestSuite) TestListAuditEventsInvalid() {
+	WithAdminAPIKey(testAdminAPIKey)(s.impl)
+
+	rec := s.serve(http.MethodGet, "/api/v1/admin/audit-events?action=read", testAdminAPIKey, "")
+	s.Equal(http.StatusBadRequest, rec.Code)
+	rec = s.serve(http.MethodGet, "/api/v1/admin/audit-events?createdBefore=yesterday", testAdminAPIKey, "")
+	s.Equal(http.StatusBadRequest, rec.Code)
+	s.Contains(rec.Body.String(), "createdBefore is invalid")
+}
+
+func (s *restTestSuite) TestListAuditEventsNotAdmin() {
+	// admin APIs are forbidden without admin API key
+	rec := s.serve(http.MethodGet, "/api/v1/admin/audit-events", testAdminAPIKey, "")
+	s.Equal(http.StatusForbidden, rec.Code)
+
+	WithAdminAPIKey(testAdminAPIKey)(s.impl)
+	rec = s.serve(http.MethodGet, "/api/v1/admin/audit-events", "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+	// owners are not administrators
+	rec = s.serve(http.MethodGet, "/api/v1/admin/audit-events", testAPIKey, "")
+	s.Equal(http.StatusForbidden, rec.Code)
+	s.Contains(rec.Body.String(), api.CodeForbidden)
+	s.mockURLShortener.AssertNotCalled(s.T(), "ListAuditEvents")
+}
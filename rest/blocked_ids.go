@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+type blockedIDParams struct {
+	URLID string `param:"url_id" validate:"required"`
+}
+
+type blockIDParams struct {
+	URLID string `param:"url_id" validate:"required"`
+	api.BlockIDRequest
+}
+
+func (r *restImpl) listBlockedIDs(c echo.Context) error {
+	if err := r.authorizeAdmin(c); err != nil {
+		return err
+	}
+
+	blockedIDs, err := r.urlShortener.ListBlockedIDs()
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListBlockedIDsResponse{Items: make([]api.BlockedIDResponse, 0, len(blockedIDs))}
+	for _, blockedID := range blockedIDs {
+		resp.Items = append(resp.Items, toBlockedIDResponse(blockedID))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (r *restImpl) blockID(c echo.Context) error {
+	if err := r.authorizeAdmin(c); err != nil {
+		return err
+	}
+
+	var params blockIDParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	blockedID, err := r.urlShortener.BlockID(params.URLID, params.Reason)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toBlockedIDResponse(blockedID))
+}
+
+func (r *restImpl) unblockID(c echo.Context) error {
+	if err := r.authorizeAdmin(c); err != nil {
+		return err
+	}
+
+	var params blockedIDParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if err := r.urlShortener.UnblockID(params.URLID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func toBlockedIDResponse(blockedID *dao.BlockedID) api.BlockedIDResponse {
+	return api.BlockedIDResponse{
+		ID:        blockedID.URLID,
+		Reason:    blockedID.Reason,
+		CreatedAt: blockedID.CreatedAt.UTC(),
+	}
+}
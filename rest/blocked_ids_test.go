@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+func (s *restTestSuite) TestBlockedIDs() {
+	WithAdminAPIKey(testAdminAPIKey)(s.impl)
+	blockedID := &dao.BlockedID{ID: 1, URLID: testURLID, Reason: "abuse", CreatedAt: testNow}
+	s.mockURLShortener.On("BlockID", testURLID, "abuse").Return(blockedID, nil).Once()
+	s.mockURLShortener.On("ListBlockedIDs").Return([]*dao.BlockedID{blockedID}, nil).Once()
+	s.mockURLShortener.On("UnblockID", testURLID).Return(nil).Once()
+
+	rec := s.serve(http.MethodPut, "/api/v1/admin/blocked-ids/"+testURLID, testAdminAPIKey, `{"reason":"abuse"}`)
+	s.Equal(http.StatusOK, rec.Code)
+	expected := api.BlockedIDResponse{ID: testURLID, Reason: "abuse", CreatedAt: testNow}
+	var resp api.BlockedIDResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(expected, resp)
+
+	rec = s.serve(http.MethodGet, "/api/v1/admin/blocked-ids", testAdminAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var listResp api.ListBlockedIDsResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &listResp))
+	s.Equal(api.ListBlockedIDsResponse{Items: []api.BlockedIDResponse{expected}}, listResp)
+
+	rec = s.serve(http.MethodDelete, "/api/v1/admin/blocked-ids/"+testURLID, testAdminAPIKey, "")
+	s.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (s *restTestSuite) TestBlockedIDsNotAdmin() {
+	WithAdminAPIKey(testAdminAPIKey)(s.impl)
+
+	rec := s.serve(http.MethodGet, "/api/v1/admin/blocked-ids", testAPIKey, "")
+	s.Equal(http.StatusForbidden, rec.Code)
+	rec = s.serve(http.MethodPut, "/api/v1/admin/blocked-ids/"+testURLID, testAPIKey, "")
+	s.Equal(http.StatusForbidden, rec.Code)
+	rec = s.serve(http.MethodDelete, "/api/v1/admin/blocked-ids/"+testURLID, "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+	s.mockURLShortener.AssertNotCalled(s.T(), "BlockID")
+	s.mockURLShortener.AssertNotCalled(s.T(), "UnblockID")
+}
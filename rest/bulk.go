@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/core/bulk"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// importFileField is the multipart field of import file.
+const importFileField = "file"
+
+var bulkContentTypes = map[string]string{
+	bulk.FormatCSV:   "text/csv; charset=utf-8",
+	bulk.FormatJSONL: "application/x-ndjson",
+}
+
+type importURLsParams struct {
+	Format   string `query:"format" validate:"omitempty,oneof=csv jsonl"`
+	Conflict string `query:"conflict" validate:"omitempty,oneof=skip overwrite fail"`
+	DryRun   bool   `query:"dryRun"`
+}
+
+type exportURLsParams struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv jsonl"`
+}
+
+func (r *restImpl) importURLs(c echo.Context) error {
+	var params importURLsParams
+	// binding body would parse the whole multipart form, which is streamed instead
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &params); err != nil {
+		return err
+	}
+	err := c.Validate(&params)
+	var verr validator.ValidationErrors
+	if errors.As(err, &verr) {
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, err.Error())
+	} else if err != nil {
+		return err
+	}
+	if params.Format == "" {
+		params.Format = bulk.FormatCSV
+	}
+	if params.Conflict == "" {
+		params.Conflict = urlshortener.ConflictFail
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return urlshortener.ErrOwnerRequired
+	}
+
+	file, err := multipartFile(c.Request(), importFileField)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := bulk.NewReader(file, params.Format)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, api.CodeBadRequest, err.Error())
+	}
+	result, err := r.urlShortener.Import(c.Request().Context(), urlshortener.ImportParams{
+		Owner:    owner,
+		Reader:   reader,
+		Conflict: params.Conflict,
+		DryRun:   params.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp := api.ImportResponse{
+		Total:     result.Total,
+		Created:   result.Created,
+		Updated:   result.Updated,
+		Skipped:   result.Skipped,
+		Failed:    result.Failed,
+		DryRun:    params.DryRun,
+		Committed: result.Committed,
+	}
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, api.ImportError{Line: e.Line, ID: e.ID, Message: e.Message})
+	}
+	if result.Failed > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (r *restImpl) exportURLs(c echo.Context) error {
+	var params exportURLsParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	if params.Format == "" {
+		params.Format = bulk.FormatCSV
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return urlshortener.ErrOwnerRequired
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, bulkContentTypes[params.Format])
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "short-links."+params.Format))
+	res.WriteHeader(http.StatusOK)
+
+	w, err := bulk.NewWriter(res, params.Format)
+	if err != nil {
+		return err
+	}
+	// status is sent already, the client gets a truncated file on error
+	return r.urlShortener.Export(owner, &flushWriter{Writer: w, res: res})
+}
+
+// multipartFile returns the part of field in multipart request, which is read from request body directly
+// instead of being buffered in memory or temporary files.
+func multipartFile(req *http.Request, field string) (*multipart.Part, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, api.CodeBadRequest, "request should be multipart/form-data")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, newAPIError(http.StatusBadRequest, api.CodeBadRequest, fmt.Sprintf("%s is required", field))
+		} else if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, api.CodeBadRequest, err.Error())
+		}
+		if part.FormName() == field {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// flushWriter flushes response on every flush of records, then the export is sent in chunks.
+type flushWriter struct {
+	bulk.Writer
+	res *echo.Response
+}
+
+func (w *flushWriter) Flush() error {
+	if err := w.Writer.Flush(); err != nil {
+		return err
+	}
+	w.res.Flush()
+	return nil
+}
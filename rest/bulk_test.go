@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/georgechang0117/url-shortener/core/bulk"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+const testImportCSV = "id,url,expireAt\n" + testURLID + "," + testURL + ",2021-07-30T00:00:00Z\n"
+
+func (s *restTestSuite) serveImport(target, field, data string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(field, "links.csv")
+	s.Require().NoError(err)
+	_, err = fw.Write([]byte(data))
+	s.Require().NoError(err)
+	s.Require().NoError(mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	req.Header.Set(headerAPIKey, testAPIKey)
+	rec := httptest.NewRecorder()
+	s.impl.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *restTestSuite) TestImportURLs() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Import", mock.Anything, mock.MatchedBy(func(params urlshortener.ImportParams) bool {
+		record, err := params.Reader.Read()
+		return err == nil && record.ID == testURLID &&
+			params.Owner == &owner && params.Conflict == urlshortener.ConflictSkip && params.DryRun
+	})).Return(&urlshortener.ImportResult{Total: 1, Created: 1}, nil).Once()
+
+	rec := s.serveImport("/api/v1/urls/import?conflict=skip&dryRun=true", importFileField, testImportCSV)
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ImportResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(api.ImportResponse{Total: 1, Created: 1, DryRun: true}, resp)
+}
+
+func (s *restTestSuite) TestImportURLsFailed() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Import", mock.Anything, mock.MatchedBy(func(params urlshortener.ImportParams) bool {
+		return params.Conflict == urlshortener.ConflictFail
+	})).Return(&urlshortener.ImportResult{
+		Total:  1,
+		Failed: 1,
+		Errors: []urlshortener.ImportError{{Line: 2, ID: testURLID, Message: "id already exists"}},
+	}, nil).Once()
+
+	rec := s.serveImport("/api/v1/urls/import", importFileField, testImportCSV)
+	s.Equal(http.StatusUnprocessableEntity, rec.Code)
+	var resp api.ImportResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal([]api.ImportError{{Line: 2, ID: testURLID, Message: "id already exists"}}, resp.Errors)
+}
+
+func (s *restTestSuite) TestImportURLsInvalid() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Twice()
+
+	rec := s.serveImport("/api/v1/urls/import", "other", testImportCSV)
+	s.Equal(http.StatusBadRequest, rec.Code)
+
+	rec = s.serveImport("/api/v1/urls/import?format=xml", importFileField, testImportCSV)
+	s.Equal(http.StatusBadRequest, rec.Code)
+
+	rec = s.serveImport("/api/v1/urls/import", importFileField, "url\n"+testURL+"\n")
+	s.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *restTestSuite) TestExportURLs() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Export", &owner, mock.Anything).Return(func(owner *dao.Owner, w bulk.Writer) error {
+		if err := w.Write(&bulk.Record{ID: testURLID, URL: testURL, ExpireAt: testNow}); err != nil {
+			return err
+		}
+		return w.Flush()
+	}).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/export?format=jsonl", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("application/x-ndjson", rec.Header().Get(echo.HeaderContentType))
+	s.Contains(rec.Header().Get(echo.HeaderContentDisposition), "short-links.jsonl")
+	s.True(rec.Flushed)
+	var record bulk.Record
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &record))
+	s.Equal(testURLID, record.ID)
+
+	rec = s.serve(http.MethodGet, "/api/v1/urls/export", "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/base/logging"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// apiError is an error responded as problem details with a stable code, see api.Code*.
+type apiError struct {
+	status int
+	code   string
+	detail string
+}
+
+func newAPIError(status int, code, detail string) *apiError {
+	return &apiError{status: status, code: code, detail: detail}
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.detail)
+}
+
+var (
+	errNotFound        = newAPIError(http.StatusNotFound, api.CodeNotFound, "short link is not found")
+	errWebhookNotFound = newAPIError(http.StatusNotFound, api.CodeNotFound, "webhook is not found")
+	errInternal        = newAPIError(http.StatusInternalServerError, api.CodeInternal, "internal server error")
+	errUnauthorized    = newAPIError(http.StatusUnauthorized, api.CodeAPIKeyRequired, "api key is required")
+)
+
+// statusCodes maps status of echo.HTTPError to codes.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            api.CodeBadRequest,
+	http.StatusUnauthorized:          api.CodeAPIKeyRequired,
+	http.StatusForbidden:             api.CodeForbidden,
+	http.StatusNotFound:              api.CodeNotFound,
+	http.StatusMethodNotAllowed:      api.CodeMethodNotAllowed,
+	http.StatusConflict:              api.CodeConflict,
+	http.StatusUnsupportedMediaType:  api.CodeUnsupportedMedia,
+	http.StatusTooManyRequests:       api.CodeTooManyRequests,
+	http.StatusRequestEntityTooLarge: api.CodeBadRequest,
+}
+
+// toAPIError maps errors of handlers to apiError, errors not known are internal and their messages are not
+// revealed.
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	switch {
+	case errors.Is(err, urlshortener.ErrInvalidParams):
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, err.Error())
+	case errors.Is(err, urlshortener.ErrInvalidURL):
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidURL, err.Error())
+	case errors.Is(err, urlshortener.ErrInvalidRules):
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidRules, err.Error())
+	case errors.Is(err, urlshortener.ErrInvalidVariants):
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidVariants, err.Error())
+	case errors.Is(err, urlshortener.ErrDomainNotFound):
+		return newAPIError(http.StatusBadRequest, api.CodeDomainNotFound, "domain is not registered")
+	case errors.Is(err, urlshortener.ErrOwnerRequired):
+		return errUnauthorized
+	case errors.Is(err, urlshortener.ErrOwnerNotFound):
+		return newAPIError(http.StatusUnauthorized, api.CodeInvalidAPIKey, "api key is invalid")
+	case errors.Is(err, urlshortener.ErrDomainNotAllowed):
+		return newAPIError(http.StatusForbidden, api.CodeDomainNotAllowed, "domain is not allowed")
+	case errors.Is(err, urlshortener.ErrNotFound):
+		return errNotFound
+	case errors.Is(err, urlshortener.ErrWebhookNotFound):
+		return errWebhookNotFound
+	case errors.Is(err, urlshortener.ErrExpired):
+		return newAPIError(http.StatusNotFound, api.CodeExpired, "short link is expired")
+	case errors.Is(err, urlshortener.ErrDisabled):
+		return newAPIError(http.StatusGone, api.CodeDisabled, "short link is disabled")
+	case errors.Is(err, urlshortener.ErrDeleted):
+		return newAPIError(http.StatusGone, api.CodeDeleted, "short link is deleted")
+	case errors.Is(err, urlshortener.ErrConflict):
+		return newAPIError(http.StatusConflict, api.CodeConflict, "id is used by another short link")
+	case errors.Is(err, urlshortener.ErrUnavailable):
+		return newAPIError(http.StatusServiceUnavailable, api.CodeUnavailable, "service is temporarily unavailable")
+	}
+
+	// errors of echo, e.g. binding errors and unknown routes
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Code >= http.StatusInternalServerError {
+			return newAPIError(httpErr.Code, api.CodeInternal, http.StatusText(httpErr.Code))
+		}
+		code, ok := statusCodes[httpErr.Code]
+		if !ok {
+			code = api.CodeBadRequest
+		}
+		return newAPIError(httpErr.Code, code, fmt.Sprint(httpErr.Message))
+	}
+
+	return errInternal
+}
+
+// errorHandler responds errors in RFC 7807 problem details with request ID.
+func errorHandler(err error, c echo.Context) {
+	res := c.Response()
+	if res.Committed {
+		return
+	}
+
+	logger := logging.FromContext(c.Request().Context())
+	apiErr := toAPIError(err)
+	if apiErr.status >= http.StatusInternalServerError {
+		logger.Error("internal error", zap.Error(err))
+	}
+
+	problem := api.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(apiErr.status),
+		Status:    apiErr.status,
+		Detail:    apiErr.detail,
+		Instance:  c.Request().URL.Path,
+		Code:      apiErr.code,
+		RequestID: res.Header().Get(echo.HeaderXRequestID),
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.status)
+	} else if b, merr := json.Marshal(problem); merr != nil {
+		err = merr
+	} else {
+		err = c.Blob(apiErr.status, api.MIMEProblemJSON, b)
+	}
+	if err != nil {
+		logger.Error("fail to respond error", zap.Error(err))
+	}
+}
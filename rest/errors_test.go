@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *restTestSuite) TestErrorHandler() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrExpired).Once()
+
+	rec := s.serve(http.MethodGet, "/"+testURLID, "", "")
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Equal(api.MIMEProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	var problem api.Problem
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &problem))
+	s.Equal(api.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusNotFound),
+		Status:    http.StatusNotFound,
+		Detail:    "short link is expired",
+		Instance:  "/" + testURLID,
+		Code:      api.CodeExpired,
+		RequestID: rec.Header().Get(echo.HeaderXRequestID),
+	}, problem)
+	s.NotEmpty(problem.RequestID)
+}
+
+func (s *restTestSuite) TestErrorHandlerInternal() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, errors.New("dial tcp 10.0.0.1:3306")).Once()
+
+	rec := s.serve(http.MethodGet, "/"+testURLID, "", "")
+	s.Equal(http.StatusInternalServerError, rec.Code)
+	s.NotContains(rec.Body.String(), "10.0.0.1")
+	var problem api.Problem
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &problem))
+	s.Equal(api.CodeInternal, problem.Code)
+}
+
+func (s *restTestSuite) TestToAPIError() {
+	for err, code := range map[error]string{
+		urlshortener.ErrInvalidURL:                           api.CodeInvalidURL,
+		urlshortener.ErrOwnerNotFound:                        api.CodeInvalidAPIKey,
+		urlshortener.ErrConflict:                             api.CodeConflict,
+		urlshortener.ErrUnavailable:                          api.CodeUnavailable,
+		echo.ErrMethodNotAllowed:                             api.CodeMethodNotAllowed,
+		echo.NewHTTPError(http.StatusBadGateway, "upstream"): api.CodeInternal,
+	} {
+		s.Equal(code, toAPIError(err).code, err.Error())
+	}
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/base/logging"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const errorTemplate = "error.html"
+
+// Fallback defines how browsers are responded for short links which are missing or not active. The page of
+// errorTemplate is rendered with the status of the error unless they are redirected.
+type Fallback struct {
+	// Owner redirects to fallback URL of the owner of short link if the owner has one, see dao.Owner. It's prior
+	// to URL, and ignored for missing short links.
+	Owner bool
+	// URL is redirected to if it's not empty.
+	URL string
+}
+
+// Fallbacks defines Fallback of each reason, deleted short links fall back like disabled ones.
+type Fallbacks struct {
+	NotFound Fallback
+	Expired  Fallback
+	Disabled Fallback
+}
+
+// WithFallbacks responds browsers for short links which are missing or not active by fallbacks, the page is rendered
+// for all reasons by default.
+func WithFallbacks(fallbacks Fallbacks) Option {
+	return func(r *restImpl) {
+		r.fallbacks = fallbacks
+	}
+}
+
+// ParseFallback parses Fallback in the form of comma-separated "owner" and URL, e.g. "owner,https://example.com",
+// empty or "page" renders the page.
+func ParseFallback(s string) (Fallback, error) {
+	var fallback Fallback
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "", "page":
+		case "owner":
+			fallback.Owner = true
+		default:
+			u, err := url.Parse(part)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return Fallback{}, fmt.Errorf("fallback should be page, owner or an absolute URL, got %q", part)
+			}
+			if fallback.URL != "" {
+				return Fallback{}, fmt.Errorf("fallback has more than one URL: %q", s)
+			}
+			fallback.URL = part
+		}
+	}
+	return fallback, nil
+}
+
+type errorPage struct {
+	Status    int
+	Title     string
+	Code      string
+	Detail    string
+	RequestID string
+}
+
+// respondInactive responds err of short link urlID which is missing or not active. Browsers are responded by the
+// Fallback of the reason, and other clients get problem details by errorHandler.
+func (r *restImpl) respondInactive(c echo.Context, urlID string, err error) error {
+	req := c.Request()
+	if !prefersHTML(req.Header.Get(echo.HeaderAccept)) {
+		return err
+	}
+
+	apiErr := toAPIError(err)
+	var fallback Fallback
+	switch apiErr.code {
+	case api.CodeNotFound:
+		fallback = r.fallbacks.NotFound
+		fallback.Owner = false
+	case api.CodeExpired:
+		fallback = r.fallbacks.Expired
+	case api.CodeDisabled, api.CodeDeleted:
+		fallback = r.fallbacks.Disabled
+	default:
+		return err
+	}
+
+	fallbackURL := fallback.URL
+	if fallback.Owner {
+		ownerURL, ferr := r.urlShortener.OwnerFallbackURL(req.Context(), req.Host, urlID)
+		if ferr != nil {
+			logging.FromContext(req.Context()).Warn("fail to get fallback url of owner", zap.String("urlId", urlID),
+				zap.Error(ferr))
+		} else if ownerURL != "" {
+			fallbackURL = ownerURL
+		}
+	}
+	if fallbackURL != "" {
+		// the short link could be active again, so the redirect is never cached
+		c.Response().Header().Set(headerCacheControl, "private, no-cache")
+		return c.Redirect(http.StatusFound, fallbackURL)
+	}
+
+	return r.renderPage(c, apiErr.status, errorTemplate, errorPage{
+		Status:    apiErr.status,
+		Title:     http.StatusText(apiErr.status),
+		Code:      apiErr.code,
+		Detail:    apiErr.detail,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	})
+}
+
+// prefersHTML tells if Accept header prefers HTML to JSON, i.e. the client is a browser. Wildcards count for
+// neither, so clients accepting anything get JSON.
+func prefersHTML(accept string) bool {
+	var htmlQ, jsonQ float64
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		q := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) != "q" {
+				continue
+			}
+			if v, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+				q = v
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case echo.MIMETextHTML, "application/xhtml+xml":
+			htmlQ = math.Max(htmlQ, q)
+		case echo.MIMEApplicationJSON, api.MIMEProblemJSON:
+			jsonQ = math.Max(jsonQ, q)
+		}
+	}
+	return htmlQ > jsonQ
+}
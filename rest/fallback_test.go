@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+const testBrowserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
+
+func (s *restTestSuite) serveBrowser(target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set(echo.HeaderAccept, testBrowserAccept)
+	rec := httptest.NewRecorder()
+	s.impl.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *restTestSuite) TestPrefersHTML() {
+	s.True(prefersHTML(testBrowserAccept))
+	s.True(prefersHTML("application/json;q=0.5, text/html"))
+	s.False(prefersHTML(""))
+	s.False(prefersHTML("*/*"))
+	s.False(prefersHTML("application/json"))
+	s.False(prefersHTML("text/html;q=0.5, application/problem+json"))
+}
+
+func (s *restTestSuite) TestParseFallback() {
+	fallback, err := ParseFallback("")
+	s.Require().NoError(err)
+	s.Equal(Fallback{}, fallback)
+
+	fallback, err = ParseFallback("owner, https://example.com/gone")
+	s.Require().NoError(err)
+	s.Equal(Fallback{Owner: true, URL: "https://example.com/gone"}, fallback)
+
+	for _, invalid := range []string{"redirect", "/gone", "javascript:alert(1)", "https://a.com,https://b.com"} {
+		_, err = ParseFallback(invalid)
+		s.Error(err, invalid)
+	}
+}
+
+func (s *restTestSuite) TestRedirectNotFoundPage() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrNotFound).Once()
+
+	rec := s.serveBrowser("/" + testURLID)
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Equal(echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	s.Contains(rec.Body.String(), "Link not found")
+	s.Contains(rec.Body.String(), rec.Header().Get(echo.HeaderXRequestID))
+
+	// API clients get problem details
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrExpired).Once()
+	rec = s.serve(http.MethodGet, "/"+testURLID, "", "")
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Contains(rec.Body.String(), `"code":"expired"`)
+}
+
+func (s *restTestSuite) TestRedirectExpiredPage() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrExpired).Once()
+
+	rec := s.serveBrowser("/" + testURLID)
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Contains(rec.Body.String(), "This link has expired")
+}
+
+func (s *restTestSuite) TestRedirectFallbackURL() {
+	s.impl.fallbacks = Fallbacks{
+		NotFound: Fallback{URL: "https://example.com/not-found"},
+		Disabled: Fallback{Owner: true, URL: "https://example.com/gone"},
+	}
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrNotFound).Once()
+	rec := s.serveBrowser("/" + testURLID)
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://example.com/not-found", rec.Header().Get(echo.HeaderLocation))
+	s.Equal("private, no-cache", rec.Header().Get(headerCacheControl))
+
+	// invalid url_id falls back like missing short links
+	rec = s.serveBrowser("/not-an-id!")
+	s.Equal("https://example.com/not-found", rec.Header().Get(echo.HeaderLocation))
+
+	// fallback URL of owner is prior to the global one
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrDeleted).Once()
+	s.mockURLShortener.On("OwnerFallbackURL", mock.Anything, testHost, testURLID).Return("https://owner.example.com", nil).Once()
+	rec = s.serveBrowser("/" + testURLID)
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://owner.example.com", rec.Header().Get(echo.HeaderLocation))
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrDisabled).Once()
+	s.mockURLShortener.On("OwnerFallbackURL", mock.Anything, testHost, testURLID).Return("", nil).Once()
+	rec = s.serveBrowser("/" + testURLID)
+	s.Equal("https://example.com/gone", rec.Header().Get(echo.HeaderLocation))
+
+	// expired short links render the page without fallback
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrExpired).Once()
+	rec = s.serveBrowser("/" + testURLID)
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Empty(rec.Header().Get(echo.HeaderLocation))
+}
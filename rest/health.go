@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (r *restImpl) getHealth(c echo.Context) error {
+	var params urlParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	health, err := r.urlShortener.Health(owner, params.Domain, params.URLID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toHealthResponse(params.URLID, health))
+}
+
+func toHealthResponse(urlID string, health *dao.LinkHealth) api.HealthResponse {
+	resp := api.HealthResponse{
+		ID:                  urlID,
+		Broken:              health.BrokenSince != nil,
+		StatusCode:          health.StatusCode,
+		LatencyMS:           health.LatencyMS,
+		Error:               health.Error,
+		ConsecutiveFailures: health.ConsecutiveFailures,
+	}
+	if health.BrokenSince != nil {
+		brokenSince := health.BrokenSince.UTC()
+		resp.BrokenSince = &brokenSince
+	}
+	if !health.CheckedAt.IsZero() {
+		checkedAt := health.CheckedAt.UTC()
+		resp.CheckedAt = &checkedAt
+	}
+	return resp
+}
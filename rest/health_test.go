@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+func (s *restTestSuite) TestGetHealth() {
+	owner := dao.Owner{ID: 3}
+	brokenSince := testNow.Add(-time.Hour)
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Health", &owner, "", testURLID).Return(&dao.LinkHealth{
+		ShortLinkID:         7,
+		URL:                 testURL,
+		StatusCode:          http.StatusNotFound,
+		LatencyMS:           120,
+		ConsecutiveFailures: 3,
+		BrokenSince:         &brokenSince,
+		CheckedAt:           testNow,
+	}, nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/"+testURLID+"/health", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.HealthResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(api.HealthResponse{
+		ID:                  testURLID,
+		Broken:              true,
+		StatusCode:          http.StatusNotFound,
+		LatencyMS:           120,
+		ConsecutiveFailures: 3,
+		BrokenSince:         &brokenSince,
+		CheckedAt:           &testNow,
+	}, resp)
+}
+
+func (s *restTestSuite) TestGetHealthNotChecked() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Health", &owner, "", testURLID).Return(&dao.LinkHealth{ShortLinkID: 7}, nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/"+testURLID+"/health", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	s.JSONEq(`{"id":"`+testURLID+`","broken":false,"consecutiveFailures":0}`, rec.Body.String())
+}
+
+func (s *restTestSuite) TestGetHealthAnonymous() {
+	s.mockURLShortener.On("Health", (*dao.Owner)(nil), "", testURLID).Return(nil, urlshortener.ErrOwnerRequired).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/"+testURLID+"/health", "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+}
@@ -1,6 +1,9 @@
 package rest
 
+import "net/http"
+
 // Rest defines interface of rest operations.
 type Rest interface {
+	http.Handler
 	Start()
 }
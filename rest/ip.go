@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WithTrustedProxies takes client IP from X-Forwarded-For set by proxies in trustedProxies. Client IP is the
+// address of the connection by default, since the headers are given by clients and could be forged.
+func WithTrustedProxies(trustedProxies []*net.IPNet) Option {
+	return func(r *restImpl) {
+		r.trustedProxies = trustedProxies
+	}
+}
+
+// ParseTrustedProxies parses comma-separated IP ranges in CIDR notation or IPs, e.g. "10.0.0.0/8,192.0.2.1".
+func ParseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", part)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", part, err)
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges, nil
+}
+
+// ipExtractor returns the extractor of client IP, which is recorded in audit and access logs and looked up for
+// routing rules.
+func ipExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	// loopback and private networks are not trusted unless they are given
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range trustedProxies {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *restTestSuite) TestParseTrustedProxies() {
+	ranges, err := ParseTrustedProxies("")
+	s.Require().NoError(err)
+	s.Empty(ranges)
+
+	ranges, err = ParseTrustedProxies("10.0.0.0/8, 192.0.2.1,2001:db8::1")
+	s.Require().NoError(err)
+	s.Require().Len(ranges, 3)
+	s.Equal("10.0.0.0/8", ranges[0].String())
+	s.Equal("192.0.2.1/32", ranges[1].String())
+	s.Equal("2001:db8::1/128", ranges[2].String())
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	s.Error(err)
+	_, err = ParseTrustedProxies("proxy")
+	s.Error(err)
+}
+
+func (s *restTestSuite) TestRealIPForged() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+	req.Header.Set(echo.HeaderXRealIP, "198.51.100.1")
+
+	// headers of clients are ignored without trusted proxies
+	s.Equal("203.0.113.7", s.impl.e.NewContext(req, httptest.NewRecorder()).RealIP())
+}
+
+func (s *restTestSuite) TestRealIPTrustedProxies() {
+	_, trusted, err := net.ParseCIDR("10.0.0.0/8")
+	s.Require().NoError(err)
+	impl, err := NewRest(testBaseURL, testPort, s.mockURLShortener, s.mockStats, s.impl.clock,
+		WithTrustedProxies([]*net.IPNet{trusted}))
+	s.Require().NoError(err)
+	e := impl.(*restImpl).e
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1, 203.0.113.7, 10.0.0.3")
+	// the nearest address not of trusted proxies, those before it could be forged by the client
+	s.Equal("203.0.113.7", e.NewContext(req, httptest.NewRecorder()).RealIP())
+
+	// X-Forwarded-For of untrusted peers is ignored
+	req.RemoteAddr = "192.168.0.2:1234"
+	s.Equal("192.168.0.2", e.NewContext(req, httptest.NewRecorder()).RealIP())
+}
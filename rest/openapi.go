@@ -0,0 +1,31 @@
+package rest
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// docsContentSecurityPolicy allows docs page to load Redoc from its CDN, which renders spec in web workers.
+const docsContentSecurityPolicy = "default-src 'self'; script-src https://cdn.redoc.ly; " +
+	"style-src 'unsafe-inline' https://fonts.googleapis.com; font-src https://fonts.gstatic.com; " +
+	"img-src 'self' data: https://cdn.redoc.ly; worker-src blob:"
+
+//go:embed openapi/openapi.json
+var embeddedOpenAPISpec []byte
+
+//go:embed openapi/docs.html
+var embeddedDocsPage []byte
+
+// openAPISpec serves the OpenAPI 3 document of /api/v1, which is checked against routes and api types by tests.
+func (r *restImpl) openAPISpec(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, embeddedOpenAPISpec)
+}
+
+func (r *restImpl) openAPIDocs(c echo.Context) error {
+	header := c.Response().Header()
+	header.Set(headerContentSecurityPolicy, docsContentSecurityPolicy)
+	header.Set(headerXFrameOptions, "DENY")
+	return c.HTMLBlob(http.StatusOK, embeddedDocsPage)
+}
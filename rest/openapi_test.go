@@ -0,0 +1,221 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+		Schemas    map[string]openAPISchema    `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters []openAPIParameter `json:"parameters"`
+}
+
+type openAPIParameter struct {
+	Ref  string `json:"$ref"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+type openAPISchema struct {
+	Required   []string                   `json:"required"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+var echoPathParam = regexp.MustCompile(`:(\w+)`)
+
+func (s *restTestSuite) openAPIDocument() *openAPIDocument {
+	var doc openAPIDocument
+	s.Require().NoError(json.Unmarshal(embeddedOpenAPISpec, &doc))
+	return &doc
+}
+
+func (s *restTestSuite) TestOpenAPIRoutes() {
+	var routes []string
+	for _, route := range s.impl.e.Routes() {
+		if strings.HasPrefix(route.Path, "/api/v1/") {
+			routes = append(routes, route.Method+" "+echoPathParam.ReplaceAllString(route.Path, "{$1}"))
+		}
+	}
+
+	var operations []string
+	for path, methods := range s.openAPIDocument().Paths {
+		for method := range methods {
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(operations)
+	s.Equal(routes, operations)
+}
+
+func (s *restTestSuite) TestOpenAPIParameters() {
+	doc := s.openAPIDocument()
+	// params bound by handlers of operations
+	operationParams := map[string]interface{}{
+		"POST /api/v1/urls":                                                  struct{}{},
+		"GET /api/v1/urls":                                                   listURLsParams{},
+		"POST /api/v1/urls/import":                                           importURLsParams{},
+		"GET /api/v1/urls/export":                                            exportURLsParams{},
+		"GET /api/v1/urls/{url_id}":                                          urlParams{},
+		"PATCH /api/v1/urls/{url_id}":                                        updateURLParams{},
+		"DELETE /api/v1/urls/{url_id}":                                       deleteURLParams{},
+		"POST /api/v1/urls/{url_id}/restore":                                 urlParams{},
+		"POST /api/v1/urls/{url_id}/expiry":                                  setExpiryParams{},
+		"GET /api/v1/urls/{url_id}/qr":                                       qrCodeParams{},
+		"GET /api/v1/urls/{url_id}/stats":                                    getStatsParams{},
+		"GET /api/v1/urls/{url_id}/health":                                   urlParams{},
+		"POST /api/v1/webhooks":                                              struct{}{},
+		"GET /api/v1/webhooks":                                               struct{}{},
+		"DELETE /api/v1/webhooks/{webhook_id}":                               webhookParams{},
+		"GET /api/v1/webhooks/{webhook_id}/deliveries":                       listDeliveriesParams{},
+		"POST /api/v1/webhooks/{webhook_id}/deliveries/{delivery_id}/replay": replayDeliveryParams{},
+		"GET /api/v1/admin/audit-events":                                     listAuditEventsParams{},
+		"GET /api/v1/admin/blocked-ids":                                      struct{}{},
+		"PUT /api/v1/admin/blocked-ids/{url_id}":                             blockIDParams{},
+		"DELETE /api/v1/admin/blocked-ids/{url_id}":                          blockedIDParams{},
+		"GET /api/v1/openapi.json":                                           struct{}{},
+		"GET /api/v1/docs":                                                   struct{}{},
+	}
+
+	for path, methods := range doc.Paths {
+		for method, operation := range methods {
+			key := strings.ToUpper(method) + " " + path
+			params, ok := operationParams[key]
+			if !s.True(ok, "params of %s are not checked", key) {
+				continue
+			}
+
+			var specParams []string
+			for _, param := range operation.Parameters {
+				if param.Ref != "" {
+					param = doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+				}
+				specParams = append(specParams, param.In+":"+param.Name)
+			}
+			sort.Strings(specParams)
+			s.Equal(boundParams(reflect.TypeOf(params)), specParams, key)
+		}
+	}
+}
+
+// boundParams returns path and query params bound to fields of t by echo.
+func boundParams(t reflect.Type) []string {
+	var params []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := field.Tag.Get("param"); name != "" && name != "*" {
+			params = append(params, "path:"+name)
+		}
+		if name := field.Tag.Get("query"); name != "" {
+			params = append(params, "query:"+name)
+		}
+	}
+	sort.Strings(params)
+	return params
+}
+
+func (s *restTestSuite) TestOpenAPISchemas() {
+	doc := s.openAPIDocument()
+	schemas := map[string]struct {
+		value interface{}
+		// fields of responses are always present unless they're omitempty
+		response bool
+	}{
+		"UploadURLRequest":        {value: api.UploadURLRequest{}},
+		"UploadURLResponse":       {value: api.UploadURLResponse{}, response: true},
+		"UpdateURLRequest":        {value: api.UpdateURLRequest{}},
+		"ExpiryRequest":           {value: api.ExpiryRequest{}},
+		"ShortLinkResponse":       {value: api.ShortLinkResponse{}, response: true},
+		"ListURLsResponse":        {value: api.ListURLsResponse{}, response: true},
+		"StatsResponse":           {value: api.StatsResponse{}, response: true},
+		"HealthResponse":          {value: api.HealthResponse{}, response: true},
+		"ImportResponse":          {value: api.ImportResponse{}, response: true},
+		"ImportError":             {value: api.ImportError{}, response: true},
+		"CreateWebhookRequest":    {value: api.CreateWebhookRequest{}},
+		"WebhookResponse":         {value: api.WebhookResponse{}, response: true},
+		"ListWebhooksResponse":    {value: api.ListWebhooksResponse{}, response: true},
+		"DeliveryResponse":        {value: api.DeliveryResponse{}, response: true},
+		"ListDeliveriesResponse":  {value: api.ListDeliveriesResponse{}, response: true},
+		"AuditEventResponse":      {value: api.AuditEventResponse{}, response: true},
+		"ListAuditEventsResponse": {value: api.ListAuditEventsResponse{}, response: true},
+		"BlockIDRequest":          {value: api.BlockIDRequest{}},
+		"BlockedIDResponse":       {value: api.BlockedIDResponse{}, response: true},
+		"ListBlockedIDsResponse":  {value: api.ListBlockedIDsResponse{}, response: true},
+		"LinkSnapshot":            {value: dao.LinkSnapshot{}, response: true},
+		"Problem":                 {value: api.Problem{}, response: true},
+		"Rule":                    {value: rules.Rule{}},
+		"Variant":                 {value: split.Variant{}},
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		// UTM is map[string]string with known keys, EventType is an enum of strings
+		if name == "UTM" || name == "EventType" {
+			continue
+		}
+		expected, ok := schemas[name]
+		if !s.True(ok, "schema %s is not checked", name) {
+			continue
+		}
+
+		var properties []string
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		sort.Strings(schema.Required)
+
+		t := reflect.TypeOf(expected.value)
+		var fields, required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			omitempty := len(tag) > 1 && tag[1] == "omitempty"
+			fields = append(fields, tag[0])
+
+			validations := strings.Split(field.Tag.Get("validate"), ",")
+			if validations[0] == "required" || (expected.response && !omitempty) {
+				required = append(required, tag[0])
+			}
+		}
+		sort.Strings(fields)
+		sort.Strings(required)
+		s.Equal(fields, properties, name)
+		if expected.response {
+			s.Equal(required, schema.Required, name)
+		} else {
+			s.Subset(schema.Required, required, name)
+			s.Subset(properties, schema.Required, name)
+		}
+	}
+}
+
+func (s *restTestSuite) TestOpenAPIServed() {
+	rec := s.serve(http.MethodGet, "/api/v1/openapi.json", "", "")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal(echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	s.True(json.Valid(rec.Body.Bytes()))
+
+	rec = s.serve(http.MethodGet, "/api/v1/docs", "", "")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal(docsContentSecurityPolicy, rec.Header().Get(headerContentSecurityPolicy))
+	s.Contains(rec.Body.String(), `spec-url="openapi.json"`)
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/base/qrcode"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	qrCodeFormatPNG = "png"
+	qrCodeFormatSVG = "svg"
+
+	// qrCodeMaxAge is max age of QR code in client cache, short URL of a link never changes.
+	qrCodeMaxAge = 24 * 60 * 60
+)
+
+type qrCodeParams struct {
+	URLID  string `param:"url_id" validate:"required"`
+	Domain string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+	Format string `query:"format" validate:"oneof=png svg"`
+	Size   int    `query:"size" validate:"min=64,max=2048"`
+	Level  string `query:"level" validate:"oneof=L M Q H l m q h"`
+	Margin int    `query:"margin" validate:"min=0,max=16"`
+}
+
+func (r *restImpl) qrCode(c echo.Context) error {
+	params := qrCodeParams{
+		Format: qrCodeFormatPNG,
+		Size:   256,
+		Level:  string(qrcode.LevelMedium),
+		Margin: 4,
+	}
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	shortLink, err := r.urlShortener.LoadActive(c.Request().Context(), params.Domain, params.URLID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	level, err := qrcode.ParseLevel(params.Level)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, "level is invalid")
+	}
+	opts := qrcode.Options{
+		Size:   params.Size,
+		Level:  level,
+		Margin: params.Margin,
+	}
+	shortURL := shortLink.Domain.ShortURL(shortLink.URLID)
+
+	// QR code is determined by short URL and options, so it's used as ETag
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum([]byte(fmt.Sprintf("%s|%s|%d|%s|%d", shortURL, params.Format, opts.Size, opts.Level, opts.Margin))))
+	c.Response().Header().Set(headerCacheControl, fmt.Sprintf("public, max-age=%d", qrCodeMaxAge))
+	c.Response().Header().Set(headerETag, etag)
+	if etagMatch(c.Request().Header.Get(headerIfNoneMatch), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	var b []byte
+	var contentType string
+	switch params.Format {
+	case qrCodeFormatSVG:
+		b, err = qrcode.SVG(shortURL, opts)
+		contentType = "image/svg+xml"
+	default:
+		b, err = qrcode.PNG(shortURL, opts)
+		contentType = "image/png"
+	}
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, err.Error())
+	}
+
+	return c.Blob(http.StatusOK, contentType, b)
+}
+
+// etagMatch checks if etag matches value of If-None-Match header.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *restTestSuite) newQRCodeContext(target string, header http.Header) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/api/v1/urls/:url_id/qr")
+	c.SetParamNames("url_id")
+	c.SetParamValues(testURLID)
+	return c, rec
+}
+
+func (s *restTestSuite) mockQRCodeShortLink(domain string) {
+	shortLink := dao.ShortLink{
+		DomainID: testDomain.ID,
+		URLID:    testURLID,
+		URL:      testURL,
+		Domain:   &testDomain,
+		ExpireAt: s.impl.clock.Now().Add(10),
+	}
+	s.mockURLShortener.On("LoadActive", mock.Anything, domain, testURLID).Return(&shortLink, nil).Once()
+}
+
+func (s *restTestSuite) TestQRCode() {
+	c, rec := s.newQRCodeContext("/?size=128", nil)
+	s.mockQRCodeShortLink("")
+
+	s.Require().NoError(s.impl.qrCode(c))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("image/png", rec.Header().Get(echo.HeaderContentType))
+	s.NotEmpty(rec.Header().Get(headerETag))
+	img, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+	s.Require().NoError(err)
+	s.Equal(128, img.Bounds().Dx())
+}
+
+func (s *restTestSuite) TestQRCodeSVG() {
+	c, rec := s.newQRCodeContext("/?format=svg&domain=go.example.com&level=H&margin=0", nil)
+	s.mockQRCodeShortLink("go.example.com")
+
+	s.Require().NoError(s.impl.qrCode(c))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("image/svg+xml", rec.Header().Get(echo.HeaderContentType))
+	s.True(strings.HasPrefix(rec.Body.String(), "<svg"))
+}
+
+func (s *restTestSuite) TestQRCodeNotModified() {
+	c, rec := s.newQRCodeContext("/", nil)
+	s.mockQRCodeShortLink("")
+	s.Require().NoError(s.impl.qrCode(c))
+	etag := rec.Header().Get(headerETag)
+
+	c, rec = s.newQRCodeContext("/", http.Header{headerIfNoneMatch: []string{etag}})
+	s.mockQRCodeShortLink("")
+	s.Require().NoError(s.impl.qrCode(c))
+	s.Equal(http.StatusNotModified, rec.Code)
+	s.Empty(rec.Body.Bytes())
+
+	c, rec = s.newQRCodeContext("/?size=512", http.Header{headerIfNoneMatch: []string{etag}})
+	s.mockQRCodeShortLink("")
+	s.Require().NoError(s.impl.qrCode(c))
+	s.Equal(http.StatusOK, rec.Code)
+}
+
+func (s *restTestSuite) TestQRCodeInvalidParams() {
+	for _, target := range []string{"/?size=10", "/?format=gif", "/?level=X", "/?margin=100"} {
+		c, _ := s.newQRCodeContext(target, nil)
+		err := s.impl.qrCode(c)
+		s.Require().Error(err, target)
+		s.Equal(http.StatusBadRequest, toAPIError(err).status)
+	}
+}
+
+func (s *restTestSuite) TestQRCodeExpired() {
+	c, _ := s.newQRCodeContext("/", nil)
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(nil, urlshortener.ErrExpired).Once()
+
+	s.Equal(http.StatusNotFound, toAPIError(s.impl.qrCode(c)).status)
+}
@@ -3,12 +3,20 @@ package rest
 import (
 	"errors"
 	"fmt"
+	"html/template"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/georgechang0117/url-shortener/base/base62"
 	"github.com/georgechang0117/url-shortener/base/cache"
+	"github.com/georgechang0117/url-shortener/base/logging"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/redirect"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/stats"
 	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
 
 	"code.cloudfoundry.org/clock"
 	"github.com/go-playground/validator/v10"
@@ -16,27 +24,92 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// headerAPIKey is the request header carrying owner's API key.
+	headerAPIKey = "X-API-Key"
+
+	headerAcceptLanguage = "Accept-Language"
+	headerCacheControl   = "Cache-Control"
+	headerETag           = "ETag"
+	headerIfNoneMatch    = "If-None-Match"
+
+	// previewSuffix appended to url_id renders preview page instead of redirecting.
+	previewSuffix = "+"
+
+	// variantCookiePrefix is prefix of cookie keeping variant assigned to client, followed by url_id.
+	variantCookiePrefix = "sl_variant_"
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
 type restImpl struct {
 	e            *echo.Echo
 	baseURL      string
 	port         int
 	remoteCache  cache.RemoteCache
 	urlShortener urlshortener.URLShortener
+	stats        stats.Stats
 	clock        clock.Clock
+	preview      bool
+	templateDir  string
+	templates    *template.Template
+	geoIP        rules.GeoIP
+	resolver     redirect.Resolver
+	accessLogger logging.AccessLogger
+	fallbacks    Fallbacks
+	// adminAPIKey authorizes admin APIs, which are forbidden if it's empty.
+	adminAPIKey string
+	// trustedProxies are proxies whose X-Forwarded-For is trusted, see WithTrustedProxies.
+	trustedProxies []*net.IPNet
 }
 
-type uploadURLParams struct {
-	URL      string `json:"url" validate:"required,uri"`
-	ExpireAt string `json:"expireAt" validate:"required"`
+// Option defines optional configuration of Rest.
+type Option func(r *restImpl)
+
+// WithPreview renders preview page for every short link instead of redirecting.
+func WithPreview(preview bool) Option {
+	return func(r *restImpl) {
+		r.preview = preview
+	}
 }
 
-type uploadURLResp struct {
-	ID       string `json:"id"`
-	ShortURL string `json:"shortUrl"`
+// WithTemplateDir overrides embedded page templates by templates with the same file name in dir.
+func WithTemplateDir(dir string) Option {
+	return func(r *restImpl) {
+		r.templateDir = dir
+	}
+}
+
+// WithGeoIP looks up country of client IP by geoIP for routing rules.
+func WithGeoIP(geoIP rules.GeoIP) Option {
+	return func(r *restImpl) {
+		r.geoIP = geoIP
+	}
+}
+
+// WithAccessLogger writes access logs by accessLogger, which logs every request at info level by default.
+func WithAccessLogger(accessLogger logging.AccessLogger) Option {
+	return func(r *restImpl) {
+		r.accessLogger = accessLogger
+	}
+}
+
+// WithAdminAPIKey authorizes requests with adminAPIKey in X-API-Key to call admin APIs, e.g. the audit log.
+func WithAdminAPIKey(adminAPIKey string) Option {
+	return func(r *restImpl) {
+		r.adminAPIKey = adminAPIKey
+	}
 }
 
 type redirectParams struct {
 	URLID string `param:"url_id" validate:"required"`
+	// Path is the path following url_id, used by {path} placeholder.
+	Path string `param:"*"`
+}
+
+type previewPage struct {
+	ShortURL  string
+	URL       string
+	CreatedAt time.Time
 }
 
 // NewRest creates an instance of Rest.
@@ -44,33 +117,78 @@ func NewRest(
 	baseURL string,
 	port int,
 	urlshortener urlshortener.URLShortener,
+	stats stats.Stats,
 	clock clock.Clock,
-) Rest {
+	opts ...Option,
+) (Rest, error) {
 	r := &restImpl{
 		e:            newEcho(),
 		baseURL:      baseURL,
 		port:         port,
 		urlShortener: urlshortener,
+		stats:        stats,
 		clock:        clock,
+		accessLogger: logging.NewAccessLogger(zap.L(), logging.AccessLogConfig{}),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	templates, err := parseTemplates(r.templateDir)
+	if err != nil {
+		return nil, err
+	}
+	r.templates = templates
+	r.resolver = redirect.NewResolver(r.geoIP)
+	r.e.IPExtractor = ipExtractor(r.trustedProxies)
 
-	r.e.Use(requestLogger)
+	r.e.Use(r.requestLogger)
 	apiGroup := r.e.Group("/api")
 	apiV1Group := apiGroup.Group("/v1")
 	apiV1Group.POST("/urls", r.uploadURL)
+	apiV1Group.GET("/urls", r.listURLs)
+	apiV1Group.POST("/urls/import", r.importURLs)
+	apiV1Group.GET("/urls/export", r.exportURLs)
+	apiV1Group.GET("/urls/:url_id", r.getURL)
+	apiV1Group.PATCH("/urls/:url_id", r.updateURL)
+	apiV1Group.DELETE("/urls/:url_id", r.deleteURL)
+	apiV1Group.POST("/urls/:url_id/restore", r.restoreURL)
+	apiV1Group.POST("/urls/:url_id/expiry", r.setExpiry)
+	apiV1Group.GET("/urls/:url_id/qr", r.qrCode)
+	apiV1Group.GET("/urls/:url_id/stats", r.getStats)
+	apiV1Group.GET("/urls/:url_id/health", r.getHealth)
+	apiV1Group.POST("/webhooks", r.createWebhook)
+	apiV1Group.GET("/webhooks", r.listWebhooks)
+	apiV1Group.DELETE("/webhooks/:webhook_id", r.deleteWebhook)
+	apiV1Group.GET("/webhooks/:webhook_id/deliveries", r.listDeliveries)
+	apiV1Group.POST("/webhooks/:webhook_id/deliveries/:delivery_id/replay", r.replayDelivery)
+	apiV1Group.GET("/admin/audit-events", r.listAuditEvents)
+	apiV1Group.GET("/admin/blocked-ids", r.listBlockedIDs)
+	apiV1Group.PUT("/admin/blocked-ids/:url_id", r.blockID)
+	apiV1Group.DELETE("/admin/blocked-ids/:url_id", r.unblockID)
+	apiV1Group.GET("/openapi.json", r.openAPISpec)
+	apiV1Group.GET("/docs", r.openAPIDocs)
 
 	r.e.GET("/:url_id", r.redirect)
+	r.e.GET("/:url_id/*", r.redirect)
 
-	return r
+	return r, nil
 }
 
 func (r *restImpl) Start() {
 	r.e.Start(fmt.Sprintf(":%d", r.port))
 }
 
+func (r *restImpl) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.e.ServeHTTP(w, req)
+}
+
 func newEcho() *echo.Echo {
 	e := echo.New()
 	e.Validator = &defaultValidator{v: validator.New()}
+	e.HTTPErrorHandler = errorHandler
+	// echo trusts X-Forwarded-For and X-Real-IP of anyone without an extractor
+	e.IPExtractor = echo.ExtractIPDirect()
 	return e
 }
 
@@ -90,7 +208,7 @@ func bindParams(c echo.Context, params interface{}) error {
 	err := c.Validate(params)
 	var verr validator.ValidationErrors
 	if errors.As(err, &verr) {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, err.Error())
 	}
 	return err
 }
@@ -104,27 +222,46 @@ func parseTime(timeStr string) (time.Time, error) {
 }
 
 func (r *restImpl) uploadURL(c echo.Context) error {
-	var params uploadURLParams
+	var params api.UploadURLRequest
 	if err := bindParams(c, &params); err != nil {
 		return err
 	}
 
 	expireAtTime, err := parseTime(params.ExpireAt)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "expireAt is invalid")
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, "expireAt is invalid")
 	}
 	if expireAtTime.Before(r.clock.Now()) {
-		return echo.NewHTTPError(http.StatusBadRequest, "expireAt should be greater than now")
+		return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, "expireAt should be greater than now")
 	}
 
-	shorLink, err := r.urlShortener.Upload(params.URL, expireAtTime)
+	owner, err := r.authenticate(c)
 	if err != nil {
 		return err
 	}
 
-	resp := uploadURLResp{
+	shorLink, err := r.urlShortener.Upload(c.Request().Context(), urlshortener.UploadParams{
+		Owner:       owner,
+		Domain:      params.Domain,
+		URL:         params.URL,
+		ExpireAt:    expireAtTime,
+		Preview:     params.Preview,
+		QueryMode:   params.QueryMode,
+		UTM:         params.UTM,
+		Rules:       params.Rules,
+		Variants:    params.Variants,
+		Title:       params.Title,
+		Description: params.Description,
+		Tags:        params.Tags,
+		FallbackURL: params.FallbackURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp := api.UploadURLResponse{
 		ID:       shorLink.URLID,
-		ShortURL: fmt.Sprintf("%s/%s", r.baseURL, shorLink.URLID),
+		ShortURL: shorLink.Domain.ShortURL(shorLink.URLID),
 	}
 
 	return c.JSON(http.StatusCreated, resp)
@@ -136,56 +273,123 @@ func (r *restImpl) redirect(c echo.Context) error {
 		return err
 	}
 
-	if len(params.URLID) != 11 {
-		return c.JSON(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	preview := strings.HasSuffix(params.URLID, previewSuffix)
+	urlID := strings.TrimSuffix(params.URLID, previewSuffix)
+
+	if !urlshortener.IsValidURLID(urlID) {
+		return r.respondInactive(c, urlID, errNotFound)
 	}
-	_, err := base62.Decode(params.URLID)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+
+	shortLink, err := r.urlShortener.LoadActive(c.Request().Context(), c.Request().Host, urlID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return r.respondInactive(c, urlID, errNotFound)
+	} else if err != nil {
+		return r.respondInactive(c, urlID, err)
 	}
 
-	shortLink, err := r.urlShortener.Load(params.URLID)
+	req := c.Request()
+	cookieName := variantCookiePrefix + urlID
+	var variant string
+	if cookie, err := c.Cookie(cookieName); err == nil {
+		variant = cookie.Value
+	}
+	resolution, err := r.resolver.Resolve(shortLink, redirect.Request{
+		Path:           params.Path,
+		Query:          c.QueryParams(),
+		UserAgent:      req.UserAgent(),
+		AcceptLanguage: req.Header.Get(headerAcceptLanguage),
+		IP:             net.ParseIP(c.RealIP()),
+		Time:           r.clock.Now(),
+		Variant:        variant,
+	})
 	if err != nil {
-		zap.S().Errorf("fail to load by urlID: %s, err: %v", params.URLID, err)
+		logging.FromContext(req.Context()).Error("fail to resolve destination", zap.String("urlId", urlID), zap.Error(err))
 		return err
 	}
 
-	if shortLink.ExpireAt.Before(r.clock.Now()) {
-		return c.JSON(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	if preview || r.preview || shortLink.Preview {
+		return r.renderPage(c, http.StatusOK, previewTemplate, previewPage{
+			ShortURL:  shortLink.Domain.ShortURL(shortLink.URLID),
+			URL:       resolution.URL,
+			CreatedAt: shortLink.CreatedAt,
+		})
+	}
+
+	r.stats.Record(shortLink.ID, resolution.Variant)
+
+	// browsers cache permanent redirect, then the variant would never change, clicks are not counted,
+	// redirect would never fall back and rules would not be evaluated per visitor
+	if len(shortLink.Variants) > 0 || len(shortLink.Rules) > 0 || shortLink.FallbackURL != "" {
+		if resolution.Variant != "" {
+			c.SetCookie(&http.Cookie{
+				Name:     cookieName,
+				Value:    resolution.Variant,
+				Path:     "/" + urlID,
+				MaxAge:   variantCookieMaxAge,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		c.Response().Header().Set(headerCacheControl, "private, no-cache")
+		return c.Redirect(http.StatusFound, resolution.URL)
+	}
+
+	return c.Redirect(http.StatusMovedPermanently, resolution.URL)
+}
+
+// authenticate returns owner of the API key in request header, or nil if the request is anonymous.
+func (r *restImpl) authenticate(c echo.Context) (*dao.Owner, error) {
+	apiKey := c.Request().Header.Get(headerAPIKey)
+	if apiKey == "" {
+		return nil, nil
+	}
+
+	owner, err := r.urlShortener.Authenticate(apiKey)
+	if err != nil {
+		return nil, err
 	}
 
-	return c.Redirect(http.StatusMovedPermanently, shortLink.URL)
+	return owner, nil
 }
 
-func requestLogger(next echo.HandlerFunc) echo.HandlerFunc {
+// requestLogger puts logger with request ID and the source of the request for audit log into request context,
+// then writes access log of the request. Request ID is propagated from X-Request-ID header, or generated if there
+// is none, and responded in X-Request-ID.
+func (r *restImpl) requestLogger(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) (err error) {
 		req := c.Request()
 		res := c.Response()
 		start := time.Now()
+
+		requestID := req.Header.Get(echo.HeaderXRequestID)
+		if !logging.IsValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		res.Header().Set(echo.HeaderXRequestID, requestID)
+		logger := logging.FromContext(req.Context()).With(zap.String("requestId", requestID))
+		ctx := logging.NewContext(req.Context(), logger)
+		c.SetRequest(req.WithContext(urlshortener.NewAuditContext(ctx, c.RealIP(), requestID)))
+
 		if err = next(c); err != nil {
 			c.Error(err)
 		}
-		stop := time.Now()
 
-		if err != nil {
-			zap.S().Errorf("error: %v", err.Error())
+		fields := []zap.Field{
+			zap.String("requestId", requestID),
+			zap.String("method", req.Method),
+			zap.Int("status", res.Status),
+			zap.Int64("bytes", res.Size),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("remoteIp", c.RealIP()),
+			zap.String("userAgent", req.UserAgent()),
 		}
-
-		path := req.URL.Path
-		if path == "" {
-			path = "/"
+		if urlID := c.Param("url_id"); urlID != "" {
+			fields = append(fields, zap.String("urlId", strings.TrimSuffix(urlID, previewSuffix)))
 		}
-		contentLength := req.Header.Get(echo.HeaderContentLength)
-		if contentLength == "" {
-			contentLength = "0"
+		if err != nil {
+			fields = append(fields, zap.Error(err))
 		}
-		zap.S().Infof(
-			"uri=%s method=%s status=%d latency=%d",
-			req.RequestURI,
-			req.Method,
-			res.Status,
-			stop.Sub(start).Microseconds(),
-		)
+		r.accessLogger.Log(c.Path(), res.Status >= http.StatusInternalServerError, fields...)
 
 		return err
 	}
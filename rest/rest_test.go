@@ -2,6 +2,7 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,12 +11,22 @@ import (
 	"time"
 
 	cachemocks "github.com/georgechang0117/url-shortener/base/cache/mocks"
+	"github.com/georgechang0117/url-shortener/base/logging"
 	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+	statsmocks "github.com/georgechang0117/url-shortener/core/stats/mocks"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
 	urlshortenermocks "github.com/georgechang0117/url-shortener/core/urlshortener/mocks"
+	"github.com/georgechang0117/url-shortener/rest/api"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 const (
@@ -23,9 +34,14 @@ const (
 	testPort    = 8080
 	testURL     = "https://boards.greenhouse.io/dcard/jobs/871123?gh_src=ok8y1h1/"
 	testURLID   = "abcdefghijk"
+	testHost    = "example.com"
+	testAPIKey  = "test-api-key"
 )
 
-var testNow = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+var (
+	testNow    = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+	testDomain = dao.Domain{ID: 1, Host: "localhost:8080", Scheme: "http"}
+)
 
 type restTestSuite struct {
 	suite.Suite
@@ -33,13 +49,17 @@ type restTestSuite struct {
 	echo             *echo.Echo
 	mockRemoteCache  *cachemocks.RemoteCache
 	mockURLShortener *urlshortenermocks.URLShortener
+	mockStats        *statsmocks.Stats
 }
 
 func (s *restTestSuite) SetupTest() {
 	s.echo = newEcho()
 	s.mockURLShortener = &urlshortenermocks.URLShortener{}
 	s.mockRemoteCache = &cachemocks.RemoteCache{}
-	impl := NewRest(testBaseURL, testPort, s.mockURLShortener, fakeclock.NewFakeClock(testNow))
+	s.mockStats = &statsmocks.Stats{}
+	s.mockStats.On("Record", mock.Anything, mock.Anything).Maybe()
+	impl, err := NewRest(testBaseURL, testPort, s.mockURLShortener, s.mockStats, fakeclock.NewFakeClock(testNow))
+	s.Require().NoError(err)
 	s.impl = impl.(*restImpl)
 }
 
@@ -48,7 +68,7 @@ func TestRestTestSuite(t *testing.T) {
 }
 
 func (s *restTestSuite) TestUploadURL() {
-	params := uploadURLParams{
+	params := api.UploadURLRequest{
 		URL:      testURL,
 		ExpireAt: "2021-07-30T00:00:00Z",
 	}
@@ -62,23 +82,117 @@ func (s *restTestSuite) TestUploadURL() {
 
 	expireAtTime, _ := parseTime(params.ExpireAt)
 	mockShortLink := dao.ShortLink{
+		DomainID: testDomain.ID,
 		URLID:    testURLID,
 		URL:      testURL,
+		Domain:   &testDomain,
 		ExpireAt: expireAtTime,
 	}
 
-	s.mockURLShortener.On("Upload", params.URL, expireAtTime).Return(&mockShortLink, nil).Once()
+	s.mockURLShortener.On("Upload", mock.Anything, urlshortener.UploadParams{
+		URL:      params.URL,
+		ExpireAt: expireAtTime,
+	}).Return(&mockShortLink, nil).Once()
 
 	s.Require().NoError(s.impl.uploadURL(c))
 	s.Equal(http.StatusCreated, rec.Code)
-	var resp uploadURLResp
+	var resp api.UploadURLResponse
 	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
 	s.Equal(mockShortLink.URLID, resp.ID)
 	s.Equal(fmt.Sprintf("%s/%s", s.impl.baseURL, testURLID), resp.ShortURL)
 }
 
+func (s *restTestSuite) TestUploadURLWithDomain() {
+	params := api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: "2021-07-30T00:00:00Z",
+		Domain:   "go.example.com",
+	}
+	b, _ := json.Marshal(&params)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(headerAPIKey, testAPIKey)
+
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+
+	expireAtTime, _ := parseTime(params.ExpireAt)
+	owner := dao.Owner{ID: 2, APIKey: testAPIKey}
+	domain := dao.Domain{ID: 3, Host: params.Domain, Scheme: "https"}
+	mockShortLink := dao.ShortLink{
+		DomainID: domain.ID,
+		OwnerID:  owner.ID,
+		URLID:    testURLID,
+		URL:      testURL,
+		Domain:   &domain,
+		ExpireAt: expireAtTime,
+	}
+
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Upload", mock.Anything, urlshortener.UploadParams{
+		Owner:    &owner,
+		Domain:   params.Domain,
+		URL:      params.URL,
+		ExpireAt: expireAtTime,
+	}).Return(&mockShortLink, nil).Once()
+
+	s.Require().NoError(s.impl.uploadURL(c))
+	s.Equal(http.StatusCreated, rec.Code)
+	var resp api.UploadURLResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal("https://go.example.com/"+testURLID, resp.ShortURL)
+}
+
+func (s *restTestSuite) TestUploadURLDomainNotAllowed() {
+	params := api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: "2021-07-30T00:00:00Z",
+		Domain:   "go.example.com",
+	}
+	b, _ := json.Marshal(&params)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+
+	expireAtTime, _ := parseTime(params.ExpireAt)
+	s.mockURLShortener.On("Upload", mock.Anything, urlshortener.UploadParams{
+		Domain:   params.Domain,
+		URL:      params.URL,
+		ExpireAt: expireAtTime,
+	}).Return(nil, urlshortener.ErrDomainNotAllowed).Once()
+
+	err := s.impl.uploadURL(c)
+	s.Require().Error(err)
+	s.Equal(http.StatusForbidden, toAPIError(err).status)
+}
+
+func (s *restTestSuite) TestUploadURLInvalidAPIKey() {
+	params := api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: "2021-07-30T00:00:00Z",
+	}
+	b, _ := json.Marshal(&params)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(headerAPIKey, testAPIKey)
+
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(nil, urlshortener.ErrOwnerNotFound).Once()
+
+	err := s.impl.uploadURL(c)
+	s.Require().Error(err)
+	s.Equal(http.StatusUnauthorized, toAPIError(err).status)
+}
+
 func (s *restTestSuite) TestUploadURLExpireAtNotTimeFormat() {
-	params := uploadURLParams{
+	params := api.UploadURLRequest{
 		URL:      testURL,
 		ExpireAt: "invalid",
 	}
@@ -92,11 +206,11 @@ func (s *restTestSuite) TestUploadURLExpireAtNotTimeFormat() {
 
 	err := s.impl.uploadURL(c)
 	s.Require().Error(err)
-	s.Equal(http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	s.Equal(http.StatusBadRequest, toAPIError(err).status)
 }
 
 func (s *restTestSuite) TestUploadURLExpireAtTooOld() {
-	params := uploadURLParams{
+	params := api.UploadURLRequest{
 		URL:      testURL,
 		ExpireAt: s.impl.clock.Now().Add(-1).Format(time.RFC3339),
 	}
@@ -110,7 +224,7 @@ func (s *restTestSuite) TestUploadURLExpireAtTooOld() {
 
 	err := s.impl.uploadURL(c)
 	s.Require().Error(err)
-	s.Equal(http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	s.Equal(http.StatusBadRequest, toAPIError(err).status)
 }
 
 func (s *restTestSuite) TestRedirect() {
@@ -127,13 +241,26 @@ func (s *restTestSuite) TestRedirect() {
 		ExpireAt: s.impl.clock.Now().Add(10),
 	}
 
-	s.mockURLShortener.On("Load", testURLID).Return(&shortLink, nil).Once()
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
 
 	s.Require().NoError(s.impl.redirect(c))
 	s.Equal(http.StatusMovedPermanently, rec.Code)
 	s.Equal(testURL, rec.HeaderMap.Get("Location"))
 }
 
+func (s *restTestSuite) TestRedirectUnknownDomain() {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/:url_id")
+	c.SetParamNames("url_id")
+	c.SetParamValues(testURLID)
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrDomainNotFound).Once()
+
+	s.Equal(errNotFound, s.impl.redirect(c))
+}
+
 func (s *restTestSuite) TestRedirectExpired() {
 	req := httptest.NewRequest(http.MethodPost, "/", nil)
 	rec := httptest.NewRecorder()
@@ -142,14 +269,180 @@ func (s *restTestSuite) TestRedirectExpired() {
 	c.SetParamNames("url_id")
 	c.SetParamValues(testURLID)
 
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(nil, urlshortener.ErrExpired).Once()
+
+	apiErr := toAPIError(s.impl.redirect(c))
+	s.Equal(http.StatusNotFound, apiErr.status)
+	s.Equal(api.CodeExpired, apiErr.code)
+}
+
+func (s *restTestSuite) TestRedirectGone() {
+	for err, code := range map[error]string{
+		urlshortener.ErrDisabled: api.CodeDisabled,
+		urlshortener.ErrDeleted:  api.CodeDeleted,
+	} {
+		s.mockURLShortener.On("LoadActive", mock.Anything, "example.com", testURLID).Return(nil, err).Once()
+
+		rec := s.serve(http.MethodGet, "/"+testURLID, "", "")
+		s.Equal(http.StatusGone, rec.Code)
+		s.Contains(rec.Body.String(), `"code":"`+code+`"`)
+	}
+}
+
+func (s *restTestSuite) TestRedirectQueryPassthrough() {
+	req := httptest.NewRequest(http.MethodGet, "/?utm_source=ads&lang=en", nil)
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/:url_id/*")
+	c.SetParamNames("url_id", "*")
+	c.SetParamValues(testURLID, "guide/intro")
+
 	shortLink := dao.ShortLink{
-		URLID:    "testID",
+		URLID:     testURLID,
+		URL:       "https://example.com/docs/{path}?lang={query.lang}#top",
+		QueryMode: dao.QueryModePreserve,
+		UTM:       "utm_medium=social",
+		ExpireAt:  s.impl.clock.Now().Add(10),
+	}
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+	s.Require().NoError(s.impl.redirect(c))
+	s.Equal(http.StatusMovedPermanently, rec.Code)
+	s.Equal("https://example.com/docs/guide/intro?lang=en&utm_source=ads&utm_medium=social#top", rec.Header().Get("Location"))
+}
+
+func (s *restTestSuite) TestRedirectRules() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 11; Pixel 5)")
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/:url_id")
+	c.SetParamNames("url_id")
+	c.SetParamValues(testURLID)
+
+	set, err := rules.Compile([]rules.Rule{
+		{Platforms: []string{rules.PlatformIOS}, URL: "https://apps.apple.com/app/id1"},
+		{Platforms: []string{rules.PlatformAndroid}, URL: "https://play.google.com/store/apps/details?id=app"},
+	})
+	s.Require().NoError(err)
+	shortLink := dao.ShortLink{
+		URLID:    testURLID,
 		URL:      testURL,
-		ExpireAt: s.impl.clock.Now().Add(-1),
+		Rules:    set,
+		ExpireAt: s.impl.clock.Now().Add(10),
 	}
 
-	s.mockURLShortener.On("Load", testURLID).Return(&shortLink, nil).Once()
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
 
 	s.Require().NoError(s.impl.redirect(c))
-	s.Equal(http.StatusNotFound, rec.Code)
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("private, no-cache", rec.Header().Get(headerCacheControl))
+	s.Equal("https://play.google.com/store/apps/details?id=app", rec.Header().Get("Location"))
+}
+
+func (s *restTestSuite) TestUploadURLInvalidRules() {
+	params := api.UploadURLRequest{
+		URL:      testURL,
+		ExpireAt: "2021-07-30T00:00:00Z",
+		Rules:    []rules.Rule{{Platforms: []string{"blackberry"}, URL: testURL}},
+	}
+	b, _ := json.Marshal(&params)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+
+	err := s.impl.uploadURL(c)
+	s.Require().Error(err)
+	s.Equal(http.StatusBadRequest, toAPIError(err).status)
+}
+
+func (s *restTestSuite) TestRedirectVariants() {
+	set, err := split.Compile([]split.Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/", Weight: 1},
+	})
+	s.Require().NoError(err)
+	shortLink := dao.ShortLink{
+		ID:       7,
+		URLID:    testURLID,
+		URL:      testURL,
+		Variants: set,
+		ExpireAt: s.impl.clock.Now().Add(10),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: variantCookiePrefix + testURLID, Value: "b"})
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/:url_id")
+	c.SetParamNames("url_id")
+	c.SetParamValues(testURLID)
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+	s.Require().NoError(s.impl.redirect(c))
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://b.example.com/", rec.Header().Get("Location"))
+	s.Contains(rec.Header().Get("Set-Cookie"), variantCookiePrefix+testURLID+"=b")
+	s.mockStats.AssertCalled(s.T(), "Record", uint64(7), "b")
+}
+
+func (s *restTestSuite) TestRedirectFallback() {
+	shortLink := dao.ShortLink{
+		URLID:       testURLID,
+		URL:         testURL,
+		FallbackURL: "https://example.com/maintenance",
+		ExpireAt:    s.impl.clock.Now().Add(10),
+	}
+	for broken, location := range map[bool]string{false: testURL, true: shortLink.FallbackURL} {
+		c, rec := s.newRedirectContext(testURLID)
+		shortLink.Broken = broken
+		s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+		s.Require().NoError(s.impl.redirect(c))
+		// permanent redirect cached by browsers would never fall back
+		s.Equal(http.StatusFound, rec.Code)
+		s.Equal("private, no-cache", rec.Header().Get(headerCacheControl))
+		s.Equal(location, rec.Header().Get("Location"))
+	}
+}
+
+func (s *restTestSuite) TestRequestLogger() {
+	core, logs := observer.New(zapcore.InfoLevel)
+	s.impl.accessLogger = logging.NewAccessLogger(zap.New(core), logging.AccessLogConfig{})
+	shortLink := dao.ShortLink{URLID: testURLID, URL: testURL, ExpireAt: s.impl.clock.Now().Add(10)}
+	s.mockURLShortener.On("LoadActive", mock.MatchedBy(func(ctx context.Context) bool {
+		return logging.FromContext(ctx) != zap.L()
+	}), testHost, testURLID).Return(&shortLink, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/"+testURLID, nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-request-id")
+	req.Header.Set("User-Agent", "test-agent")
+	rec := httptest.NewRecorder()
+	s.impl.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusMovedPermanently, rec.Code)
+	s.Equal("client-request-id", rec.Header().Get(echo.HeaderXRequestID))
+	entries := logs.AllUntimed()
+	s.Require().Len(entries, 1)
+	fields := entries[0].ContextMap()
+	s.Equal("/:url_id", fields["route"])
+	s.Equal("client-request-id", fields["requestId"])
+	s.Equal(int64(http.StatusMovedPermanently), fields["status"])
+	s.Equal("test-agent", fields["userAgent"])
+	s.Equal(testURLID, fields["urlId"])
+	s.Contains(fields, "bytes")
+	s.Contains(fields, "latency")
+	s.Contains(fields, "remoteIp")
+
+	// invalid request ID is replaced
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/urls/not.valid", nil)
+	req.Header.Set(echo.HeaderXRequestID, "not valid")
+	rec = httptest.NewRecorder()
+	s.impl.ServeHTTP(rec, req)
+	s.Len(rec.Header().Get(echo.HeaderXRequestID), 32)
 }
@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+type getStatsParams struct {
+	URLID  string `param:"url_id" validate:"required"`
+	Domain string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+}
+
+func (r *restImpl) getStats(c echo.Context) error {
+	var params getStatsParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	shortLink, err := r.urlShortener.Load(c.Request().Context(), params.Domain, params.URLID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+	// stats of owned short link are visible to its owner only, and it's not revealed to others
+	if shortLink.OwnerID != 0 && (owner == nil || owner.ID != shortLink.OwnerID) {
+		return errNotFound
+	}
+
+	summary, err := r.stats.Get(shortLink.ID)
+	if err != nil {
+		return err
+	}
+
+	resp := api.StatsResponse{
+		ID:       shortLink.URLID,
+		Clicks:   summary.Clicks,
+		Variants: summary.Variants,
+	}
+	return c.JSON(http.StatusOK, resp)
+}
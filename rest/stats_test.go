@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/stats"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *restTestSuite) newStatsContext(apiKey string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if apiKey != "" {
+		req.Header.Set(headerAPIKey, apiKey)
+	}
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/api/v1/urls/:url_id/stats")
+	c.SetParamNames("url_id")
+	c.SetParamValues(testURLID)
+	return c, rec
+}
+
+func (s *restTestSuite) TestGetStats() {
+	c, rec := s.newStatsContext("")
+	shortLink := dao.ShortLink{ID: 7, URLID: testURLID, URL: testURL}
+	s.mockURLShortener.On("Load", mock.Anything, "", testURLID).Return(&shortLink, nil).Once()
+	s.mockStats.On("Get", uint64(7)).Return(&stats.Summary{
+		Clicks:   10,
+		Variants: map[string]int64{"a": 4, "b": 6},
+	}, nil).Once()
+
+	s.Require().NoError(s.impl.getStats(c))
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.StatsResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(int64(10), resp.Clicks)
+	s.Equal(map[string]int64{"a": 4, "b": 6}, resp.Variants)
+}
+
+func (s *restTestSuite) TestGetStatsOfOthers() {
+	c, _ := s.newStatsContext(testAPIKey)
+	shortLink := dao.ShortLink{ID: 7, OwnerID: 3, URLID: testURLID, URL: testURL}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&dao.Owner{ID: 4}, nil).Once()
+	s.mockURLShortener.On("Load", mock.Anything, "", testURLID).Return(&shortLink, nil).Once()
+
+	s.Equal(errNotFound, s.impl.getStats(c))
+}
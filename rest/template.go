@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"path/filepath"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	previewTemplate = "preview.html"
+
+	headerContentSecurityPolicy = "Content-Security-Policy"
+	headerXFrameOptions         = "X-Frame-Options"
+
+	// pageContentSecurityPolicy allows inline style only, pages never run scripts.
+	pageContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'"
+)
+
+//go:embed templates/*.html
+var embeddedTemplates embed.FS
+
+// parseTemplates parses embedded templates, templates in dir override embedded ones with the same file name.
+func parseTemplates(dir string) (*template.Template, error) {
+	t, err := template.ParseFS(embeddedTemplates, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return t, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return t, nil
+	}
+	return t.ParseFiles(files...)
+}
+
+// renderPage renders HTML page from template, data is escaped by html/template.
+func (r *restImpl) renderPage(c echo.Context, code int, name string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := r.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(headerContentSecurityPolicy, pageContentSecurityPolicy)
+	header.Set(headerXFrameOptions, "DENY")
+	return c.HTMLBlob(code, buf.Bytes())
+}
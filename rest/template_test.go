@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *restTestSuite) newRedirectContext(urlID string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := s.echo.NewContext(req, rec)
+	c.SetPath("/:url_id")
+	c.SetParamNames("url_id")
+	c.SetParamValues(urlID)
+	return c, rec
+}
+
+func (s *restTestSuite) TestRedirectPreviewSuffix() {
+	c, rec := s.newRedirectContext(testURLID + previewSuffix)
+	shortLink := dao.ShortLink{
+		URLID:     testURLID,
+		URL:       testURL,
+		Domain:    &testDomain,
+		ExpireAt:  s.impl.clock.Now().Add(10),
+		CreatedAt: testNow,
+	}
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+	s.Require().NoError(s.impl.redirect(c))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal(echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	s.NotEmpty(rec.Header().Get(headerContentSecurityPolicy))
+	s.Contains(rec.Body.String(), `href="https://boards.greenhouse.io/dcard/jobs/871123?gh_src=ok8y1h1/"`)
+	s.Contains(rec.Body.String(), "2021-07-01 00:00 UTC")
+}
+
+func (s *restTestSuite) TestRedirectPreviewLink() {
+	c, rec := s.newRedirectContext(testURLID)
+	shortLink := dao.ShortLink{
+		URLID:    testURLID,
+		URL:      testURL,
+		Preview:  true,
+		Domain:   &testDomain,
+		ExpireAt: s.impl.clock.Now().Add(10),
+	}
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+	s.Require().NoError(s.impl.redirect(c))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Empty(rec.Header().Get("Location"))
+}
+
+func (s *restTestSuite) TestRedirectPreviewEscaped() {
+	c, rec := s.newRedirectContext(testURLID + previewSuffix)
+	shortLink := dao.ShortLink{
+		URLID:    testURLID,
+		URL:      `javascript:alert(1)//"><script>alert(1)</script>`,
+		Domain:   &testDomain,
+		ExpireAt: s.impl.clock.Now().Add(10),
+	}
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+	s.Require().NoError(s.impl.redirect(c))
+	s.Equal(http.StatusOK, rec.Code)
+	s.NotContains(rec.Body.String(), "<script>")
+	s.NotContains(rec.Body.String(), `href="javascript:`)
+}
+
+func (s *restTestSuite) TestTemplateDir() {
+	dir, err := ioutil.TempDir("", "templates")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, previewTemplate), []byte(`custom {{.URL}}`), 0644))
+
+	impl, err := NewRest(
+		testBaseURL,
+		testPort,
+		s.mockURLShortener,
+		s.mockStats,
+		fakeclock.NewFakeClock(testNow),
+		WithPreview(true),
+		WithTemplateDir(dir),
+	)
+	s.Require().NoError(err)
+
+	c, rec := s.newRedirectContext(testURLID)
+	shortLink := dao.ShortLink{
+		URLID:    testURLID,
+		URL:      testURL,
+		Domain:   &testDomain,
+		ExpireAt: s.impl.clock.Now().Add(10),
+	}
+	s.mockURLShortener.On("LoadActive", mock.Anything, testHost, testURLID).Return(&shortLink, nil).Once()
+
+	s.Require().NoError(impl.(*restImpl).redirect(c))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("custom "+testURL, rec.Body.String())
+}
@@ -0,0 +1,332 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+type urlParams struct {
+	URLID  string `param:"url_id" validate:"required"`
+	Domain string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+}
+
+type deleteURLParams struct {
+	URLID  string `param:"url_id" validate:"required"`
+	Domain string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+	// Purge deletes the short link permanently, otherwise it's soft deleted and could be restored.
+	Purge bool `query:"purge"`
+}
+
+type listURLsParams struct {
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit" validate:"min=0,max=100"`
+	// Tag is repeated for short links having all of tags.
+	Tag []string `query:"tag" validate:"omitempty,max=10,dive,required,max=64"`
+	// CreatedAfter, CreatedBefore, ExpireAfter and ExpireBefore are in RFC3339 format, after is inclusive and
+	// before is exclusive.
+	CreatedAfter  string `query:"createdAfter"`
+	CreatedBefore string `query:"createdBefore"`
+	ExpireAfter   string `query:"expireAfter"`
+	ExpireBefore  string `query:"expireBefore"`
+	Q             string `query:"q" validate:"max=256"`
+	Search        string `query:"search" validate:"omitempty,oneof=substring fulltext"`
+	// Broken lists short links whose destinations are broken only.
+	Broken bool `query:"broken"`
+	// Status lists short links in the status only, deleted ones are excluded if it's empty.
+	Status string `query:"status" validate:"omitempty,oneof=active disabled deleted"`
+}
+
+type updateURLParams struct {
+	URLID  string `param:"url_id" validate:"required"`
+	Domain string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+	api.UpdateURLRequest
+}
+
+type setExpiryParams struct {
+	URLID  string `param:"url_id" validate:"required"`
+	Domain string `query:"domain" validate:"omitempty,hostname_port|hostname"`
+	api.ExpiryRequest
+}
+
+func (r *restImpl) getURL(c echo.Context) error {
+	var params urlParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	shortLink, err := r.urlShortener.Load(c.Request().Context(), params.Domain, params.URLID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+	// owned short link is visible to its owner only, and it's not revealed to others
+	if shortLink.OwnerID != 0 && (owner == nil || owner.ID != shortLink.OwnerID) {
+		return errNotFound
+	}
+
+	return c.JSON(http.StatusOK, toShortLinkResponse(shortLink))
+}
+
+func (r *restImpl) listURLs(c echo.Context) error {
+	var params listURLsParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	listParams := urlshortener.ListParams{
+		Cursor: params.Cursor,
+		Limit:  params.Limit,
+		Tags:   params.Tag,
+		Query:  params.Q,
+		Search: params.Search,
+		Broken: params.Broken,
+		Status: params.Status,
+	}
+	for _, bound := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{
+		{"createdAfter", params.CreatedAfter, &listParams.CreatedFrom},
+		{"createdBefore", params.CreatedBefore, &listParams.CreatedTo},
+		{"expireAfter", params.ExpireAfter, &listParams.ExpireFrom},
+		{"expireBefore", params.ExpireBefore, &listParams.ExpireTo},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseTime(bound.value)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, bound.name+" is invalid")
+		}
+		*bound.time = t
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+	listParams.Owner = owner
+
+	shortLinks, next, err := r.urlShortener.List(listParams)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListURLsResponse{
+		Items:      make([]api.ShortLinkResponse, 0, len(shortLinks)),
+		NextCursor: next,
+	}
+	for _, shortLink := range shortLinks {
+		resp.Items = append(resp.Items, toShortLinkResponse(shortLink))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (r *restImpl) updateURL(c echo.Context) error {
+	var params updateURLParams
+	// query params are not bound for requests with body
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &params); err != nil {
+		return err
+	}
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	updateParams := urlshortener.UpdateParams{
+		Domain:      params.Domain,
+		URLID:       params.URLID,
+		URL:         params.URL,
+		Preview:     params.Preview,
+		QueryMode:   params.QueryMode,
+		UTM:         params.UTM,
+		Rules:       params.Rules,
+		Variants:    params.Variants,
+		Title:       params.Title,
+		Description: params.Description,
+		Tags:        params.Tags,
+		FallbackURL: params.FallbackURL,
+		Status:      params.Status,
+	}
+	if params.ExpireAt != nil {
+		expireAtTime, err := parseTime(*params.ExpireAt)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, "expireAt is invalid")
+		}
+		updateParams.ExpireAt = &expireAtTime
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+	updateParams.Owner = owner
+
+	shortLink, err := r.urlShortener.Update(c.Request().Context(), updateParams)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toShortLinkResponse(shortLink))
+}
+
+func (r *restImpl) deleteURL(c echo.Context) error {
+	var params deleteURLParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	if params.Purge {
+		err = r.urlShortener.Purge(c.Request().Context(), owner, params.Domain, params.URLID)
+	} else {
+		err = r.urlShortener.Delete(c.Request().Context(), owner, params.Domain, params.URLID)
+	}
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (r *restImpl) restoreURL(c echo.Context) error {
+	var params urlParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	shortLink, err := r.urlShortener.Restore(c.Request().Context(), owner, params.Domain, params.URLID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toShortLinkResponse(shortLink))
+}
+
+func (r *restImpl) setExpiry(c echo.Context) error {
+	var params setExpiryParams
+	// query params are not bound for requests with body
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &params); err != nil {
+		return err
+	}
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	if !urlshortener.IsValidURLID(params.URLID) {
+		return errNotFound
+	}
+
+	expiryParams := urlshortener.ExpiryParams{
+		Domain:   params.Domain,
+		URLID:    params.URLID,
+		ExtendBy: time.Duration(params.ExtendSeconds) * time.Second,
+		Never:    params.Never,
+	}
+	if params.ExpireAt != "" {
+		expireAtTime, err := parseTime(params.ExpireAt)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, api.CodeInvalidParams, "expireAt is invalid")
+		}
+		expiryParams.ExpireAt = &expireAtTime
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+	expiryParams.Owner = owner
+
+	shortLink, err := r.urlShortener.SetExpiry(c.Request().Context(), expiryParams)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toShortLinkResponse(shortLink))
+}
+
+func toShortLinkResponse(shortLink *dao.ShortLink) api.ShortLinkResponse {
+	resp := api.ShortLinkResponse{
+		ID:          shortLink.URLID,
+		URL:         shortLink.URL,
+		ExpireAt:    shortLink.ExpireAt.UTC(),
+		Preview:     shortLink.Preview,
+		QueryMode:   shortLink.QueryMode,
+		UTM:         shortLink.UTMParams(),
+		Rules:       shortLink.Rules,
+		Variants:    shortLink.Variants,
+		Title:       shortLink.Title,
+		Description: shortLink.Description,
+		Tags:        shortLink.Tags,
+		ImageURL:    shortLink.ImageURL,
+		FaviconURL:  shortLink.FaviconURL,
+		FallbackURL: shortLink.FallbackURL,
+		Broken:      shortLink.Broken,
+		Status:      shortLink.Status,
+		CreatedAt:   shortLink.CreatedAt.UTC(),
+		UpdatedAt:   shortLink.UpdatedAt.UTC(),
+	}
+	// short links cached before status is added are active
+	if resp.Status == "" {
+		resp.Status = dao.StatusActive
+	}
+	if shortLink.MetadataFetchedAt != nil {
+		fetchedAt := shortLink.MetadataFetchedAt.UTC()
+		resp.MetadataFetchedAt = &fetchedAt
+	}
+	if shortLink.DeletedAt != nil {
+		deletedAt := shortLink.DeletedAt.UTC()
+		resp.DeletedAt = &deletedAt
+	}
+	if shortLink.Domain != nil {
+		resp.ShortURL = shortLink.Domain.ShortURL(shortLink.URLID)
+		resp.Domain = shortLink.Domain.Host
+	}
+	return resp
+}
@@ -0,0 +1,262 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *restTestSuite) serve(method, target, apiKey, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if apiKey != "" {
+		req.Header.Set(headerAPIKey, apiKey)
+	}
+	rec := httptest.NewRecorder()
+	s.impl.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *restTestSuite) TestGetURL() {
+	shortLink := dao.ShortLink{
+		URLID:    testURLID,
+		URL:      testURL,
+		UTM:      "utm_source=newsletter",
+		ImageURL: "https://example.com/og.png",
+		ExpireAt: testNow.AddDate(0, 1, 0),
+		Domain:   &testDomain,
+	}
+	s.mockURLShortener.On("Load", mock.Anything, "", testURLID).Return(&shortLink, nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/"+testURLID, "", "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ShortLinkResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(testURL, resp.URL)
+	s.Equal("http://localhost:8080/"+testURLID, resp.ShortURL)
+	s.Equal(map[string]string{"utm_source": "newsletter"}, resp.UTM)
+	s.Equal(shortLink.ImageURL, resp.ImageURL)
+	// metadata is not fetched yet
+	s.Nil(resp.MetadataFetchedAt)
+}
+
+func (s *restTestSuite) TestGetURLOfOthers() {
+	shortLink := dao.ShortLink{OwnerID: 3, URLID: testURLID, URL: testURL, Domain: &testDomain}
+	s.mockURLShortener.On("Load", mock.Anything, "", testURLID).Return(&shortLink, nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/"+testURLID, "", "")
+	s.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *restTestSuite) TestListURLs() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("List", urlshortener.ListParams{Owner: &owner, Cursor: "abc", Limit: 2}).Return([]*dao.ShortLink{
+		{URLID: testURLID, URL: testURL, Domain: &testDomain},
+		{URLID: "bcdefghijkl", URL: testURL, Domain: &testDomain},
+	}, "next", nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls?cursor=abc&limit=2", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ListURLsResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Len(resp.Items, 2)
+	s.Equal("next", resp.NextCursor)
+}
+
+func (s *restTestSuite) TestListURLsFilter() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("List", urlshortener.ListParams{
+		Owner:       &owner,
+		Tags:        []string{"campaign", "summer"},
+		CreatedFrom: testNow,
+		ExpireTo:    testNow.AddDate(0, 1, 0),
+		Query:       "sale",
+		Search:      urlshortener.SearchFullText,
+		Broken:      true,
+	}).Return([]*dao.ShortLink{
+		{URLID: testURLID, URL: testURL, Title: "Summer sale", Tags: dao.Tags{"campaign", "summer"}, Broken: true, Domain: &testDomain},
+	}, "", nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls?tag=campaign&tag=summer&createdAfter=2021-07-01T00:00:00Z"+
+		"&expireBefore=2021-08-01T00:00:00Z&q=sale&search=fulltext&broken=true", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ListURLsResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Require().Len(resp.Items, 1)
+	s.Equal("Summer sale", resp.Items[0].Title)
+	s.Equal([]string{"campaign", "summer"}, resp.Items[0].Tags)
+	s.True(resp.Items[0].Broken)
+
+	for _, query := range []string{"createdAfter=yesterday", "search=regexp"} {
+		rec = s.serve(http.MethodGet, "/api/v1/urls?"+query, testAPIKey, "")
+		s.Equal(http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func (s *restTestSuite) TestListURLsAnonymous() {
+	s.mockURLShortener.On("List", mock.Anything).Return(nil, "", urlshortener.ErrOwnerRequired).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls", "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+}
+
+func (s *restTestSuite) TestUpdateURL() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Update", mock.Anything, mock.MatchedBy(func(params urlshortener.UpdateParams) bool {
+		return params.Owner == &owner &&
+			params.Domain == "go.example.com" &&
+			params.URLID == testURLID &&
+			*params.URL == "https://example.com" &&
+			params.ExpireAt.Equal(testNow.AddDate(0, 2, 0)) &&
+			params.Preview == nil &&
+			params.Rules != nil && len(*params.Rules) == 0
+	})).Return(&dao.ShortLink{URLID: testURLID, URL: "https://example.com", Domain: &testDomain}, nil).Once()
+
+	rec := s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID+"?domain=go.example.com", testAPIKey,
+		`{"url": "https://example.com", "expireAt": "2021-09-01T00:00:00Z", "rules": []}`)
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ShortLinkResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal("https://example.com", resp.URL)
+}
+
+func (s *restTestSuite) TestUpdateURLInvalid() {
+	rec := s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID, testAPIKey, `{"url": "not a url"}`)
+	s.Equal(http.StatusBadRequest, rec.Code)
+
+	rec = s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID, testAPIKey, `{"queryMode": "unknown"}`)
+	s.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *restTestSuite) TestUpdateURLNotFound() {
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&dao.Owner{ID: 4}, nil).Once()
+	s.mockURLShortener.On("Update", mock.Anything, mock.Anything).Return(nil, urlshortener.ErrNotFound).Once()
+
+	rec := s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID, testAPIKey, `{"preview": true}`)
+	s.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *restTestSuite) TestDeleteURL() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Delete", mock.Anything, &owner, "", testURLID).Return(nil).Once()
+
+	rec := s.serve(http.MethodDelete, "/api/v1/urls/"+testURLID, testAPIKey, "")
+	s.Equal(http.StatusNoContent, rec.Code)
+
+	s.mockURLShortener.On("Delete", mock.Anything, (*dao.Owner)(nil), "", testURLID).Return(urlshortener.ErrOwnerRequired).Once()
+	rec = s.serve(http.MethodDelete, "/api/v1/urls/"+testURLID, "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+}
+
+func (s *restTestSuite) TestPurgeURL() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Purge", mock.Anything, &owner, "", testURLID).Return(nil).Once()
+
+	rec := s.serve(http.MethodDelete, "/api/v1/urls/"+testURLID+"?purge=true", testAPIKey, "")
+	s.Equal(http.StatusNoContent, rec.Code)
+	s.mockURLShortener.AssertNotCalled(s.T(), "Delete", mock.Anything, &owner, "", testURLID)
+}
+
+func (s *restTestSuite) TestRestoreURL() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Restore", mock.Anything, &owner, "", testURLID).Return(&dao.ShortLink{
+		URLID:  testURLID,
+		URL:    testURL,
+		Status: dao.StatusActive,
+		Domain: &testDomain,
+	}, nil).Once()
+
+	rec := s.serve(http.MethodPost, "/api/v1/urls/"+testURLID+"/restore", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ShortLinkResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(dao.StatusActive, resp.Status)
+	s.Nil(resp.DeletedAt)
+}
+
+func (s *restTestSuite) TestSetExpiry() {
+	owner := dao.Owner{ID: 3}
+	expireAt := testNow.AddDate(0, 1, 0)
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Twice()
+	s.mockURLShortener.On("SetExpiry", mock.Anything, urlshortener.ExpiryParams{
+		Owner:    &owner,
+		URLID:    testURLID,
+		ExtendBy: 30 * 24 * time.Hour,
+	}).Return(&dao.ShortLink{URLID: testURLID, URL: testURL, ExpireAt: expireAt, Domain: &testDomain}, nil).Once()
+
+	rec := s.serve(http.MethodPost, "/api/v1/urls/"+testURLID+"/expiry", testAPIKey, `{"extendSeconds":2592000}`)
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ShortLinkResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(expireAt, resp.ExpireAt)
+
+	s.mockURLShortener.On("SetExpiry", mock.Anything, mock.MatchedBy(func(params urlshortener.ExpiryParams) bool {
+		return params.Never
+	})).Return(nil, urlshortener.ErrExpired).Once()
+	rec = s.serve(http.MethodPost, "/api/v1/urls/"+testURLID+"/expiry", testAPIKey, `{"never":true}`)
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Contains(rec.Body.String(), `"code":"expired"`)
+
+	rec = s.serve(http.MethodPost, "/api/v1/urls/"+testURLID+"/expiry", testAPIKey, `{"expireAt":"tomorrow"}`)
+	s.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *restTestSuite) TestGetDeletedURL() {
+	owner := dao.Owner{ID: 3}
+	deletedAt := testNow.Add(-time.Hour)
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("Load", mock.Anything, "", testURLID).Return(&dao.ShortLink{
+		OwnerID:   owner.ID,
+		URLID:     testURLID,
+		URL:       testURL,
+		Status:    dao.StatusDeleted,
+		DeletedAt: &deletedAt,
+		Domain:    &testDomain,
+	}, nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/urls/"+testURLID, testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ShortLinkResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(dao.StatusDeleted, resp.Status)
+	s.Require().NotNil(resp.DeletedAt)
+	s.True(deletedAt.Equal(*resp.DeletedAt))
+}
+
+func (s *restTestSuite) TestUpdateURLStatus() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Twice()
+	s.mockURLShortener.On("Update", mock.Anything, mock.MatchedBy(func(params urlshortener.UpdateParams) bool {
+		return params.Status != nil && *params.Status == dao.StatusDisabled
+	})).Return(&dao.ShortLink{URLID: testURLID, URL: testURL, Status: dao.StatusDisabled, Domain: &testDomain}, nil).Once()
+
+	rec := s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID, testAPIKey, `{"status": "disabled"}`)
+	s.Equal(http.StatusOK, rec.Code)
+	s.Contains(rec.Body.String(), `"status":"disabled"`)
+
+	// deleted short links are restored instead
+	rec = s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID, testAPIKey, `{"status": "deleted"}`)
+	s.Equal(http.StatusBadRequest, rec.Code)
+
+	s.mockURLShortener.On("Update", mock.Anything, mock.Anything).Return(nil, urlshortener.ErrDeleted).Once()
+	rec = s.serve(http.MethodPatch, "/api/v1/urls/"+testURLID, testAPIKey, `{"status": "active"}`)
+	s.Equal(http.StatusGone, rec.Code)
+	s.Contains(rec.Body.String(), api.CodeDeleted)
+}
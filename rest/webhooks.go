@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+
+	"github.com/labstack/echo/v4"
+)
+
+type webhookParams struct {
+	WebhookID string `param:"webhook_id" validate:"required"`
+}
+
+type listDeliveriesParams struct {
+	WebhookID string `param:"webhook_id" validate:"required"`
+	Status    string `query:"status" validate:"omitempty,oneof=pending delivered dead"`
+	Cursor    string `query:"cursor"`
+	Limit     int    `query:"limit" validate:"min=0,max=100"`
+}
+
+type replayDeliveryParams struct {
+	WebhookID  string `param:"webhook_id" validate:"required"`
+	DeliveryID string `param:"delivery_id" validate:"required"`
+}
+
+func (r *restImpl) createWebhook(c echo.Context) error {
+	var params api.CreateWebhookRequest
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	w, err := r.urlShortener.CreateWebhook(urlshortener.WebhookParams{
+		Owner:  owner,
+		URL:    params.URL,
+		Events: params.Events,
+		Secret: params.Secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the secret is not responded afterwards
+	resp := toWebhookResponse(w)
+	resp.Secret = w.Secret
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (r *restImpl) listWebhooks(c echo.Context) error {
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := r.urlShortener.ListWebhooks(owner)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListWebhooksResponse{Items: make([]api.WebhookResponse, 0, len(webhooks))}
+	for _, w := range webhooks {
+		resp.Items = append(resp.Items, toWebhookResponse(w))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (r *restImpl) deleteWebhook(c echo.Context) error {
+	var params webhookParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	webhookID, err := base62.Decode(params.WebhookID)
+	if err != nil {
+		return errWebhookNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	if err := r.urlShortener.DeleteWebhook(owner, webhookID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (r *restImpl) listDeliveries(c echo.Context) error {
+	var params listDeliveriesParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	webhookID, err := base62.Decode(params.WebhookID)
+	if err != nil {
+		return errWebhookNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	events, next, err := r.urlShortener.ListDeliveries(urlshortener.ListDeliveriesParams{
+		Owner:     owner,
+		WebhookID: webhookID,
+		Status:    params.Status,
+		Cursor:    params.Cursor,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp := api.ListDeliveriesResponse{
+		Items:      make([]api.DeliveryResponse, 0, len(events)),
+		NextCursor: next,
+	}
+	for _, event := range events {
+		resp.Items = append(resp.Items, toDeliveryResponse(event))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (r *restImpl) replayDelivery(c echo.Context) error {
+	var params replayDeliveryParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	webhookID, err := base62.Decode(params.WebhookID)
+	if err != nil {
+		return errWebhookNotFound
+	}
+	eventID, err := base62.Decode(params.DeliveryID)
+	if err != nil {
+		return errWebhookNotFound
+	}
+
+	owner, err := r.authenticate(c)
+	if err != nil {
+		return err
+	}
+
+	event, err := r.urlShortener.ReplayDelivery(owner, webhookID, eventID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, toDeliveryResponse(event))
+}
+
+func toWebhookResponse(w *dao.Webhook) api.WebhookResponse {
+	return api.WebhookResponse{
+		ID:        base62.Encode(w.ID),
+		URL:       w.URL,
+		Events:    w.EventTypes(),
+		CreatedAt: w.CreatedAt.UTC(),
+	}
+}
+
+func toDeliveryResponse(event *dao.OutboxEvent) api.DeliveryResponse {
+	resp := api.DeliveryResponse{
+		ID:        base62.Encode(event.ID),
+		Event:     event.Type,
+		Status:    event.Status,
+		Attempts:  event.Attempts,
+		Payload:   json.RawMessage(event.Payload),
+		LastError: event.LastError,
+		CreatedAt: event.CreatedAt.UTC(),
+	}
+	// next attempt is meaningless once the event is delivered or dead
+	if event.Status == dao.DeliveryPending {
+		nextAttemptAt := event.NextAttemptAt.UTC()
+		resp.NextAttemptAt = &nextAttemptAt
+	}
+	if event.DeliveredAt != nil {
+		deliveredAt := event.DeliveredAt.UTC()
+		resp.DeliveredAt = &deliveredAt
+	}
+	return resp
+}
@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/georgechang0117/url-shortener/base/base62"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rest/api"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+var (
+	testWebhookID  = base62.Encode(62)
+	testDeliveryID = base62.Encode(7)
+)
+
+func (s *restTestSuite) TestCreateWebhook() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("CreateWebhook", urlshortener.WebhookParams{
+		Owner:  &owner,
+		URL:    "https://example.com/hook",
+		Events: []string{dao.EventLinkCreated, dao.EventLinkDeleted},
+	}).Return(&dao.Webhook{
+		ID:        62,
+		OwnerID:   owner.ID,
+		URL:       "https://example.com/hook",
+		Secret:    testWebhookSecret,
+		Events:    "link.created,link.deleted",
+		CreatedAt: testNow,
+	}, nil).Once()
+
+	rec := s.serve(http.MethodPost, "/api/v1/webhooks", testAPIKey,
+		`{"url":"https://example.com/hook","events":["link.created","link.deleted"]}`)
+	s.Equal(http.StatusCreated, rec.Code)
+	var resp api.WebhookResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(api.WebhookResponse{
+		ID:        testWebhookID,
+		URL:       "https://example.com/hook",
+		Events:    []string{dao.EventLinkCreated, dao.EventLinkDeleted},
+		Secret:    testWebhookSecret,
+		CreatedAt: testNow,
+	}, resp)
+}
+
+func (s *restTestSuite) TestCreateWebhookInvalid() {
+	rec := s.serve(http.MethodPost, "/api/v1/webhooks", testAPIKey, `{"url":"https://example.com/hook","events":[]}`)
+	s.Equal(http.StatusBadRequest, rec.Code)
+	s.Contains(rec.Body.String(), api.CodeInvalidParams)
+}
+
+func (s *restTestSuite) TestListWebhooks() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("ListWebhooks", &owner).Return([]*dao.Webhook{
+		{ID: 1, URL: "https://example.com/hook", Secret: testWebhookSecret, Events: "link.expired", CreatedAt: testNow},
+	}, nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/webhooks", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	// secrets are not listed
+	s.JSONEq(`{"items":[{"id":"`+base62.Encode(1)+`","url":"https://example.com/hook","events":["link.expired"],"createdAt":"2021-07-01T00:00:00Z"}]}`,
+		rec.Body.String())
+}
+
+func (s *restTestSuite) TestDeleteWebhook() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Twice()
+	s.mockURLShortener.On("DeleteWebhook", &owner, uint64(62)).Return(nil).Once()
+	s.mockURLShortener.On("DeleteWebhook", &owner, uint64(63)).Return(urlshortener.ErrWebhookNotFound).Once()
+
+	rec := s.serve(http.MethodDelete, "/api/v1/webhooks/"+testWebhookID, testAPIKey, "")
+	s.Equal(http.StatusNoContent, rec.Code)
+
+	rec = s.serve(http.MethodDelete, "/api/v1/webhooks/"+base62.Encode(63), testAPIKey, "")
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Contains(rec.Body.String(), "webhook is not found")
+
+	rec = s.serve(http.MethodDelete, "/api/v1/webhooks/in-valid", testAPIKey, "")
+	s.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *restTestSuite) TestListDeliveries() {
+	owner := dao.Owner{ID: 3}
+	deliveredAt := testNow.Add(1)
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("ListDeliveries", urlshortener.ListDeliveriesParams{
+		Owner:     &owner,
+		WebhookID: 62,
+		Status:    dao.DeliveryDelivered,
+		Limit:     1,
+	}).Return([]*dao.OutboxEvent{{
+		ID:          7,
+		Type:        dao.EventLinkCreated,
+		Status:      dao.DeliveryDelivered,
+		Attempts:    2,
+		Payload:     `{"id":"abc"}`,
+		DeliveredAt: &deliveredAt,
+		CreatedAt:   testNow,
+	}}, base62.Encode(7), nil).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/webhooks/"+testWebhookID+"/deliveries?status=delivered&limit=1", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	var resp api.ListDeliveriesResponse
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	s.Equal(api.ListDeliveriesResponse{
+		Items: []api.DeliveryResponse{{
+			ID:          testDeliveryID,
+			Event:       dao.EventLinkCreated,
+			Status:      dao.DeliveryDelivered,
+			Attempts:    2,
+			Payload:     json.RawMessage(`{"id":"abc"}`),
+			DeliveredAt: &deliveredAt,
+			CreatedAt:   testNow,
+		}},
+		NextCursor: testDeliveryID,
+	}, resp)
+
+	rec = s.serve(http.MethodGet, "/api/v1/webhooks/"+testWebhookID+"/deliveries?status=failed", testAPIKey, "")
+	s.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *restTestSuite) TestReplayDelivery() {
+	owner := dao.Owner{ID: 3}
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&owner, nil).Once()
+	s.mockURLShortener.On("ReplayDelivery", &owner, uint64(62), uint64(7)).Return(&dao.OutboxEvent{
+		ID:            7,
+		Type:          dao.EventLinkExpired,
+		Status:        dao.DeliveryPending,
+		Payload:       `{}`,
+		NextAttemptAt: testNow,
+		CreatedAt:     testNow,
+	}, nil).Once()
+
+	rec := s.serve(http.MethodPost, "/api/v1/webhooks/"+testWebhookID+"/deliveries/"+testDeliveryID+"/replay", testAPIKey, "")
+	s.Equal(http.StatusOK, rec.Code)
+	s.JSONEq(`{"id":"`+testDeliveryID+`","event":"link.expired","status":"pending","attempts":0,"payload":{},`+
+		`"nextAttemptAt":"2021-07-01T00:00:00Z","createdAt":"2021-07-01T00:00:00Z"}`, rec.Body.String())
+}
+
+func (s *restTestSuite) TestWebhooksAnonymous() {
+	s.mockURLShortener.On("ListWebhooks", (*dao.Owner)(nil)).Return(nil, urlshortener.ErrOwnerRequired).Once()
+
+	rec := s.serve(http.MethodGet, "/api/v1/webhooks", "", "")
+	s.Equal(http.StatusUnauthorized, rec.Code)
+}
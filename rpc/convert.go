@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/split"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rpc/pb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func uploadParams(owner *dao.Owner, req *pb.CreateRequest) urlshortener.UploadParams {
+	return urlshortener.UploadParams{
+		Owner:       owner,
+		Domain:      req.Domain,
+		URL:         req.Url,
+		ExpireAt:    toTime(req.ExpireAt),
+		Preview:     req.Preview,
+		QueryMode:   req.QueryMode,
+		UTM:         req.Utm,
+		Rules:       toRules(req.Rules),
+		Variants:    toVariants(req.Variants),
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        req.Tags,
+		FallbackURL: req.FallbackUrl,
+	}
+}
+
+func toShortLink(shortLink *dao.ShortLink) *pb.ShortLink {
+	resp := &pb.ShortLink{
+		Id:          shortLink.URLID,
+		Url:         shortLink.URL,
+		ExpireAt:    timestamppb.New(shortLink.ExpireAt),
+		Preview:     shortLink.Preview,
+		QueryMode:   shortLink.QueryMode,
+		Utm:         shortLink.UTMParams(),
+		Title:       shortLink.Title,
+		Description: shortLink.Description,
+		Tags:        shortLink.Tags,
+		ImageUrl:    shortLink.ImageURL,
+		FaviconUrl:  shortLink.FaviconURL,
+		FallbackUrl: shortLink.FallbackURL,
+		Broken:      shortLink.Broken,
+		Status:      shortLink.Status,
+		CreatedAt:   timestamppb.New(shortLink.CreatedAt),
+		UpdatedAt:   timestamppb.New(shortLink.UpdatedAt),
+	}
+	// short links cached before status is added are active
+	if resp.Status == "" {
+		resp.Status = dao.StatusActive
+	}
+	if shortLink.MetadataFetchedAt != nil {
+		resp.MetadataFetchedAt = timestamppb.New(*shortLink.MetadataFetchedAt)
+	}
+	if shortLink.DeletedAt != nil {
+		resp.DeletedAt = timestamppb.New(*shortLink.DeletedAt)
+	}
+	if shortLink.Domain != nil {
+		resp.ShortUrl = shortLink.Domain.ShortURL(shortLink.URLID)
+		resp.Domain = shortLink.Domain.Host
+	}
+	for _, rule := range shortLink.Rules {
+		resp.Rules = append(resp.Rules, &pb.Rule{
+			Platforms: rule.Platforms,
+			Languages: rule.Languages,
+			Countries: rule.Countries,
+			StartAt:   toTimestamp(rule.StartAt),
+			EndAt:     toTimestamp(rule.EndAt),
+			Url:       rule.URL,
+		})
+	}
+	for _, variant := range shortLink.Variants {
+		resp.Variants = append(resp.Variants, &pb.Variant{
+			Name:   variant.Name,
+			Url:    variant.URL,
+			Weight: int32(variant.Weight),
+		})
+	}
+	return resp
+}
+
+func toRules(ruleList []*pb.Rule) []rules.Rule {
+	if len(ruleList) == 0 {
+		return nil
+	}
+	result := make([]rules.Rule, 0, len(ruleList))
+	for _, rule := range ruleList {
+		result = append(result, rules.Rule{
+			Platforms: rule.Platforms,
+			Languages: rule.Languages,
+			Countries: rule.Countries,
+			StartAt:   toTimePtr(rule.StartAt),
+			EndAt:     toTimePtr(rule.EndAt),
+			URL:       rule.Url,
+		})
+	}
+	return result
+}
+
+func toVariants(variants []*pb.Variant) []split.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]split.Variant, 0, len(variants))
+	for _, variant := range variants {
+		result = append(result, split.Variant{
+			Name:   variant.Name,
+			URL:    variant.Url,
+			Weight: int(variant.Weight),
+		})
+	}
+	return result
+}
+
+// toTime returns zero time if ts is absent, so it fails validation of required fields.
+func toTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func toTimePtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,7 @@
+package rpc
+
+// RPC defines interface of gRPC server operations.
+type RPC interface {
+	Start() error
+	Stop()
+}
@@ -0,0 +1,328 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// URLShortenerClient is the client API for URLShortener service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type URLShortenerClient interface {
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*ShortLink, error)
+	// BatchCreate creates all short links or none of them.
+	BatchCreate(ctx context.Context, in *BatchCreateRequest, opts ...grpc.CallOption) (*BatchCreateResponse, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*ShortLink, error)
+	// Resolve returns destination of a short link for the client described in request, as redirecting does.
+	Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error)
+	// Update updates fields present in request, only the owner can update a short link.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*ShortLink, error)
+	// Delete soft deletes a short link or purges it permanently, only the owner can delete a short link.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Restore makes a deleted short link active again, only the owner can restore a short link.
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*ShortLink, error)
+}
+
+type uRLShortenerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewURLShortenerClient(cc grpc.ClientConnInterface) URLShortenerClient {
+	return &uRLShortenerClient{cc}
+}
+
+func (c *uRLShortenerClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*ShortLink, error) {
+	out := new(ShortLink)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShortenerClient) BatchCreate(ctx context.Context, in *BatchCreateRequest, opts ...grpc.CallOption) (*BatchCreateResponse, error) {
+	out := new(BatchCreateResponse)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/BatchCreate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShortenerClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*ShortLink, error) {
+	out := new(ShortLink)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShortenerClient) Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error) {
+	out := new(ResolveResponse)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/Resolve", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShortenerClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*ShortLink, error) {
+	out := new(ShortLink)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShortenerClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShortenerClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*ShortLink, error) {
+	out := new(ShortLink)
+	err := c.cc.Invoke(ctx, "/urlshortener.v1.URLShortener/Restore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// URLShortenerServer is the server API for URLShortener service.
+// All implementations must embed UnimplementedURLShortenerServer
+// for forward compatibility
+type URLShortenerServer interface {
+	Create(context.Context, *CreateRequest) (*ShortLink, error)
+	// BatchCreate creates all short links or none of them.
+	BatchCreate(context.Context, *BatchCreateRequest) (*BatchCreateResponse, error)
+	Get(context.Context, *GetRequest) (*ShortLink, error)
+	// Resolve returns destination of a short link for the client described in request, as redirecting does.
+	Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error)
+	// Update updates fields present in request, only the owner can update a short link.
+	Update(context.Context, *UpdateRequest) (*ShortLink, error)
+	// Delete soft deletes a short link or purges it permanently, only the owner can delete a short link.
+	Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	// Restore makes a deleted short link active again, only the owner can restore a short link.
+	Restore(context.Context, *RestoreRequest) (*ShortLink, error)
+	mustEmbedUnimplementedURLShortenerServer()
+}
+
+// UnimplementedURLShortenerServer must be embedded to have forward compatible implementations.
+type UnimplementedURLShortenerServer struct {
+}
+
+func (UnimplementedURLShortenerServer) Create(context.Context, *CreateRequest) (*ShortLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedURLShortenerServer) BatchCreate(context.Context, *BatchCreateRequest) (*BatchCreateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCreate not implemented")
+}
+func (UnimplementedURLShortenerServer) Get(context.Context, *GetRequest) (*ShortLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedURLShortenerServer) Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resolve not implemented")
+}
+func (UnimplementedURLShortenerServer) Update(context.Context, *UpdateRequest) (*ShortLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedURLShortenerServer) Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedURLShortenerServer) Restore(context.Context, *RestoreRequest) (*ShortLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedURLShortenerServer) mustEmbedUnimplementedURLShortenerServer() {}
+
+// UnsafeURLShortenerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to URLShortenerServer will
+// result in compilation errors.
+type UnsafeURLShortenerServer interface {
+	mustEmbedUnimplementedURLShortenerServer()
+}
+
+func RegisterURLShortenerServer(s grpc.ServiceRegistrar, srv URLShortenerServer) {
+	s.RegisterService(&URLShortener_ServiceDesc, srv)
+}
+
+func _URLShortener_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShortener_BatchCreate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).BatchCreate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/BatchCreate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).BatchCreate(ctx, req.(*BatchCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShortener_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShortener_Resolve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).Resolve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/Resolve",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).Resolve(ctx, req.(*ResolveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShortener_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShortener_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShortener_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShortenerServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/urlshortener.v1.URLShortener/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShortenerServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// URLShortener_ServiceDesc is the grpc.ServiceDesc for URLShortener service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var URLShortener_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "urlshortener.v1.URLShortener",
+	HandlerType: (*URLShortenerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _URLShortener_Create_Handler,
+		},
+		{
+			MethodName: "BatchCreate",
+			Handler:    _URLShortener_BatchCreate_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _URLShortener_Get_Handler,
+		},
+		{
+			MethodName: "Resolve",
+			Handler:    _URLShortener_Resolve_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _URLShortener_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _URLShortener_Delete_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _URLShortener_Restore_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pb/urlshortener.proto",
+}
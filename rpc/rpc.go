@@ -0,0 +1,408 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/base/logging"
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/redirect"
+	"github.com/georgechang0117/url-shortener/core/rules"
+	"github.com/georgechang0117/url-shortener/core/stats"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	"github.com/georgechang0117/url-shortener/rpc/pb"
+
+	"code.cloudfoundry.org/clock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	// metadataAPIKey is the metadata key carrying owner's API key.
+	metadataAPIKey = "x-api-key"
+	// metadataRequestID is the metadata key carrying request ID, in both request and response header.
+	metadataRequestID = "x-request-id"
+)
+
+var errNotFound = status.Error(codes.NotFound, "short link not found")
+
+type ownerKey struct{}
+
+type rpcImpl struct {
+	pb.UnimplementedURLShortenerServer
+	server       *grpc.Server
+	port         int
+	urlShortener urlshortener.URLShortener
+	stats        stats.Stats
+	clock        clock.Clock
+	geoIP        rules.GeoIP
+	resolver     redirect.Resolver
+	accessLogger logging.AccessLogger
+}
+
+// Option defines optional configuration of RPC.
+type Option func(r *rpcImpl)
+
+// WithGeoIP looks up country of client IP by geoIP for routing rules.
+func WithGeoIP(geoIP rules.GeoIP) Option {
+	return func(r *rpcImpl) {
+		r.geoIP = geoIP
+	}
+}
+
+// WithAccessLogger writes access logs by accessLogger, which logs every call at info level by default.
+func WithAccessLogger(accessLogger logging.AccessLogger) Option {
+	return func(r *rpcImpl) {
+		r.accessLogger = accessLogger
+	}
+}
+
+// NewRPC creates an instance of RPC serving the URLShortener gRPC service.
+func NewRPC(
+	port int,
+	urlShortener urlshortener.URLShortener,
+	stats stats.Stats,
+	clock clock.Clock,
+	opts ...Option,
+) RPC {
+	r := &rpcImpl{
+		port:         port,
+		urlShortener: urlShortener,
+		stats:        stats,
+		clock:        clock,
+		accessLogger: logging.NewAccessLogger(zap.L(), logging.AccessLogConfig{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.resolver = redirect.NewResolver(r.geoIP)
+
+	r.server = grpc.NewServer(grpc.ChainUnaryInterceptor(r.requestLogger, r.authenticate))
+	pb.RegisterURLShortenerServer(r.server, r)
+
+	return r
+}
+
+func (r *rpcImpl) Start() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", r.port))
+	if err != nil {
+		return err
+	}
+	return r.server.Serve(lis)
+}
+
+func (r *rpcImpl) Stop() {
+	r.server.GracefulStop()
+}
+
+func (r *rpcImpl) Create(ctx context.Context, req *pb.CreateRequest) (*pb.ShortLink, error) {
+	shortLink, err := r.urlShortener.Upload(ctx, uploadParams(ownerFromContext(ctx), req))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toShortLink(shortLink), nil
+}
+
+func (r *rpcImpl) BatchCreate(ctx context.Context, req *pb.BatchCreateRequest) (*pb.BatchCreateResponse, error) {
+	if len(req.Requests) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "requests is empty")
+	}
+	// rejected before converting requests, though BatchUpload checks it as well
+	if len(req.Requests) > urlshortener.MaxBatchUploadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "requests should be at most %d", urlshortener.MaxBatchUploadSize)
+	}
+
+	owner := ownerFromContext(ctx)
+	params := make([]urlshortener.UploadParams, 0, len(req.Requests))
+	for _, createReq := range req.Requests {
+		params = append(params, uploadParams(owner, createReq))
+	}
+
+	shortLinks, err := r.urlShortener.BatchUpload(ctx, params)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	resp := &pb.BatchCreateResponse{ShortLinks: make([]*pb.ShortLink, 0, len(shortLinks))}
+	for _, shortLink := range shortLinks {
+		resp.ShortLinks = append(resp.ShortLinks, toShortLink(shortLink))
+	}
+	return resp, nil
+}
+
+func (r *rpcImpl) Get(ctx context.Context, req *pb.GetRequest) (*pb.ShortLink, error) {
+	shortLink, err := r.load(ctx, req.Domain, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	// owned short link is visible to its owner only, and it's not revealed to others
+	owner := ownerFromContext(ctx)
+	if shortLink.OwnerID != 0 && (owner == nil || owner.ID != shortLink.OwnerID) {
+		return nil, errNotFound
+	}
+
+	return toShortLink(shortLink), nil
+}
+
+func (r *rpcImpl) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	shortLink, err := r.load(ctx, req.Domain, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := url.ParseQuery(req.RawQuery)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "raw_query is invalid")
+	}
+	resolution, err := r.resolver.Resolve(shortLink, redirect.Request{
+		Path:           req.Path,
+		Query:          query,
+		UserAgent:      req.UserAgent,
+		AcceptLanguage: req.AcceptLanguage,
+		IP:             net.ParseIP(req.Ip),
+		Time:           r.clock.Now(),
+		Variant:        req.Variant,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("fail to resolve destination", zap.String("urlId", req.Id), zap.Error(err))
+		return nil, toStatus(ctx, err)
+	}
+
+	if req.RecordClick && !shortLink.Preview {
+		r.stats.Record(shortLink.ID, resolution.Variant)
+	}
+
+	return &pb.ResolveResponse{
+		Url:      resolution.URL,
+		Variant:  resolution.Variant,
+		Preview:  shortLink.Preview,
+		Fallback: resolution.Fallback,
+	}, nil
+}
+
+func (r *rpcImpl) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.ShortLink, error) {
+	if !urlshortener.IsValidURLID(req.Id) {
+		return nil, errNotFound
+	}
+
+	params := urlshortener.UpdateParams{
+		Owner:       ownerFromContext(ctx),
+		Domain:      req.Domain,
+		URLID:       req.Id,
+		URL:         req.Url,
+		Preview:     req.Preview,
+		QueryMode:   req.QueryMode,
+		Title:       req.Title,
+		Description: req.Description,
+		FallbackURL: req.FallbackUrl,
+		Status:      req.Status,
+	}
+	if req.ExpireAt != nil {
+		expireAt := req.ExpireAt.AsTime()
+		params.ExpireAt = &expireAt
+	}
+	if req.Utm != nil {
+		params.UTM = map[string]string{}
+		for k, v := range req.Utm.Values {
+			params.UTM[k] = v
+		}
+	}
+	if req.Rules != nil {
+		ruleList := toRules(req.Rules.Rules)
+		params.Rules = &ruleList
+	}
+	if req.Variants != nil {
+		variants := toVariants(req.Variants.Variants)
+		params.Variants = &variants
+	}
+	if req.Tags != nil {
+		tags := req.Tags.Tags
+		params.Tags = &tags
+	}
+
+	shortLink, err := r.urlShortener.Update(ctx, params)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toShortLink(shortLink), nil
+}
+
+func (r *rpcImpl) Delete(ctx context.Context, req *pb.DeleteRequest) (*emptypb.Empty, error) {
+	if !urlshortener.IsValidURLID(req.Id) {
+		return nil, errNotFound
+	}
+
+	var err error
+	if req.Purge {
+		err = r.urlShortener.Purge(ctx, ownerFromContext(ctx), req.Domain, req.Id)
+	} else {
+		err = r.urlShortener.Delete(ctx, ownerFromContext(ctx), req.Domain, req.Id)
+	}
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (r *rpcImpl) Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.ShortLink, error) {
+	if !urlshortener.IsValidURLID(req.Id) {
+		return nil, errNotFound
+	}
+
+	shortLink, err := r.urlShortener.Restore(ctx, ownerFromContext(ctx), req.Domain, req.Id)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toShortLink(shortLink), nil
+}
+
+// load returns the short link which is active and not expired, or NotFound status.
+func (r *rpcImpl) load(ctx context.Context, host, urlID string) (*dao.ShortLink, error) {
+	if !urlshortener.IsValidURLID(urlID) {
+		return nil, errNotFound
+	}
+
+	shortLink, err := r.urlShortener.LoadActive(ctx, host, urlID)
+	if errors.Is(err, urlshortener.ErrDomainNotFound) {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return shortLink, nil
+}
+
+// authenticate puts owner of the API key in metadata into context, requests without API key are anonymous.
+func (r *rpcImpl) authenticate(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	apiKeys := md.Get(metadataAPIKey)
+	if len(apiKeys) == 0 || apiKeys[0] == "" {
+		return handler(ctx, req)
+	}
+
+	owner, err := r.urlShortener.Authenticate(apiKeys[0])
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return handler(context.WithValue(ctx, ownerKey{}, owner), req)
+}
+
+func ownerFromContext(ctx context.Context) *dao.Owner {
+	owner, _ := ctx.Value(ownerKey{}).(*dao.Owner)
+	return owner
+}
+
+// toStatus maps errors of urlshortener to gRPC status, as rest maps them to HTTP status.
+func toStatus(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, urlshortener.ErrInvalidParams),
+		errors.Is(err, urlshortener.ErrInvalidURL),
+		errors.Is(err, urlshortener.ErrInvalidRules),
+		errors.Is(err, urlshortener.ErrInvalidVariants):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, urlshortener.ErrDomainNotFound):
+		return status.Error(codes.InvalidArgument, "domain is not registered")
+	case errors.Is(err, urlshortener.ErrDomainNotAllowed):
+		return status.Error(codes.PermissionDenied, "domain is not allowed")
+	case errors.Is(err, urlshortener.ErrOwnerRequired):
+		return status.Error(codes.Unauthenticated, "api key is required")
+	case errors.Is(err, urlshortener.ErrOwnerNotFound):
+		return status.Error(codes.Unauthenticated, "api key is invalid")
+	case errors.Is(err, urlshortener.ErrNotFound):
+		return errNotFound
+	case errors.Is(err, urlshortener.ErrExpired):
+		return status.Error(codes.NotFound, "short link is expired")
+	case errors.Is(err, urlshortener.ErrDisabled):
+		return status.Error(codes.NotFound, "short link is disabled")
+	case errors.Is(err, urlshortener.ErrDeleted):
+		return status.Error(codes.NotFound, "short link is deleted")
+	case errors.Is(err, urlshortener.ErrConflict):
+		return status.Error(codes.AlreadyExists, "id is used by another short link")
+	case errors.Is(err, urlshortener.ErrUnavailable):
+		return status.Error(codes.Unavailable, "service is temporarily unavailable")
+	default:
+		logging.FromContext(ctx).Error("internal error", zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// requestLogger puts logger with request ID and the source of the call for audit log into context, then writes
+// access log of the call. Request ID is propagated from x-request-id metadata, or generated if there is none, and
+// sent back in header metadata.
+func (r *rpcImpl) requestLogger(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var requestID string
+	if ids := md.Get(metadataRequestID); len(ids) > 0 && logging.IsValidRequestID(ids[0]) {
+		requestID = ids[0]
+	} else {
+		requestID = logging.NewRequestID()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(metadataRequestID, requestID)); err != nil {
+		return nil, err
+	}
+	logger := logging.FromContext(ctx).With(zap.String("requestId", requestID))
+	var sourceIP string
+	if p, ok := peer.FromContext(ctx); ok {
+		sourceIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(sourceIP); err == nil {
+			sourceIP = host
+		}
+	}
+
+	resp, err := handler(urlshortener.NewAuditContext(logging.NewContext(ctx, logger), sourceIP, requestID), req)
+
+	code := status.Code(err)
+	fields := []zap.Field{
+		zap.String("requestId", requestID),
+		zap.String("code", code.String()),
+		zap.Duration("latency", time.Since(start)),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, zap.String("remoteIp", p.Addr.String()))
+	}
+	if userAgents := md.Get("user-agent"); len(userAgents) > 0 {
+		fields = append(fields, zap.String("userAgent", userAgents[0]))
+	}
+	if urlIDReq, ok := req.(interface{ GetId() string }); ok && urlIDReq.GetId() != "" {
+		fields = append(fields, zap.String("urlId", urlIDReq.GetId()))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	r.accessLogger.Log(info.FullMethod, code == codes.Internal || code == codes.Unknown || code == codes.Unavailable,
+		fields...)
+
+	return resp, err
+}
@@ -0,0 +1,349 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/georgechang0117/url-shortener/core/dao"
+	"github.com/georgechang0117/url-shortener/core/split"
+	statsmocks "github.com/georgechang0117/url-shortener/core/stats/mocks"
+	"github.com/georgechang0117/url-shortener/core/urlshortener"
+	urlshortenermocks "github.com/georgechang0117/url-shortener/core/urlshortener/mocks"
+	"github.com/georgechang0117/url-shortener/rpc/pb"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const (
+	testURL    = "https://boards.greenhouse.io/dcard/jobs/871123?gh_src=ok8y1h1/"
+	testURLID  = "abcdefghijk"
+	testAPIKey = "test-api-key"
+)
+
+var (
+	testNow      = time.Date(2021, 7, 1, 0, 0, 00, 0, time.UTC)
+	testExpireAt = time.Date(2021, 7, 30, 0, 0, 00, 0, time.UTC)
+	testDomain   = dao.Domain{ID: 1, Host: "localhost:8080", Scheme: "http"}
+	testOwner    = dao.Owner{ID: 3, APIKey: testAPIKey}
+)
+
+type rpcTestSuite struct {
+	suite.Suite
+	impl             *rpcImpl
+	conn             *grpc.ClientConn
+	client           pb.URLShortenerClient
+	mockURLShortener *urlshortenermocks.URLShortener
+	mockStats        *statsmocks.Stats
+}
+
+func TestRPCTestSuite(t *testing.T) {
+	suite.Run(t, new(rpcTestSuite))
+}
+
+func (s *rpcTestSuite) SetupTest() {
+	s.mockURLShortener = &urlshortenermocks.URLShortener{}
+	s.mockStats = &statsmocks.Stats{}
+	s.impl = NewRPC(0, s.mockURLShortener, s.mockStats, fakeclock.NewFakeClock(testNow)).(*rpcImpl)
+
+	lis := bufconn.Listen(1 << 20)
+	go s.impl.server.Serve(lis)
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	s.Require().NoError(err)
+	s.conn = conn
+	s.client = pb.NewURLShortenerClient(conn)
+}
+
+func (s *rpcTestSuite) TearDownTest() {
+	s.conn.Close()
+	s.impl.Stop()
+}
+
+func (s *rpcTestSuite) authContext() context.Context {
+	s.mockURLShortener.On("Authenticate", testAPIKey).Return(&testOwner, nil).Once()
+	return metadata.AppendToOutgoingContext(context.Background(), metadataAPIKey, testAPIKey)
+}
+
+func (s *rpcTestSuite) TestCreate() {
+	s.mockURLShortener.On("Upload", mock.Anything, urlshortener.UploadParams{
+		URL:      testURL,
+		ExpireAt: testExpireAt,
+		UTM:      map[string]string{"utm_source": "grpc"},
+	}).Return(&dao.ShortLink{
+		URLID:    testURLID,
+		URL:      testURL,
+		UTM:      "utm_source=grpc",
+		ExpireAt: testExpireAt,
+		Domain:   &testDomain,
+	}, nil).Once()
+
+	resp, err := s.client.Create(context.Background(), &pb.CreateRequest{
+		Url:      testURL,
+		ExpireAt: timestamppb.New(testExpireAt),
+		Utm:      map[string]string{"utm_source": "grpc"},
+	})
+	s.Require().NoError(err)
+	s.Equal(testURLID, resp.Id)
+	s.Equal("http://localhost:8080/"+testURLID, resp.ShortUrl)
+	s.Equal(map[string]string{"utm_source": "grpc"}, resp.Utm)
+	s.True(testExpireAt.Equal(resp.ExpireAt.AsTime()))
+}
+
+func (s *rpcTestSuite) TestCreateWithOwner() {
+	s.mockURLShortener.On("Upload", mock.Anything, mock.MatchedBy(func(params urlshortener.UploadParams) bool {
+		return params.Owner != nil && params.Owner.ID == testOwner.ID && params.Domain == "go.example.com"
+	})).Return(&dao.ShortLink{URLID: testURLID, URL: testURL, Domain: &testDomain}, nil).Once()
+
+	_, err := s.client.Create(s.authContext(), &pb.CreateRequest{
+		Url:      testURL,
+		ExpireAt: timestamppb.New(testExpireAt),
+		Domain:   "go.example.com",
+	})
+	s.Require().NoError(err)
+}
+
+func (s *rpcTestSuite) TestCreateErrors() {
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{urlshortener.ErrInvalidParams, codes.InvalidArgument},
+		{urlshortener.ErrInvalidRules, codes.InvalidArgument},
+		{urlshortener.ErrInvalidVariants, codes.InvalidArgument},
+		{urlshortener.ErrDomainNotFound, codes.InvalidArgument},
+		{urlshortener.ErrDomainNotAllowed, codes.PermissionDenied},
+		{gorm.ErrInvalidDB, codes.Internal},
+	}
+	for _, c := range cases {
+		s.mockURLShortener.On("Upload", mock.Anything, mock.Anything).Return(nil, c.err).Once()
+
+		_, err := s.client.Create(context.Background(), &pb.CreateRequest{Url: testURL})
+		s.Equal(c.code, status.Code(err), c.err.Error())
+	}
+}
+
+func (s *rpcTestSuite) TestCreateInvalidAPIKey() {
+	s.mockURLShortener.On("Authenticate", "unknown").Return(nil, urlshortener.ErrOwnerNotFound).Once()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), metadataAPIKey, "unknown")
+	_, err := s.client.Create(ctx, &pb.CreateRequest{Url: testURL})
+	s.Equal(codes.Unauthenticated, status.Code(err))
+}
+
+func (s *rpcTestSuite) TestBatchCreate() {
+	s.mockURLShortener.On("BatchUpload", mock.Anything, mock.MatchedBy(func(params []urlshortener.UploadParams) bool {
+		return len(params) == 2 && params[1].URL == "https://example.com"
+	})).Return([]*dao.ShortLink{
+		{URLID: testURLID, URL: testURL, Domain: &testDomain},
+		{URLID: "bcdefghijkl", URL: "https://example.com", Domain: &testDomain},
+	}, nil).Once()
+
+	resp, err := s.client.BatchCreate(context.Background(), &pb.BatchCreateRequest{
+		Requests: []*pb.CreateRequest{
+			{Url: testURL, ExpireAt: timestamppb.New(testExpireAt)},
+			{Url: "https://example.com", ExpireAt: timestamppb.New(testExpireAt)},
+		},
+	})
+	s.Require().NoError(err)
+	s.Len(resp.ShortLinks, 2)
+	s.Equal("bcdefghijkl", resp.ShortLinks[1].Id)
+
+	_, err = s.client.BatchCreate(context.Background(), &pb.BatchCreateRequest{})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+
+	requests := make([]*pb.CreateRequest, urlshortener.MaxBatchUploadSize+1)
+	for i := range requests {
+		requests[i] = &pb.CreateRequest{Url: testURL, ExpireAt: timestamppb.New(testExpireAt)}
+	}
+	_, err = s.client.BatchCreate(context.Background(), &pb.BatchCreateRequest{Requests: requests})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *rpcTestSuite) TestGet() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(&dao.ShortLink{
+		URLID:    testURLID,
+		URL:      testURL,
+		Variants: split.Set{{Name: "a", URL: testURL, Weight: 1}, {Name: "b", URL: testURL, Weight: 2}},
+		ExpireAt: testExpireAt,
+		Domain:   &testDomain,
+	}, nil).Once()
+
+	resp, err := s.client.Get(context.Background(), &pb.GetRequest{Id: testURLID})
+	s.Require().NoError(err)
+	s.Equal(testURL, resp.Url)
+	s.Len(resp.Variants, 2)
+	s.Equal(int32(2), resp.Variants[1].Weight)
+}
+
+func (s *rpcTestSuite) TestGetNotFound() {
+	_, err := s.client.Get(context.Background(), &pb.GetRequest{Id: "invalid.id"})
+	s.Equal(codes.NotFound, status.Code(err))
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(nil, urlshortener.ErrExpired).Once()
+	_, err = s.client.Get(context.Background(), &pb.GetRequest{Id: testURLID})
+	s.Equal(codes.NotFound, status.Code(err))
+
+	s.mockURLShortener.On("LoadActive", mock.Anything, "unknown.example.com", testURLID).Return(nil, urlshortener.ErrDomainNotFound).Once()
+	_, err = s.client.Get(context.Background(), &pb.GetRequest{Domain: "unknown.example.com", Id: testURLID})
+	s.Equal(codes.NotFound, status.Code(err))
+
+	// owned short link is not visible to others
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(&dao.ShortLink{URLID: testURLID, URL: testURL, OwnerID: 4, ExpireAt: testExpireAt}, nil).Once()
+	_, err = s.client.Get(s.authContext(), &pb.GetRequest{Id: testURLID})
+	s.Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *rpcTestSuite) TestResolve() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(&dao.ShortLink{
+		ID:        5,
+		URLID:     testURLID,
+		URL:       "https://example.com/{path}",
+		QueryMode: dao.QueryModeOverride,
+		ExpireAt:  testExpireAt,
+		Domain:    &testDomain,
+	}, nil).Once()
+	s.mockStats.On("Record", uint64(5), "").Once()
+
+	resp, err := s.client.Resolve(context.Background(), &pb.ResolveRequest{
+		Id:          testURLID,
+		Path:        "docs",
+		RawQuery:    "a=1",
+		RecordClick: true,
+	})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/docs?a=1", resp.Url)
+	s.False(resp.Preview)
+	s.mockStats.AssertExpectations(s.T())
+}
+
+func (s *rpcTestSuite) TestResolveFallback() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(&dao.ShortLink{
+		URLID:       testURLID,
+		URL:         testURL,
+		FallbackURL: "https://example.com/fallback",
+		Broken:      true,
+		ExpireAt:    testExpireAt,
+		Domain:      &testDomain,
+	}, nil).Once()
+
+	resp, err := s.client.Resolve(context.Background(), &pb.ResolveRequest{Id: testURLID})
+	s.Require().NoError(err)
+	s.Equal("https://example.com/fallback", resp.Url)
+	s.True(resp.Fallback)
+}
+
+func (s *rpcTestSuite) TestResolveWithoutRecord() {
+	s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(&dao.ShortLink{
+		ID:       5,
+		URLID:    testURLID,
+		URL:      testURL,
+		ExpireAt: testExpireAt,
+		Domain:   &testDomain,
+	}, nil).Once()
+
+	resp, err := s.client.Resolve(context.Background(), &pb.ResolveRequest{Id: testURLID})
+	s.Require().NoError(err)
+	s.Equal(testURL, resp.Url)
+	s.mockStats.AssertNotCalled(s.T(), "Record", mock.Anything, mock.Anything)
+}
+
+func (s *rpcTestSuite) TestUpdate() {
+	url := "https://example.com"
+	s.mockURLShortener.On("Update", mock.Anything, mock.MatchedBy(func(params urlshortener.UpdateParams) bool {
+		return params.Owner.ID == testOwner.ID &&
+			*params.URL == url &&
+			params.Preview == nil &&
+			params.Rules == nil &&
+			params.Variants != nil && len(*params.Variants) == 0 &&
+			params.Title == nil &&
+			params.Tags != nil && len(*params.Tags) == 1
+	})).Return(&dao.ShortLink{URLID: testURLID, URL: url, OwnerID: testOwner.ID, Tags: dao.Tags{"docs"}, Domain: &testDomain}, nil).Once()
+
+	resp, err := s.client.Update(s.authContext(), &pb.UpdateRequest{
+		Id:       testURLID,
+		Url:      &url,
+		Variants: &pb.Variants{},
+		Tags:     &pb.Tags{Tags: []string{"docs"}},
+	})
+	s.Require().NoError(err)
+	s.Equal(url, resp.Url)
+	s.Equal([]string{"docs"}, resp.Tags)
+}
+
+func (s *rpcTestSuite) TestUpdateErrors() {
+	s.mockURLShortener.On("Update", mock.Anything, mock.Anything).Return(nil, urlshortener.ErrOwnerRequired).Once()
+	_, err := s.client.Update(context.Background(), &pb.UpdateRequest{Id: testURLID})
+	s.Equal(codes.Unauthenticated, status.Code(err))
+
+	s.mockURLShortener.On("Update", mock.Anything, mock.Anything).Return(nil, urlshortener.ErrNotFound).Once()
+	_, err = s.client.Update(s.authContext(), &pb.UpdateRequest{Id: testURLID})
+	s.Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *rpcTestSuite) TestDelete() {
+	s.mockURLShortener.On("Delete", mock.Anything, &testOwner, "", testURLID).Return(nil).Once()
+
+	_, err := s.client.Delete(s.authContext(), &pb.DeleteRequest{Id: testURLID})
+	s.Require().NoError(err)
+
+	s.mockURLShortener.On("Delete", mock.Anything, &testOwner, "", testURLID).Return(urlshortener.ErrNotFound).Once()
+	_, err = s.client.Delete(s.authContext(), &pb.DeleteRequest{Id: testURLID})
+	s.Equal(codes.NotFound, status.Code(err))
+
+	s.mockURLShortener.On("Purge", mock.Anything, &testOwner, "", testURLID).Return(nil).Once()
+	_, err = s.client.Delete(s.authContext(), &pb.DeleteRequest{Id: testURLID, Purge: true})
+	s.Require().NoError(err)
+}
+
+func (s *rpcTestSuite) TestRestore() {
+	s.mockURLShortener.On("Restore", mock.Anything, &testOwner, "", testURLID).Return(&dao.ShortLink{
+		URLID:   testURLID,
+		URL:     testURL,
+		OwnerID: testOwner.ID,
+		Status:  dao.StatusActive,
+		Domain:  &testDomain,
+	}, nil).Once()
+
+	resp, err := s.client.Restore(s.authContext(), &pb.RestoreRequest{Id: testURLID})
+	s.Require().NoError(err)
+	s.Equal(dao.StatusActive, resp.Status)
+	s.Nil(resp.DeletedAt)
+}
+
+func (s *rpcTestSuite) TestResolveNotActive() {
+	for _, err := range []error{urlshortener.ErrDisabled, urlshortener.ErrDeleted} {
+		s.mockURLShortener.On("LoadActive", mock.Anything, "", testURLID).Return(nil, err).Once()
+		_, err = s.client.Resolve(context.Background(), &pb.ResolveRequest{Id: testURLID})
+		s.Equal(codes.NotFound, status.Code(err))
+	}
+}
+
+func (s *rpcTestSuite) TestRequestID() {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), metadataRequestID, "client-request-id")
+	var header metadata.MD
+	_, err := s.client.Get(ctx, &pb.GetRequest{Id: "invalid.id"}, grpc.Header(&header))
+	s.Equal(codes.NotFound, status.Code(err))
+	s.Equal([]string{"client-request-id"}, header.Get(metadataRequestID))
+
+	_, err = s.client.Get(context.Background(), &pb.GetRequest{Id: "invalid.id"}, grpc.Header(&header))
+	s.Equal(codes.NotFound, status.Code(err))
+	s.Len(header.Get(metadataRequestID)[0], 32)
+}